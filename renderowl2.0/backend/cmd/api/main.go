@@ -16,6 +16,7 @@ import (
 	"renderowl-api/internal/middleware"
 	"renderowl-api/internal/repository"
 	"renderowl-api/internal/scheduler"
+	"renderowl-api/internal/secrets"
 	"renderowl-api/internal/service"
 	"renderowl-api/internal/service/social"
 	socialhandlers "renderowl-api/internal/handlers/social"
@@ -51,6 +52,17 @@ func main() {
 	socialPostRepo := repository.NewSocialPostRepository(db)
 	socialAnalyticsRepo := repository.NewSocialAnalyticsRepository(db)
 
+	// Encrypt social tokens at rest
+	keyring, err := secrets.LoadKeyring(cfg.TokenKeyringFile, cfg.TokenEncryptionKeyID, cfg.TokenEncryptionKeys)
+	if err != nil {
+		log.Fatalf("Failed to load token encryption keys: %v", err)
+	}
+	if keyring != nil {
+		socialAccountRepo.EnableEncryption(secrets.NewEnvelope(keyring))
+	} else {
+		log.Printf("Warning: Token encryption keys not configured, social tokens are stored in plaintext")
+	}
+
 	// Seed default templates
 	if err := templateRepo.SeedDefaultTemplates(); err != nil {
 		log.Printf("Warning: Failed to seed default templates: %v", err)
@@ -62,18 +74,38 @@ func main() {
 		redisAddr = "localhost:6379"
 	}
 	sched := scheduler.NewScheduler(redisAddr, os.Getenv("REDIS_PASSWORD"), 0)
+	sched.SetConcurrency(cfg.WorkerConcurrency)
 
 	// Initialize social media service
 	socialRegistry := social.NewPlatformRegistry()
 	socialService := social.NewService(socialRegistry, socialAccountRepo, socialPostRepo, socialAnalyticsRepo)
 	socialService.InitializePlatforms()
+	socialService.SetUploadSessions(repository.NewUploadSessionRepository(db))
+	oauthSessions := social.NewOAuthSessionStore(redisAddr, os.Getenv("REDIS_PASSWORD"))
+	defer oauthSessions.Close()
+	socialService.SetSessionStore(oauthSessions)
+	if webhookURL := os.Getenv("ACCOUNT_ALERT_WEBHOOK_URL"); webhookURL != "" {
+		socialService.SetNotifier(social.NewWebhookNotifier(webhookURL))
+	}
 
 	// Initialize publisher
-	publisher := service.NewPublisher(socialService, sched, socialPostRepo)
+	publisher := service.NewPublisher(socialService, sched, socialPostRepo, repository.NewPublishAttemptRepository(db))
 	publisher.Initialize()
 
-	// Start scheduler in background
-	go sched.ProcessJobs(context.Background())
+	// Review posts before publishing when a workspace requires approval
+	approvalService := service.NewApprovalService(socialPostRepo, repository.NewPostReviewRepository(db), socialService, publisher)
+	publisher.SetApprovals(approvalService)
+
+	// Defer publishing that would exceed platform rate limits and quotas
+	publishQuota := service.NewPublishQuota(redisAddr, os.Getenv("REDIS_PASSWORD"))
+	defer publishQuota.Close()
+	publisher.SetQuota(publishQuota)
+
+	// Refresh social tokens ahead of expiry
+	tokenRefresher := service.NewTokenRefresher(socialService, sched)
+	if err := tokenRefresher.Initialize(context.Background()); err != nil {
+		log.Printf("Warning: Failed to schedule token refresh: %v", err)
+	}
 
 	// Initialize services
 	timelineService := service.NewTimelineService(timelineRepo)
@@ -84,9 +116,52 @@ func main() {
 	aiSceneService := service.NewAISceneService()
 	ttsService := service.NewTTSService()
 	analyticsService := service.NewAnalyticsService(analyticsRepo)
+	postingTimeService := service.NewPostingTimeService(repository.NewPostingHistoryRepository(db))
+	feedService := service.NewFeedService(socialAccountRepo, socialPostRepo)
+	postSyncService := service.NewPostSyncService(socialPostRepo, socialService, approvalService)
+	inboxService := service.NewInboxService(repository.NewCommentRepository(db), socialPostRepo, socialService, sched)
+	if err := inboxService.Initialize(context.Background()); err != nil {
+		log.Printf("Warning: Failed to schedule comment sync: %v", err)
+	}
+	analyticsSyncService := service.NewAnalyticsSyncService(socialAnalyticsRepo, analyticsRepo, socialPostRepo, socialService, sched)
+	if err := analyticsSyncService.Initialize(context.Background()); err != nil {
+		log.Printf("Warning: Failed to schedule analytics sync: %v", err)
+	}
+	webhookWorker := service.NewWebhookWorker(analyticsRepo, socialPostRepo, socialService, sched)
+	if err := webhookWorker.Initialize(context.Background()); err != nil {
+		log.Printf("Warning: Failed to schedule webhook processing: %v", err)
+	}
 
-	// Initialize Content Factory services
+	// The optimizer reads the rolled up analytics; batch videos lead it
+	// back to the timelines they were rendered from
 	batchRepo := repository.NewBatchRepository(db)
+	publisher.SetBatchVideos(batchRepo)
+	optimizerService := service.NewOptimizerService(
+		service.NewOptimizerAnalytics(analyticsRepo, timelineRepo, batchRepo),
+		timelineRepo,
+		nil,
+		aiScriptService,
+	)
+
+	// Deliver analytics digests by email, or log them when SMTP is not
+	// configured
+	var mailer service.Mailer = service.LogMailer{}
+	if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+		mailer = service.NewSMTPMailer(smtpHost, os.Getenv("SMTP_PORT"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), os.Getenv("SMTP_FROM"))
+	}
+	digestService := service.NewDigestService(
+		repository.NewReportSubscriptionRepository(db),
+		analyticsService,
+		optimizerService,
+		mailer,
+		sched,
+		cfg.PublicURL,
+	)
+	if err := digestService.Initialize(context.Background()); err != nil {
+		log.Printf("Warning: Failed to schedule report digests: %v", err)
+	}
+
+	// Initialize Content Factory services
 	ideationService := service.NewIdeationService()
 	batchService, err := service.NewBatchService(
 		batchRepo,
@@ -103,8 +178,11 @@ func main() {
 	}
 	defer batchService.Close()
 	variationsService := service.NewVariationsService(nil) // Storage provider would be initialized here
-	// optimizerService := service.NewOptimizerService(analyticsRepo, timelineRepo, socialService, aiScriptService)
-	_ = socialService // Used for future optimizer service integration
+	calendarBatchService := service.NewCalendarBatchService(batchService, approvalService, socialService)
+
+	// Start scheduler in background, once every job handler is registered
+	// and the services the handlers use are wired
+	go sched.ProcessJobs(context.Background())
 
 	// Initialize handlers
 	timelineHandler := handlers.NewTimelineHandler(timelineService)
@@ -113,13 +191,24 @@ func main() {
 	templateHandler := handlers.NewTemplateHandler(templateService)
 	healthHandler := handlers.NewHealthHandler(db)
 	aiHandler := handlers.NewAIHandler(aiScriptService, aiSceneService, ttsService)
-	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
-	socialHandler := socialhandlers.NewSocialHandler(socialService, publisher, sched)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService, service.NewWebhookVerifier(service.WebhookSecrets{
+		YouTube:         os.Getenv("YOUTUBE_WEBHOOK_SECRET"),
+		Facebook:        os.Getenv("FACEBOOK_APP_SECRET"),
+		Instagram:       os.Getenv("INSTAGRAM_APP_SECRET"),
+		Threads:         os.Getenv("THREADS_APP_SECRET"),
+		MetaVerifyToken: os.Getenv("META_WEBHOOK_VERIFY_TOKEN"),
+		TikTok:          os.Getenv("TIKTOK_CLIENT_SECRET"),
+	}))
+	reportHandler := handlers.NewReportHandler(digestService)
+	socialHandler := socialhandlers.NewSocialHandler(socialService, publisher, approvalService, sched, postingTimeService, publishQuota, feedService, postSyncService, inboxService)
+	streamTokens := middleware.NewStreamTokens(cfg.StreamTokenSecret)
 	contentFactoryHandler := handlers.NewContentFactoryHandler(
 		ideationService,
 		batchService,
 		variationsService,
-		nil, // optimizerService - disabled due to interface mismatch
+		optimizerService,
+		calendarBatchService,
+		streamTokens,
 	)
 
 	// Setup router
@@ -134,8 +223,19 @@ func main() {
 	r.GET("/health/live", healthHandler.LivenessCheck)
 
 	// Webhook routes (public but with platform-specific validation)
+	r.GET("/webhooks/:platform", analyticsHandler.VerifyWebhook)
 	r.POST("/webhooks/:platform", analyticsHandler.ReceiveWebhook)
 
+	// Workspace feeds (public, addressed by their secret token)
+	r.GET("/feeds/:token/:format", socialHandler.GetFeed)
+
+	// Digest recipients confirm from a signed link in their email
+	r.GET("/reports/confirm", reportHandler.ConfirmRecipient)
+
+	// Batch event streams are opened by EventSource, which cannot send an
+	// Authorization header, so they also accept a stream token
+	r.GET("/api/v1/batch/:id/events", streamTokens.Auth(middleware.Auth(cfg)), contentFactoryHandler.StreamBatchEvents)
+
 	// Protected API routes
 	api := r.Group("/api/v1")
 	api.Use(middleware.Auth(cfg))
@@ -184,6 +284,16 @@ func main() {
 		api.GET("/analytics/engagement", analyticsHandler.GetEngagementMetrics)
 		api.GET("/analytics/growth", analyticsHandler.GetUserGrowth)
 		api.GET("/analytics/export", analyticsHandler.ExportAnalytics)
+		// Webhook events are received for every workspace, so only
+		// operators can inspect and replay them
+		operator := middleware.RequireOperator(cfg)
+		api.GET("/analytics/webhooks", operator, analyticsHandler.ListWebhookEvents)
+		api.POST("/analytics/webhooks/:id/retry", operator, analyticsHandler.RetryWebhookEvent)
+		api.GET("/analytics/reports", reportHandler.ListSubscriptions)
+		api.POST("/analytics/reports", reportHandler.CreateSubscription)
+		api.PUT("/analytics/reports/:id", reportHandler.UpdateSubscription)
+		api.DELETE("/analytics/reports/:id", reportHandler.DeleteSubscription)
+		api.POST("/analytics/reports/:id/send", reportHandler.SendSubscription)
 		
 		// Analytics tracking endpoints
 		api.POST("/analytics/track/view", analyticsHandler.TrackView)
@@ -194,13 +304,36 @@ func main() {
 		api.GET("/social/accounts", socialHandler.GetAccounts)
 		api.GET("/social/accounts/:id", socialHandler.GetAccount)
 		api.DELETE("/social/accounts/:id", socialHandler.DisconnectAccount)
+		api.POST("/social/accounts/:id/refresh", socialHandler.RefreshAccount)
+		api.GET("/social/accounts/:id/best-times", socialHandler.GetBestTimes)
 		api.GET("/social/connect/:platform", socialHandler.GetAuthURL)
 		api.POST("/social/callback/:platform", socialHandler.HandleCallback)
+		api.POST("/social/connect/:platform/password", socialHandler.ConnectWithPassword)
+		api.POST("/social/destinations/:platform", socialHandler.CreateDestination)
 		api.POST("/social/upload", socialHandler.UploadVideo)
 		api.POST("/social/crosspost", socialHandler.CrossPost)
 		api.POST("/social/schedule", socialHandler.SchedulePost)
 		api.GET("/social/schedule", socialHandler.GetScheduledPosts)
+		api.GET("/social/schedule/:id/occurrences", socialHandler.GetOccurrences)
 		api.DELETE("/social/schedule/:id", socialHandler.CancelScheduledPost)
+		api.POST("/social/schedule/:id/submit", socialHandler.SubmitForReview)
+		api.PUT("/social/schedule/:id/reviewer", socialHandler.AssignReviewer)
+		api.POST("/social/schedule/:id/approve", socialHandler.ApprovePost)
+		api.POST("/social/schedule/:id/reject", socialHandler.RejectPost)
+		api.POST("/social/schedule/:id/comments", socialHandler.CommentOnPost)
+		api.GET("/social/schedule/:id/reviews", socialHandler.GetReviewHistory)
+		api.GET("/social/reviews", socialHandler.GetReviewQueue)
+		api.GET("/social/approval-policy", socialHandler.GetApprovalPolicy)
+		api.PUT("/social/approval-policy", socialHandler.UpdateApprovalPolicy)
+		api.PATCH("/social/posts/:id", socialHandler.UpdatePost)
+		api.DELETE("/social/posts/:id", socialHandler.DeletePost)
+		api.GET("/social/inbox", socialHandler.GetInbox)
+		api.POST("/social/inbox/read", socialHandler.MarkCommentsRead)
+		api.GET("/social/inbox/:id", socialHandler.GetCommentThread)
+		api.POST("/social/inbox/:id/reply", socialHandler.ReplyToComment)
+		api.POST("/social/inbox/:id/hide", socialHandler.HideComment)
+		api.POST("/social/inbox/:id/unhide", socialHandler.UnhideComment)
+		api.DELETE("/social/inbox/:id", socialHandler.DeleteComment)
 		api.POST("/social/publish/:id", socialHandler.PublishNow)
 		api.POST("/social/retry/:id", socialHandler.RetryPost)
 		api.GET("/social/queue", socialHandler.GetPublishingQueue)
@@ -217,10 +350,15 @@ func main() {
 		// Content Factory - Batch endpoints
 		api.GET("/batch", contentFactoryHandler.ListBatches)
 		api.POST("/batch/generate", contentFactoryHandler.CreateBatch)
+		api.POST("/batch/import", contentFactoryHandler.ImportBatch)
+		api.POST("/batch/from-calendar", contentFactoryHandler.CreateBatchFromCalendar)
 		api.POST("/batch/:id/start", contentFactoryHandler.StartBatch)
 		api.GET("/batch/:id/status", contentFactoryHandler.GetBatchStatus)
+		api.POST("/batch/:id/events/token", contentFactoryHandler.CreateBatchStreamToken)
 		api.GET("/batch/:id/results", contentFactoryHandler.GetBatchResults)
 		api.POST("/batch/:id/cancel", contentFactoryHandler.CancelBatch)
+		api.POST("/batch/:id/pause", contentFactoryHandler.PauseBatch)
+		api.POST("/batch/:id/resume", contentFactoryHandler.ResumeBatch)
 		api.POST("/batch/:id/retry", contentFactoryHandler.RetryFailedVideos)
 		api.GET("/batch/queue/stats", contentFactoryHandler.GetQueueStats)
 
@@ -263,11 +401,19 @@ func migrateDB(db *gorm.DB) error {
 		&domain.VideoPerformance{},
 		&domain.PlatformStats{},
 		&domain.WebhookEvent{},
+		&domain.VideoRetention{},
+		&domain.ReportSubscription{},
 		// Social media models
 		&socialdomain.SocialAccount{},
 		&socialdomain.ScheduledPost{},
 		&socialdomain.PlatformPost{},
 		&socialdomain.AnalyticsData{},
 		&socialdomain.PlatformTrend{},
+		&socialdomain.UploadSession{},
+		&socialdomain.PublishAttempt{},
+		&socialdomain.PostReviewEvent{},
+		&socialdomain.ApprovalPolicy{},
+		&socialdomain.Comment{},
+		&repository.TokenAuditModel{},
 	)
 }
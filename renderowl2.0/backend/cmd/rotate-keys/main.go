@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"renderowl-api/internal/config"
+	"renderowl-api/internal/repository"
+	"renderowl-api/internal/secrets"
+)
+
+// rotate-keys re-encrypts social account tokens under the current
+// key-encryption key. Run it after adding a new key and making it current;
+// the old key can be removed from the keyring once it reports no rows left.
+func main() {
+	batchSize := flag.Int("batch-size", 100, "accounts to load per page")
+	dryRun := flag.Bool("dry-run", false, "count accounts needing rotation without writing")
+	flag.Parse()
+
+	// Load configuration
+	cfg := config.Load()
+
+	keyring, err := secrets.LoadKeyring(cfg.TokenKeyringFile, cfg.TokenEncryptionKeyID, cfg.TokenEncryptionKeys)
+	if err != nil {
+		log.Fatalf("Failed to load token encryption keys: %v", err)
+	}
+	if keyring == nil {
+		log.Fatalf("Token encryption keys not configured, set TOKEN_KEYRING_FILE or TOKEN_ENCRYPTION_KEYS")
+	}
+
+	// Connect to database. Migrations are owned by the API process.
+	db, err := gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	socialAccountRepo := repository.NewSocialAccountRepository(db)
+	socialAccountRepo.EnableEncryption(secrets.NewEnvelope(keyring))
+
+	rotated, err := socialAccountRepo.RotateKeys(context.Background(), *batchSize, *dryRun)
+	if err != nil {
+		log.Fatalf("Key rotation stopped after %d accounts: %v", rotated, err)
+	}
+
+	if *dryRun {
+		log.Printf("%d accounts need rotation to key %s", rotated, keyring.CurrentKeyID())
+		return
+	}
+	log.Printf("Rotated %d accounts to key %s", rotated, keyring.CurrentKeyID())
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/hibiken/asynq"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"renderowl-api/internal/config"
+	"renderowl-api/internal/repository"
+	"renderowl-api/internal/service"
+)
+
+func main() {
+	// Load configuration
+	cfg := config.Load()
+
+	// Connect to database. Migrations are owned by the API process.
+	db, err := gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	// Initialize repositories
+	timelineRepo := repository.NewTimelineRepository(db)
+	clipRepo := repository.NewClipRepository(db)
+	batchRepo := repository.NewBatchRepository(db)
+
+	// Initialize services
+	timelineService := service.NewTimelineService(timelineRepo)
+	clipService := service.NewClipService(clipRepo, timelineRepo)
+	aiScriptService := service.NewAIScriptService()
+	aiSceneService := service.NewAISceneService()
+	ttsService := service.NewTTSService()
+
+	redisAddr := os.Getenv("REDIS_ADDR")
+	if redisAddr == "" {
+		redisAddr = "localhost:6379"
+	}
+	redisPassword := os.Getenv("REDIS_PASSWORD")
+
+	batchService, err := service.NewBatchService(
+		batchRepo,
+		redisAddr,
+		redisPassword,
+		timelineService,
+		clipService,
+		aiScriptService,
+		aiSceneService,
+		ttsService,
+	)
+	if err != nil {
+		log.Fatalf("Failed to initialize batch service: %v", err)
+	}
+	defer batchService.Close()
+
+	// Requeue the videos whose tasks were lost while no worker was running
+	if err := batchService.RecoverBatches(context.Background()); err != nil {
+		log.Printf("Warning: Failed to recover batches: %v", err)
+	}
+
+	// Per-batch concurrency is capped by each batch's MaxConcurrent;
+	// WORKER_CONCURRENCY caps the videos this process renders at once.
+	srv := asynq.NewServer(
+		asynq.RedisClientOpt{
+			Addr:     redisAddr,
+			Password: redisPassword,
+			DB:       0,
+		},
+		asynq.Config{
+			Concurrency: cfg.WorkerConcurrency,
+			Queues:      map[string]int{"batch": 1},
+		},
+	)
+
+	mux := asynq.NewServeMux()
+	batchService.RegisterHandlers(mux)
+
+	log.Printf("Worker starting with concurrency %d", cfg.WorkerConcurrency)
+	if err := srv.Run(mux); err != nil {
+		log.Fatalf("Failed to start worker: %v", err)
+	}
+}
@@ -1,17 +1,22 @@
 package domain
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
 // AnalyticsView represents a video view record
 type AnalyticsView struct {
-	ID        string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	VideoID   string    `gorm:"index;not null"`
-	UserID    string    `gorm:"index"`
-	Platform  string    `gorm:"index;not null"` // youtube, tiktok, instagram, etc.
-	Count     int64     `gorm:"default:1"`
-	Date      time.Time `gorm:"index;not null"`
+	ID        string     `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	VideoID   string     `gorm:"index;not null"`
+	UserID    string     `gorm:"index"`
+	Platform  string     `gorm:"index;not null"` // youtube, tiktok, instagram, etc.
+	Count     int64      `gorm:"default:1"`
+	Date      time.Time  `gorm:"index;not null"`
+	Hour      *time.Time `gorm:"index"` // hour the views happened in, when it is known
 	IPAddress string
 	Country   string
 	CreatedAt time.Time
@@ -79,16 +84,16 @@ func (Revenue) TableName() string {
 
 // VideoPerformance aggregates metrics per video
 type VideoPerformance struct {
-	ID             string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	VideoID        string    `gorm:"uniqueIndex;not null"`
-	UserID         string    `gorm:"index;not null"`
+	ID             string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	VideoID        string `gorm:"uniqueIndex;not null"`
+	UserID         string `gorm:"index;not null"`
 	Title          string
-	TotalViews     int64     `gorm:"default:0"`
-	TotalLikes     int64     `gorm:"default:0"`
-	TotalComments  int64     `gorm:"default:0"`
-	TotalShares    int64     `gorm:"default:0"`
-	EngagementRate float64   `gorm:"default:0"`
-	Platforms      []string  `gorm:"type:text[]"`
+	TotalViews     int64       `gorm:"default:0"`
+	TotalLikes     int64       `gorm:"default:0"`
+	TotalComments  int64       `gorm:"default:0"`
+	TotalShares    int64       `gorm:"default:0"`
+	EngagementRate float64     `gorm:"default:0"`
+	Platforms      StringArray `gorm:"type:text[]"`
 	PublishedAt    *time.Time
 	LastUpdated    time.Time
 	CreatedAt      time.Time
@@ -119,20 +124,180 @@ func (PlatformStats) TableName() string {
 
 // WebhookEvent stores incoming analytics webhooks
 type WebhookEvent struct {
-	ID          string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	Platform    string    `gorm:"not null"` // youtube, tiktok, instagram
-	EventType   string    `gorm:"not null"` // view, like, share, etc.
-	VideoID     string    `gorm:"index"`
-	Payload     JSON      `gorm:"type:jsonb"`
-	Processed   bool      `gorm:"default:false"`
-	ProcessedAt *time.Time
-	CreatedAt   time.Time
+	ID            string     `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Platform      string     `gorm:"not null"` // youtube, tiktok, instagram
+	EventType     string     `gorm:"not null"` // view, like, share, etc.
+	VideoID       string     `gorm:"index"`
+	DeliveryID    string     `gorm:"index:idx_webhook_events_delivery,unique,where:delivery_id <> ''"` // platform and delivery, for dedupe
+	Payload       JSON       `gorm:"type:jsonb"`
+	Status        string     `gorm:"index;default:'pending'"` // pending, processed, dead
+	Attempts      int        `gorm:"default:0"`
+	NextAttemptAt *time.Time `gorm:"index"`
+	LastError     string
+	Processed     bool       `gorm:"default:false"`
+	ProcessedAt   *time.Time
+	CreatedAt     time.Time
 }
 
+// Webhook event statuses. Events that keep failing are moved to the dead
+// letter status, where they wait to be inspected and retried.
+const (
+	WebhookEventPending   = "pending"
+	WebhookEventProcessed = "processed"
+	WebhookEventDead      = "dead"
+)
+
 // TableName specifies the table name for WebhookEvent
 func (WebhookEvent) TableName() string {
 	return "analytics_webhook_events"
 }
 
+// VideoRetention is the latest audience retention curve of a video on a
+// platform
+type VideoRetention struct {
+	ID        string         `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	VideoID   string         `gorm:"uniqueIndex:idx_video_retention_platform;not null"`
+	Platform  string         `gorm:"uniqueIndex:idx_video_retention_platform;not null"`
+	Points    RetentionCurve `gorm:"type:jsonb"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName specifies the table name for VideoRetention
+func (VideoRetention) TableName() string {
+	return "analytics_video_retention"
+}
+
+// RetentionPoint is the share of viewers still watching at a point of a
+// video, from 0 at its start to 1 at its end
+type RetentionPoint struct {
+	Position   float64 `json:"position"`
+	WatchRatio float64 `json:"watch_ratio"`
+}
+
+// RetentionCurve is a jsonb column of retention points, ordered by position
+type RetentionCurve []RetentionPoint
+
+// Value implements driver.Valuer for database storage
+func (c RetentionCurve) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner for database retrieval
+func (c *RetentionCurve) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	default:
+		return fmt.Errorf("cannot scan %T into RetentionCurve", value)
+	}
+}
+
 // JSON is a custom type for JSON fields
 type JSON map[string]interface{}
+
+// Value implements driver.Valuer for database storage
+func (j JSON) Value() (driver.Value, error) {
+	if j == nil {
+		return nil, nil
+	}
+	return json.Marshal(j)
+}
+
+// Scan implements sql.Scanner for database retrieval
+func (j *JSON) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*j = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, j)
+	case string:
+		return json.Unmarshal([]byte(v), j)
+	default:
+		return fmt.Errorf("cannot scan %T into JSON", value)
+	}
+}
+
+// StringArray is a Postgres text[] column
+type StringArray []string
+
+// Value implements driver.Valuer for database storage
+func (a StringArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	quoted := make([]string, len(a))
+	for i, s := range a {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+		s = strings.ReplaceAll(s, `"`, `\"`)
+		quoted[i] = `"` + s + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}", nil
+}
+
+// Scan implements sql.Scanner for database retrieval
+func (a *StringArray) Scan(value interface{}) error {
+	var literal string
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		literal = string(v)
+	case string:
+		literal = v
+	default:
+		return fmt.Errorf("cannot scan %T into StringArray", value)
+	}
+
+	if len(literal) < 2 || literal[0] != '{' || literal[len(literal)-1] != '}' {
+		return fmt.Errorf("invalid array literal %q", literal)
+	}
+	literal = literal[1 : len(literal)-1]
+
+	result := StringArray{}
+	var elem strings.Builder
+	quoted, inQuotes, escaped := false, false, false
+	for _, r := range literal {
+		switch {
+		case escaped:
+			elem.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			inQuotes = !inQuotes
+			quoted = true
+		case r == ',' && !inQuotes:
+			result = append(result, arrayElement(elem.String(), quoted))
+			elem.Reset()
+			quoted = false
+		default:
+			elem.WriteRune(r)
+		}
+	}
+	if elem.Len() > 0 || quoted {
+		result = append(result, arrayElement(elem.String(), quoted))
+	}
+
+	*a = result
+	return nil
+}
+
+// arrayElement reads an element of an array literal, where an unquoted
+// NULL is a null element, stored as an empty string
+func arrayElement(s string, quoted bool) string {
+	if !quoted && s == "NULL" {
+		return ""
+	}
+	return s
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Batch represents a batch video generation job
 type Batch struct {
@@ -37,22 +40,33 @@ const (
 	BatchStatusPaused     BatchStatus = "paused"
 )
 
+// IsTerminal reports whether the batch has stopped processing
+func (s BatchStatus) IsTerminal() bool {
+	switch s {
+	case BatchStatusCompleted, BatchStatusFailed, BatchStatusCancelled:
+		return true
+	}
+	return false
+}
+
 // BatchVideo represents a single video in a batch
 type BatchVideo struct {
-	ID          string            `json:"id"`
-	BatchID     string            `json:"batchId"`
-	Title       string            `json:"title"`
-	Description string            `json:"description"`
-	Status      VideoStatus       `json:"status"`
-	TimelineID  string            `json:"timelineId,omitempty"`
-	Error       string            `json:"error,omitempty"`
-	Progress    float64           `json:"progress"`
-	Config      VideoConfig       `json:"config"`
-	Result      *VideoResult      `json:"result,omitempty"`
-	CreatedAt   time.Time         `json:"createdAt"`
-	UpdatedAt   time.Time         `json:"updatedAt"`
-	StartedAt   *time.Time        `json:"startedAt,omitempty"`
-	CompletedAt *time.Time        `json:"completedAt,omitempty"`
+	ID          string          `json:"id"`
+	BatchID     string          `json:"batchId"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Status      VideoStatus     `json:"status"`
+	Step        VideoStep       `json:"step,omitempty"`
+	TimelineID  string          `json:"timelineId,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Progress    float64         `json:"progress"`
+	Config      VideoConfig     `json:"config"`
+	Result      *VideoResult    `json:"result,omitempty"`
+	Checkpoint  VideoCheckpoint `json:"-"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	StartedAt   *time.Time      `json:"startedAt,omitempty"`
+	CompletedAt *time.Time      `json:"completedAt,omitempty"`
 }
 
 // VideoStatus represents the status of a single video
@@ -66,8 +80,33 @@ const (
 	VideoStatusFailed     VideoStatus = "failed"
 	VideoStatusSkipped    VideoStatus = "skipped"
 	VideoStatusCancelled  VideoStatus = "cancelled"
+	VideoStatusPaused     VideoStatus = "paused"
+)
+
+// IsTerminal reports whether the video will not be processed any further
+func (s VideoStatus) IsTerminal() bool {
+	switch s {
+	case VideoStatusCompleted, VideoStatusFailed, VideoStatusSkipped, VideoStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// VideoStep represents a stage of the video generation pipeline
+type VideoStep string
+
+const (
+	VideoStepScript   VideoStep = "script"
+	VideoStepScenes   VideoStep = "scenes"
+	VideoStepVoice    VideoStep = "voice"
+	VideoStepTimeline VideoStep = "timeline"
+	VideoStepDone     VideoStep = "done"
 )
 
+// VideoCheckpoint holds the output of each finished pipeline step so a
+// retried video resumes at the step that failed
+type VideoCheckpoint map[VideoStep]json.RawMessage
+
 // BatchConfig contains configuration for the batch
 type BatchConfig struct {
 	TemplateID             string                 `json:"templateId,omitempty"`
@@ -108,6 +147,12 @@ type BatchRepository interface {
 	Create(batch *Batch) error
 	Get(id string) (*Batch, error)
 	Update(batch *Batch) error
+	UpdateStatus(batchID string, status BatchStatus) error
 	List(userID string, limit, offset int) ([]*Batch, error)
 	Delete(id string) error
+	GetVideo(id string) (*BatchVideo, error)
+	UpdateVideo(video *BatchVideo) error
+	RefreshProgress(batchID string) (*Batch, error)
+	ListByStatus(statuses ...BatchStatus) ([]*Batch, error)
+	ClaimVideos(batchID string, maxActive int) ([]*BatchVideo, error)
 }
@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// Report cadences
+const (
+	ReportCadenceDaily   = "daily"
+	ReportCadenceWeekly  = "weekly"
+	ReportCadenceMonthly = "monthly"
+)
+
+// Report delivery channels
+const (
+	ReportChannelEmail   = "email"
+	ReportChannelWebhook = "webhook"
+)
+
+// Report sections
+const (
+	ReportSectionSummary     = "summary"     // dashboard summary
+	ReportSectionPlatforms   = "platforms"   // platform breakdown
+	ReportSectionPerformance = "performance" // optimizer performance report
+)
+
+// ReportSubscription is a recurring analytics digest delivered by email or
+// to an incoming webhook, such as a Slack channel
+type ReportSubscription struct {
+	ID                  string      `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	UserID              string      `gorm:"index;not null" json:"user_id"`
+	Name                string      `gorm:"not null" json:"name"`
+	Cadence             string      `gorm:"not null" json:"cadence"` // daily, weekly, monthly
+	Weekday             int         `json:"weekday"`                 // weekly digests, 0 is Sunday
+	Hour                int         `json:"hour"`                    // hour of the day the digest is sent
+	Timezone            string      `gorm:"default:'UTC'" json:"timezone"`
+	Channel             string      `gorm:"not null" json:"channel"` // email, webhook
+	Recipients          StringArray `gorm:"type:text[]" json:"recipients,omitempty"`
+	ConfirmedRecipients StringArray `gorm:"type:text[]" json:"confirmed_recipients,omitempty"` // recipients the digest is sent to
+	ConfirmKey          string      `json:"-"`                                                 // signs the confirmation links sent to recipients
+	WebhookURL          string      `json:"webhook_url,omitempty"`
+	Sections            StringArray `gorm:"type:text[]" json:"sections"`
+	Enabled             bool        `gorm:"default:true" json:"enabled"`
+	NextRunAt           time.Time   `gorm:"index" json:"next_run_at"`
+	LastSentAt          *time.Time  `json:"last_sent_at,omitempty"`
+	LastManualSendAt    *time.Time  `json:"-"` // last digest sent on request, to limit how often that can happen
+	LastError           string      `json:"last_error,omitempty"`
+	CreatedAt           time.Time   `json:"created_at"`
+	UpdatedAt           time.Time   `json:"updated_at"`
+}
+
+// TableName specifies the table name for ReportSubscription
+func (ReportSubscription) TableName() string {
+	return "analytics_report_subscriptions"
+}
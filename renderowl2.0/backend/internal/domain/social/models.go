@@ -1,6 +1,9 @@
 package social
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -14,6 +17,14 @@ const (
 	PlatformTwitter   SocialPlatform = "twitter"
 	PlatformLinkedIn  SocialPlatform = "linkedin"
 	PlatformFacebook  SocialPlatform = "facebook"
+	PlatformPinterest SocialPlatform = "pinterest"
+	PlatformThreads   SocialPlatform = "threads"
+	PlatformSnapchat  SocialPlatform = "snapchat"
+	PlatformBluesky   SocialPlatform = "bluesky"
+
+	// Custom destinations publish outside social networks
+	PlatformWebhook SocialPlatform = "webhook"
+	PlatformFeed    SocialPlatform = "feed"
 )
 
 // PlatformStatus represents the connection status of a platform
@@ -30,12 +41,16 @@ const (
 type PostStatus string
 
 const (
-	PostStatusDraft      PostStatus = "draft"
-	PostStatusScheduled  PostStatus = "scheduled"
-	PostStatusPublishing PostStatus = "publishing"
-	PostStatusPublished  PostStatus = "published"
-	PostStatusFailed     PostStatus = "failed"
-	PostStatusCancelled  PostStatus = "cancelled"
+	PostStatusDraft         PostStatus = "draft"
+	PostStatusPendingReview PostStatus = "pending_review"
+	PostStatusApproved      PostStatus = "approved"
+	PostStatusRejected      PostStatus = "rejected"
+	PostStatusScheduled     PostStatus = "scheduled"
+	PostStatusPublishing    PostStatus = "publishing"
+	PostStatusPublished     PostStatus = "published"
+	PostStatusFailed        PostStatus = "failed"
+	PostStatusCancelled     PostStatus = "cancelled"
+	PostStatusDeleted       PostStatus = "deleted" // removed from the platform after publishing
 )
 
 // SocialAccount represents a connected social media account
@@ -49,6 +64,8 @@ type SocialAccount struct {
 	RefreshToken string         `json:"-" gorm:"column:refresh_token"`
 	TokenExpiry  *time.Time     `json:"tokenExpiry"`
 	Status       PlatformStatus `json:"status"`
+	StatusReason string         `json:"statusReason,omitempty"`
+	LastRefresh  *time.Time     `json:"lastRefresh,omitempty"`
 	Metadata     JSON           `json:"metadata" gorm:"type:jsonb"`
 	CreatedAt    time.Time      `json:"createdAt"`
 	UpdatedAt    time.Time      `json:"updatedAt"`
@@ -65,7 +82,12 @@ type ScheduledPost struct {
 	ScheduledAt time.Time      `json:"scheduledAt"`
 	Timezone    string         `json:"timezone"`
 	Status      PostStatus     `json:"status"`
-	Recurring   *RecurringRule `json:"recurring,omitempty" gorm:"-"`
+	Recurring   *RecurringRule `json:"recurring,omitempty" gorm:"type:jsonb"`
+	SeriesID    string         `json:"seriesId,omitempty" gorm:"index"` // first post of a recurring series
+	Occurrence  int            `json:"occurrence,omitempty"`            // position in the series, starting at 1
+	ReviewerID  string         `json:"reviewerId,omitempty" gorm:"index"`
+	ApprovedBy  string         `json:"approvedBy,omitempty"`
+	ApprovedAt  *time.Time     `json:"approvedAt,omitempty"`
 	Metadata    JSON           `json:"metadata" gorm:"type:jsonb"`
 	CreatedAt   time.Time      `json:"createdAt"`
 	UpdatedAt   time.Time      `json:"updatedAt"`
@@ -80,6 +102,7 @@ type PlatformPost struct {
 	Platform        SocialPlatform `json:"platform"`
 	AccountID       string         `json:"accountId"`
 	Status          PostStatus     `json:"status"`
+	IdempotencyKey  string         `json:"idempotencyKey" gorm:"index"` // identifies this publish across retries
 	PlatformPostID  string         `json:"platformPostId,omitempty"`
 	PostURL         string         `json:"postUrl,omitempty"`
 	CustomTitle     string         `json:"customTitle,omitempty"`
@@ -93,13 +116,34 @@ type PlatformPost struct {
 	PublishedAt     *time.Time     `json:"publishedAt"`
 }
 
-// RecurringRule defines how a post should repeat
+// RecurringRule defines how a post should repeat. RRule takes precedence
+// over the simple fields when set.
 type RecurringRule struct {
-	Frequency  string   `json:"frequency"` // daily, weekly, monthly
-	Interval   int      `json:"interval"`  // every N days/weeks/months
-	DaysOfWeek []int    `json:"daysOfWeek,omitempty"`
-	EndDate    *string  `json:"endDate,omitempty"`
-	EndAfter   *int     `json:"endAfter,omitempty"`
+	RRule      string  `json:"rrule,omitempty"`      // RFC 5545 RRULE, e.g. FREQ=WEEKLY;BYDAY=MO,WE
+	Frequency  string  `json:"frequency,omitempty"`  // hourly, daily, weekly, monthly, yearly
+	Interval   int     `json:"interval,omitempty"`   // every N hours/days/weeks/months/years
+	DaysOfWeek []int   `json:"daysOfWeek,omitempty"` // 0 is Sunday
+	EndDate    *string `json:"endDate,omitempty"`    // YYYY-MM-DD or RFC 3339
+	EndAfter   *int    `json:"endAfter,omitempty"`   // total number of occurrences
+}
+
+// Value implements driver.Valuer for database storage
+func (r RecurringRule) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+// Scan implements sql.Scanner for database retrieval
+func (r *RecurringRule) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	default:
+		return fmt.Errorf("cannot scan %T into RecurringRule", value)
+	}
 }
 
 // AnalyticsData represents platform analytics for a post
@@ -118,6 +162,13 @@ type AnalyticsData struct {
 	RecordedAt   time.Time      `json:"recordedAt"`
 }
 
+// RetentionPoint is how many of a video's viewers are still watching at a
+// point of the video
+type RetentionPoint struct {
+	Position   float64 `json:"position"`   // elapsed share of the video, 0 to 1
+	WatchRatio float64 `json:"watchRatio"` // viewers watching per view; rewatches can exceed 1
+}
+
 // PlatformTrend represents trending topics/sounds for a platform
 type PlatformTrend struct {
 	ID          string         `json:"id" gorm:"primaryKey"`
@@ -133,12 +184,14 @@ type PlatformTrend struct {
 
 // UploadRequest represents a video upload request
 type UploadRequest struct {
-	VideoPath   string            `json:"videoPath"`
-	Title       string            `json:"title"`
-	Description string            `json:"description"`
-	Tags        []string          `json:"tags"`
-	Privacy     string            `json:"privacy"` // public, unlisted, private
-	Metadata    map[string]string `json:"metadata"`
+	VideoPath      string            `json:"videoPath"`
+	VideoURL       string            `json:"videoUrl,omitempty"` // public URL, for destinations that link to the video
+	Title          string            `json:"title"`
+	Description    string            `json:"description"`
+	Tags           []string          `json:"tags"`
+	Privacy        string            `json:"privacy"` // public, unlisted, private
+	Metadata       map[string]string `json:"metadata"`
+	IdempotencyKey string            `json:"idempotencyKey,omitempty"` // same across retries of one publish
 }
 
 // UploadResponse represents the result of an upload
@@ -148,5 +201,251 @@ type UploadResponse struct {
 	Status         string `json:"status"`
 }
 
+// Fields of a published post that platforms may be able to change
+const (
+	PostFieldTitle       = "title"
+	PostFieldDescription = "description"
+	PostFieldPrivacy     = "privacy"
+)
+
+// PostUpdate holds changes to a published post. Nil fields are left as
+// they are.
+type PostUpdate struct {
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Privacy     *string `json:"privacy,omitempty"`
+}
+
+// Fields returns the names of the fields the update changes
+func (u *PostUpdate) Fields() []string {
+	var fields []string
+	if u.Title != nil {
+		fields = append(fields, PostFieldTitle)
+	}
+	if u.Description != nil {
+		fields = append(fields, PostFieldDescription)
+	}
+	if u.Privacy != nil {
+		fields = append(fields, PostFieldPrivacy)
+	}
+	return fields
+}
+
+// Without returns a copy of the update leaving out the given fields
+func (u *PostUpdate) Without(fields []string) *PostUpdate {
+	update := *u
+	for _, field := range fields {
+		switch field {
+		case PostFieldTitle:
+			update.Title = nil
+		case PostFieldDescription:
+			update.Description = nil
+		case PostFieldPrivacy:
+			update.Privacy = nil
+		}
+	}
+	return &update
+}
+
+// SyncStatus is the outcome of propagating an edit or deletion to a
+// platform post
+type SyncStatus string
+
+const (
+	SyncStatusUpdated     SyncStatus = "updated"
+	SyncStatusDeleted     SyncStatus = "deleted"
+	SyncStatusCancelled   SyncStatus = "cancelled"   // not published yet, will not be
+	SyncStatusUnsupported SyncStatus = "unsupported" // the platform cannot do this
+	SyncStatusSkipped     SyncStatus = "skipped"
+	SyncStatusFailed      SyncStatus = "failed"
+)
+
+// PostSyncResult reports how an edit or deletion went on one platform post
+type PostSyncResult struct {
+	PlatformPostID string         `json:"platformPostId"`
+	Platform       SocialPlatform `json:"platform"`
+	AccountID      string         `json:"accountId"`
+	Status         SyncStatus     `json:"status"`
+	Unsupported    []string       `json:"unsupported,omitempty"` // fields the platform cannot change
+	Error          string         `json:"error,omitempty"`
+}
+
+// UploadStatus represents the progress of a resumable upload
+type UploadStatus string
+
+const (
+	UploadStatusUploading UploadStatus = "uploading"
+	UploadStatusUploaded  UploadStatus = "uploaded"
+)
+
+// UploadSession tracks a resumable upload so that a publish retry can
+// continue from the last byte the platform acknowledged
+type UploadSession struct {
+	ID          string         `json:"id" gorm:"primaryKey"`
+	UploadKey   string         `json:"uploadKey" gorm:"uniqueIndex"`
+	AccountID   string         `json:"accountId" gorm:"index"`
+	Platform    SocialPlatform `json:"platform"`
+	VideoPath   string         `json:"videoPath"`
+	FileSize    int64          `json:"fileSize"`
+	FileModTime time.Time      `json:"fileModTime"`
+	SessionURL  string         `json:"-"`                  // platform upload URL, may embed credentials
+	RemoteID    string         `json:"remoteId"`           // platform media, video or publish ID
+	State       string         `json:"-" gorm:"type:text"` // platform-specific protocol state
+	Offset      int64          `json:"offset"`             // bytes acknowledged by the platform
+	Status      UploadStatus   `json:"status"`
+	ExpiresAt   *time.Time     `json:"expiresAt,omitempty"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+}
+
+// ReviewAction is an entry type in the review trail of a post
+type ReviewAction string
+
+const (
+	ReviewActionSubmitted ReviewAction = "submitted"
+	ReviewActionAssigned  ReviewAction = "assigned"
+	ReviewActionCommented ReviewAction = "commented"
+	ReviewActionApproved  ReviewAction = "approved"
+	ReviewActionRejected  ReviewAction = "rejected"
+	ReviewActionScheduled ReviewAction = "scheduled"
+	ReviewActionEdited    ReviewAction = "edited" // changed after it was published
+)
+
+// PostReviewEvent is an entry in the review trail of a post. Comments are
+// kept in the trail with the status changes they were made with.
+type PostReviewEvent struct {
+	ID         string       `json:"id" gorm:"primaryKey"`
+	PostID     string       `json:"postId" gorm:"index"`
+	ActorID    string       `json:"actorId"`
+	Action     ReviewAction `json:"action"`
+	FromStatus PostStatus   `json:"fromStatus,omitempty"`
+	ToStatus   PostStatus   `json:"toStatus,omitempty"`
+	ReviewerID string       `json:"reviewerId,omitempty"`
+	Comment    string       `json:"comment,omitempty" gorm:"type:text"`
+	CreatedAt  time.Time    `json:"createdAt"`
+}
+
+// ApprovalPolicy is the approval setting of a workspace, which is the user
+// owning the posts and accounts
+type ApprovalPolicy struct {
+	UserID            string    `json:"userId" gorm:"primaryKey"`
+	RequireApproval   bool      `json:"requireApproval"`
+	DefaultReviewerID string    `json:"defaultReviewerId,omitempty"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+// PublishAttemptStatus represents the outcome of a publish attempt
+type PublishAttemptStatus string
+
+const (
+	PublishAttemptStarted   PublishAttemptStatus = "started"
+	PublishAttemptSucceeded PublishAttemptStatus = "succeeded"
+	PublishAttemptFailed    PublishAttemptStatus = "failed"
+	// PublishAttemptUnknown marks an attempt that was abandoned in flight, or
+	// whose upload failed without the platform saying whether it created
+	// the post. It is checked against the platform before uploading again.
+	PublishAttemptUnknown PublishAttemptStatus = "unknown"
+)
+
+// PublishAttempt is an entry in the publish ledger of a platform post. At
+// most one attempt per idempotency key can be in flight, and a succeeded
+// attempt keeps the platform post ID so retries never publish twice.
+type PublishAttempt struct {
+	ID             string               `json:"id" gorm:"primaryKey"`
+	IdempotencyKey string               `json:"idempotencyKey" gorm:"index;uniqueIndex:idx_publish_attempts_in_flight,where:status = 'started'"`
+	PlatformPostID string               `json:"platformPostId" gorm:"index"` // our PlatformPost ID
+	AccountID      string               `json:"accountId"`
+	Platform       SocialPlatform       `json:"platform"`
+	Attempt        int                  `json:"attempt"`
+	Status         PublishAttemptStatus `json:"status"`
+	RemotePostID   string               `json:"remotePostId,omitempty"` // post ID on the platform
+	PostURL        string               `json:"postUrl,omitempty"`
+	Error          string               `json:"error,omitempty"`
+	StartedAt      time.Time            `json:"startedAt"`
+	FinishedAt     *time.Time           `json:"finishedAt,omitempty"`
+}
+
+// CommentStatus is the moderation state of a comment
+type CommentStatus string
+
+const (
+	CommentStatusVisible CommentStatus = "visible"
+	CommentStatusHidden  CommentStatus = "hidden"
+	CommentStatusDeleted CommentStatus = "deleted"
+)
+
+// Sentiment is the tone of a comment, used to triage the inbox
+type Sentiment string
+
+const (
+	SentimentPositive Sentiment = "positive"
+	SentimentNeutral  Sentiment = "neutral"
+	SentimentNegative Sentiment = "negative"
+)
+
+// Comment is a comment or reply on a published platform post. Replies
+// point at the top-level comment of their thread.
+type Comment struct {
+	ID              string         `json:"id" gorm:"primaryKey"`
+	UserID          string         `json:"userId" gorm:"index"` // workspace owning the post
+	ScheduledPostID string         `json:"scheduledPostId" gorm:"index"`
+	PlatformPostID  string         `json:"platformPostId" gorm:"index"` // our PlatformPost ID
+	AccountID       string         `json:"accountId" gorm:"uniqueIndex:idx_comments_remote"`
+	Platform        SocialPlatform `json:"platform" gorm:"index"`
+	RemoteID        string         `json:"remoteId" gorm:"uniqueIndex:idx_comments_remote"` // comment ID on the platform
+	RemotePostID    string         `json:"remotePostId"`                                   // post ID on the platform
+	ParentID        string         `json:"parentId,omitempty" gorm:"index"`
+	RemoteParentID  string         `json:"remoteParentId,omitempty"`
+	AuthorID        string         `json:"authorId,omitempty"`
+	AuthorName      string         `json:"authorName"`
+	AuthorAvatarURL string         `json:"authorAvatarUrl,omitempty"`
+	FromAccount     bool           `json:"fromAccount"` // written by the connected account
+	Text            string         `json:"text" gorm:"type:text"`
+	Likes           int64          `json:"likes"`
+	Sentiment       Sentiment      `json:"sentiment" gorm:"index"`
+	Status          CommentStatus  `json:"status"`
+	Read            bool           `json:"read"`
+	Replies         []Comment      `json:"replies,omitempty" gorm:"-"`
+	PublishedAt     time.Time      `json:"publishedAt" gorm:"index"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	UpdatedAt       time.Time      `json:"updatedAt"`
+}
+
+// CommentFilter narrows the comments listed in the inbox. Empty fields
+// match everything.
+type CommentFilter struct {
+	Platform  SocialPlatform
+	AccountID string
+	PostID    string // scheduled post
+	Sentiment Sentiment
+	Status    CommentStatus
+	Unread    bool
+	Limit     int
+	Offset    int
+}
+
 // JSON is a custom type for JSONB fields
 type JSON map[string]interface{}
+
+// Value implements driver.Valuer for database storage
+func (j JSON) Value() (driver.Value, error) {
+	if j == nil {
+		return nil, nil
+	}
+	return json.Marshal(j)
+}
+
+// Scan implements sql.Scanner for database retrieval
+func (j *JSON) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*j = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, j)
+	case string:
+		return json.Unmarshal([]byte(v), j)
+	default:
+		return fmt.Errorf("cannot scan %T into JSON", value)
+	}
+}
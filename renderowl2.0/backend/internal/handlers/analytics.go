@@ -1,6 +1,10 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -13,12 +17,13 @@ import (
 
 // AnalyticsHandler handles analytics HTTP requests
 type AnalyticsHandler struct {
-	service *service.AnalyticsService
+	service  *service.AnalyticsService
+	verifier *service.WebhookVerifier
 }
 
 // NewAnalyticsHandler creates a new analytics handler
-func NewAnalyticsHandler(service *service.AnalyticsService) *AnalyticsHandler {
-	return &AnalyticsHandler{service: service}
+func NewAnalyticsHandler(service *service.AnalyticsService, verifier *service.WebhookVerifier) *AnalyticsHandler {
+	return &AnalyticsHandler{service: service, verifier: verifier}
 }
 
 // GetOverview returns the analytics overview
@@ -262,76 +267,134 @@ func (h *AnalyticsHandler) ExportAnalytics(c *gin.Context) {
 		}
 	}
 
-	format := c.DefaultQuery("format", "json")
+	format := c.DefaultQuery("format", service.ExportFormatJSON)
+	file, ok := service.ExportFileFor(format)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "format must be one of json, csv, xlsx or pdf",
+			"code":  "VALIDATION_ERROR",
+		})
+		return
+	}
 
 	req := &service.ExportAnalyticsRequest{
 		UserID:    user.ID,
 		StartDate: startDate,
 		EndDate:   endDate,
 		Format:    format,
+		Title:     c.Query("title"),
 	}
 
-	data, err := h.service.ExportAnalytics(c.Request.Context(), req)
-	if err != nil {
+	filename := fmt.Sprintf("analytics-%s-%s.%s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), file.Extension)
+	c.Header("Content-Type", file.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	// The export is streamed, so errors can only be reported while
+	// nothing has been written yet
+	if err := h.service.ExportAnalytics(c.Request.Context(), req, c.Writer); err != nil {
+		if c.Writer.Written() {
+			log.Printf("Failed to stream analytics export for user %s: %v", user.ID, err)
+			c.Error(err)
+			return
+		}
+
+		// Errors are sent as JSON, not as the file
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		if errors.Is(err, service.ErrInvalidExportRange) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+				"code":  "VALIDATION_ERROR",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 			"code":  "INTERNAL_ERROR",
 		})
-		return
 	}
+}
+
+// maxWebhookBody is the largest webhook body accepted
+const maxWebhookBody = 1 << 20
 
-	// Set appropriate headers
-	if format == "csv" {
-		c.Header("Content-Type", "text/csv")
-		c.Header("Content-Disposition", "attachment; filename=analytics.csv")
-	} else {
-		c.Header("Content-Type", "application/json")
-		c.Header("Content-Disposition", "attachment; filename=analytics.json")
+// VerifyWebhook answers the subscription verification requests of
+// platforms that confirm a webhook endpoint before sending to it
+func (h *AnalyticsHandler) VerifyWebhook(c *gin.Context) {
+	challenge, err := h.verifier.Challenge(c.Param("platform"), c.Request.URL.Query())
+	if err != nil {
+		respondWebhookError(c, err)
+		return
 	}
 
-	c.Data(http.StatusOK, c.Writer.Header().Get("Content-Type"), data)
+	c.String(http.StatusOK, challenge)
 }
 
-// ReceiveWebhook handles incoming webhooks from platforms
+// ReceiveWebhook handles incoming webhooks from platforms. Each webhook
+// must carry a valid signature and a recent timestamp for its platform;
+// deliveries that were already received are acknowledged but not stored
+// again.
 func (h *AnalyticsHandler) ReceiveWebhook(c *gin.Context) {
-	platform := c.Param("platform")
-	if platform == "" {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody+1))
+	if err != nil || len(body) > maxWebhookBody {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Platform is required",
+			"error": "Invalid webhook body",
 			"code":  "VALIDATION_ERROR",
 		})
 		return
 	}
 
-	var payload map[string]interface{}
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
+	req, err := h.verifier.Verify(c.Param("platform"), c.Request.Header, body)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	eventID, err := h.service.StoreWebhookEvent(c.Request.Context(), req)
+	if errors.Is(err, service.ErrWebhookDuplicate) {
+		c.JSON(http.StatusOK, gin.H{"message": "Webhook already received"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
-			"code":  "VALIDATION_ERROR",
+			"code":  "INTERNAL_ERROR",
 		})
 		return
 	}
 
-	// Determine event type from payload
-	eventType := "unknown"
-	if et, ok := payload["event_type"].(string); ok {
-		eventType = et
-	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "Webhook received",
+		"event_id": eventID,
+	})
+}
 
-	// Get video ID from payload
-	videoID := ""
-	if vid, ok := payload["video_id"].(string); ok {
-		videoID = vid
+// ListWebhookEvents lists received webhook events of every workspace, for
+// operators. Filter with status=dead to inspect the events that could not
+// be processed.
+func (h *AnalyticsHandler) ListWebhookEvents(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
 	}
 
-	req := &service.WebhookEventRequest{
-		Platform:  platform,
-		EventType: eventType,
-		VideoID:   videoID,
-		Payload:   payload,
+	// Pagination
+	limit := 50
+	offset := 0
+
+	if l := c.Query("limit"); l != "" {
+		if val, err := strconv.Atoi(l); err == nil && val > 0 && val <= 200 {
+			limit = val
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if val, err := strconv.Atoi(o); err == nil && val >= 0 {
+			offset = val
+		}
 	}
 
-	eventID, err := h.service.StoreWebhookEvent(c.Request.Context(), req)
+	events, err := h.service.ListWebhookEvents(c.Request.Context(), c.Query("status"), c.Query("platform"), limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -340,8 +403,51 @@ func (h *AnalyticsHandler) ReceiveWebhook(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusAccepted, gin.H{
-		"message":  "Webhook received",
-		"event_id": eventID,
+	c.JSON(http.StatusOK, gin.H{
+		"data": events,
+		"meta": gin.H{
+			"limit":  limit,
+			"offset": offset,
+		},
 	})
 }
+
+// RetryWebhookEvent moves a dead letter webhook event back to the queue
+func (h *AnalyticsHandler) RetryWebhookEvent(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	err := h.service.RetryWebhookEvent(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, service.ErrWebhookEventNotDead) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+			"code":  "CONFLICT",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook event queued for retry"})
+}
+
+func respondWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrWebhookUnsupported):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": "NOT_FOUND"})
+	case errors.Is(err, service.ErrWebhookSignature), errors.Is(err, service.ErrWebhookStale):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "code": "UNAUTHORIZED"})
+	case errors.Is(err, service.ErrWebhookChallenge):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "FORBIDDEN"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "VALIDATION_ERROR"})
+	}
+}
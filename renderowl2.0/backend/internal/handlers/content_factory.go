@@ -1,10 +1,18 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"renderowl-api/internal/domain"
 	"renderowl-api/internal/middleware"
 	"renderowl-api/internal/service"
 )
@@ -15,6 +23,8 @@ type ContentFactoryHandler struct {
 	batchService      *service.BatchService
 	variationsService *service.VariationsService
 	optimizerService  *service.OptimizerService
+	calendarService   *service.CalendarBatchService
+	streamTokens      *middleware.StreamTokens
 }
 
 // NewContentFactoryHandler creates a new content factory handler
@@ -23,12 +33,16 @@ func NewContentFactoryHandler(
 	batchService *service.BatchService,
 	variationsService *service.VariationsService,
 	optimizerService *service.OptimizerService,
+	calendarService *service.CalendarBatchService,
+	streamTokens *middleware.StreamTokens,
 ) *ContentFactoryHandler {
 	return &ContentFactoryHandler{
 		ideationService:   ideationService,
 		batchService:      batchService,
 		variationsService: variationsService,
 		optimizerService:  optimizerService,
+		calendarService:   calendarService,
+		streamTokens:      streamTokens,
 	}
 }
 
@@ -210,6 +224,144 @@ func (h *ContentFactoryHandler) CreateBatch(c *gin.Context) {
 	c.JSON(http.StatusCreated, batch)
 }
 
+// maxImportFileSize caps the size of batch import uploads
+const maxImportFileSize = 5 << 20
+
+// ImportBatch creates batches from an uploaded CSV or JSON Lines file.
+// The multipart form carries the file plus name, description, format,
+// mapping (JSON object of field to column) and config (JSON BatchConfig).
+// POST /api/v1/batch/import
+func (h *ContentFactoryHandler) ImportBatch(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "file is required",
+			"code":  "VALIDATION_ERROR",
+		})
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "file exceeds 5MB",
+			"code":  "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	req := service.ImportBatchRequest{
+		Name:        c.PostForm("name"),
+		Description: c.PostForm("description"),
+		Format:      service.ImportFormat(strings.ToLower(c.PostForm("format"))),
+	}
+	if req.Format == "" {
+		switch strings.ToLower(filepath.Ext(fileHeader.Filename)) {
+		case ".jsonl", ".ndjson":
+			req.Format = service.ImportFormatJSONL
+		default:
+			req.Format = service.ImportFormatCSV
+		}
+	}
+	if mapping := c.PostForm("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &req.Mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid mapping: " + err.Error(),
+				"code":  "VALIDATION_ERROR",
+			})
+			return
+		}
+	}
+	if config := c.PostForm("config"); config != "" {
+		if err := json.Unmarshal([]byte(config), &req.Config); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid config: " + err.Error(),
+				"code":  "VALIDATION_ERROR",
+			})
+			return
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "VALIDATION_ERROR",
+		})
+		return
+	}
+	defer file.Close()
+
+	result, err := h.batchService.ImportBatches(c.Request.Context(), user.ID, &req, file)
+	if err != nil {
+		var validationErr *service.ImportValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  err.Error(),
+				"code":   "VALIDATION_ERROR",
+				"errors": validationErr.Errors,
+			})
+			return
+		}
+		if result != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   err.Error(),
+				"code":    "BATCH_IMPORT_ERROR",
+				"batches": result.Batches,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "BATCH_IMPORT_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// CreateBatchFromCalendar creates a batch from a content calendar and
+// schedules each video for publishing on its calendar date
+// POST /api/v1/batch/from-calendar
+func (h *ContentFactoryHandler) CreateBatchFromCalendar(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req service.CalendarBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	result, err := h.calendarService.CreateFromCalendar(c.Request.Context(), user.ID, &req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if result != nil {
+			// The batch exists but scheduling or starting it failed
+			status = http.StatusInternalServerError
+		}
+		c.JSON(status, gin.H{
+			"error":  err.Error(),
+			"code":   "CALENDAR_BATCH_ERROR",
+			"result": result,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
 // StartBatch starts processing a batch
 // POST /api/v1/batch/:id/start
 func (h *ContentFactoryHandler) StartBatch(c *gin.Context) {
@@ -220,6 +372,9 @@ func (h *ContentFactoryHandler) StartBatch(c *gin.Context) {
 	}
 
 	batchID := c.Param("id")
+	if !h.ownBatch(c, user.ID, batchID) {
+		return
+	}
 
 	if err := h.batchService.StartBatch(c.Request.Context(), batchID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -245,6 +400,9 @@ func (h *ContentFactoryHandler) GetBatchStatus(c *gin.Context) {
 	}
 
 	batchID := c.Param("id")
+	if !h.ownBatch(c, user.ID, batchID) {
+		return
+	}
 
 	progress, err := h.batchService.GetBatchProgress(c.Request.Context(), batchID)
 	if err != nil {
@@ -268,6 +426,9 @@ func (h *ContentFactoryHandler) GetBatchResults(c *gin.Context) {
 	}
 
 	batchID := c.Param("id")
+	if !h.ownBatch(c, user.ID, batchID) {
+		return
+	}
 
 	results, err := h.batchService.GetBatchResults(c.Request.Context(), batchID)
 	if err != nil {
@@ -312,6 +473,119 @@ func (h *ContentFactoryHandler) ListBatches(c *gin.Context) {
 	})
 }
 
+// CreateBatchStreamToken issues a short-lived token to open the event
+// stream of a batch from a browser, whose EventSource cannot send an
+// Authorization header
+// POST /api/v1/batch/:id/events/token
+func (h *ContentFactoryHandler) CreateBatchStreamToken(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	batch, err := h.batchService.GetBatch(c.Request.Context(), c.Param("id"))
+	if err != nil || batch.UserID != user.ID {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "batch not found",
+			"code":  "NOT_FOUND",
+		})
+		return
+	}
+
+	path := strings.TrimSuffix(c.Request.URL.Path, "/token")
+	token, expiresAt, err := h.streamTokens.Issue(user.ID, path)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"token":     token,
+			"url":       path + "?token=" + url.QueryEscape(token),
+			"expiresAt": expiresAt,
+		},
+	})
+}
+
+// StreamBatchEvents streams live batch progress as Server-Sent Events.
+// The first event is a snapshot of the current progress; the stream ends
+// once the batch stops processing. Browsers authenticate with a token from
+// CreateBatchStreamToken.
+// GET /api/v1/batch/:id/events
+func (h *ContentFactoryHandler) StreamBatchEvents(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	batchID := c.Param("id")
+	ctx := c.Request.Context()
+
+	batch, err := h.batchService.GetBatch(ctx, batchID)
+	if err != nil || batch.UserID != user.ID {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "batch not found",
+			"code":  "NOT_FOUND",
+		})
+		return
+	}
+
+	// Subscribe before taking the snapshot so no update falls in between
+	events, unsubscribe, err := h.batchService.SubscribeBatchEvents(ctx, batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+			"code":  "STREAM_ERROR",
+		})
+		return
+	}
+	defer unsubscribe()
+
+	progress, err := h.batchService.GetBatchProgress(ctx, batchID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+			"code":  "NOT_FOUND",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.SSEvent("progress", progress)
+	c.Writer.Flush()
+	if domain.BatchStatus(progress.Status).IsTerminal() {
+		return
+	}
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return event.Type != service.BatchEventBatch || !domain.BatchStatus(event.Status).IsTerminal()
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		}
+	})
+}
+
 // CancelBatch cancels a batch
 // POST /api/v1/batch/:id/cancel
 func (h *ContentFactoryHandler) CancelBatch(c *gin.Context) {
@@ -322,6 +596,9 @@ func (h *ContentFactoryHandler) CancelBatch(c *gin.Context) {
 	}
 
 	batchID := c.Param("id")
+	if !h.ownBatch(c, user.ID, batchID) {
+		return
+	}
 
 	if err := h.batchService.CancelBatch(c.Request.Context(), batchID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -337,6 +614,62 @@ func (h *ContentFactoryHandler) CancelBatch(c *gin.Context) {
 	})
 }
 
+// PauseBatch pauses a batch
+// POST /api/v1/batch/:id/pause
+func (h *ContentFactoryHandler) PauseBatch(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	batchID := c.Param("id")
+	if !h.ownBatch(c, user.ID, batchID) {
+		return
+	}
+
+	if err := h.batchService.PauseBatch(c.Request.Context(), batchID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "PAUSE_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Batch paused successfully",
+		"batchId": batchID,
+	})
+}
+
+// ResumeBatch resumes a paused batch
+// POST /api/v1/batch/:id/resume
+func (h *ContentFactoryHandler) ResumeBatch(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	batchID := c.Param("id")
+	if !h.ownBatch(c, user.ID, batchID) {
+		return
+	}
+
+	if err := h.batchService.ResumeBatch(c.Request.Context(), batchID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "RESUME_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Batch resumed successfully",
+		"batchId": batchID,
+	})
+}
+
 // RetryFailedVideos retries failed videos in a batch
 // POST /api/v1/batch/:id/retry
 func (h *ContentFactoryHandler) RetryFailedVideos(c *gin.Context) {
@@ -347,6 +680,9 @@ func (h *ContentFactoryHandler) RetryFailedVideos(c *gin.Context) {
 	}
 
 	batchID := c.Param("id")
+	if !h.ownBatch(c, user.ID, batchID) {
+		return
+	}
 
 	if err := h.batchService.RetryFailedVideos(c.Request.Context(), batchID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -362,6 +698,20 @@ func (h *ContentFactoryHandler) RetryFailedVideos(c *gin.Context) {
 	})
 }
 
+// ownBatch checks that a batch belongs to the user, responding with 404
+// when it does not exist or belongs to someone else
+func (h *ContentFactoryHandler) ownBatch(c *gin.Context, userID, batchID string) bool {
+	batch, err := h.batchService.GetBatch(c.Request.Context(), batchID)
+	if err != nil || batch.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "batch not found",
+			"code":  "NOT_FOUND",
+		})
+		return false
+	}
+	return true
+}
+
 // GetQueueStats returns queue statistics
 // GET /api/v1/batch/queue/stats
 func (h *ContentFactoryHandler) GetQueueStats(c *gin.Context) {
@@ -460,7 +810,14 @@ func (h *ContentFactoryHandler) AnalyzeVideo(c *gin.Context) {
 		return
 	}
 
-	suggestions, err := h.optimizerService.AnalyzeVideo(c.Request.Context(), req.VideoID)
+	suggestions, err := h.optimizerService.AnalyzeVideo(c.Request.Context(), user.ID, req.VideoID)
+	if errors.Is(err, service.ErrVideoAnalyticsNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+			"code":  "NOT_FOUND",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"renderowl-api/internal/middleware"
+	"renderowl-api/internal/service"
+)
+
+// ReportHandler handles analytics digest subscription HTTP requests
+type ReportHandler struct {
+	service *service.DigestService
+}
+
+// NewReportHandler creates a new report handler
+func NewReportHandler(service *service.DigestService) *ReportHandler {
+	return &ReportHandler{service: service}
+}
+
+// ListSubscriptions lists the digest subscriptions of the user
+func (h *ReportHandler) ListSubscriptions(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	subs, err := h.service.ListSubscriptions(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": subs})
+}
+
+// CreateSubscription creates a digest subscription
+func (h *ReportHandler) CreateSubscription(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req service.ReportSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	sub, err := h.service.CreateSubscription(c.Request.Context(), user.ID, user.Email, &req)
+	if err != nil {
+		respondReportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": sub})
+}
+
+// UpdateSubscription replaces the settings of a digest subscription
+func (h *ReportHandler) UpdateSubscription(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req service.ReportSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	sub, err := h.service.UpdateSubscription(c.Request.Context(), user.ID, user.Email, c.Param("id"), &req)
+	if err != nil {
+		respondReportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": sub})
+}
+
+// DeleteSubscription deletes a digest subscription
+func (h *ReportHandler) DeleteSubscription(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.service.DeleteSubscription(c.Request.Context(), user.ID, c.Param("id")); err != nil {
+		respondReportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Report subscription deleted"})
+}
+
+// SendSubscription delivers a subscription's digest immediately
+func (h *ReportHandler) SendSubscription(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.service.SendNow(c.Request.Context(), user.ID, c.Param("id")); err != nil {
+		if errors.Is(err, service.ErrReportSubscriptionNotFound) || errors.Is(err, service.ErrDigestSendTooSoon) ||
+			errors.Is(err, service.ErrNoConfirmedRecipients) {
+			respondReportError(c, err)
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": err.Error(),
+			"code":  "DELIVERY_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Report digest sent"})
+}
+
+// ConfirmRecipient confirms that a recipient wants a digest, from the
+// link emailed to them. It is public; the link is signed.
+func (h *ReportHandler) ConfirmRecipient(c *gin.Context) {
+	err := h.service.ConfirmRecipient(c.Request.Context(), c.Query("subscription"), c.Query("email"), c.Query("token"))
+	if err != nil {
+		respondReportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "You will receive this analytics digest"})
+}
+
+func respondReportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrReportSubscriptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": "NOT_FOUND"})
+	case errors.Is(err, service.ErrInvalidReportSubscription):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "VALIDATION_ERROR"})
+	case errors.Is(err, service.ErrInvalidRecipientConfirmation):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_TOKEN"})
+	case errors.Is(err, service.ErrDigestSendTooSoon):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "code": "RATE_LIMITED"})
+	case errors.Is(err, service.ErrNoConfirmedRecipients):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "NOT_CONFIRMED"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "code": "INTERNAL_ERROR"})
+	}
+}
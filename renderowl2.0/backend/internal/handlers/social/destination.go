@@ -0,0 +1,90 @@
+package social
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	socialdomain "renderowl-api/internal/domain/social"
+	"renderowl-api/internal/service"
+)
+
+// CreateDestination adds a custom destination, such as a webhook endpoint
+// or the workspace feed. The signing secret of a webhook is only returned
+// here.
+func (h *Handler) CreateDestination(c *gin.Context) {
+	userID := c.GetString("userID")
+	platform := socialdomain.SocialPlatform(c.Param("platform"))
+
+	var settings socialdomain.JSON
+	if err := c.ShouldBindJSON(&settings); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if settings == nil {
+		settings = socialdomain.JSON{}
+	}
+
+	account, err := h.socialService.ConnectDestination(c.Request.Context(), userID, platform, settings)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := gin.H{"account": account}
+	switch account.Platform {
+	case socialdomain.PlatformWebhook:
+		resp["signingSecret"] = account.AccessToken
+	case socialdomain.PlatformFeed:
+		resp["rssUrl"] = feedURL(c, account.AccountID, service.FeedFormatRSS)
+		resp["atomUrl"] = feedURL(c, account.AccountID, service.FeedFormatAtom)
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// GetFeed serves a workspace feed as Media RSS or Atom. Feeds are public;
+// the token in the URL is what keeps them private.
+func (h *Handler) GetFeed(c *gin.Context) {
+	format := strings.TrimSuffix(c.Param("format"), ".xml")
+
+	body, contentType, err := h.feeds.Render(c.Request.Context(), c.Param("token"), format, requestURL(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrFeedNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Feed not found"})
+		case errors.Is(err, service.ErrFeedFormat):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// feedURL returns the public URL of a feed on the host serving the request
+func feedURL(c *gin.Context, token, format string) string {
+	return baseURL(c) + "/feeds/" + token + "/" + format + ".xml"
+}
+
+// requestURL returns the absolute URL of the request
+func requestURL(c *gin.Context) string {
+	return baseURL(c) + c.Request.URL.Path
+}
+
+// baseURL returns the scheme and host of the request, honouring the
+// headers set by a TLS-terminating proxy
+func baseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
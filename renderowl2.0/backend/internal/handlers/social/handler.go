@@ -1,8 +1,9 @@
 package social
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -16,19 +17,37 @@ import (
 type Handler struct {
 	socialService *socialsvc.Service
 	publisher     *service.Publisher
+	approvals     *service.ApprovalService
 	scheduler     *scheduler.Scheduler
+	postingTimes  *service.PostingTimeService
+	quota         *service.PublishQuota
+	feeds         *service.FeedService
+	sync          *service.PostSyncService
+	inbox         *service.InboxService
 }
 
 // NewSocialHandler creates a new social media handler
 func NewSocialHandler(
 	socialService *socialsvc.Service,
 	publisher *service.Publisher,
+	approvals *service.ApprovalService,
 	scheduler *scheduler.Scheduler,
+	postingTimes *service.PostingTimeService,
+	quota *service.PublishQuota,
+	feeds *service.FeedService,
+	sync *service.PostSyncService,
+	inbox *service.InboxService,
 ) *Handler {
 	return &Handler{
 		socialService: socialService,
 		publisher:     publisher,
+		approvals:     approvals,
 		scheduler:     scheduler,
+		postingTimes:  postingTimes,
+		quota:         quota,
+		feeds:         feeds,
+		sync:          sync,
+		inbox:         inbox,
 	}
 }
 
@@ -68,6 +87,31 @@ func (h *Handler) GetAccount(c *gin.Context) {
 	c.JSON(http.StatusOK, account)
 }
 
+// GetBestTimes recommends hours of the week for an account to post at
+func (h *Handler) GetBestTimes(c *gin.Context) {
+	userID := c.GetString("userID")
+	accountID := c.Param("id")
+
+	limit := 5
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 168 {
+		limit = l
+	}
+
+	account, err := h.socialService.GetAccount(c.Request.Context(), accountID)
+	if err != nil || account.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+
+	result, err := h.postingTimes.BestTimes(c.Request.Context(), account, c.Query("timezone"), limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // DisconnectAccount removes a connected account
 func (h *Handler) DisconnectAccount(c *gin.Context) {
 	accountID := c.Param("id")
@@ -80,15 +124,32 @@ func (h *Handler) DisconnectAccount(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Account disconnected"})
 }
 
+// RefreshAccount refreshes an account's access token and reports its health
+func (h *Handler) RefreshAccount(c *gin.Context) {
+	userID := c.GetString("userID")
+	accountID := c.Param("id")
+
+	account, err := h.socialService.GetAccount(c.Request.Context(), accountID)
+	if err != nil || account.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+
+	account, err = h.socialService.RefreshAccount(c.Request.Context(), accountID)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "account": account})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
 // GetAuthURL returns OAuth URL for a platform
 func (h *Handler) GetAuthURL(c *gin.Context) {
+	userID := c.GetString("userID")
 	platform := socialdomain.SocialPlatform(c.Param("platform"))
-	state := c.Query("state")
-	if state == "" {
-		state = generateState()
-	}
 
-	authURL, err := h.socialService.GetAuthURL(platform, state)
+	authURL, state, err := h.socialService.GetAuthURL(c.Request.Context(), userID, platform)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -106,7 +167,8 @@ func (h *Handler) HandleCallback(c *gin.Context) {
 	userID := c.GetString("userID")
 
 	var req struct {
-		Code string `json:"code"`
+		Code  string `json:"code" binding:"required"`
+		State string `json:"state" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -114,8 +176,12 @@ func (h *Handler) HandleCallback(c *gin.Context) {
 		return
 	}
 
-	account, err := h.socialService.ConnectAccount(c.Request.Context(), platform, req.Code, userID)
+	account, err := h.socialService.ConnectAccount(c.Request.Context(), platform, req.State, req.Code, userID)
 	if err != nil {
+		if errors.Is(err, socialsvc.ErrOAuthSessionNotFound) || errors.Is(err, socialsvc.ErrOAuthSessionMismatch) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired OAuth state"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -123,11 +189,41 @@ func (h *Handler) HandleCallback(c *gin.Context) {
 	c.JSON(http.StatusOK, account)
 }
 
+// ConnectWithPassword connects an account with an app password
+func (h *Handler) ConnectWithPassword(c *gin.Context) {
+	platform := socialdomain.SocialPlatform(c.Param("platform"))
+	userID := c.GetString("userID")
+
+	var req struct {
+		Identifier string `json:"identifier" binding:"required"`
+		Password   string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	account, err := h.socialService.ConnectWithPassword(c.Request.Context(), userID, platform, req.Identifier, req.Password)
+	if err != nil {
+		var apiErr *socialsvc.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid identifier or app password"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
 // UploadVideo uploads a video immediately
 func (h *Handler) UploadVideo(c *gin.Context) {
 	var req struct {
 		AccountID   string   `json:"accountId"`
 		VideoPath   string   `json:"videoPath"`
+		VideoURL    string   `json:"videoUrl"`
 		Title       string   `json:"title"`
 		Description string   `json:"description"`
 		Tags        []string `json:"tags"`
@@ -141,6 +237,7 @@ func (h *Handler) UploadVideo(c *gin.Context) {
 
 	uploadReq := &socialdomain.UploadRequest{
 		VideoPath:   req.VideoPath,
+		VideoURL:    req.VideoURL,
 		Title:       req.Title,
 		Description: req.Description,
 		Tags:        req.Tags,
@@ -161,6 +258,7 @@ func (h *Handler) CrossPost(c *gin.Context) {
 	var req struct {
 		AccountIDs  []string `json:"accountIds"`
 		VideoPath   string   `json:"videoPath"`
+		VideoURL    string   `json:"videoUrl"`
 		Title       string   `json:"title"`
 		Description string   `json:"description"`
 		Tags        []string `json:"tags"`
@@ -174,6 +272,7 @@ func (h *Handler) CrossPost(c *gin.Context) {
 
 	uploadReq := &socialdomain.UploadRequest{
 		VideoPath:   req.VideoPath,
+		VideoURL:    req.VideoURL,
 		Title:       req.Title,
 		Description: req.Description,
 		Tags:        req.Tags,
@@ -203,6 +302,9 @@ func (h *Handler) SchedulePost(c *gin.Context) {
 		ScheduledAt string                   `json:"scheduledAt"`
 		Timezone    string                   `json:"timezone"`
 		Recurring   *socialdomain.RecurringRule `json:"recurring,omitempty"`
+		ReviewerID  string                   `json:"reviewerId,omitempty"`
+		VideoURL     string                  `json:"videoUrl,omitempty"`
+		ThumbnailURL string                  `json:"thumbnailUrl,omitempty"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -210,12 +312,46 @@ func (h *Handler) SchedulePost(c *gin.Context) {
 		return
 	}
 
-	scheduledAt, err := parseTime(req.ScheduledAt)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled time"})
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
 		return
 	}
 
+	// "auto" picks the next best slot for the target accounts
+	autoScheduled := req.ScheduledAt == "auto"
+	var scheduledAt time.Time
+	var err error
+	if autoScheduled {
+		var accounts []*socialdomain.SocialAccount
+		for _, p := range req.Platforms {
+			account, err := h.socialService.GetAccount(c.Request.Context(), p.AccountID)
+			if err != nil || account.UserID != userID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Account " + p.AccountID + " not found"})
+				return
+			}
+			accounts = append(accounts, account)
+		}
+
+		scheduledAt, err = h.postingTimes.NextBestTime(c.Request.Context(), accounts, req.Timezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		scheduledAt, err = parseTime(req.ScheduledAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled time"})
+			return
+		}
+	}
+
+	if req.Recurring != nil {
+		if _, err := scheduler.NewRecurrence(req.Recurring, scheduledAt, req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recurrence: " + err.Error()})
+			return
+		}
+	}
+
 	post := &socialdomain.ScheduledPost{
 		UserID:      userID,
 		VideoID:     req.VideoID,
@@ -228,6 +364,16 @@ func (h *Handler) SchedulePost(c *gin.Context) {
 			"videoPath": req.VideoID, // Would be resolved from video service
 		},
 	}
+	if autoScheduled {
+		post.Metadata["autoScheduled"] = true
+	}
+	// Custom destinations link to the rendered video instead of uploading it
+	if req.VideoURL != "" {
+		post.Metadata["videoUrl"] = req.VideoURL
+	}
+	if req.ThumbnailURL != "" {
+		post.Metadata["thumbnailUrl"] = req.ThumbnailURL
+	}
 
 	// Convert platform requests
 	for _, p := range req.Platforms {
@@ -241,14 +387,20 @@ func (h *Handler) SchedulePost(c *gin.Context) {
 		})
 	}
 
-	if err := h.socialService.SchedulePost(c.Request.Context(), post); err != nil {
+	// Check the content against each platform's rules before saving anything
+	if err := service.ComposePost(post); err != nil {
+		var validationErr *service.PostValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "errors": validationErr.Errors})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Schedule with publisher
-	if err := h.publisher.SchedulePublish(c.Request.Context(), post); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Schedule with publisher, or submit for review first
+	if err := h.approvals.SchedulePost(c.Request.Context(), post, req.ReviewerID); err != nil {
+		respondReviewError(c, err)
 		return
 	}
 
@@ -270,12 +422,50 @@ func (h *Handler) GetScheduledPosts(c *gin.Context) {
 	})
 }
 
+// GetOccurrences lists the upcoming occurrences of a recurring post
+func (h *Handler) GetOccurrences(c *gin.Context) {
+	userID := c.GetString("userID")
+	postID := c.Param("id")
+
+	limit := 10
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+
+	post, err := h.socialService.GetScheduledPost(c.Request.Context(), postID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if post.Recurring == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Post is not recurring"})
+		return
+	}
+
+	occurrences, err := h.publisher.UpcomingOccurrences(c.Request.Context(), post, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"postId":      post.ID,
+		"seriesId":    post.SeriesID,
+		"timezone":    post.Timezone,
+		"occurrences": occurrences,
+	})
+}
+
 // CancelScheduledPost cancels a scheduled post
 func (h *Handler) CancelScheduledPost(c *gin.Context) {
 	userID := c.GetString("userID")
 	postID := c.Param("id")
 
 	if err := h.socialService.CancelScheduledPost(c.Request.Context(), postID, userID); err != nil {
+		if errors.Is(err, socialsvc.ErrPostPublishing) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -285,10 +475,11 @@ func (h *Handler) CancelScheduledPost(c *gin.Context) {
 
 // PublishNow publishes a scheduled post immediately
 func (h *Handler) PublishNow(c *gin.Context) {
+	userID := c.GetString("userID")
 	postID := c.Param("id")
 
-	if err := h.publisher.PublishNow(c.Request.Context(), postID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.publisher.PublishNow(c.Request.Context(), userID, postID); err != nil {
+		respondReviewError(c, err)
 		return
 	}
 
@@ -297,10 +488,11 @@ func (h *Handler) PublishNow(c *gin.Context) {
 
 // RetryPost retries a failed post
 func (h *Handler) RetryPost(c *gin.Context) {
+	userID := c.GetString("userID")
 	postID := c.Param("id")
 
-	if err := h.publisher.RetryFailedPost(c.Request.Context(), postID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.publisher.RetryFailedPost(c.Request.Context(), userID, postID); err != nil {
+		respondReviewError(c, err)
 		return
 	}
 
@@ -354,13 +546,37 @@ func (h *Handler) GetTrends(c *gin.Context) {
 
 // GetQueueStats returns queue statistics
 func (h *Handler) GetQueueStats(c *gin.Context) {
+	userID := c.GetString("userID")
+
 	stats, err := h.scheduler.GetQueueStats(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	response := gin.H{}
+	for key, value := range stats {
+		response[key] = value
+	}
+
+	// Remaining publishing quota of the user's accounts
+	accounts, err := h.socialService.GetAccounts(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	quota := make([]*service.QuotaUsage, 0, len(accounts))
+	for _, account := range accounts {
+		usage, err := h.quota.Usage(c.Request.Context(), account)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		quota = append(quota, usage)
+	}
+	response["quota"] = quota
+
+	c.JSON(http.StatusOK, response)
 }
 
 // Helper types and functions
@@ -374,16 +590,6 @@ type PlatformScheduleReq struct {
 	Privacy     string   `json:"privacy"`
 }
 
-func generateState() string {
-	// Generate random state string
-	return "state_" + generateID()
-}
-
-func generateID() string {
-	// Simple ID generation
-	return fmt.Sprintf("%d", time.Now().UnixNano())
-}
-
 func parseTime(s string) (time.Time, error) {
 	// Parse ISO 8601 time
 	return time.Parse(time.RFC3339, s)
@@ -0,0 +1,149 @@
+package social
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	socialdomain "renderowl-api/internal/domain/social"
+	"renderowl-api/internal/service"
+	socialsvc "renderowl-api/internal/service/social"
+)
+
+// GetInbox lists the comments on published posts across platforms,
+// newest first. Comments can be filtered by platform, account, post,
+// sentiment, status and unread.
+func (h *Handler) GetInbox(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	filter := socialdomain.CommentFilter{
+		Platform:  socialdomain.SocialPlatform(c.Query("platform")),
+		AccountID: c.Query("accountId"),
+		PostID:    c.Query("postId"),
+		Sentiment: socialdomain.Sentiment(c.Query("sentiment")),
+		Status:    socialdomain.CommentStatus(c.Query("status")),
+		Unread:    c.Query("unread") == "true",
+	}
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		filter.Limit = l
+	}
+	if o, err := strconv.Atoi(c.Query("offset")); err == nil && o > 0 {
+		filter.Offset = o
+	}
+
+	comments, err := h.inbox.List(c.Request.Context(), userID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comments": comments,
+	})
+}
+
+// GetCommentThread returns the thread a comment is in, with its replies
+func (h *Handler) GetCommentThread(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	thread, err := h.inbox.GetThread(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		respondInboxError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
+
+// ReplyToComment replies to a comment on its platform
+func (h *Handler) ReplyToComment(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	reply, err := h.inbox.Reply(c.Request.Context(), userID, c.Param("id"), req.Text)
+	if err != nil {
+		respondInboxError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, reply)
+}
+
+// HideComment hides a comment on its platform
+func (h *Handler) HideComment(c *gin.Context) {
+	h.setCommentHidden(c, true)
+}
+
+// UnhideComment shows a hidden comment again
+func (h *Handler) UnhideComment(c *gin.Context) {
+	h.setCommentHidden(c, false)
+}
+
+func (h *Handler) setCommentHidden(c *gin.Context, hidden bool) {
+	userID := c.GetString("userID")
+
+	comment, err := h.inbox.Hide(c.Request.Context(), userID, c.Param("id"), hidden)
+	if err != nil {
+		respondInboxError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// DeleteComment deletes a comment on its platform
+func (h *Handler) DeleteComment(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	if err := h.inbox.Delete(c.Request.Context(), userID, c.Param("id")); err != nil {
+		respondInboxError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
+}
+
+// MarkCommentsRead marks comments as read, or unread with "read": false
+func (h *Handler) MarkCommentsRead(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req struct {
+		IDs  []string `json:"ids" binding:"required"`
+		Read *bool    `json:"read"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids are required"})
+		return
+	}
+	read := req.Read == nil || *req.Read
+
+	if err := h.inbox.MarkRead(c.Request.Context(), userID, req.IDs, read); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comments updated"})
+}
+
+func respondInboxError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrCommentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+	case errors.Is(err, service.ErrReplyRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCommentDeleted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, socialsvc.ErrUnsupported):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
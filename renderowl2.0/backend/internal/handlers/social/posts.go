@@ -0,0 +1,67 @@
+package social
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	socialdomain "renderowl-api/internal/domain/social"
+	"renderowl-api/internal/service"
+)
+
+// UpdatePost edits the title, description or privacy of a published post
+// on every platform it was published on. Each platform's outcome is
+// reported in the results, including fields it cannot change.
+func (h *Handler) UpdatePost(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var update socialdomain.PostUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	post, results, err := h.sync.Update(c.Request.Context(), c.Param("id"), userID, &update)
+	if err != nil {
+		respondSyncError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"post":    post,
+		"results": results,
+	})
+}
+
+// DeletePost deletes a post from every platform it was published on and
+// cancels its unpublished platform posts
+func (h *Handler) DeletePost(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	post, results, err := h.sync.Delete(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		respondSyncError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"post":    post,
+		"results": results,
+	})
+}
+
+func respondSyncError(c *gin.Context, err error) {
+	var validationErr *service.PostValidationError
+	switch {
+	case errors.Is(err, service.ErrPostNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+	case errors.Is(err, service.ErrNothingToUpdate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "errors": validationErr.Errors})
+	case errors.Is(err, service.ErrPostNotPublished):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
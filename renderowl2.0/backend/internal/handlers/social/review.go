@@ -0,0 +1,202 @@
+package social
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	socialdomain "renderowl-api/internal/domain/social"
+	"renderowl-api/internal/service"
+)
+
+// reviewRequest is the body of review actions
+type reviewRequest struct {
+	ReviewerID string `json:"reviewerId"`
+	Comment    string `json:"comment"`
+}
+
+// SubmitForReview submits a draft or rejected post for review
+func (h *Handler) SubmitForReview(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req reviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	post, err := h.approvals.Submit(c.Request.Context(), c.Param("id"), userID, req.ReviewerID, req.Comment)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+// AssignReviewer changes the reviewer of a post
+func (h *Handler) AssignReviewer(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req reviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ReviewerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reviewerId is required"})
+		return
+	}
+
+	post, err := h.approvals.AssignReviewer(c.Request.Context(), c.Param("id"), userID, req.ReviewerID)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+// ApprovePost approves a post under review and schedules it
+func (h *Handler) ApprovePost(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req reviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	post, err := h.approvals.Approve(c.Request.Context(), c.Param("id"), userID, req.Comment)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+// RejectPost rejects a post under review with a comment
+func (h *Handler) RejectPost(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req reviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	post, err := h.approvals.Reject(c.Request.Context(), c.Param("id"), userID, req.Comment)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+// CommentOnPost adds a comment to the review trail of a post
+func (h *Handler) CommentOnPost(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req reviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	event, err := h.approvals.Comment(c.Request.Context(), c.Param("id"), userID, req.Comment)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, event)
+}
+
+// GetReviewHistory returns the review trail of a post
+func (h *Handler) GetReviewHistory(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	events, err := h.approvals.History(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+	})
+}
+
+// GetReviewQueue returns the posts waiting for the user to review them
+func (h *Handler) GetReviewQueue(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	posts, err := h.approvals.ReviewQueue(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts": posts,
+	})
+}
+
+// GetApprovalPolicy returns the approval policy of the user's workspace
+func (h *Handler) GetApprovalPolicy(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	policy, err := h.approvals.GetPolicy(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdateApprovalPolicy sets whether posts of the user's workspace must be
+// approved before they are published
+func (h *Handler) UpdateApprovalPolicy(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req struct {
+		RequireApproval   bool   `json:"requireApproval"`
+		DefaultReviewerID string `json:"defaultReviewerId"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	policy := &socialdomain.ApprovalPolicy{
+		UserID:            userID,
+		RequireApproval:   req.RequireApproval,
+		DefaultReviewerID: req.DefaultReviewerID,
+	}
+	if err := h.approvals.SetPolicy(c.Request.Context(), policy); err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// respondReviewError maps approval workflow errors to HTTP statuses
+func respondReviewError(c *gin.Context, err error) {
+	var stateErr *service.ReviewStateError
+	switch {
+	case errors.Is(err, service.ErrReviewPostNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+	case errors.Is(err, service.ErrApprovalRequired),
+		errors.Is(err, service.ErrNotReviewer),
+		errors.Is(err, service.ErrNotPostOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrReviewerRequired),
+		errors.Is(err, service.ErrSelfReview),
+		errors.Is(err, service.ErrCommentRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.As(err, &stateErr):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
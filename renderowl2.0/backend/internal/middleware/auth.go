@@ -90,11 +90,38 @@ func Auth(cfg *config.Config) gin.HandlerFunc {
 			Email: email,
 		}
 		c.Set(UserContextKey, user)
+		c.Set("userID", userID) // read by the social handlers
 
 		c.Next()
 	}
 }
 
+// RequireOperator only lets operators, listed in OPERATOR_USER_IDS, through
+// to routes that act on every workspace. It runs after Auth.
+func RequireOperator(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := GetUser(c)
+		if user == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Unauthorized",
+				"code":  "AUTH_MISSING",
+			})
+			return
+		}
+
+		for _, id := range cfg.OperatorUserIDs {
+			if id == user.ID {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "Only operators can do this",
+			"code":  "FORBIDDEN",
+		})
+	}
+}
+
 // GetUser retrieves the authenticated user from context
 func GetUser(c *gin.Context) *domain.UserContext {
 	user, exists := c.Get(UserContextKey)
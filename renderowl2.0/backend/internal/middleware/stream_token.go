@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"renderowl-api/internal/domain"
+)
+
+// StreamTokenTTL is how long a stream token can be used to open its stream.
+// Streams stay open once opened; reconnecting needs a new token.
+const StreamTokenTTL = 2 * time.Minute
+
+// StreamTokens issues and verifies short-lived tokens for event streams.
+// Browsers open streams with EventSource, which cannot send an
+// Authorization header, so the token is passed in the token query
+// parameter instead. A token is bound to the user and to the path of the
+// stream it was issued for.
+type StreamTokens struct {
+	key []byte
+}
+
+// NewStreamTokens creates stream tokens signed with a secret. Without a
+// secret, a random key is used, and tokens only work on the process that
+// issued them.
+func NewStreamTokens(secret string) *StreamTokens {
+	key := []byte(secret)
+	if secret == "" {
+		log.Printf("Warning: STREAM_TOKEN_SECRET is not set; stream tokens only work on this instance")
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+	}
+	return &StreamTokens{key: key}
+}
+
+// Issue issues a token for a user to open the stream at path
+func (t *StreamTokens) Issue(userID, path string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(StreamTokenTTL)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   userID,
+		Audience:  jwt.ClaimStrings{path},
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	})
+	signed, err := token.SignedString(t.key)
+	return signed, expiresAt, err
+}
+
+// Auth authenticates stream requests by their token query parameter, set
+// for the requested path. Requests with an Authorization header are
+// authenticated like any other API request.
+func (t *StreamTokens) Auth(headerAuth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			headerAuth(c)
+			return
+		}
+
+		tokenString := c.Query("token")
+		if tokenString == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Authorization header or stream token required",
+				"code":  "AUTH_MISSING",
+			})
+			return
+		}
+
+		var claims jwt.RegisteredClaims
+		token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+			return t.key, nil
+		},
+			jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+			jwt.WithAudience(c.Request.URL.Path),
+			jwt.WithExpirationRequired(),
+		)
+		if err != nil || !token.Valid || claims.Subject == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid or expired stream token",
+				"code":  "AUTH_INVALID_TOKEN",
+			})
+			return
+		}
+
+		c.Set(UserContextKey, &domain.UserContext{ID: claims.Subject})
+		c.Set("userID", claims.Subject) // read by the social handlers
+
+		c.Next()
+	}
+}
@@ -2,9 +2,11 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"renderowl-api/internal/domain"
 )
@@ -21,11 +23,33 @@ func NewAnalyticsRepository(db *gorm.DB) *AnalyticsRepository {
 
 // TrackView records a video view
 func (r *AnalyticsRepository) TrackView(ctx context.Context, videoID, userID, platform string) error {
+	return r.RecordHourlyViews(ctx, videoID, userID, platform, 1, time.Now())
+}
+
+// RecordViews records a number of views of a video on a day, when the hour
+// they happened in is not known
+func (r *AnalyticsRepository) RecordViews(ctx context.Context, videoID, userID, platform string, count int64, date time.Time) error {
 	view := domain.AnalyticsView{
 		VideoID:  videoID,
 		UserID:   userID,
 		Platform: platform,
-		Date:     time.Now().UTC().Truncate(24 * time.Hour),
+		Count:    count,
+		Date:     date.UTC().Truncate(24 * time.Hour),
+	}
+	return r.db.WithContext(ctx).Create(&view).Error
+}
+
+// RecordHourlyViews records a number of views of a video that happened in
+// the hour of a time
+func (r *AnalyticsRepository) RecordHourlyViews(ctx context.Context, videoID, userID, platform string, count int64, at time.Time) error {
+	hour := at.UTC().Truncate(time.Hour)
+	view := domain.AnalyticsView{
+		VideoID:  videoID,
+		UserID:   userID,
+		Platform: platform,
+		Count:    count,
+		Date:     hour.Truncate(24 * time.Hour),
+		Hour:     &hour,
 	}
 	return r.db.WithContext(ctx).Create(&view).Error
 }
@@ -36,7 +60,7 @@ func (r *AnalyticsRepository) GetViewsByDateRange(ctx context.Context, userID st
 	
 	err := r.db.WithContext(ctx).Model(&domain.AnalyticsView{}).
 		Select("date, platform, SUM(count) as total_views").
-		Where("date >= ? AND date <= ?", startDate, endDate).
+		Where("user_id = ? AND date >= ? AND date <= ?", userID, startDate, endDate).
 		Group("date, platform").
 		Order("date DESC").
 		Find(&results).Error
@@ -103,6 +127,31 @@ func (r *AnalyticsRepository) TrackEngagement(ctx context.Context, videoID, plat
 	}).FirstOrCreate(&engagement).Error
 }
 
+// AddEngagement adds to the engagement of a video on a day
+func (r *AnalyticsRepository) AddEngagement(ctx context.Context, videoID, platform string, likes, comments, shares int64, date time.Time) error {
+	date = date.UTC().Truncate(24 * time.Hour)
+
+	result := r.db.WithContext(ctx).Model(&domain.AnalyticsEngagement{}).
+		Where("video_id = ? AND platform = ? AND date = ?", videoID, platform, date).
+		Updates(map[string]interface{}{
+			"likes":    gorm.Expr("likes + ?", likes),
+			"comments": gorm.Expr("comments + ?", comments),
+			"shares":   gorm.Expr("shares + ?", shares),
+		})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	return r.db.WithContext(ctx).Create(&domain.AnalyticsEngagement{
+		VideoID:  videoID,
+		Platform: platform,
+		Likes:    likes,
+		Comments: comments,
+		Shares:   shares,
+		Date:     date,
+	}).Error
+}
+
 // GetEngagementByVideo gets engagement metrics for a video
 func (r *AnalyticsRepository) GetEngagementByVideo(ctx context.Context, videoID string) (*EngagementSummary, error) {
 	var result EngagementSummary
@@ -115,6 +164,31 @@ func (r *AnalyticsRepository) GetEngagementByVideo(ctx context.Context, videoID
 	return &result, err
 }
 
+// GetEngagementByDateRange gets the engagement of a user's videos for a
+// date range, per day and platform
+func (r *AnalyticsRepository) GetEngagementByDateRange(ctx context.Context, userID string, startDate, endDate time.Time) ([]EngagementAggregate, error) {
+	var results []EngagementAggregate
+
+	err := r.db.WithContext(ctx).Model(&domain.AnalyticsEngagement{}).
+		Select("analytics_engagement.date, analytics_engagement.platform, SUM(likes) as likes, SUM(comments) as comments, SUM(shares) as shares").
+		Joins("JOIN analytics_video_performance ON analytics_video_performance.video_id = analytics_engagement.video_id").
+		Where("analytics_video_performance.user_id = ? AND analytics_engagement.date >= ? AND analytics_engagement.date <= ?", userID, startDate, endDate).
+		Group("analytics_engagement.date, analytics_engagement.platform").
+		Order("analytics_engagement.date ASC").
+		Find(&results).Error
+
+	return results, err
+}
+
+// EngagementAggregate represents aggregated engagement data for a day
+type EngagementAggregate struct {
+	Date     time.Time `json:"date"`
+	Platform string    `json:"platform"`
+	Likes    int64     `json:"likes"`
+	Comments int64     `json:"comments"`
+	Shares   int64     `json:"shares"`
+}
+
 // EngagementSummary represents aggregated engagement data
 type EngagementSummary struct {
 	TotalLikes    int64 `json:"total_likes"`
@@ -236,15 +310,36 @@ func (r *AnalyticsRepository) GetVideoPerformance(ctx context.Context, userID st
 
 // VideoPerformanceData represents video performance metrics
 type VideoPerformanceData struct {
-	VideoID        string    `json:"video_id"`
-	Title          string    `json:"title"`
-	TotalViews     int64     `json:"total_views"`
-	TotalLikes     int64     `json:"total_likes"`
-	TotalComments  int64     `json:"total_comments"`
-	TotalShares    int64     `json:"total_shares"`
-	EngagementRate float64   `json:"engagement_rate"`
-	Platforms      []string  `json:"platforms"`
-	PublishedAt    *time.Time `json:"published_at"`
+	VideoID        string             `json:"video_id"`
+	Title          string             `json:"title"`
+	TotalViews     int64              `json:"total_views"`
+	TotalLikes     int64              `json:"total_likes"`
+	TotalComments  int64              `json:"total_comments"`
+	TotalShares    int64              `json:"total_shares"`
+	EngagementRate float64            `json:"engagement_rate"`
+	Platforms      domain.StringArray `json:"platforms"`
+	PublishedAt    *time.Time         `json:"published_at"`
+}
+
+// GetVideoCountsByPlatform counts a user's videos per platform they were
+// published to
+func (r *AnalyticsRepository) GetVideoCountsByPlatform(ctx context.Context, userID string) (map[string]int64, error) {
+	var rows []struct {
+		Platform string
+		Videos   int64
+	}
+
+	err := r.db.WithContext(ctx).Model(&domain.VideoPerformance{}).
+		Select("unnest(platforms) as platform, COUNT(*) as videos").
+		Where("user_id = ?", userID).
+		Group("1").
+		Find(&rows).Error
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Platform] = row.Videos
+	}
+	return counts, err
 }
 
 // UpdateVideoPerformance updates or creates video performance record
@@ -254,15 +349,79 @@ func (r *AnalyticsRepository) UpdateVideoPerformance(ctx context.Context, perfor
 	return r.db.WithContext(ctx).Where(
 		"video_id = ?", performance.VideoID,
 	).Assign(domain.VideoPerformance{
+		Title:          performance.Title,
 		TotalViews:     performance.TotalViews,
 		TotalLikes:     performance.TotalLikes,
 		TotalComments:  performance.TotalComments,
 		TotalShares:    performance.TotalShares,
 		EngagementRate: performance.EngagementRate,
+		Platforms:      performance.Platforms,
+		PublishedAt:    performance.PublishedAt,
 		LastUpdated:    performance.LastUpdated,
 	}).FirstOrCreate(performance).Error
 }
 
+// UpdatePlatformStats updates or creates the stats record of a platform
+func (r *AnalyticsRepository) UpdatePlatformStats(ctx context.Context, stats *domain.PlatformStats) error {
+	now := time.Now().UTC()
+	stats.LastSyncedAt = &now
+
+	return r.db.WithContext(ctx).Where(
+		"platform = ?", stats.Platform,
+	).Assign(map[string]interface{}{
+		"total_views":    stats.TotalViews,
+		"total_videos":   stats.TotalVideos,
+		"total_likes":    stats.TotalLikes,
+		"last_synced_at": stats.LastSyncedAt,
+	}).FirstOrCreate(stats).Error
+}
+
+// GetVideoPerformanceByID gets the performance record of a video. It
+// returns nil if the video has no published posts with analytics.
+func (r *AnalyticsRepository) GetVideoPerformanceByID(ctx context.Context, videoID string) (*domain.VideoPerformance, error) {
+	var performance domain.VideoPerformance
+	if err := r.db.WithContext(ctx).First(&performance, "video_id = ?", videoID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &performance, nil
+}
+
+// GetLowestPerforming gets the published videos of a user with the fewest
+// views
+func (r *AnalyticsRepository) GetLowestPerforming(ctx context.Context, userID string, limit int) ([]VideoPerformanceData, error) {
+	var results []VideoPerformanceData
+
+	err := r.db.WithContext(ctx).Model(&domain.VideoPerformance{}).
+		Where("user_id = ? AND published_at IS NOT NULL", userID).
+		Order("total_views ASC").
+		Limit(limit).
+		Find(&results).Error
+
+	return results, err
+}
+
+// UpdateRetention updates or creates the retention curve of a video on a
+// platform
+func (r *AnalyticsRepository) UpdateRetention(ctx context.Context, retention *domain.VideoRetention) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "video_id"}, {Name: "platform"}},
+		DoUpdates: clause.AssignmentColumns([]string{"points", "updated_at"}),
+	}).Create(retention).Error
+}
+
+// GetRetention gets the retention curves of a video on every platform
+func (r *AnalyticsRepository) GetRetention(ctx context.Context, videoID string) ([]domain.VideoRetention, error) {
+	var results []domain.VideoRetention
+	err := r.db.WithContext(ctx).
+		Where("video_id = ?", videoID).
+		Order("platform ASC").
+		Find(&results).Error
+	return results, err
+}
+
 // GetPlatformStats gets aggregated stats for all platforms
 func (r *AnalyticsRepository) GetPlatformStats(ctx context.Context) ([]PlatformStatData, error) {
 	var results []PlatformStatData
@@ -283,19 +442,54 @@ type PlatformStatData struct {
 	Followers   int64  `json:"followers"`
 }
 
-// StoreWebhookEvent stores an incoming webhook event
-func (r *AnalyticsRepository) StoreWebhookEvent(ctx context.Context, event *domain.WebhookEvent) error {
-	return r.db.WithContext(ctx).Create(event).Error
+// StoreWebhookEvent stores an incoming webhook event. It returns false
+// without storing anything if the delivery was already stored.
+func (r *AnalyticsRepository) StoreWebhookEvent(ctx context.Context, event *domain.WebhookEvent) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
 }
 
-// GetUnprocessedWebhookEvents gets unprocessed webhook events
-func (r *AnalyticsRepository) GetUnprocessedWebhookEvents(ctx context.Context, limit int) ([]domain.WebhookEvent, error) {
+// ClaimWebhookEvents claims up to limit pending webhook events that are
+// due, oldest first. Rows are locked with SKIP LOCKED so that concurrent
+// workers claim different events, and each claimed event is leased: it is
+// not claimed again until the lease expires, in case its worker dies.
+// The attempt count of each claimed event is incremented.
+func (r *AnalyticsRepository) ClaimWebhookEvents(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookEvent, error) {
 	var events []domain.WebhookEvent
-	
-	err := r.db.WithContext(ctx).Where("processed = ?", false).
-		Limit(limit).
-		Find(&events).Error
-	
+	now := time.Now().UTC()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)", domain.WebhookEventPending, now).
+			Order("created_at ASC").
+			Limit(limit).
+			Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		ids := make([]string, len(events))
+		leaseUntil := now.Add(lease)
+		for i := range events {
+			ids[i] = events[i].ID
+			events[i].Attempts++
+			events[i].NextAttemptAt = &leaseUntil
+		}
+		return tx.Model(&domain.WebhookEvent{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"attempts":        gorm.Expr("attempts + 1"),
+				"next_attempt_at": leaseUntil,
+			}).Error
+	})
+
 	return events, err
 }
 
@@ -305,11 +499,73 @@ func (r *AnalyticsRepository) MarkWebhookEventProcessed(ctx context.Context, eve
 	return r.db.WithContext(ctx).Model(&domain.WebhookEvent{}).
 		Where("id = ?", eventID).
 		Updates(map[string]interface{}{
-			"processed":    true,
-			"processed_at": now,
+			"status":          domain.WebhookEventProcessed,
+			"processed":       true,
+			"processed_at":    now,
+			"next_attempt_at": nil,
+			"last_error":      "",
 		}).Error
 }
 
+// RetryWebhookEvent records a failed attempt at a webhook event and
+// schedules the next one
+func (r *AnalyticsRepository) RetryWebhookEvent(ctx context.Context, eventID, lastError string, next time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.WebhookEvent{}).
+		Where("id = ?", eventID).
+		Updates(map[string]interface{}{
+			"next_attempt_at": next,
+			"last_error":      lastError,
+		}).Error
+}
+
+// DeadLetterWebhookEvent moves a webhook event that cannot be processed to
+// the dead letter status
+func (r *AnalyticsRepository) DeadLetterWebhookEvent(ctx context.Context, eventID, lastError string) error {
+	return r.db.WithContext(ctx).Model(&domain.WebhookEvent{}).
+		Where("id = ?", eventID).
+		Updates(map[string]interface{}{
+			"status":          domain.WebhookEventDead,
+			"next_attempt_at": nil,
+			"last_error":      lastError,
+		}).Error
+}
+
+// RequeueWebhookEvent moves a dead letter webhook event back to pending
+// with its attempts reset. It returns false if the event is not a dead
+// letter.
+func (r *AnalyticsRepository) RequeueWebhookEvent(ctx context.Context, eventID string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.WebhookEvent{}).
+		Where("id = ? AND status = ?", eventID, domain.WebhookEventDead).
+		Updates(map[string]interface{}{
+			"status":          domain.WebhookEventPending,
+			"attempts":        0,
+			"next_attempt_at": nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ListWebhookEvents lists webhook events, newest first, optionally
+// filtered by status and platform
+func (r *AnalyticsRepository) ListWebhookEvents(ctx context.Context, status, platform string, limit, offset int) ([]domain.WebhookEvent, error) {
+	var events []domain.WebhookEvent
+
+	query := r.db.WithContext(ctx).Model(&domain.WebhookEvent{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if platform != "" {
+		query = query.Where("platform = ?", platform)
+	}
+	err := query.Order("created_at DESC").
+		Limit(limit).Offset(offset).
+		Find(&events).Error
+
+	return events, err
+}
+
 // GetDashboardSummary gets comprehensive dashboard summary
 func (r *AnalyticsRepository) GetDashboardSummary(ctx context.Context, userID string) (*DashboardSummary, error) {
 	summary := &DashboardSummary{}
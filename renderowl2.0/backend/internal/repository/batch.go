@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"renderowl-api/internal/domain"
 )
 
@@ -43,20 +44,22 @@ func (BatchModel) TableName() string {
 
 // BatchVideoModel is the database model for batch videos
 type BatchVideoModel struct {
-	ID          string  `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	BatchID     string  `gorm:"index;not null"`
-	Title       string  `gorm:"not null"`
-	Description string
-	Status      string  `gorm:"not null;default:'pending'"`
-	TimelineID  string
-	Error       string
-	Progress    float64 `gorm:"default:0"`
-	ConfigJSON  string  `gorm:"type:jsonb"`
-	ResultJSON  string  `gorm:"type:jsonb"`
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	StartedAt   *time.Time
-	CompletedAt *time.Time
+	ID             string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	BatchID        string `gorm:"index;not null"`
+	Title          string `gorm:"not null"`
+	Description    string
+	Status         string `gorm:"not null;default:'pending'"`
+	Step           string
+	TimelineID     string
+	Error          string
+	Progress       float64 `gorm:"default:0"`
+	ConfigJSON     string  `gorm:"type:jsonb"`
+	ResultJSON     string  `gorm:"type:jsonb"`
+	CheckpointJSON string  `gorm:"type:jsonb"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	StartedAt      *time.Time
+	CompletedAt    *time.Time
 }
 
 // TableName specifies the table name
@@ -132,21 +135,28 @@ func (r *BatchRepository) createVideo(video *domain.BatchVideo) error {
 		}
 	}
 
+	checkpointJSON, err := json.Marshal(video.Checkpoint)
+	if err != nil {
+		return err
+	}
+
 	model := &BatchVideoModel{
-		ID:          video.ID,
-		BatchID:     video.BatchID,
-		Title:       video.Title,
-		Description: video.Description,
-		Status:      string(video.Status),
-		TimelineID:  video.TimelineID,
-		Error:       video.Error,
-		Progress:    video.Progress,
-		ConfigJSON:  string(configJSON),
-		ResultJSON:  string(resultJSON),
-		CreatedAt:   video.CreatedAt,
-		UpdatedAt:   video.UpdatedAt,
-		StartedAt:   video.StartedAt,
-		CompletedAt: video.CompletedAt,
+		ID:             video.ID,
+		BatchID:        video.BatchID,
+		Title:          video.Title,
+		Description:    video.Description,
+		Status:         string(video.Status),
+		Step:           string(video.Step),
+		TimelineID:     video.TimelineID,
+		Error:          video.Error,
+		Progress:       video.Progress,
+		ConfigJSON:     string(configJSON),
+		ResultJSON:     string(resultJSON),
+		CheckpointJSON: string(checkpointJSON),
+		CreatedAt:      video.CreatedAt,
+		UpdatedAt:      video.UpdatedAt,
+		StartedAt:      video.StartedAt,
+		CompletedAt:    video.CompletedAt,
 	}
 
 	return r.db.Create(model).Error
@@ -210,6 +220,20 @@ func (r *BatchRepository) Update(batch *domain.Batch) error {
 	return nil
 }
 
+// UpdateStatus updates only the status of a batch. Moving a batch back
+// into the queue clears its completion time so it can be finalized again.
+func (r *BatchRepository) UpdateStatus(batchID string, status domain.BatchStatus) error {
+	updates := map[string]interface{}{
+		"status":     string(status),
+		"updated_at": time.Now(),
+	}
+	if status == domain.BatchStatusQueued || status == domain.BatchStatusProcessing {
+		updates["completed_at"] = nil
+	}
+
+	return r.db.Model(&BatchModel{}).Where("id = ?", batchID).Updates(updates).Error
+}
+
 // updateVideo updates a batch video
 func (r *BatchRepository) updateVideo(video *domain.BatchVideo) error {
 	configJSON, err := json.Marshal(video.Config)
@@ -225,25 +249,248 @@ func (r *BatchRepository) updateVideo(video *domain.BatchVideo) error {
 		}
 	}
 
+	checkpointJSON, err := json.Marshal(video.Checkpoint)
+	if err != nil {
+		return err
+	}
+
 	model := &BatchVideoModel{
-		ID:          video.ID,
-		BatchID:     video.BatchID,
-		Title:       video.Title,
-		Description: video.Description,
-		Status:      string(video.Status),
-		TimelineID:  video.TimelineID,
-		Error:       video.Error,
-		Progress:    video.Progress,
-		ConfigJSON:  string(configJSON),
-		ResultJSON:  string(resultJSON),
-		UpdatedAt:   video.UpdatedAt,
-		StartedAt:   video.StartedAt,
-		CompletedAt: video.CompletedAt,
+		ID:             video.ID,
+		BatchID:        video.BatchID,
+		Title:          video.Title,
+		Description:    video.Description,
+		Status:         string(video.Status),
+		Step:           string(video.Step),
+		TimelineID:     video.TimelineID,
+		Error:          video.Error,
+		Progress:       video.Progress,
+		ConfigJSON:     string(configJSON),
+		ResultJSON:     string(resultJSON),
+		CheckpointJSON: string(checkpointJSON),
+		UpdatedAt:      video.UpdatedAt,
+		StartedAt:      video.StartedAt,
+		CompletedAt:    video.CompletedAt,
 	}
 
 	return r.db.Save(model).Error
 }
 
+// GetVideo retrieves a single batch video by ID
+func (r *BatchRepository) GetVideo(id string) (*domain.BatchVideo, error) {
+	var model BatchVideoModel
+	if err := r.db.First(&model, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("batch video not found")
+		}
+		return nil, err
+	}
+
+	return r.videoToDomain(&model), nil
+}
+
+// UpdateVideo persists the processing state of a single batch video.
+// Only state columns are written so concurrent workers never clobber
+// each other's rows or the parent batch.
+func (r *BatchRepository) UpdateVideo(video *domain.BatchVideo) error {
+	var resultJSON string
+	if video.Result != nil {
+		data, err := json.Marshal(video.Result)
+		if err != nil {
+			return err
+		}
+		resultJSON = string(data)
+	}
+
+	checkpointJSON, err := json.Marshal(video.Checkpoint)
+	if err != nil {
+		return err
+	}
+
+	video.UpdatedAt = time.Now()
+
+	return r.db.Model(&BatchVideoModel{}).
+		Where("id = ?", video.ID).
+		Updates(map[string]interface{}{
+			"status":          string(video.Status),
+			"step":            string(video.Step),
+			"timeline_id":     video.TimelineID,
+			"error":           video.Error,
+			"progress":        video.Progress,
+			"result_json":     resultJSON,
+			"checkpoint_json": string(checkpointJSON),
+			"updated_at":      video.UpdatedAt,
+			"started_at":      video.StartedAt,
+			"completed_at":    video.CompletedAt,
+		}).Error
+}
+
+// RefreshProgress recalculates the batch counters from its video rows and
+// finalizes the batch once every video has reached a terminal status
+func (r *BatchRepository) RefreshProgress(batchID string) (*domain.Batch, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var model BatchModel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&model, "id = ?", batchID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("batch not found")
+			}
+			return err
+		}
+
+		var counts []struct {
+			Status string
+			Count  int
+		}
+		if err := tx.Model(&BatchVideoModel{}).
+			Select("status, COUNT(*) AS count").
+			Where("batch_id = ?", batchID).
+			Group("status").
+			Scan(&counts).Error; err != nil {
+			return err
+		}
+
+		total, completed, failed, inProgress, finished := 0, 0, 0, 0, 0
+		for _, c := range counts {
+			status := domain.VideoStatus(c.Status)
+			total += c.Count
+			switch status {
+			case domain.VideoStatusCompleted:
+				completed += c.Count
+			case domain.VideoStatusFailed:
+				failed += c.Count
+			case domain.VideoStatusProcessing:
+				inProgress += c.Count
+			}
+			if status.IsTerminal() {
+				finished += c.Count
+			}
+		}
+
+		updates := map[string]interface{}{
+			"completed":   completed,
+			"failed":      failed,
+			"in_progress": inProgress,
+			"updated_at":  time.Now(),
+		}
+		if total > 0 {
+			updates["progress"] = float64(completed+failed) / float64(total) * 100
+		}
+
+		status := domain.BatchStatus(model.Status)
+		if status == domain.BatchStatusQueued || status == domain.BatchStatusProcessing {
+			if total > 0 && finished >= total {
+				now := time.Now()
+				updates["completed_at"] = &now
+				if completed > 0 {
+					updates["status"] = string(domain.BatchStatusCompleted) // Partial success included
+				} else {
+					updates["status"] = string(domain.BatchStatusFailed)
+				}
+			} else if inProgress > 0 {
+				updates["status"] = string(domain.BatchStatusProcessing)
+			}
+		}
+
+		return tx.Model(&BatchModel{}).Where("id = ?", batchID).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(batchID)
+}
+
+// ClaimVideos marks pending videos of a queued or processing batch as
+// queued, up to maxActive videos queued or processing at once. The batch
+// row is locked so concurrent dispatchers never exceed the limit.
+func (r *BatchRepository) ClaimVideos(batchID string, maxActive int) ([]*domain.BatchVideo, error) {
+	var claimed []*domain.BatchVideo
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var model BatchModel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&model, "id = ?", batchID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("batch not found")
+			}
+			return err
+		}
+
+		status := domain.BatchStatus(model.Status)
+		if status != domain.BatchStatusQueued && status != domain.BatchStatusProcessing {
+			return nil
+		}
+
+		var active int64
+		if err := tx.Model(&BatchVideoModel{}).
+			Where("batch_id = ? AND status IN ?", batchID, []string{
+				string(domain.VideoStatusQueued),
+				string(domain.VideoStatusProcessing),
+			}).
+			Count(&active).Error; err != nil {
+			return err
+		}
+
+		slots := maxActive - int(active)
+		if slots <= 0 {
+			return nil
+		}
+
+		var models []BatchVideoModel
+		if err := tx.Where("batch_id = ? AND status = ?", batchID, string(domain.VideoStatusPending)).
+			Order("created_at ASC").
+			Limit(slots).
+			Find(&models).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, m := range models {
+			if err := tx.Model(&BatchVideoModel{}).
+				Where("id = ?", m.ID).
+				Updates(map[string]interface{}{
+					"status":     string(domain.VideoStatusQueued),
+					"updated_at": now,
+				}).Error; err != nil {
+				return err
+			}
+			m.Status = string(domain.VideoStatusQueued)
+			m.UpdatedAt = now
+			claimed = append(claimed, r.videoToDomain(&m))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+// ListByStatus lists all batches in any of the given statuses
+func (r *BatchRepository) ListByStatus(statuses ...domain.BatchStatus) ([]*domain.Batch, error) {
+	values := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		values = append(values, string(status))
+	}
+
+	var models []BatchModel
+	if err := r.db.Where("status IN ?", values).
+		Order("created_at ASC").
+		Preload("Videos").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	var batches []*domain.Batch
+	for _, model := range models {
+		batches = append(batches, r.toDomain(&model))
+	}
+
+	return batches, nil
+}
+
 // List lists all batches for a user
 func (r *BatchRepository) List(userID string, limit, offset int) ([]*domain.Batch, error) {
 	var models []BatchModel
@@ -319,17 +566,25 @@ func (r *BatchRepository) videoToDomain(model *BatchVideoModel) *domain.BatchVid
 		json.Unmarshal([]byte(model.ResultJSON), &result)
 	}
 
+	// Deserialize checkpoint
+	var checkpoint domain.VideoCheckpoint
+	if model.CheckpointJSON != "" {
+		json.Unmarshal([]byte(model.CheckpointJSON), &checkpoint)
+	}
+
 	return &domain.BatchVideo{
 		ID:          model.ID,
 		BatchID:     model.BatchID,
 		Title:       model.Title,
 		Description: model.Description,
 		Status:      domain.VideoStatus(model.Status),
+		Step:        domain.VideoStep(model.Step),
 		TimelineID:  model.TimelineID,
 		Error:       model.Error,
 		Progress:    model.Progress,
 		Config:      config,
 		Result:      result,
+		Checkpoint:  checkpoint,
 		CreatedAt:   model.CreatedAt,
 		UpdatedAt:   model.UpdatedAt,
 		StartedAt:   model.StartedAt,
@@ -342,8 +597,14 @@ type BatchRepositoryInterface interface {
 	Create(batch *domain.Batch) error
 	Get(id string) (*domain.Batch, error)
 	Update(batch *domain.Batch) error
+	UpdateStatus(batchID string, status domain.BatchStatus) error
 	List(userID string, limit, offset int) ([]*domain.Batch, error)
 	Delete(id string) error
+	GetVideo(id string) (*domain.BatchVideo, error)
+	UpdateVideo(video *domain.BatchVideo) error
+	RefreshProgress(batchID string) (*domain.Batch, error)
+	ListByStatus(statuses ...domain.BatchStatus) ([]*domain.Batch, error)
+	ClaimVideos(batchID string, maxActive int) ([]*domain.BatchVideo, error)
 }
 
 var _ BatchRepositoryInterface = (*BatchRepository)(nil)
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"renderowl-api/internal/domain/social"
+)
+
+// CommentRepository stores the comments of published posts
+type CommentRepository struct {
+	db *gorm.DB
+}
+
+// NewCommentRepository creates a new repository
+func NewCommentRepository(db *gorm.DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+// GetByID gets a comment by ID
+func (r *CommentRepository) GetByID(ctx context.Context, id string) (*social.Comment, error) {
+	var comment social.Comment
+	err := r.db.WithContext(ctx).First(&comment, "id = ?", id).Error
+	return &comment, err
+}
+
+// GetByPlatformPost gets the stored comments of a platform post
+func (r *CommentRepository) GetByPlatformPost(ctx context.Context, platformPostID string) ([]*social.Comment, error) {
+	var comments []*social.Comment
+	err := r.db.WithContext(ctx).
+		Where("platform_post_id = ?", platformPostID).
+		Find(&comments).Error
+	return comments, err
+}
+
+// GetReplies gets the replies of top-level comments, oldest first
+func (r *CommentRepository) GetReplies(ctx context.Context, parentIDs []string) ([]*social.Comment, error) {
+	if len(parentIDs) == 0 {
+		return nil, nil
+	}
+	var replies []*social.Comment
+	err := r.db.WithContext(ctx).
+		Where("parent_id IN ?", parentIDs).
+		Order("published_at ASC").
+		Find(&replies).Error
+	return replies, err
+}
+
+// List gets the comments of a workspace matching a filter, newest first
+func (r *CommentRepository) List(ctx context.Context, userID string, filter social.CommentFilter) ([]*social.Comment, error) {
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if filter.Platform != "" {
+		query = query.Where("platform = ?", filter.Platform)
+	}
+	if filter.AccountID != "" {
+		query = query.Where("account_id = ?", filter.AccountID)
+	}
+	if filter.PostID != "" {
+		query = query.Where("scheduled_post_id = ?", filter.PostID)
+	}
+	if filter.Sentiment != "" {
+		query = query.Where("sentiment = ?", filter.Sentiment)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	} else {
+		query = query.Where("status <> ?", social.CommentStatusDeleted)
+	}
+	if filter.Unread {
+		query = query.Where("read = ?", false)
+	}
+
+	var comments []*social.Comment
+	err := query.
+		Order("published_at DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&comments).Error
+	return comments, err
+}
+
+// Save creates or updates a comment
+func (r *CommentRepository) Save(ctx context.Context, comment *social.Comment) error {
+	return r.db.WithContext(ctx).Save(comment).Error
+}
+
+// SaveAll creates or updates comments in one transaction
+func (r *CommentRepository) SaveAll(ctx context.Context, comments []*social.Comment) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, comment := range comments {
+			if err := tx.Save(comment).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MarkRead marks comments of a workspace as read or unread
+func (r *CommentRepository) MarkRead(ctx context.Context, userID string, ids []string, read bool) error {
+	return r.db.WithContext(ctx).
+		Model(&social.Comment{}).
+		Where("user_id = ? AND id IN ?", userID, ids).
+		Update("read", read).Error
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"renderowl-api/internal/domain/social"
+)
+
+// PostReviewRepository stores approval policies and the review trail of
+// scheduled posts
+type PostReviewRepository struct {
+	db *gorm.DB
+}
+
+// NewPostReviewRepository creates a new repository
+func NewPostReviewRepository(db *gorm.DB) *PostReviewRepository {
+	return &PostReviewRepository{db: db}
+}
+
+// GetPolicy gets the approval policy of a workspace, or nil if it has
+// none
+func (r *PostReviewRepository) GetPolicy(ctx context.Context, userID string) (*social.ApprovalPolicy, error) {
+	var policy social.ApprovalPolicy
+	err := r.db.WithContext(ctx).First(&policy, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// SavePolicy creates or replaces the approval policy of a workspace
+func (r *PostReviewRepository) SavePolicy(ctx context.Context, policy *social.ApprovalPolicy) error {
+	return r.db.WithContext(ctx).Save(policy).Error
+}
+
+// AddEvent appends an entry to the review trail of a post
+func (r *PostReviewRepository) AddEvent(ctx context.Context, event *social.PostReviewEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// GetEvents gets the review trail of a post, oldest first
+func (r *PostReviewRepository) GetEvents(ctx context.Context, postID string) ([]*social.PostReviewEvent, error) {
+	var events []*social.PostReviewEvent
+	err := r.db.WithContext(ctx).
+		Where("post_id = ?", postID).
+		Order("created_at ASC").
+		Find(&events).Error
+	return events, err
+}
+
+// GetAwaitingReview gets the posts waiting for a reviewer, oldest first
+func (r *PostReviewRepository) GetAwaitingReview(ctx context.Context, reviewerID string, limit int) ([]*social.ScheduledPost, error) {
+	var posts []*social.ScheduledPost
+	err := r.db.WithContext(ctx).
+		Where("reviewer_id = ? AND status = ?", reviewerID, social.PostStatusPendingReview).
+		Order("scheduled_at ASC").
+		Limit(limit).
+		Find(&posts).Error
+	return posts, err
+}
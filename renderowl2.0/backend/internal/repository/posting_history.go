@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"renderowl-api/internal/domain"
+	"renderowl-api/internal/domain/social"
+)
+
+// PostingHistoryRepository reads the publishing and view history used to
+// recommend posting times
+type PostingHistoryRepository struct {
+	db *gorm.DB
+}
+
+// NewPostingHistoryRepository creates a new repository
+func NewPostingHistoryRepository(db *gorm.DB) *PostingHistoryRepository {
+	return &PostingHistoryRepository{db: db}
+}
+
+// PublishedPostStats is the latest analytics of a published platform post
+type PublishedPostStats struct {
+	PublishedAt time.Time `json:"published_at"`
+	Views       int64     `json:"views"`
+	Likes       int64     `json:"likes"`
+	Comments    int64     `json:"comments"`
+	Shares      int64     `json:"shares"`
+}
+
+// HourlyViews represents views tracked within an hour
+type HourlyViews struct {
+	Hour  time.Time `json:"hour"`
+	Views int64     `json:"views"`
+}
+
+// GetPublishedPostStats gets the latest analytics of each post an account
+// published since a time
+func (r *PostingHistoryRepository) GetPublishedPostStats(ctx context.Context, accountID string, since time.Time) ([]PublishedPostStats, error) {
+	var results []PublishedPostStats
+
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT pp.published_at, ad.views, ad.likes, ad.comments, ad.shares
+		FROM platform_posts pp
+		JOIN LATERAL (
+			SELECT views, likes, comments, shares
+			FROM analytics_data
+			WHERE analytics_data.post_id = pp.id
+			ORDER BY recorded_at DESC
+			LIMIT 1
+		) ad ON true
+		WHERE pp.account_id = ? AND pp.status = ? AND pp.published_at >= ?`,
+		accountID, social.PostStatusPublished, since,
+	).Scan(&results).Error
+
+	return results, err
+}
+
+// GetHourlyViews gets the views of a user's videos on a platform per hour
+// since a time
+func (r *PostingHistoryRepository) GetHourlyViews(ctx context.Context, userID, platform string, since time.Time) ([]HourlyViews, error) {
+	var results []HourlyViews
+
+	// Views counted over a day or longer have no hour and are left out
+	err := r.db.WithContext(ctx).Model(&domain.AnalyticsView{}).
+		Select("hour, SUM(count) AS views").
+		Where("user_id = ? AND platform = ? AND hour >= ?", userID, platform, since).
+		Group("hour").
+		Find(&results).Error
+
+	return results, err
+}
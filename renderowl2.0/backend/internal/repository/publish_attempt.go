@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"renderowl-api/internal/domain/social"
+)
+
+// PublishAttemptRepository stores the publish ledger
+type PublishAttemptRepository struct {
+	db *gorm.DB
+}
+
+// NewPublishAttemptRepository creates a new repository
+func NewPublishAttemptRepository(db *gorm.DB) *PublishAttemptRepository {
+	return &PublishAttemptRepository{db: db}
+}
+
+// Begin records a started attempt. It returns false without recording
+// anything if another attempt with the same idempotency key is in flight.
+func (r *PublishAttemptRepository) Begin(ctx context.Context, attempt *social.PublishAttempt) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(attempt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Finish records the outcome of an attempt
+func (r *PublishAttemptRepository) Finish(ctx context.Context, attempt *social.PublishAttempt) error {
+	return r.db.WithContext(ctx).
+		Model(&social.PublishAttempt{}).
+		Where("id = ?", attempt.ID).
+		Updates(map[string]interface{}{
+			"status":         attempt.Status,
+			"remote_post_id": attempt.RemotePostID,
+			"post_url":       attempt.PostURL,
+			"error":          attempt.Error,
+			"finished_at":    attempt.FinishedAt,
+		}).Error
+}
+
+// GetLatest gets the most recent attempt for an idempotency key, or nil if
+// there is none
+func (r *PublishAttemptRepository) GetLatest(ctx context.Context, idempotencyKey string) (*social.PublishAttempt, error) {
+	var attempt social.PublishAttempt
+	err := r.db.WithContext(ctx).
+		Where("idempotency_key = ?", idempotencyKey).
+		Order("attempt DESC").
+		First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// GetSucceeded gets the succeeded attempt for an idempotency key, or nil
+// if the post has not been published
+func (r *PublishAttemptRepository) GetSucceeded(ctx context.Context, idempotencyKey string) (*social.PublishAttempt, error) {
+	var attempt social.PublishAttempt
+	err := r.db.WithContext(ctx).
+		Where("idempotency_key = ? AND status = ?", idempotencyKey, social.PublishAttemptSucceeded).
+		First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
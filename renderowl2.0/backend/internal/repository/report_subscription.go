@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"renderowl-api/internal/domain"
+)
+
+// ReportSubscriptionRepository stores analytics digest subscriptions
+type ReportSubscriptionRepository struct {
+	db *gorm.DB
+}
+
+// NewReportSubscriptionRepository creates a new repository
+func NewReportSubscriptionRepository(db *gorm.DB) *ReportSubscriptionRepository {
+	return &ReportSubscriptionRepository{db: db}
+}
+
+// Create stores a new subscription
+func (r *ReportSubscriptionRepository) Create(ctx context.Context, sub *domain.ReportSubscription) error {
+	return r.db.WithContext(ctx).Create(sub).Error
+}
+
+// GetByID gets a subscription by ID
+func (r *ReportSubscriptionRepository) GetByID(ctx context.Context, id string) (*domain.ReportSubscription, error) {
+	var sub domain.ReportSubscription
+	err := r.db.WithContext(ctx).First(&sub, "id = ?", id).Error
+	return &sub, err
+}
+
+// ListByUser gets the subscriptions of a user, oldest first
+func (r *ReportSubscriptionRepository) ListByUser(ctx context.Context, userID string) ([]*domain.ReportSubscription, error) {
+	var subs []*domain.ReportSubscription
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&subs).Error
+	return subs, err
+}
+
+// Update saves a subscription
+func (r *ReportSubscriptionRepository) Update(ctx context.Context, sub *domain.ReportSubscription) error {
+	return r.db.WithContext(ctx).Save(sub).Error
+}
+
+// Delete deletes a subscription of a user
+func (r *ReportSubscriptionRepository) Delete(ctx context.Context, id, userID string) error {
+	return r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		Delete(&domain.ReportSubscription{}).Error
+}
+
+// GetDue gets the enabled subscriptions whose next digest is due
+func (r *ReportSubscriptionRepository) GetDue(ctx context.Context, now time.Time, limit int) ([]*domain.ReportSubscription, error) {
+	var subs []*domain.ReportSubscription
+	err := r.db.WithContext(ctx).
+		Where("enabled = ? AND next_run_at <= ?", true, now).
+		Order("next_run_at ASC").
+		Limit(limit).
+		Find(&subs).Error
+	return subs, err
+}
+
+// ClaimDue moves a due subscription to its next digest before the digest
+// is sent, so runs that overlap do not send it too. It reports whether
+// the subscription was still due.
+func (r *ReportSubscriptionRepository) ClaimDue(ctx context.Context, id string, now, nextRunAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.ReportSubscription{}).
+		Where("id = ? AND enabled = ? AND next_run_at <= ?", id, true, now).
+		Update("next_run_at", nextRunAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// RecordRun records a delivery attempt and schedules the next digest. A
+// successful delivery clears the last error.
+func (r *ReportSubscriptionRepository) RecordRun(ctx context.Context, id string, nextRunAt time.Time, sentAt *time.Time, lastError string) error {
+	updates := map[string]interface{}{
+		"next_run_at": nextRunAt,
+		"last_error":  lastError,
+		"updated_at":  time.Now().UTC(),
+	}
+	if sentAt != nil {
+		updates["last_sent_at"] = *sentAt
+	}
+	return r.db.WithContext(ctx).Model(&domain.ReportSubscription{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
+
+// ClaimManualSend records a digest sent on request of a user, unless the
+// user had one sent after since. It reports whether the send was claimed.
+func (r *ReportSubscriptionRepository) ClaimManualSend(ctx context.Context, id, userID string, now, since time.Time) (bool, error) {
+	recent := r.db.Model(&domain.ReportSubscription{}).
+		Select("1").
+		Where("user_id = ? AND last_manual_send_at > ?", userID, since)
+	result := r.db.WithContext(ctx).Model(&domain.ReportSubscription{}).
+		Where("id = ? AND user_id = ? AND NOT EXISTS (?)", id, userID, recent).
+		Update("last_manual_send_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ConfirmRecipient adds a recipient to those who confirmed the digest of
+// a subscription, if it is still one of its recipients
+func (r *ReportSubscriptionRepository) ConfirmRecipient(ctx context.Context, id, address string) error {
+	return r.db.WithContext(ctx).Model(&domain.ReportSubscription{}).
+		Where("id = ? AND ? = ANY(recipients) AND NOT ? = ANY(COALESCE(confirmed_recipients, '{}'))", id, address, address).
+		Update("confirmed_recipients", gorm.Expr("array_append(confirmed_recipients, ?)", address)).Error
+}
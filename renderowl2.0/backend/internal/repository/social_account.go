@@ -2,14 +2,20 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"time"
 
 	"gorm.io/gorm"
 	"renderowl-api/internal/domain/social"
+	"renderowl-api/internal/secrets"
 )
 
 // SocialAccountRepository implements social.AccountRepository
 type SocialAccountRepository struct {
-	db *gorm.DB
+	db       *gorm.DB
+	envelope *secrets.Envelope
+	audit    *TokenAuditRepository
 }
 
 // NewSocialAccountRepository creates a new repository
@@ -17,8 +23,23 @@ func NewSocialAccountRepository(db *gorm.DB) *SocialAccountRepository {
 	return &SocialAccountRepository{db: db}
 }
 
+// EnableEncryption encrypts tokens with the envelope when they are written
+// and decrypts them when they are read. Decrypts are recorded in the token
+// audit log. Rows still holding plaintext tokens are read as is until they
+// are next written or rotated.
+func (r *SocialAccountRepository) EnableEncryption(envelope *secrets.Envelope) {
+	r.envelope = envelope
+	r.audit = NewTokenAuditRepository(r.db)
+}
+
 // Create creates a new social account
 func (r *SocialAccountRepository) Create(ctx context.Context, account *social.SocialAccount) error {
+	restore, err := r.sealTokens(account)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
 	return r.db.WithContext(ctx).Create(account).Error
 }
 
@@ -26,25 +47,65 @@ func (r *SocialAccountRepository) Create(ctx context.Context, account *social.So
 func (r *SocialAccountRepository) GetByID(ctx context.Context, id string) (*social.SocialAccount, error) {
 	var account social.SocialAccount
 	err := r.db.WithContext(ctx).First(&account, "id = ?", id).Error
-	return &account, err
+	if err != nil {
+		return &account, err
+	}
+	return &account, r.openTokens(ctx, &account)
 }
 
 // GetByUser gets accounts by user ID
 func (r *SocialAccountRepository) GetByUser(ctx context.Context, userID string) ([]*social.SocialAccount, error) {
 	var accounts []*social.SocialAccount
 	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&accounts).Error
-	return accounts, err
+	if err != nil {
+		return accounts, err
+	}
+	return accounts, r.openTokens(ctx, accounts...)
 }
 
 // GetByUserAndPlatform gets account by user and platform
 func (r *SocialAccountRepository) GetByUserAndPlatform(ctx context.Context, userID string, platform social.SocialPlatform) (*social.SocialAccount, error) {
 	var account social.SocialAccount
 	err := r.db.WithContext(ctx).Where("user_id = ? AND platform = ?", userID, platform).First(&account).Error
-	return &account, err
+	if err != nil {
+		return &account, err
+	}
+	return &account, r.openTokens(ctx, &account)
+}
+
+// GetByPlatformAccount gets an account by its platform and the ID the
+// platform knows it by
+func (r *SocialAccountRepository) GetByPlatformAccount(ctx context.Context, platform social.SocialPlatform, accountID string) (*social.SocialAccount, error) {
+	var account social.SocialAccount
+	err := r.db.WithContext(ctx).Where("platform = ? AND account_id = ?", platform, accountID).First(&account).Error
+	if err != nil {
+		return &account, err
+	}
+	return &account, r.openTokens(ctx, &account)
+}
+
+// GetExpiring gets connected or erroring accounts whose token expires before a time
+func (r *SocialAccountRepository) GetExpiring(ctx context.Context, before time.Time) ([]*social.SocialAccount, error) {
+	var accounts []*social.SocialAccount
+	err := r.db.WithContext(ctx).
+		Where("status IN ? AND token_expiry IS NOT NULL AND token_expiry <= ?",
+			[]social.PlatformStatus{social.StatusConnected, social.StatusError}, before).
+		Order("token_expiry ASC").
+		Find(&accounts).Error
+	if err != nil {
+		return accounts, err
+	}
+	return accounts, r.openTokens(ctx, accounts...)
 }
 
 // Update updates an account
 func (r *SocialAccountRepository) Update(ctx context.Context, account *social.SocialAccount) error {
+	restore, err := r.sealTokens(account)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
 	return r.db.WithContext(ctx).Save(account).Error
 }
 
@@ -52,3 +113,174 @@ func (r *SocialAccountRepository) Update(ctx context.Context, account *social.So
 func (r *SocialAccountRepository) Delete(ctx context.Context, id string) error {
 	return r.db.WithContext(ctx).Delete(&social.SocialAccount{}, "id = ?", id).Error
 }
+
+// RotateKeys re-encrypts every token that is stored in plaintext or sealed
+// under a key other than the current one. Accounts are processed in pages
+// of batchSize. With dryRun set nothing is written and the number of
+// accounts that would be rotated is returned.
+func (r *SocialAccountRepository) RotateKeys(ctx context.Context, batchSize int, dryRun bool) (int, error) {
+	if r.envelope == nil {
+		return 0, fmt.Errorf("token encryption is not enabled")
+	}
+
+	rotated := 0
+	lastID := ""
+	for {
+		var accounts []*social.SocialAccount
+		err := r.db.WithContext(ctx).
+			Where("id > ?", lastID).
+			Order("id ASC").
+			Limit(batchSize).
+			Find(&accounts).Error
+		if err != nil {
+			return rotated, err
+		}
+		if len(accounts) == 0 {
+			return rotated, nil
+		}
+		lastID = accounts[len(accounts)-1].ID
+
+		for _, account := range accounts {
+			if !r.envelope.NeedsRotation(account.AccessToken) && !r.envelope.NeedsRotation(account.RefreshToken) {
+				continue
+			}
+			if dryRun {
+				rotated++
+				continue
+			}
+
+			if err := r.rotateAccount(ctx, account); err != nil {
+				return rotated, fmt.Errorf("failed to rotate account %s: %w", account.ID, err)
+			}
+			rotated++
+		}
+	}
+}
+
+// rotateAccount re-seals the tokens of one account under the current key
+func (r *SocialAccountRepository) rotateAccount(ctx context.Context, account *social.SocialAccount) error {
+	var entries []TokenAuditModel
+	updates := make(map[string]interface{})
+
+	for _, field := range []struct {
+		column string
+		value  string
+	}{
+		{"access_token", account.AccessToken},
+		{"refresh_token", account.RefreshToken},
+	} {
+		if !r.envelope.NeedsRotation(field.value) {
+			continue
+		}
+
+		context := tokenContext(account.ID, field.column)
+		plaintext, keyID, err := r.envelope.Decrypt(field.value, context)
+		if err != nil {
+			return err
+		}
+		sealed, err := r.envelope.Encrypt(plaintext, context)
+		if err != nil {
+			return err
+		}
+
+		updates[field.column] = sealed
+		if keyID != "" {
+			entries = append(entries, r.auditEntry(account, field.column, keyID, TokenAuditRotate))
+		}
+	}
+
+	// UpdateColumns leaves updated_at alone, rotation is not a user change.
+	// Matching on the old values skips rows a token refresh wrote meanwhile,
+	// those are already sealed under the current key.
+	result := r.db.WithContext(ctx).
+		Model(&social.SocialAccount{}).
+		Where("id = ? AND access_token = ? AND refresh_token = ?",
+			account.ID, account.AccessToken, account.RefreshToken).
+		UpdateColumns(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	return r.audit.Record(ctx, entries)
+}
+
+// sealTokens encrypts the tokens of an account in place for writing and
+// returns a function putting the plaintext tokens back
+func (r *SocialAccountRepository) sealTokens(account *social.SocialAccount) (func(), error) {
+	if r.envelope == nil {
+		return func() {}, nil
+	}
+	if account.ID == "" {
+		return nil, fmt.Errorf("account has no ID to seal its tokens for")
+	}
+
+	accessToken, refreshToken := account.AccessToken, account.RefreshToken
+
+	sealedAccess, err := r.envelope.Encrypt(accessToken, tokenContext(account.ID, "access_token"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt access token: %w", err)
+	}
+	sealedRefresh, err := r.envelope.Encrypt(refreshToken, tokenContext(account.ID, "refresh_token"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt refresh token: %w", err)
+	}
+
+	account.AccessToken, account.RefreshToken = sealedAccess, sealedRefresh
+	return func() {
+		account.AccessToken, account.RefreshToken = accessToken, refreshToken
+	}, nil
+}
+
+// openTokens decrypts the tokens of loaded accounts and audits each decrypt
+func (r *SocialAccountRepository) openTokens(ctx context.Context, accounts ...*social.SocialAccount) error {
+	if r.envelope == nil {
+		return nil
+	}
+
+	var entries []TokenAuditModel
+	for _, account := range accounts {
+		for _, field := range []struct {
+			column string
+			value  *string
+		}{
+			{"access_token", &account.AccessToken},
+			{"refresh_token", &account.RefreshToken},
+		} {
+			plaintext, keyID, err := r.envelope.Decrypt(*field.value, tokenContext(account.ID, field.column))
+			if err != nil {
+				return fmt.Errorf("failed to decrypt %s of account %s: %w", field.column, account.ID, err)
+			}
+			*field.value = plaintext
+			if keyID != "" {
+				entries = append(entries, r.auditEntry(account, field.column, keyID, TokenAuditDecrypt))
+			}
+		}
+	}
+
+	// A failed audit write must not lock users out of their accounts
+	if err := r.audit.Record(ctx, entries); err != nil {
+		log.Printf("Warning: Failed to record token audit: %v", err)
+	}
+	return nil
+}
+
+// tokenContext binds a sealed token to the account and column it is stored
+// in, so a token copied to another account or column cannot be opened
+func tokenContext(accountID, column string) string {
+	return "social_accounts/" + accountID + "/" + column
+}
+
+func (r *SocialAccountRepository) auditEntry(account *social.SocialAccount, field, keyID, operation string) TokenAuditModel {
+	return TokenAuditModel{
+		AccountID: account.ID,
+		UserID:    account.UserID,
+		Field:     field,
+		KeyID:     keyID,
+		Operation: operation,
+		Caller:    auditCaller(),
+		CreatedAt: time.Now(),
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"gorm.io/gorm"
+	"renderowl-api/internal/domain"
 	"renderowl-api/internal/domain/social"
 )
 
@@ -41,3 +42,39 @@ func (r *SocialAnalyticsRepository) GetLatestByPost(ctx context.Context, postID
 		First(&data).Error
 	return &data, err
 }
+
+// GetLatestByPosts gets the latest analytics of each of several posts.
+// Posts without analytics are left out.
+func (r *SocialAnalyticsRepository) GetLatestByPosts(ctx context.Context, postIDs []string) ([]*social.AnalyticsData, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	var data []*social.AnalyticsData
+	err := r.db.WithContext(ctx).
+		Select("DISTINCT ON (post_id) *").
+		Where("post_id IN ?", postIDs).
+		Order("post_id, recorded_at DESC").
+		Find(&data).Error
+	return data, err
+}
+
+// GetPlatformTotals sums the latest analytics of the live platform posts of
+// videos on each platform
+func (r *SocialAnalyticsRepository) GetPlatformTotals(ctx context.Context) ([]domain.PlatformStats, error) {
+	var totals []domain.PlatformStats
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT latest.platform, SUM(latest.views) AS total_views, SUM(latest.likes) AS total_likes, COUNT(*) AS total_videos
+		FROM (
+			SELECT DISTINCT ON (ad.post_id) pp.platform, ad.views, ad.likes
+			FROM analytics_data ad
+			JOIN platform_posts pp ON pp.id = ad.post_id
+			JOIN scheduled_posts sp ON sp.id = pp.scheduled_post_id
+			WHERE pp.status = ? AND pp.platform_post_id <> '' AND sp.video_id <> ''
+			ORDER BY ad.post_id, ad.recorded_at DESC
+		) latest
+		GROUP BY latest.platform`,
+		social.PostStatusPublished,
+	).Scan(&totals).Error
+	return totals, err
+}
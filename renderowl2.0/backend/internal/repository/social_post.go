@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"gorm.io/gorm"
 	"renderowl-api/internal/domain/social"
@@ -17,9 +18,20 @@ func NewSocialPostRepository(db *gorm.DB) *SocialPostRepository {
 	return &SocialPostRepository{db: db}
 }
 
-// Create creates a new scheduled post
+// Create creates a new scheduled post with its platform posts
 func (r *SocialPostRepository) Create(ctx context.Context, post *social.ScheduledPost) error {
-	return r.db.WithContext(ctx).Create(post).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(post).Error; err != nil {
+			return err
+		}
+		for i := range post.Platforms {
+			post.Platforms[i].ScheduledPostID = post.ID
+			if err := tx.Create(&post.Platforms[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // GetByID gets post by ID
@@ -49,6 +61,106 @@ func (r *SocialPostRepository) GetByUser(ctx context.Context, userID string, lim
 	return posts, err
 }
 
+// GetPublishedToAccount gets the posts most recently published to an
+// account, newest first. Each post carries only its platform post for the
+// account.
+func (r *SocialPostRepository) GetPublishedToAccount(ctx context.Context, accountID string, limit int) ([]*social.ScheduledPost, error) {
+	var platformPosts []social.PlatformPost
+	err := r.db.WithContext(ctx).
+		Where("account_id = ? AND status = ?", accountID, social.PostStatusPublished).
+		Order("published_at DESC").
+		Limit(limit).
+		Find(&platformPosts).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.withPosts(ctx, platformPosts)
+}
+
+// GetPublishedSince gets the platform posts published since a time that
+// are still live, oldest first. Each post carries only one platform post,
+// so a post published to several accounts appears once per account.
+func (r *SocialPostRepository) GetPublishedSince(ctx context.Context, since time.Time) ([]*social.ScheduledPost, error) {
+	var platformPosts []social.PlatformPost
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND platform_post_id <> '' AND published_at >= ?", social.PostStatusPublished, since).
+		Order("published_at ASC").
+		Find(&platformPosts).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.withPosts(ctx, platformPosts)
+}
+
+// GetPublishedByVideos gets the live platform posts of videos. Each post
+// carries only one platform post.
+func (r *SocialPostRepository) GetPublishedByVideos(ctx context.Context, videoIDs []string) ([]*social.ScheduledPost, error) {
+	if len(videoIDs) == 0 {
+		return nil, nil
+	}
+
+	var platformPosts []social.PlatformPost
+	err := r.db.WithContext(ctx).
+		Joins("JOIN scheduled_posts ON scheduled_posts.id = platform_posts.scheduled_post_id").
+		Where("scheduled_posts.video_id IN ?", videoIDs).
+		Where("platform_posts.status = ? AND platform_posts.platform_post_id <> ''", social.PostStatusPublished).
+		Order("platform_posts.published_at ASC").
+		Find(&platformPosts).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.withPosts(ctx, platformPosts)
+}
+
+// GetByRemoteID gets the post published to a platform under a remote ID.
+// The post carries only the platform post with that ID.
+func (r *SocialPostRepository) GetByRemoteID(ctx context.Context, platform social.SocialPlatform, remoteID string) (*social.ScheduledPost, error) {
+	var platformPosts []social.PlatformPost
+	err := r.db.WithContext(ctx).
+		Where("platform = ? AND platform_post_id = ?", platform, remoteID).
+		Limit(1).
+		Find(&platformPosts).Error
+	if err != nil {
+		return nil, err
+	}
+	posts, err := r.withPosts(ctx, platformPosts)
+	if err != nil || len(posts) == 0 {
+		return nil, err
+	}
+	return posts[0], nil
+}
+
+// withPosts loads the scheduled post of each platform post. The returned
+// posts carry only the platform post they were loaded for.
+func (r *SocialPostRepository) withPosts(ctx context.Context, platformPosts []social.PlatformPost) ([]*social.ScheduledPost, error) {
+	if len(platformPosts) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(platformPosts))
+	for i, platformPost := range platformPosts {
+		ids[i] = platformPost.ScheduledPostID
+	}
+	var found []*social.ScheduledPost
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*social.ScheduledPost, len(found))
+	for _, post := range found {
+		byID[post.ID] = post
+	}
+
+	posts := make([]*social.ScheduledPost, 0, len(platformPosts))
+	for _, platformPost := range platformPosts {
+		if post, ok := byID[platformPost.ScheduledPostID]; ok {
+			item := *post
+			item.Platforms = []social.PlatformPost{platformPost}
+			posts = append(posts, &item)
+		}
+	}
+	return posts, nil
+}
+
 // GetPending gets posts scheduled before a certain time
 func (r *SocialPostRepository) GetPending(ctx context.Context, before string) ([]*social.ScheduledPost, error) {
 	var posts []*social.ScheduledPost
@@ -58,9 +170,74 @@ func (r *SocialPostRepository) GetPending(ctx context.Context, before string) ([
 	return posts, err
 }
 
-// Update updates a post
+// Update updates a post and its platform posts
 func (r *SocialPostRepository) Update(ctx context.Context, post *social.ScheduledPost) error {
-	return r.db.WithContext(ctx).Save(post).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(post).Error; err != nil {
+			return err
+		}
+		for i := range post.Platforms {
+			post.Platforms[i].ScheduledPostID = post.ID
+			if err := tx.Save(&post.Platforms[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpdatePlatformPost updates a single platform post
+func (r *SocialPostRepository) UpdatePlatformPost(ctx context.Context, platformPost *social.PlatformPost) error {
+	return r.db.WithContext(ctx).Save(platformPost).Error
+}
+
+// UpdateContent updates the title and description of a post, leaving its
+// status and platform posts as they are
+func (r *SocialPostRepository) UpdateContent(ctx context.Context, post *social.ScheduledPost) error {
+	return r.db.WithContext(ctx).
+		Model(&social.ScheduledPost{}).
+		Where("id = ?", post.ID).
+		Updates(map[string]interface{}{
+			"title":       post.Title,
+			"description": post.Description,
+		}).Error
+}
+
+// SwapPlatformPostStatus moves a platform post from one status to another.
+// It reports false if the platform post is no longer in the from status.
+func (r *SocialPostRepository) SwapPlatformPostStatus(ctx context.Context, id string, from, to social.PostStatus) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&social.PlatformPost{}).
+		Where("id = ? AND status = ?", id, from).
+		Update("status", to)
+	return result.RowsAffected > 0, result.Error
+}
+
+// AdvanceStatus updates the status of a post unless it was cancelled or
+// deleted, so the progress of a publish does not undo a withdrawal
+func (r *SocialPostRepository) AdvanceStatus(ctx context.Context, id string, status social.PostStatus, errorMsg string) error {
+	return r.notWithdrawn(ctx, id).
+		Updates(map[string]interface{}{
+			"status":    status,
+			"error_msg": errorMsg,
+		}).Error
+}
+
+// MarkPublished marks a post published unless it was cancelled or deleted
+// in the meantime
+func (r *SocialPostRepository) MarkPublished(ctx context.Context, id string, publishedAt time.Time) error {
+	return r.notWithdrawn(ctx, id).
+		Updates(map[string]interface{}{
+			"status":       social.PostStatusPublished,
+			"published_at": publishedAt,
+		}).Error
+}
+
+// notWithdrawn scopes an update to a post that was not cancelled or deleted
+func (r *SocialPostRepository) notWithdrawn(ctx context.Context, id string) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Model(&social.ScheduledPost{}).
+		Where("id = ? AND status NOT IN ?", id, []social.PostStatus{social.PostStatusCancelled, social.PostStatusDeleted})
 }
 
 // UpdateStatus updates post status
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Token audit operations
+const (
+	TokenAuditDecrypt = "decrypt"
+	TokenAuditRotate  = "rotate"
+)
+
+// TokenAuditModel records an access to an encrypted social account token
+type TokenAuditModel struct {
+	ID        uint   `gorm:"primaryKey"`
+	AccountID string `gorm:"index;not null"`
+	UserID    string `gorm:"index"`
+	Field     string `gorm:"not null"`
+	KeyID     string `gorm:"not null"`
+	Operation string `gorm:"not null"`
+	Caller    string
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// TableName specifies the table name
+func (TokenAuditModel) TableName() string {
+	return "token_audit_logs"
+}
+
+// TokenAuditRepository stores token audit entries
+type TokenAuditRepository struct {
+	db *gorm.DB
+}
+
+// NewTokenAuditRepository creates a new token audit repository
+func NewTokenAuditRepository(db *gorm.DB) *TokenAuditRepository {
+	return &TokenAuditRepository{db: db}
+}
+
+// Record stores audit entries
+func (r *TokenAuditRepository) Record(ctx context.Context, entries []TokenAuditModel) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&entries).Error
+}
+
+// auditCaller returns the first function outside the repository package
+// on the call stack, identifying which code path asked for a token
+func auditCaller() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.Function, "/internal/repository.") {
+			return frame.Function
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"renderowl-api/internal/domain/social"
+)
+
+// UploadSessionRepository implements social.UploadSessionRepository
+type UploadSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewUploadSessionRepository creates a new repository
+func NewUploadSessionRepository(db *gorm.DB) *UploadSessionRepository {
+	return &UploadSessionRepository{db: db}
+}
+
+// GetByKey gets the session of an upload, or nil if there is none
+func (r *UploadSessionRepository) GetByKey(ctx context.Context, key string) (*social.UploadSession, error) {
+	var session social.UploadSession
+	err := r.db.WithContext(ctx).First(&session, "upload_key = ?", key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Save creates or updates a session
+func (r *UploadSessionRepository) Save(ctx context.Context, session *social.UploadSession) error {
+	return r.db.WithContext(ctx).Save(session).Error
+}
+
+// Delete deletes a session
+func (r *UploadSessionRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&social.UploadSession{}, "id = ?", id).Error
+}
@@ -0,0 +1,721 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"renderowl-api/internal/domain/social"
+)
+
+// Frequency is the FREQ of a recurrence rule
+type Frequency int
+
+const (
+	Yearly Frequency = iota
+	Monthly
+	Weekly
+	Daily
+	Hourly
+)
+
+var frequencyNames = map[Frequency]string{
+	Yearly:  "YEARLY",
+	Monthly: "MONTHLY",
+	Weekly:  "WEEKLY",
+	Daily:   "DAILY",
+	Hourly:  "HOURLY",
+}
+
+var weekdayNames = map[time.Weekday]string{
+	time.Sunday:    "SU",
+	time.Monday:    "MO",
+	time.Tuesday:   "TU",
+	time.Wednesday: "WE",
+	time.Thursday:  "TH",
+	time.Friday:    "FR",
+	time.Saturday:  "SA",
+}
+
+// maxPeriods bounds how many periods are searched for occurrences, so a
+// rule that can never match does not loop forever
+const maxPeriods = 100000
+
+// WeekdayNum is a BYDAY entry. N selects the nth weekday of the month or
+// year, counting from the end when negative; zero selects every one.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// RRule is a recurrence rule in the RFC 5545 RRULE format. It supports
+// FREQ from YEARLY to HOURLY with INTERVAL, COUNT, UNTIL, BYMONTH,
+// BYMONTHDAY, BYDAY, BYHOUR, BYMINUTE, BYSETPOS and WKST.
+type RRule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByMonth    []int
+	ByMonthDay []int
+	ByDay      []WeekdayNum
+	ByHour     []int
+	ByMinute   []int
+	BySetPos   []int
+	WeekStart  time.Weekday
+
+	// A floating UNTIL is a wall clock time in the timezone of the
+	// recurrence rather than an instant
+	untilFloating bool
+}
+
+// Occurrence is a single time a recurrence happens
+type Occurrence struct {
+	Number int       `json:"occurrence"`
+	At     time.Time `json:"scheduledAt"`
+}
+
+// ParseRRule parses an RFC 5545 RRULE value, with or without the "RRULE:"
+// prefix
+func ParseRRule(value string) (*RRule, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	value = strings.TrimPrefix(value, "RRULE:")
+
+	rule := &RRule{Interval: 1, WeekStart: time.Monday}
+	hasFreq := false
+
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		var err error
+		switch key {
+		case "FREQ":
+			hasFreq = false
+			for freq, name := range frequencyNames {
+				if name == val {
+					rule.Freq = freq
+					hasFreq = true
+				}
+			}
+			if !hasFreq {
+				return nil, fmt.Errorf("unsupported FREQ %q", val)
+			}
+		case "INTERVAL":
+			rule.Interval, err = parsePositive(val)
+		case "COUNT":
+			rule.Count, err = parsePositive(val)
+		case "UNTIL":
+			rule.Until, rule.untilFloating, err = parseUntil(val)
+		case "BYMONTH":
+			rule.ByMonth, err = parseIntList(val, 1, 12, false)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseIntList(val, -31, 31, true)
+		case "BYHOUR":
+			rule.ByHour, err = parseIntList(val, 0, 23, false)
+		case "BYMINUTE":
+			rule.ByMinute, err = parseIntList(val, 0, 59, false)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(val)
+		case "BYSETPOS":
+			rule.BySetPos, err = parseIntList(val, -366, 366, true)
+		case "WKST":
+			rule.WeekStart, err = parseWeekday(val)
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+
+	if !hasFreq {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if err := rule.validate(); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// RuleFromRecurring converts a RecurringRule to an RRule, parsing its
+// RRULE when it has one
+func RuleFromRecurring(recurring *social.RecurringRule) (*RRule, error) {
+	if recurring == nil {
+		return nil, fmt.Errorf("no recurrence rule")
+	}
+	if recurring.RRule != "" {
+		return ParseRRule(recurring.RRule)
+	}
+
+	rule := &RRule{Interval: 1, WeekStart: time.Monday}
+
+	switch strings.ToLower(recurring.Frequency) {
+	case "hourly":
+		rule.Freq = Hourly
+	case "daily":
+		rule.Freq = Daily
+	case "weekly":
+		rule.Freq = Weekly
+	case "monthly":
+		rule.Freq = Monthly
+	case "yearly":
+		rule.Freq = Yearly
+	default:
+		return nil, fmt.Errorf("unsupported frequency %q", recurring.Frequency)
+	}
+
+	if recurring.Interval > 1 {
+		rule.Interval = recurring.Interval
+	}
+
+	for _, day := range recurring.DaysOfWeek {
+		if day < 0 || day > 6 {
+			return nil, fmt.Errorf("invalid day of week %d, expected 0 (Sunday) to 6", day)
+		}
+		rule.ByDay = append(rule.ByDay, WeekdayNum{Day: time.Weekday(day)})
+	}
+
+	if recurring.EndDate != nil && *recurring.EndDate != "" {
+		if t, err := time.Parse(time.RFC3339, *recurring.EndDate); err == nil {
+			rule.Until = t
+		} else if d, err := time.Parse("2006-01-02", *recurring.EndDate); err == nil {
+			// The whole end date is included
+			rule.Until = d.Add(24*time.Hour - time.Second)
+			rule.untilFloating = true
+		} else {
+			return nil, fmt.Errorf("invalid end date %q, expected YYYY-MM-DD", *recurring.EndDate)
+		}
+	}
+
+	if recurring.EndAfter != nil {
+		if *recurring.EndAfter < 1 {
+			return nil, fmt.Errorf("endAfter must be at least 1")
+		}
+		rule.Count = *recurring.EndAfter
+	}
+
+	if err := rule.validate(); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// String formats the rule as an RRULE value
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + frequencyNames[r.Freq]}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if !r.Until.IsZero() {
+		if r.untilFloating {
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102T150405"))
+		} else {
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+		}
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+formatIntList(r.ByMonth))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+formatIntList(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = weekdayNames[wd.Day]
+			if wd.N != 0 {
+				days[i] = strconv.Itoa(wd.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByHour) > 0 {
+		parts = append(parts, "BYHOUR="+formatIntList(r.ByHour))
+	}
+	if len(r.ByMinute) > 0 {
+		parts = append(parts, "BYMINUTE="+formatIntList(r.ByMinute))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+formatIntList(r.BySetPos))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayNames[r.WeekStart])
+	}
+	return strings.Join(parts, ";")
+}
+
+func (r *RRule) validate() error {
+	if r.Count > 0 && !r.Until.IsZero() {
+		return fmt.Errorf("COUNT and UNTIL cannot both be set")
+	}
+	if r.Freq == Weekly && len(r.ByMonthDay) > 0 {
+		return fmt.Errorf("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	if len(r.BySetPos) > 0 && len(r.ByMonth)+len(r.ByMonthDay)+len(r.ByDay)+len(r.ByHour)+len(r.ByMinute) == 0 {
+		return fmt.Errorf("BYSETPOS needs another BYxxx rule part")
+	}
+	if r.Freq != Monthly && r.Freq != Yearly {
+		for _, wd := range r.ByDay {
+			if wd.N != 0 {
+				return fmt.Errorf("numbered BYDAY is only allowed with FREQ=MONTHLY or FREQ=YEARLY")
+			}
+		}
+	}
+	return nil
+}
+
+// Recurrence is a rule anchored at its first occurrence in a timezone
+type Recurrence struct {
+	rule  *RRule
+	start time.Time
+	until time.Time
+}
+
+// NewRecurrence anchors a rule at the first occurrence. Times are
+// calculated on the wall clock of the timezone, so a daily post at 09:00
+// stays at 09:00 across daylight saving changes. An empty timezone is UTC.
+func NewRecurrence(recurring *social.RecurringRule, start time.Time, timezone string) (*Recurrence, error) {
+	rule, err := RuleFromRecurring(recurring)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q", timezone)
+	}
+
+	r := &Recurrence{rule: rule, start: start.In(loc)}
+	if !rule.Until.IsZero() {
+		r.until = rule.Until
+		if rule.untilFloating {
+			u := rule.Until
+			r.until = time.Date(u.Year(), u.Month(), u.Day(), u.Hour(), u.Minute(), u.Second(), 0, loc)
+		}
+	}
+
+	return r, nil
+}
+
+// Rule returns the rule of the recurrence
+func (r *Recurrence) Rule() *RRule {
+	return r.rule
+}
+
+// Next returns the first occurrence after a time, or false if the
+// recurrence has ended
+func (r *Recurrence) Next(after time.Time) (Occurrence, bool) {
+	var next Occurrence
+	found := false
+	r.iterate(func(o Occurrence) bool {
+		if o.At.After(after) {
+			next, found = o, true
+			return false
+		}
+		return true
+	})
+	return next, found
+}
+
+// Upcoming returns up to limit occurrences after a time
+func (r *Recurrence) Upcoming(after time.Time, limit int) []Occurrence {
+	var occurrences []Occurrence
+	if limit <= 0 {
+		return occurrences
+	}
+	r.iterate(func(o Occurrence) bool {
+		if o.At.After(after) {
+			occurrences = append(occurrences, o)
+		}
+		return len(occurrences) < limit
+	})
+	return occurrences
+}
+
+// iterate calls fn with each occurrence in order until it returns false.
+// The start always counts as the first occurrence.
+func (r *Recurrence) iterate(fn func(Occurrence) bool) {
+	number := 1
+	if !fn(Occurrence{Number: number, At: r.start}) {
+		return
+	}
+
+	for period := 1; period < maxPeriods; period++ {
+		candidates, periodStart := r.expand(period - 1)
+		if !r.until.IsZero() && periodStart.After(r.until) {
+			return
+		}
+
+		for _, t := range candidates {
+			if !t.After(r.start) {
+				continue
+			}
+			if !r.until.IsZero() && t.After(r.until) {
+				return
+			}
+			if r.rule.Count > 0 && number >= r.rule.Count {
+				return
+			}
+			number++
+			if !fn(Occurrence{Number: number, At: t}) {
+				return
+			}
+		}
+	}
+}
+
+// expand returns the sorted candidate times of the nth period after the
+// start, narrowed down by BYSETPOS, and the beginning of that period
+func (r *Recurrence) expand(n int) ([]time.Time, time.Time) {
+	times, periodStart := r.expandPeriod(n)
+	return r.setPositions(times), periodStart
+}
+
+// expandPeriod returns the sorted candidate times of the nth period after
+// the start and the beginning of that period
+func (r *Recurrence) expandPeriod(n int) ([]time.Time, time.Time) {
+	rule := r.rule
+	start := r.start
+	loc := start.Location()
+	step := n * rule.Interval
+
+	var days []time.Time
+	var periodStart time.Time
+
+	switch rule.Freq {
+	case Yearly:
+		year := start.Year() + step
+		periodStart = time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+		days = r.yearDays(year)
+
+	case Monthly:
+		periodStart = time.Date(start.Year(), start.Month()+time.Month(step), 1, 0, 0, 0, 0, loc)
+		if matchesInt(rule.ByMonth, int(periodStart.Month())) {
+			days = r.monthDays(periodStart.Year(), periodStart.Month())
+		}
+
+	case Weekly:
+		offset := (int(start.Weekday()) - int(rule.WeekStart) + 7) % 7
+		periodStart = time.Date(start.Year(), start.Month(), start.Day()-offset+7*step, 0, 0, 0, 0, loc)
+		for i := 0; i < 7; i++ {
+			day := time.Date(periodStart.Year(), periodStart.Month(), periodStart.Day()+i, 0, 0, 0, 0, loc)
+			if !matchesInt(rule.ByMonth, int(day.Month())) {
+				continue
+			}
+			if len(rule.ByDay) == 0 && day.Weekday() != start.Weekday() {
+				continue
+			}
+			if len(rule.ByDay) > 0 && !matchesWeekday(rule.ByDay, day.Weekday()) {
+				continue
+			}
+			days = append(days, day)
+		}
+
+	case Daily:
+		periodStart = time.Date(start.Year(), start.Month(), start.Day()+step, 0, 0, 0, 0, loc)
+		if r.matchesDay(periodStart) {
+			days = append(days, periodStart)
+		}
+
+	case Hourly:
+		hour := time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), 0, 0, 0, loc)
+		periodStart = hour.Add(time.Duration(step) * time.Hour)
+		if !r.matchesDay(periodStart) || !matchesInt(rule.ByHour, periodStart.Hour()) {
+			return nil, periodStart
+		}
+		var times []time.Time
+		for _, minute := range orDefault(rule.ByMinute, start.Minute()) {
+			times = append(times, periodStart.Add(time.Duration(minute)*time.Minute+time.Duration(start.Second())*time.Second))
+		}
+		return sortTimes(times), periodStart
+	}
+
+	var times []time.Time
+	for _, day := range days {
+		for _, hour := range orDefault(rule.ByHour, start.Hour()) {
+			for _, minute := range orDefault(rule.ByMinute, start.Minute()) {
+				times = append(times, wallClock(day.Year(), day.Month(), day.Day(), hour, minute, start.Second(), loc))
+			}
+		}
+	}
+
+	return sortTimes(times), periodStart
+}
+
+// yearDays returns the days of a year selected by BYMONTH, BYMONTHDAY and
+// BYDAY, following RFC 5545: BYMONTH and BYMONTHDAY select days within
+// each month, while BYDAY on its own selects weekdays across the whole
+// year, numbered within the year. Without any of them it is the month and
+// day of the start, so a rule started on February 29 only recurs in leap
+// years.
+func (r *Recurrence) yearDays(year int) []time.Time {
+	rule := r.rule
+	loc := r.start.Location()
+
+	var days []time.Time
+	switch {
+	case len(rule.ByMonth) > 0:
+		for _, month := range rule.ByMonth {
+			days = append(days, r.monthDays(year, time.Month(month))...)
+		}
+	case len(rule.ByMonthDay) > 0:
+		for month := time.January; month <= time.December; month++ {
+			days = append(days, r.monthDays(year, month)...)
+		}
+	case len(rule.ByDay) > 0:
+		first := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+		daysIn := time.Date(year, time.December, 31, 0, 0, 0, 0, loc).YearDay()
+		days = weekdaysIn(first, daysIn, rule.ByDay)
+	default:
+		days = r.monthDays(year, r.start.Month())
+	}
+	return days
+}
+
+// monthDays returns the days of a month selected by BYMONTHDAY and BYDAY,
+// or the day of the month of the start if neither is set
+func (r *Recurrence) monthDays(year int, month time.Month) []time.Time {
+	loc := r.start.Location()
+	daysIn := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	date := func(day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, loc)
+	}
+
+	var days []time.Time
+	switch {
+	case len(r.rule.ByMonthDay) > 0:
+		for _, md := range r.rule.ByMonthDay {
+			day := md
+			if md < 0 {
+				day = daysIn + md + 1
+			}
+			if day < 1 || day > daysIn {
+				continue
+			}
+			if len(r.rule.ByDay) > 0 && !matchesWeekday(r.rule.ByDay, date(day).Weekday()) {
+				continue
+			}
+			days = append(days, date(day))
+		}
+
+	case len(r.rule.ByDay) > 0:
+		days = weekdaysIn(date(1), daysIn, r.rule.ByDay)
+
+	default:
+		// Months without the start day are skipped, as RFC 5545 requires
+		if r.start.Day() <= daysIn {
+			days = append(days, date(r.start.Day()))
+		}
+	}
+
+	return days
+}
+
+// weekdaysIn returns the days selected by BYDAY among count days from
+// first. Numbered weekdays count within those days, from the end when
+// negative.
+func weekdaysIn(first time.Time, count int, byDay []WeekdayNum) []time.Time {
+	var days []time.Time
+	for _, wd := range byDay {
+		var matches []time.Time
+		for i := 0; i < count; i++ {
+			day := time.Date(first.Year(), first.Month(), first.Day()+i, 0, 0, 0, 0, first.Location())
+			if day.Weekday() == wd.Day {
+				matches = append(matches, day)
+			}
+		}
+		switch {
+		case wd.N == 0:
+			days = append(days, matches...)
+		case wd.N > 0 && wd.N <= len(matches):
+			days = append(days, matches[wd.N-1])
+		case wd.N < 0 && -wd.N <= len(matches):
+			days = append(days, matches[len(matches)+wd.N])
+		}
+	}
+	return days
+}
+
+// setPositions keeps the candidate times of a period at the BYSETPOS
+// positions, counting from the end when negative
+func (r *Recurrence) setPositions(times []time.Time) []time.Time {
+	if len(r.rule.BySetPos) == 0 {
+		return times
+	}
+	var selected []time.Time
+	for _, pos := range r.rule.BySetPos {
+		switch {
+		case pos > 0 && pos <= len(times):
+			selected = append(selected, times[pos-1])
+		case pos < 0 && -pos <= len(times):
+			selected = append(selected, times[len(times)+pos])
+		}
+	}
+	return sortTimes(selected)
+}
+
+// matchesDay applies the BYMONTH, BYMONTHDAY and BYDAY filters of daily
+// and hourly rules
+func (r *Recurrence) matchesDay(t time.Time) bool {
+	if !matchesInt(r.rule.ByMonth, int(t.Month())) {
+		return false
+	}
+	if len(r.rule.ByMonthDay) > 0 {
+		daysIn := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+		matched := false
+		for _, md := range r.rule.ByMonthDay {
+			if md == t.Day() || (md < 0 && daysIn+md+1 == t.Day()) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(r.rule.ByDay) > 0 && !matchesWeekday(r.rule.ByDay, t.Weekday()) {
+		return false
+	}
+	return true
+}
+
+// wallClock returns a wall clock time in a timezone. A time skipped when
+// the clocks go forward is read with the offset from before the change, as
+// RFC 5545 requires, so it moves later by the length of the gap.
+func wallClock(year int, month time.Month, day, hour, minute, second int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, hour, minute, second, 0, loc)
+	if t.Hour() == hour && t.Minute() == minute {
+		return t
+	}
+	_, offset := t.Add(-12 * time.Hour).Zone()
+	naive := time.Date(year, month, day, hour, minute, second, 0, time.UTC)
+	return naive.Add(-time.Duration(offset) * time.Second).In(loc)
+}
+
+func matchesInt(values []int, v int) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesWeekday(days []WeekdayNum, day time.Weekday) bool {
+	for _, wd := range days {
+		if wd.Day == day {
+			return true
+		}
+	}
+	return false
+}
+
+func orDefault(values []int, def int) []int {
+	if len(values) == 0 {
+		return []int{def}
+	}
+	return values
+}
+
+// sortTimes sorts times and drops duplicates
+func sortTimes(times []time.Time) []time.Time {
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	unique := times[:0]
+	for i, t := range times {
+		if i == 0 || !t.Equal(times[i-1]) {
+			unique = append(unique, t)
+		}
+	}
+	return unique
+}
+
+func parsePositive(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%q is not a positive number", value)
+	}
+	return n, nil
+}
+
+func parseIntList(value string, min, max int, nonZero bool) ([]int, error) {
+	var values []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimPrefix(item, "+"))
+		if err != nil || n < min || n > max || (nonZero && n == 0) {
+			return nil, fmt.Errorf("%q is out of range", item)
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(value, ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid day %q", item)
+		}
+		day, err := parseWeekday(item[len(item)-2:])
+		if err != nil {
+			return nil, err
+		}
+		wd := WeekdayNum{Day: day}
+		if prefix := item[:len(item)-2]; prefix != "" {
+			n, err := strconv.Atoi(strings.TrimPrefix(prefix, "+"))
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("invalid day %q", item)
+			}
+			wd.N = n
+		}
+		days = append(days, wd)
+	}
+	return days, nil
+}
+
+func parseWeekday(value string) (time.Weekday, error) {
+	for day, name := range weekdayNames {
+		if name == value {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", value)
+}
+
+// parseUntil parses an UNTIL value. UTC times are instants, while local
+// times and dates are floating and resolved in the recurrence timezone.
+func parseUntil(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse("20060102T150405", value); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse("20060102", value); err == nil {
+		return t.Add(24*time.Hour - time.Second), true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("%q is not a date or date-time", value)
+}
+
+func formatIntList(values []int) string {
+	items := make([]string, len(values))
+	for i, v := range values {
+		items[i] = strconv.Itoa(v)
+	}
+	return strings.Join(items, ",")
+}
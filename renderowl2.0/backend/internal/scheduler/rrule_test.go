@@ -0,0 +1,284 @@
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"renderowl-api/internal/domain/social"
+)
+
+func TestRecurrenceOccurrences(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		start    string // wall clock time in the timezone
+		timezone string
+		want     []string
+		ends     bool // the rule has no occurrences after want
+	}{
+		{
+			name:     "yearly numbered weekday of the year",
+			rule:     "FREQ=YEARLY;BYDAY=20MO",
+			start:    "1997-05-19 09:00",
+			timezone: "America/New_York",
+			want: []string{
+				"1997-05-19T09:00:00-04:00",
+				"1998-05-18T09:00:00-04:00",
+				"1999-05-17T09:00:00-04:00",
+			},
+		},
+		{
+			name:     "yearly month day in every month",
+			rule:     "FREQ=YEARLY;BYMONTHDAY=1;COUNT=4",
+			start:    "2025-01-01 09:00",
+			timezone: "UTC",
+			want: []string{
+				"2025-01-01T09:00:00Z",
+				"2025-02-01T09:00:00Z",
+				"2025-03-01T09:00:00Z",
+				"2025-04-01T09:00:00Z",
+			},
+			ends: true,
+		},
+		{
+			name:     "yearly last friday of the year",
+			rule:     "FREQ=YEARLY;BYDAY=-1FR",
+			start:    "2025-12-26 12:00",
+			timezone: "UTC",
+			want: []string{
+				"2025-12-26T12:00:00Z",
+				"2026-12-25T12:00:00Z",
+				"2027-12-31T12:00:00Z",
+			},
+		},
+		{
+			name:     "yearly by month keeps the start day",
+			rule:     "FREQ=YEARLY;BYMONTH=3,9",
+			start:    "2025-03-15 10:00",
+			timezone: "UTC",
+			want: []string{
+				"2025-03-15T10:00:00Z",
+				"2025-09-15T10:00:00Z",
+				"2026-03-15T10:00:00Z",
+			},
+		},
+		{
+			name:     "yearly from a leap day",
+			rule:     "FREQ=YEARLY",
+			start:    "2024-02-29 09:00",
+			timezone: "UTC",
+			want: []string{
+				"2024-02-29T09:00:00Z",
+				"2028-02-29T09:00:00Z",
+				"2032-02-29T09:00:00Z",
+			},
+		},
+		{
+			name:     "leap day month day",
+			rule:     "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29;COUNT=3",
+			start:    "2024-02-29 09:00",
+			timezone: "UTC",
+			want: []string{
+				"2024-02-29T09:00:00Z",
+				"2028-02-29T09:00:00Z",
+				"2032-02-29T09:00:00Z",
+			},
+			ends: true,
+		},
+		{
+			name:     "monthly 29th skips short februaries",
+			rule:     "FREQ=MONTHLY;BYMONTHDAY=29",
+			start:    "2025-01-29 09:00",
+			timezone: "UTC",
+			want: []string{
+				"2025-01-29T09:00:00Z",
+				"2025-03-29T09:00:00Z",
+				"2025-04-29T09:00:00Z",
+			},
+		},
+		{
+			name:     "monthly last friday",
+			rule:     "FREQ=MONTHLY;BYDAY=-1FR",
+			start:    "2025-01-31 09:00",
+			timezone: "UTC",
+			want: []string{
+				"2025-01-31T09:00:00Z",
+				"2025-02-28T09:00:00Z",
+				"2025-03-28T09:00:00Z",
+				"2025-04-25T09:00:00Z",
+			},
+		},
+		{
+			name:     "set position last work day of the month",
+			rule:     "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+			start:    "2025-01-31 17:00",
+			timezone: "UTC",
+			want: []string{
+				"2025-01-31T17:00:00Z",
+				"2025-02-28T17:00:00Z",
+				"2025-03-31T17:00:00Z",
+				"2025-04-30T17:00:00Z",
+			},
+		},
+		{
+			name:     "set position third of several weekdays",
+			rule:     "FREQ=MONTHLY;COUNT=3;BYDAY=TU,WE,TH;BYSETPOS=3",
+			start:    "1997-09-04 09:00",
+			timezone: "America/New_York",
+			want: []string{
+				"1997-09-04T09:00:00-04:00",
+				"1997-10-07T09:00:00-04:00",
+				"1997-11-06T09:00:00-05:00",
+			},
+			ends: true,
+		},
+		{
+			name:     "count",
+			rule:     "FREQ=DAILY;COUNT=3",
+			start:    "2025-01-01 09:00",
+			timezone: "UTC",
+			want: []string{
+				"2025-01-01T09:00:00Z",
+				"2025-01-02T09:00:00Z",
+				"2025-01-03T09:00:00Z",
+			},
+			ends: true,
+		},
+		{
+			name:     "until is inclusive",
+			rule:     "FREQ=WEEKLY;UNTIL=20250122T090000Z",
+			start:    "2025-01-01 09:00",
+			timezone: "UTC",
+			want: []string{
+				"2025-01-01T09:00:00Z",
+				"2025-01-08T09:00:00Z",
+				"2025-01-15T09:00:00Z",
+				"2025-01-22T09:00:00Z",
+			},
+			ends: true,
+		},
+		{
+			name:     "floating until date in the timezone",
+			rule:     "FREQ=DAILY;UNTIL=20250103",
+			start:    "2025-01-01 21:00",
+			timezone: "America/New_York",
+			want: []string{
+				"2025-01-01T21:00:00-05:00",
+				"2025-01-02T21:00:00-05:00",
+				"2025-01-03T21:00:00-05:00",
+			},
+			ends: true,
+		},
+		{
+			name:     "daily keeps the wall clock when clocks go forward",
+			rule:     "FREQ=DAILY",
+			start:    "2025-03-08 09:00",
+			timezone: "America/New_York",
+			want: []string{
+				"2025-03-08T09:00:00-05:00",
+				"2025-03-09T09:00:00-04:00",
+				"2025-03-10T09:00:00-04:00",
+			},
+		},
+		{
+			name:     "daily keeps the wall clock when clocks go back",
+			rule:     "FREQ=DAILY",
+			start:    "2025-11-01 09:00",
+			timezone: "Europe/Berlin",
+			want: []string{
+				"2025-11-01T09:00:00+01:00",
+				"2025-11-02T09:00:00+01:00",
+			},
+		},
+		{
+			name:     "daily time skipped by the clocks going forward",
+			rule:     "FREQ=DAILY",
+			start:    "2025-03-08 02:30",
+			timezone: "America/New_York",
+			want: []string{
+				"2025-03-08T02:30:00-05:00",
+				"2025-03-09T03:30:00-04:00",
+				"2025-03-10T02:30:00-04:00",
+			},
+		},
+		{
+			name:     "weekly across the end of summer time",
+			rule:     "FREQ=WEEKLY;BYDAY=SU",
+			start:    "2025-10-19 10:00",
+			timezone: "Europe/London",
+			want: []string{
+				"2025-10-19T10:00:00+01:00",
+				"2025-10-26T10:00:00Z",
+				"2025-11-02T10:00:00Z",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := time.LoadLocation(tt.timezone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start, err := time.ParseInLocation("2006-01-02 15:04", tt.start, loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recurrence, err := NewRecurrence(&social.RecurringRule{RRule: tt.rule}, start, tt.timezone)
+			if err != nil {
+				t.Fatalf("NewRecurrence: %v", err)
+			}
+
+			limit := len(tt.want)
+			if tt.ends {
+				limit++
+			}
+			var got []string
+			for i, o := range recurrence.Upcoming(start.Add(-time.Second), limit) {
+				if o.Number != i+1 {
+					t.Errorf("occurrence %d is numbered %d", i+1, o.Number)
+				}
+				got = append(got, o.At.Format(time.RFC3339))
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("occurrences\n got: %v\nwant: %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		{rule: "RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", want: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1"},
+		{rule: "FREQ=YEARLY;BYDAY=20MO", want: "FREQ=YEARLY;BYDAY=20MO"},
+		{rule: "FREQ=DAILY;BYSETPOS=1", wantErr: true},
+		{rule: "FREQ=MONTHLY;BYDAY=MO;BYSETPOS=0", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=1MO", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=2;UNTIL=20250101", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := ParseRRule(tt.rule)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseRRule(%q) = %s, want an error", tt.rule, rule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRRule(%q): %v", tt.rule, err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
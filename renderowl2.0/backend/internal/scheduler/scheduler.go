@@ -3,6 +3,7 @@ package scheduler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -39,11 +40,43 @@ const (
 // JobHandler is a function that processes a job
 type JobHandler func(ctx context.Context, job *Job) error
 
+// DeferError is returned by a handler whose job cannot run yet. The job runs
+// again after Delay without using up one of its retries.
+type DeferError struct {
+	Delay  time.Duration
+	Reason string
+}
+
+func (e *DeferError) Error() string {
+	return fmt.Sprintf("deferred for %s: %s", e.Delay, e.Reason)
+}
+
+// Defer defers a job by delay
+func Defer(delay time.Duration, reason string) error {
+	return &DeferError{Delay: delay, Reason: reason}
+}
+
+// defaultConcurrency is how many jobs run at the same time by default
+const defaultConcurrency = 10
+
+// initialRunWindow is how long the run of a recurring job added on startup
+// is not added again, so replicas that start together run it once
+const initialRunWindow = 15 * time.Minute
+
+// occurrenceClaimTTL is how long the claim of a recurring job's occurrence
+// is kept, so only one replica adds the occurrence
+const occurrenceClaimTTL = 24 * time.Hour
+
+// jobTimeout is how long a job may run. It is longer than the timeout of
+// video uploads, so a large upload is not cut off by its job.
+const jobTimeout = 12 * time.Minute
+
 // Scheduler manages job scheduling and execution
 type Scheduler struct {
 	client   *redis.Client
 	handlers map[string]JobHandler
 	quit     chan bool
+	slots    chan struct{}
 }
 
 // NewScheduler creates a new scheduler instance
@@ -58,6 +91,15 @@ func NewScheduler(redisAddr string, redisPass string, redisDB int) *Scheduler {
 		client:   client,
 		handlers: make(map[string]JobHandler),
 		quit:     make(chan bool),
+		slots:    make(chan struct{}, defaultConcurrency),
+	}
+}
+
+// SetConcurrency sets how many jobs run at the same time. It must be
+// called before jobs are processed.
+func (s *Scheduler) SetConcurrency(n int) {
+	if n > 0 {
+		s.slots = make(chan struct{}, n)
 	}
 }
 
@@ -95,22 +137,105 @@ func (s *Scheduler) AddJob(ctx context.Context, job *Job) error {
 	}).Err()
 }
 
-// AddRecurringJob adds a recurring job
+// recurringJob is the stored definition of a recurring job
+type recurringJob struct {
+	Name    string                `json:"name"`
+	Data    json.RawMessage       `json:"data"`
+	Rule    *social.RecurringRule `json:"rule"`
+	Start   time.Time             `json:"start"`
+	LastRun time.Time             `json:"last_run"`
+	Runs    int                   `json:"runs"`
+}
+
+// ScheduleRecurring registers the handler of a recurring job, runs the job
+// once now and schedules it by rule. It is safe to call on every startup
+// and from every replica: the run now has a fixed job ID and is added once
+// per initialRunWindow, and the rule keeps its anchor while it is unchanged.
+func (s *Scheduler) ScheduleRecurring(ctx context.Context, name string, rule *social.RecurringRule, handler JobHandler) error {
+	s.RegisterHandler(name, handler)
+
+	jobID := name + "_initial"
+	claimed, err := s.claimJob(ctx, jobID, initialRunWindow)
+	if err != nil {
+		return fmt.Errorf("failed to claim initial run of %s: %w", name, err)
+	}
+	if claimed {
+		if err := s.AddJob(ctx, &Job{ID: jobID, Name: name, MaxRetries: 1}); err != nil {
+			return err
+		}
+	}
+
+	return s.AddRecurringJob(ctx, name, nil, rule, handler)
+}
+
+// AddRecurringJob adds a recurring job. The rule is anchored at the time
+// the job is first added in UTC, and that anchor is the first occurrence of
+// the rule, so the first run is one interval later. Adding the job again
+// with the same rule and data keeps its anchor. A nil rule runs daily.
 func (s *Scheduler) AddRecurringJob(ctx context.Context, name string, data interface{}, rule *social.RecurringRule, handler JobHandler) error {
-	// Store recurring job definition
-	recurringData := map[string]interface{}{
-		"name":     name,
-		"data":     data,
-		"rule":     rule,
-		"last_run": time.Now(),
+	if rule == nil {
+		rule = &social.RecurringRule{Frequency: "daily"}
+	}
+
+	now := time.Now().UTC()
+	if _, err := NewRecurrence(rule, now, "UTC"); err != nil {
+		return fmt.Errorf("invalid recurrence for %s: %w", name, err)
+	}
+
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	key := "scheduler:recurring:" + name
+	existing, err := s.client.Get(ctx, key).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	if err == nil {
+		var stored recurringJob
+		if json.Unmarshal([]byte(existing), &stored) == nil && sameRecurrence(&stored, rawData, rule) {
+			return nil
+		}
 	}
 
-	jsonData, err := json.Marshal(recurringData)
+	// Store recurring job definition
+	jsonData, err := json.Marshal(recurringJob{
+		Name:    name,
+		Data:    rawData,
+		Rule:    rule,
+		Start:   now,
+		LastRun: now,
+	})
 	if err != nil {
 		return err
 	}
 
-	return s.client.Set(ctx, "scheduler:recurring:"+name, jsonData, 0).Err()
+	// A definition another replica stored meanwhile is kept
+	if existing == "" {
+		return s.client.SetNX(ctx, key, jsonData, 0).Err()
+	}
+	return s.client.Set(ctx, key, jsonData, 0).Err()
+}
+
+// sameRecurrence reports whether a stored recurring job has the given data
+// and rule
+func sameRecurrence(stored *recurringJob, data json.RawMessage, rule *social.RecurringRule) bool {
+	storedRule, err := json.Marshal(stored.Rule)
+	if err != nil {
+		return false
+	}
+	newRule, err := json.Marshal(rule)
+	if err != nil {
+		return false
+	}
+	return string(storedRule) == string(newRule) && string(stored.Data) == string(data)
+}
+
+// claimJob reserves a job ID for ttl. It reports whether this scheduler
+// reserved it, so a job that every replica would add is added once.
+func (s *Scheduler) claimJob(ctx context.Context, jobID string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, "scheduler:claimed:"+jobID, 1, ttl).Result()
 }
 
 // ProcessJobs starts processing jobs (blocking)
@@ -218,19 +343,32 @@ func (s *Scheduler) processPendingJobs(ctx context.Context) {
 	}
 
 	for _, jobData := range jobs {
+		// Leave the remaining jobs for the next tick when every slot is busy
+		if len(s.slots) == cap(s.slots) {
+			return
+		}
+
 		var job Job
 		if err := json.Unmarshal([]byte(jobData), &job); err != nil {
 			continue
 		}
 
-		// Remove from delayed set
-		s.client.ZRem(ctx, "scheduler:delayed", jobData)
+		// Remove from delayed set. Only the scheduler that removes the job
+		// runs it, so a job is never picked up twice.
+		removed, err := s.client.ZRem(ctx, "scheduler:delayed", jobData).Result()
+		if err != nil || removed == 0 {
+			continue
+		}
 
 		// Add to active queue
-		s.client.RPush(ctx, "scheduler:active", jobData)
+		s.client.RPush(ctx, "scheduler:active", job.ID)
 
 		// Process job
-		go s.executeJob(ctx, &job)
+		s.slots <- struct{}{}
+		go func(job *Job) {
+			defer func() { <-s.slots }()
+			s.executeJob(ctx, job)
+		}(&job)
 	}
 }
 
@@ -247,22 +385,33 @@ func (s *Scheduler) processRecurringJobs(ctx context.Context) {
 			continue
 		}
 
-		var recurring struct {
-			Name    string                `json:"name"`
-			Data    json.RawMessage       `json:"data"`
-			Rule    *social.RecurringRule `json:"rule"`
-			LastRun time.Time             `json:"last_run"`
-		}
-
+		var recurring recurringJob
 		if err := json.Unmarshal([]byte(data), &recurring); err != nil {
 			continue
 		}
 
 		// Check if job should run
-		nextRun := calculateNextRun(recurring.LastRun, recurring.Rule)
+		nextRun, ok := calculateNextRun(&recurring)
+		if !ok {
+			log.Printf("Recurring job %s has ended after %d runs", recurring.Name, recurring.Runs)
+			s.client.Del(ctx, key)
+			continue
+		}
 		if time.Now().After(nextRun) {
-			// Create job
+			// Each occurrence has a fixed ID, so replicas add it once
+			jobID := fmt.Sprintf("%s_%d", recurring.Name, nextRun.Unix())
+			claimed, err := s.claimJob(ctx, jobID, occurrenceClaimTTL)
+			if err != nil {
+				log.Printf("Failed to claim recurring job %s: %v", jobID, err)
+				continue
+			}
+			if !claimed {
+				// Another replica added it and records the run
+				continue
+			}
+
 			job := &Job{
+				ID:    jobID,
 				Name:  recurring.Name,
 				Data:  recurring.Data,
 				RunAt: nextRun,
@@ -274,6 +423,7 @@ func (s *Scheduler) processRecurringJobs(ctx context.Context) {
 
 			// Update last run
 			recurring.LastRun = time.Now()
+			recurring.Runs++
 			updatedData, _ := json.Marshal(recurring)
 			s.client.Set(ctx, key, updatedData, 0)
 		}
@@ -293,11 +443,22 @@ func (s *Scheduler) executeJob(ctx context.Context, job *Job) {
 	job.Attempts++
 
 	// Execute with timeout
-	jobCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	jobCtx, cancel := context.WithTimeout(ctx, jobTimeout)
 	defer cancel()
 
 	err := handler(jobCtx, job)
 
+	var deferred *DeferError
+	if errors.As(err, &deferred) {
+		job.Attempts--
+		job.Error = deferred.Reason
+		job.RunAt = time.Now().Add(deferred.Delay)
+		job.Status = JobStatusDelayed
+		s.client.LRem(ctx, "scheduler:active", 0, job.ID)
+		s.AddJob(ctx, job)
+		return
+	}
+
 	if err != nil {
 		job.Error = err.Error()
 
@@ -306,6 +467,7 @@ func (s *Scheduler) executeJob(ctx context.Context, job *Job) {
 			delay := time.Duration(job.Attempts) * time.Minute * 5
 			job.RunAt = time.Now().Add(delay)
 			job.Status = JobStatusDelayed
+			s.client.LRem(ctx, "scheduler:active", 0, job.ID)
 			s.AddJob(ctx, job)
 			return
 		}
@@ -336,19 +498,26 @@ func generateJobID() string {
 	return fmt.Sprintf("job_%d", time.Now().UnixNano())
 }
 
-func calculateNextRun(lastRun time.Time, rule *social.RecurringRule) time.Time {
+// calculateNextRun returns when a recurring job runs next, or false once
+// its rule has ended
+func calculateNextRun(recurring *recurringJob) (time.Time, bool) {
+	rule := recurring.Rule
 	if rule == nil {
-		return lastRun.Add(24 * time.Hour)
+		rule = &social.RecurringRule{Frequency: "daily"}
 	}
 
-	switch rule.Frequency {
-	case "daily":
-		return lastRun.AddDate(0, 0, rule.Interval)
-	case "weekly":
-		return lastRun.AddDate(0, 0, 7*rule.Interval)
-	case "monthly":
-		return lastRun.AddDate(0, rule.Interval, 0)
-	default:
-		return lastRun.Add(24 * time.Hour)
+	// Definitions stored before the anchor was recorded start at their last run
+	start := recurring.Start
+	if start.IsZero() {
+		start = recurring.LastRun
 	}
+
+	recurrence, err := NewRecurrence(rule, start, "UTC")
+	if err != nil {
+		log.Printf("Invalid recurrence for job %s: %v", recurring.Name, err)
+		return time.Time{}, false
+	}
+
+	next, ok := recurrence.Next(recurring.LastRun)
+	return next.At, ok
 }
@@ -0,0 +1,283 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// envelopePrefix marks values produced by Envelope.Encrypt. Values marked
+// with legacyEnvelopePrefix were sealed without a context; they can still be
+// opened and always need rotation.
+const (
+	envelopePrefix       = "enc:v2:"
+	legacyEnvelopePrefix = "enc:v1:"
+)
+
+// ErrUnknownKey is returned when a value was sealed with a key that is not loaded
+var ErrUnknownKey = errors.New("unknown key-encryption key")
+
+// KeyProvider supplies key-encryption keys by ID
+type KeyProvider interface {
+	// CurrentKeyID returns the ID of the key new values are sealed with
+	CurrentKeyID() string
+	// Key returns the 32-byte key with the given ID
+	Key(id string) ([]byte, error)
+}
+
+// Keyring is an in-memory KeyProvider. It stands in for a KMS and is
+// loaded from configuration or a local key file.
+type Keyring struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewKeyring creates a keyring from base64-encoded 32-byte keys
+func NewKeyring(current string, encodedKeys map[string]string) (*Keyring, error) {
+	keyring := &Keyring{
+		current: current,
+		keys:    make(map[string][]byte, len(encodedKeys)),
+	}
+
+	for id, encoded := range encodedKeys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %s is not valid base64: %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %s must be 32 bytes, got %d", id, len(key))
+		}
+		if strings.Contains(id, ":") {
+			return nil, fmt.Errorf("key ID %q must not contain ':'", id)
+		}
+		keyring.keys[id] = key
+	}
+
+	if _, ok := keyring.keys[current]; !ok {
+		return nil, fmt.Errorf("current key %q is not in the keyring", current)
+	}
+
+	return keyring, nil
+}
+
+// ParseKeyring parses keys in the form "id1:base64key,id2:base64key"
+func ParseKeyring(current, spec string) (*Keyring, error) {
+	encodedKeys := make(map[string]string)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, key, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid key entry %q, expected id:base64key", entry)
+		}
+		encodedKeys[id] = key
+	}
+
+	return NewKeyring(current, encodedKeys)
+}
+
+// LoadKeyringFile loads a keyring from a JSON file of the form
+// {"current": "id", "keys": {"id": "base64key"}}
+func LoadKeyringFile(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+
+	var file struct {
+		Current string            `json:"current"`
+		Keys    map[string]string `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse keyring: %w", err)
+	}
+
+	return NewKeyring(file.Current, file.Keys)
+}
+
+// LoadKeyring loads the keyring from a key file when one is given, and
+// otherwise from an inline key spec. It returns nil when neither is set.
+func LoadKeyring(file, current, spec string) (*Keyring, error) {
+	if file != "" {
+		return LoadKeyringFile(file)
+	}
+	if spec == "" {
+		return nil, nil
+	}
+	return ParseKeyring(current, spec)
+}
+
+// CurrentKeyID returns the ID of the key new values are sealed with
+func (k *Keyring) CurrentKeyID() string {
+	return k.current
+}
+
+// Key returns the key with the given ID
+func (k *Keyring) Key(id string) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// Envelope encrypts values with a fresh data key per value. The data key
+// is sealed with the current key-encryption key, so rotating the
+// key-encryption key only needs the data keys re-wrapped.
+//
+// Every value is sealed for a context naming where it is stored, such as
+// the row and column, which is authenticated but not stored. A sealed value
+// copied to another place fails to open there.
+//
+// Sealed values look like enc:v2:<key id>:<wrapped data key>:<ciphertext>.
+type Envelope struct {
+	keys KeyProvider
+}
+
+// NewEnvelope creates an envelope using the given key provider
+func NewEnvelope(keys KeyProvider) *Envelope {
+	return &Envelope{keys: keys}
+}
+
+// IsSealed reports whether a value was produced by Encrypt
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix) || strings.HasPrefix(value, legacyEnvelopePrefix)
+}
+
+// Encrypt seals a value for a context under the current key-encryption
+// key. Empty values are left empty.
+func (e *Envelope) Encrypt(plaintext, context string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	keyID := e.keys.CurrentKeyID()
+	kek, err := e.keys.Key(keyID)
+	if err != nil {
+		return "", err
+	}
+
+	dek := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	aad := []byte(context)
+	wrapped, err := seal(kek, dek, aad)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(dek, []byte(plaintext), aad)
+	if err != nil {
+		return "", err
+	}
+
+	return envelopePrefix + keyID + ":" +
+		base64.RawStdEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt opens a value sealed for a context and returns it with the ID of
+// the key it was sealed under. Values that were never sealed are returned
+// as is with an empty key ID, so existing plaintext rows keep working until
+// rotated.
+func (e *Envelope) Decrypt(value, context string) (string, string, error) {
+	if !IsSealed(value) {
+		return value, "", nil
+	}
+
+	aad := []byte(context)
+	if strings.HasPrefix(value, legacyEnvelopePrefix) {
+		aad = nil
+	}
+
+	parts := strings.Split(value[len(envelopePrefix):], ":")
+	if len(parts) != 3 {
+		return "", "", fmt.Errorf("malformed sealed value")
+	}
+	keyID := parts[0]
+
+	kek, err := e.keys.Key(keyID)
+	if err != nil {
+		return "", keyID, err
+	}
+
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", keyID, fmt.Errorf("malformed data key: %w", err)
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", keyID, fmt.Errorf("malformed ciphertext: %w", err)
+	}
+
+	dek, err := open(kek, wrapped, aad)
+	if err != nil {
+		return "", keyID, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	plaintext, err := open(dek, ciphertext, aad)
+	if err != nil {
+		return "", keyID, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+
+	return string(plaintext), keyID, nil
+}
+
+// NeedsRotation reports whether a value is plaintext, sealed without a
+// context or sealed under a key other than the current one
+func (e *Envelope) NeedsRotation(value string) bool {
+	if value == "" {
+		return false
+	}
+	if !IsSealed(value) {
+		return true
+	}
+	return !strings.HasPrefix(value, envelopePrefix+e.keys.CurrentKeyID()+":")
+}
+
+// seal encrypts with AES-GCM, prefixing the random nonce
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// open decrypts a value produced by seal
+func open(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,92 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testKey returns a base64 32-byte key filled with b
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), 32)))
+}
+
+func TestEnvelopeOpen(t *testing.T) {
+	keyring, err := NewKeyring("k1", map[string]string{"k1": testKey('a'), "k2": testKey('b')})
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope := NewEnvelope(keyring)
+
+	const context = "social_accounts:acc-1:access_token"
+	sealed, err := envelope.Encrypt("token", context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same value sealed under a key that is not loaded
+	otherKeyring, err := NewKeyring("k3", map[string]string{"k3": testKey('c')})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknownKey, err := NewEnvelope(otherKeyring).Encrypt("token", context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same key ID naming a different key
+	swappedKeyring, err := NewKeyring("k1", map[string]string{"k1": testKey('d')})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongKey, err := NewEnvelope(swappedKeyring).Encrypt("token", context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(sealed, ":")
+	ciphertext, _ := base64.RawStdEncoding.DecodeString(parts[len(parts)-1])
+	ciphertext[len(ciphertext)-1] ^= 1
+	parts[len(parts)-1] = base64.RawStdEncoding.EncodeToString(ciphertext)
+	tampered := strings.Join(parts, ":")
+
+	tests := []struct {
+		name    string
+		value   string
+		context string
+		want    string
+		wantErr error // nil for any error when fails is set
+		fails   bool
+	}{
+		{name: "valid", value: sealed, context: context, want: "token"},
+		{name: "plaintext", value: "token", context: context, want: "token"},
+		{name: "other column", value: sealed, context: "social_accounts:acc-1:refresh_token", fails: true},
+		{name: "copied to another account", value: sealed, context: "social_accounts:acc-2:access_token", fails: true},
+		{name: "tampered ciphertext", value: tampered, context: context, fails: true},
+		{name: "sealed with another key", value: wrongKey, context: context, fails: true},
+		{name: "sealed with an unknown key", value: unknownKey, context: context, fails: true, wantErr: ErrUnknownKey},
+		{name: "malformed", value: envelopePrefix + "k1:abc", context: context, fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := envelope.Decrypt(tt.value, tt.context)
+			if tt.fails {
+				if err == nil {
+					t.Fatalf("Decrypt = %q, want an error", got)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("Decrypt error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decrypt error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Decrypt = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
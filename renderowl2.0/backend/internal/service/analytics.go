@@ -2,7 +2,7 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -487,100 +487,87 @@ type WebhookEventRequest struct {
 	Platform  string                 `json:"platform" binding:"required"` // youtube, tiktok, instagram
 	EventType string                 `json:"event_type" binding:"required"`
 	VideoID   string                 `json:"video_id"`
+	DeliveryID string                `json:"delivery_id"`
 	Payload   map[string]interface{} `json:"payload"`
 }
 
-// StoreWebhookEvent stores a webhook event
+// StoreWebhookEvent stores a webhook event. Deliveries that were already
+// stored return ErrWebhookDuplicate.
 func (s *AnalyticsService) StoreWebhookEvent(ctx context.Context, req *WebhookEventRequest) (string, error) {
 	event := &domain.WebhookEvent{
-		Platform:  req.Platform,
-		EventType: req.EventType,
-		VideoID:   req.VideoID,
-		Payload:   req.Payload,
+		Platform:   req.Platform,
+		EventType:  req.EventType,
+		VideoID:    req.VideoID,
+		DeliveryID: req.DeliveryID,
+		Payload:    req.Payload,
 	}
 	
-	err := s.analyticsRepo.StoreWebhookEvent(ctx, event)
+	stored, err := s.analyticsRepo.StoreWebhookEvent(ctx, event)
 	if err != nil {
 		return "", err
 	}
+	if !stored {
+		return "", ErrWebhookDuplicate
+	}
 	
 	return event.ID, nil
 }
 
-// ProcessWebhookEvents processes unprocessed webhook events
-func (s *AnalyticsService) ProcessWebhookEvents(ctx context.Context, limit int) error {
-	events, err := s.analyticsRepo.GetUnprocessedWebhookEvents(ctx, limit)
+// ErrWebhookEventNotDead is returned when retrying a webhook event that
+// is not a dead letter
+var ErrWebhookEventNotDead = errors.New("webhook event is not a dead letter")
+
+// WebhookEventResponse represents a stored webhook event
+type WebhookEventResponse struct {
+	ID            string                 `json:"id"`
+	Platform      string                 `json:"platform"`
+	EventType     string                 `json:"event_type"`
+	VideoID       string                 `json:"video_id,omitempty"`
+	Status        string                 `json:"status"`
+	Attempts      int                    `json:"attempts"`
+	NextAttemptAt *time.Time             `json:"next_attempt_at,omitempty"`
+	LastError     string                 `json:"last_error,omitempty"`
+	Payload       map[string]interface{} `json:"payload"`
+	ProcessedAt   *time.Time             `json:"processed_at,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+// ListWebhookEvents lists stored webhook events, newest first. Dead
+// letters are listed with the status "dead".
+func (s *AnalyticsService) ListWebhookEvents(ctx context.Context, status, platform string, limit, offset int) ([]WebhookEventResponse, error) {
+	events, err := s.analyticsRepo.ListWebhookEvents(ctx, status, platform, limit, offset)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	
-	for _, event := range events {
-		// Process based on event type and platform
-		switch event.EventType {
-		case "view":
-			if videoID, ok := event.Payload["video_id"].(string); ok {
-				if count, ok := event.Payload["count"].(float64); ok {
-					s.analyticsRepo.TrackView(ctx, videoID, "", event.Platform)
-					_ = count // Use the count value
-				}
-			}
-		case "engagement":
-			if videoID, ok := event.Payload["video_id"].(string); ok {
-				likes := int64(0)
-				comments := int64(0)
-				shares := int64(0)
-				
-				if v, ok := event.Payload["likes"].(float64); ok {
-					likes = int64(v)
-				}
-				if v, ok := event.Payload["comments"].(float64); ok {
-					comments = int64(v)
-				}
-				if v, ok := event.Payload["shares"].(float64); ok {
-					shares = int64(v)
-				}
-				
-				s.analyticsRepo.TrackEngagement(ctx, videoID, event.Platform, likes, comments, shares)
-			}
-		}
-		
-		// Mark as processed
-		s.analyticsRepo.MarkWebhookEventProcessed(ctx, event.ID)
+	result := make([]WebhookEventResponse, 0, len(events))
+	for _, e := range events {
+		result = append(result, WebhookEventResponse{
+			ID:            e.ID,
+			Platform:      e.Platform,
+			EventType:     e.EventType,
+			VideoID:       e.VideoID,
+			Status:        e.Status,
+			Attempts:      e.Attempts,
+			NextAttemptAt: e.NextAttemptAt,
+			LastError:     e.LastError,
+			Payload:       e.Payload,
+			ProcessedAt:   e.ProcessedAt,
+			CreatedAt:     e.CreatedAt,
+		})
 	}
 	
-	return nil
+	return result, nil
 }
 
-// ExportAnalyticsRequest represents an analytics export request
-type ExportAnalyticsRequest struct {
-	UserID    string    `json:"user_id" binding:"required"`
-	StartDate time.Time `json:"start_date" binding:"required"`
-	EndDate   time.Time `json:"end_date" binding:"required"`
-	Format    string    `json:"format"` // csv, json
-}
-
-// ExportAnalytics exports analytics data
-func (s *AnalyticsService) ExportAnalytics(ctx context.Context, req *ExportAnalyticsRequest) ([]byte, error) {
-	if req.Format == "" {
-		req.Format = "json"
-	}
-	
-	// Get views data
-	views, err := s.analyticsRepo.GetViewsByDateRange(ctx, req.UserID, req.StartDate, req.EndDate)
+// RetryWebhookEvent moves a dead letter webhook event back to the queue
+func (s *AnalyticsService) RetryWebhookEvent(ctx context.Context, eventID string) error {
+	requeued, err := s.analyticsRepo.RequeueWebhookEvent(ctx, eventID)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	
-	// For now, return JSON format
-	if req.Format == "json" {
-		return json.Marshal(map[string]interface{}{
-			"period": map[string]string{
-				"start": req.StartDate.Format("2006-01-02"),
-				"end":   req.EndDate.Format("2006-01-02"),
-			},
-			"views": views,
-		})
+	if !requeued {
+		return ErrWebhookEventNotDead
 	}
-	
-	return nil, fmt.Errorf("unsupported format: %s", req.Format)
+	return nil
 }
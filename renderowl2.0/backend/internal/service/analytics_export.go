@@ -0,0 +1,485 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"renderowl-api/internal/repository"
+)
+
+// Export formats
+const (
+	ExportFormatJSON = "json"
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+	ExportFormatPDF  = "pdf"
+)
+
+// ExportFile describes the file an export format produces
+type ExportFile struct {
+	ContentType string
+	Extension   string
+}
+
+var exportFiles = map[string]ExportFile{
+	ExportFormatJSON: {ContentType: "application/json", Extension: "json"},
+	ExportFormatCSV:  {ContentType: "text/csv", Extension: "csv"},
+	ExportFormatXLSX: {ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Extension: "xlsx"},
+	ExportFormatPDF:  {ContentType: "application/pdf", Extension: "pdf"},
+}
+
+// ExportFileFor returns the file an export format produces
+func ExportFileFor(format string) (ExportFile, bool) {
+	file, ok := exportFiles[format]
+	return file, ok
+}
+
+var (
+	// ErrUnsupportedExportFormat is returned for unknown export formats
+	ErrUnsupportedExportFormat = errors.New("unsupported export format")
+
+	// ErrInvalidExportRange is returned when an export ends before it starts
+	ErrInvalidExportRange = errors.New("end date is before start date")
+)
+
+// exportPageSize is how many videos are read at a time while an export is
+// written, so that large libraries are streamed
+const exportPageSize = 500
+
+// ExportAnalyticsRequest represents an analytics export request
+type ExportAnalyticsRequest struct {
+	UserID    string    `json:"user_id" binding:"required"`
+	StartDate time.Time `json:"start_date" binding:"required"`
+	EndDate   time.Time `json:"end_date" binding:"required"`
+	Format    string    `json:"format"` // json, csv, xlsx, pdf
+	Title     string    `json:"title"`  // shown on PDF reports, e.g. the client's name
+}
+
+// PlatformExport represents the views and engagement of a platform over
+// an export period
+type PlatformExport struct {
+	Platform   string  `json:"platform"`
+	Views      int64   `json:"views"`
+	Percentage float64 `json:"percentage"`
+	Likes      int64   `json:"likes"`
+	Comments   int64   `json:"comments"`
+	Shares     int64   `json:"shares"`
+	Videos     int64   `json:"videos"`
+}
+
+// analyticsExport holds the data of an export. Video performance is not
+// held but read page by page as the export is written.
+type analyticsExport struct {
+	req        *ExportAnalyticsRequest
+	views      []repository.ViewAggregate
+	engagement []repository.EngagementAggregate
+	platforms  []PlatformExport
+}
+
+// ExportAnalytics writes the views, engagement, video performance and
+// platform breakdown of a period to w, as JSON, CSV, XLSX or a PDF report.
+// Unsupported formats and invalid ranges fail before anything is written.
+func (s *AnalyticsService) ExportAnalytics(ctx context.Context, req *ExportAnalyticsRequest, w io.Writer) error {
+	if req.Format == "" {
+		req.Format = ExportFormatJSON
+	}
+	if _, ok := exportFiles[req.Format]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedExportFormat, req.Format)
+	}
+	if req.EndDate.Before(req.StartDate) {
+		return ErrInvalidExportRange
+	}
+
+	export, err := s.loadExport(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	switch req.Format {
+	case ExportFormatCSV:
+		return s.writeCSV(ctx, export, w)
+	case ExportFormatXLSX:
+		return s.writeXLSX(ctx, export, w)
+	case ExportFormatPDF:
+		return s.writePDF(ctx, export, w)
+	default:
+		return s.writeJSON(ctx, export, w)
+	}
+}
+
+// loadExport loads the daily views and engagement of a period and sums
+// them per platform
+func (s *AnalyticsService) loadExport(ctx context.Context, req *ExportAnalyticsRequest) (*analyticsExport, error) {
+	views, err := s.analyticsRepo.GetViewsByDateRange(ctx, req.UserID, req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load views: %w", err)
+	}
+	sort.Slice(views, func(i, j int) bool {
+		if !views[i].Date.Equal(views[j].Date) {
+			return views[i].Date.Before(views[j].Date)
+		}
+		return views[i].Platform < views[j].Platform
+	})
+
+	engagement, err := s.analyticsRepo.GetEngagementByDateRange(ctx, req.UserID, req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load engagement: %w", err)
+	}
+
+	videoCounts, err := s.analyticsRepo.GetVideoCountsByPlatform(ctx, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load video counts: %w", err)
+	}
+
+	byPlatform := make(map[string]*PlatformExport)
+	platform := func(name string) *PlatformExport {
+		p, ok := byPlatform[name]
+		if !ok {
+			p = &PlatformExport{Platform: name, Videos: videoCounts[name]}
+			byPlatform[name] = p
+		}
+		return p
+	}
+	var totalViews int64
+	for _, v := range views {
+		platform(v.Platform).Views += v.TotalViews
+		totalViews += v.TotalViews
+	}
+	for _, e := range engagement {
+		p := platform(e.Platform)
+		p.Likes += e.Likes
+		p.Comments += e.Comments
+		p.Shares += e.Shares
+	}
+
+	platforms := make([]PlatformExport, 0, len(byPlatform))
+	for _, p := range byPlatform {
+		if totalViews > 0 {
+			p.Percentage = float64(p.Views) / float64(totalViews) * 100
+		}
+		platforms = append(platforms, *p)
+	}
+	sort.Slice(platforms, func(i, j int) bool {
+		if platforms[i].Views != platforms[j].Views {
+			return platforms[i].Views > platforms[j].Views
+		}
+		return platforms[i].Platform < platforms[j].Platform
+	})
+
+	return &analyticsExport{
+		req:        req,
+		views:      views,
+		engagement: engagement,
+		platforms:  platforms,
+	}, nil
+}
+
+// eachVideo calls fn with the performance of each of a user's videos,
+// most viewed first, reading them a page at a time
+func (s *AnalyticsService) eachVideo(ctx context.Context, userID string, fn func(repository.VideoPerformanceData) error) error {
+	for offset := 0; ; offset += exportPageSize {
+		videos, err := s.analyticsRepo.GetVideoPerformance(ctx, userID, exportPageSize, offset)
+		if err != nil {
+			return fmt.Errorf("failed to load video performance: %w", err)
+		}
+		for _, video := range videos {
+			if err := fn(video); err != nil {
+				return err
+			}
+		}
+		if len(videos) < exportPageSize {
+			return nil
+		}
+	}
+}
+
+// writeJSON writes the export as one JSON object, streaming the videos
+func (s *AnalyticsService) writeJSON(ctx context.Context, export *analyticsExport, w io.Writer) error {
+	head, err := json.Marshal(map[string]interface{}{
+		"period": map[string]string{
+			"start": export.req.StartDate.Format("2006-01-02"),
+			"end":   export.req.EndDate.Format("2006-01-02"),
+		},
+		"views":      export.views,
+		"engagement": export.engagement,
+		"platforms":  export.platforms,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Reopen the object to append the videos
+	if _, err := w.Write(head[:len(head)-1]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `,"videos":[`); err != nil {
+		return err
+	}
+	first := true
+	err = s.eachVideo(ctx, export.req.UserID, func(video repository.VideoPerformanceData) error {
+		data, err := json.Marshal(video)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
+
+// exportSection is a table of an export, as written to a CSV section or an
+// XLSX sheet
+type exportSection struct {
+	name    string
+	header  []string
+	widths  []float64
+	rows    func(row func([]interface{}) error) error
+	percent []int // columns holding percentages
+}
+
+// sections lists the tables of an export: views and engagement per day
+// and platform, the platform breakdown and video performance
+func (s *AnalyticsService) sections(ctx context.Context, export *analyticsExport) []exportSection {
+	return []exportSection{
+		{
+			name:   "Views",
+			header: []string{"Date", "Platform", "Views"},
+			widths: []float64{14, 14, 12},
+			rows: func(row func([]interface{}) error) error {
+				for _, v := range export.views {
+					if err := row([]interface{}{v.Date, v.Platform, v.TotalViews}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			name:   "Engagement",
+			header: []string{"Date", "Platform", "Likes", "Comments", "Shares"},
+			widths: []float64{14, 14, 12, 12, 12},
+			rows: func(row func([]interface{}) error) error {
+				for _, e := range export.engagement {
+					if err := row([]interface{}{e.Date, e.Platform, e.Likes, e.Comments, e.Shares}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			name:    "Platforms",
+			header:  []string{"Platform", "Views", "Share of Views (%)", "Likes", "Comments", "Shares", "Videos"},
+			widths:  []float64{14, 12, 18, 12, 12, 12, 10},
+			percent: []int{2},
+			rows: func(row func([]interface{}) error) error {
+				for _, p := range export.platforms {
+					if err := row([]interface{}{p.Platform, p.Views, p.Percentage, p.Likes, p.Comments, p.Shares, p.Videos}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			name:    "Videos",
+			header:  []string{"Video ID", "Title", "Published", "Views", "Likes", "Comments", "Shares", "Engagement Rate (%)", "Platforms"},
+			widths:  []float64{38, 40, 14, 12, 12, 12, 12, 20, 30},
+			percent: []int{7},
+			rows: func(row func([]interface{}) error) error {
+				return s.eachVideo(ctx, export.req.UserID, func(v repository.VideoPerformanceData) error {
+					var published interface{}
+					if v.PublishedAt != nil {
+						published = *v.PublishedAt
+					}
+					return row([]interface{}{
+						v.VideoID, v.Title, published, v.TotalViews, v.TotalLikes, v.TotalComments,
+						v.TotalShares, v.EngagementRate, joinPlatforms(v.Platforms),
+					})
+				})
+			},
+		},
+	}
+}
+
+// writeCSV writes the sections of the export one after another, each
+// under a row naming it and separated by a blank line
+func (s *AnalyticsService) writeCSV(ctx context.Context, export *analyticsExport, w io.Writer) error {
+	out := csv.NewWriter(w)
+
+	for i, section := range s.sections(ctx, export) {
+		if i > 0 {
+			if err := out.Write(nil); err != nil {
+				return err
+			}
+		}
+		if err := out.Write([]string{section.name}); err != nil {
+			return err
+		}
+		if err := out.Write(section.header); err != nil {
+			return err
+		}
+
+		err := section.rows(func(values []interface{}) error {
+			record := make([]string, len(values))
+			for i, value := range values {
+				record[i] = csvValue(value)
+			}
+			return out.Write(record)
+		})
+		if err != nil {
+			return err
+		}
+		out.Flush()
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// csvValue formats a value for a CSV cell. Text starting with a character
+// that spreadsheets read as a formula, such as a video title of "=1+1", is
+// prefixed with a quote so it is shown as text.
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+			return "'" + v
+		}
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	case time.Time:
+		return v.Format("2006-01-02")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeXLSX writes each section of the export to its own sheet. Sheets
+// are streamed so that large exports are not held as cell objects.
+func (s *AnalyticsService) writeXLSX(ctx context.Context, export *analyticsExport, w io.Writer) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Color: "FFFFFF"},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{reportBlue.hex()}},
+	})
+	if err != nil {
+		return err
+	}
+	dateFormat := "yyyy-mm-dd"
+	dateStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+	if err != nil {
+		return err
+	}
+	percentFormat := "0.00"
+	percentStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &percentFormat})
+	if err != nil {
+		return err
+	}
+
+	for i, section := range s.sections(ctx, export) {
+		if i == 0 {
+			if err := f.SetSheetName("Sheet1", section.name); err != nil {
+				return err
+			}
+		} else if _, err := f.NewSheet(section.name); err != nil {
+			return err
+		}
+
+		sw, err := f.NewStreamWriter(section.name)
+		if err != nil {
+			return err
+		}
+		for col, width := range section.widths {
+			if err := sw.SetColWidth(col+1, col+1, width); err != nil {
+				return err
+			}
+		}
+
+		header := make([]interface{}, len(section.header))
+		for col, name := range section.header {
+			header[col] = excelize.Cell{StyleID: headerStyle, Value: name}
+		}
+		if err := sw.SetRow("A1", header); err != nil {
+			return err
+		}
+
+		row := 2
+		err = section.rows(func(values []interface{}) error {
+			cells := make([]interface{}, len(values))
+			for col, value := range values {
+				switch {
+				case containsInt(section.percent, col):
+					cells[col] = excelize.Cell{StyleID: percentStyle, Value: value}
+				default:
+					if t, ok := value.(time.Time); ok {
+						cells[col] = excelize.Cell{StyleID: dateStyle, Value: t}
+					} else {
+						cells[col] = value
+					}
+				}
+			}
+			cell, err := excelize.CoordinatesToCellName(1, row)
+			if err != nil {
+				return err
+			}
+			row++
+			return sw.SetRow(cell, cells)
+		})
+		if err != nil {
+			return err
+		}
+		if err := sw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	f.SetActiveSheet(0)
+	return f.Write(w)
+}
+
+func containsInt(slice []int, item int) bool {
+	for _, n := range slice {
+		if n == item {
+			return true
+		}
+	}
+	return false
+}
+
+func joinPlatforms(platforms []string) string {
+	result := ""
+	for i, p := range platforms {
+		if i > 0 {
+			result += ", "
+		}
+		result += p
+	}
+	return result
+}
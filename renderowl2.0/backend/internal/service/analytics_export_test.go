@@ -0,0 +1,37 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCSVValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{name: "plain title", value: "My video", want: "My video"},
+		{name: "empty", value: "", want: ""},
+		{name: "nil", value: nil, want: ""},
+		{name: "formula", value: "=1+1", want: "'=1+1"},
+		{name: "hyperlink formula", value: `=HYPERLINK("http://evil.example","click")`, want: `'=HYPERLINK("http://evil.example","click")`},
+		{name: "plus", value: "+cmd|' /C calc'!A0", want: "'+cmd|' /C calc'!A0"},
+		{name: "minus", value: "-2+3", want: "'-2+3"},
+		{name: "at", value: "@SUM(A1)", want: "'@SUM(A1)"},
+		{name: "tab", value: "\t=1", want: "'\t=1"},
+		{name: "carriage return", value: "\r=1", want: "'\r=1"},
+		{name: "formula after the first character", value: "Top 10 = best", want: "Top 10 = best"},
+		{name: "negative count", value: int64(-5), want: "-5"},
+		{name: "rate", value: 0.125, want: "0.12"},
+		{name: "date", value: time.Date(2026, 3, 4, 15, 0, 0, 0, time.UTC), want: "2026-03-04"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := csvValue(tt.value); got != tt.want {
+				t.Errorf("csvValue(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
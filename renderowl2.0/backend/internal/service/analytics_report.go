@@ -0,0 +1,425 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"renderowl-api/internal/repository"
+)
+
+// reportColor is an RGB colour of the PDF report
+type reportColor struct{ r, g, b int }
+
+func (c reportColor) hex() string {
+	return fmt.Sprintf("%02X%02X%02X", c.r, c.g, c.b)
+}
+
+// Brand colours of the PDF report
+var (
+	reportBlue   = reportColor{37, 99, 235}  // blue-600
+	reportPurple = reportColor{147, 51, 234} // purple-600
+	reportGray   = reportColor{107, 114, 128}
+	reportLight  = reportColor{243, 244, 246}
+	reportText   = reportColor{17, 24, 39}
+)
+
+// reportTopVideos is how many videos the PDF report lists
+const reportTopVideos = 25
+
+// Page layout of the PDF report, in millimetres
+const (
+	reportMargin = 15.0
+	reportWidth  = 210.0 - 2*reportMargin
+)
+
+// viewPoint is the views of a bucket of the views chart
+type viewPoint struct {
+	label string
+	views int64
+}
+
+// writePDF writes the export as a branded report for client delivery: the
+// period's totals, a chart of views over time, the platform breakdown and
+// the top videos
+func (s *AnalyticsService) writePDF(ctx context.Context, export *analyticsExport, w io.Writer) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetMargins(reportMargin, 40, reportMargin)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AliasNbPages("")
+
+	period := fmt.Sprintf("%s - %s",
+		export.req.StartDate.Format("Jan 2, 2006"), export.req.EndDate.Format("Jan 2, 2006"))
+
+	pdf.SetHeaderFunc(func() {
+		setFill(pdf, reportBlue)
+		pdf.Rect(0, 0, 210, 28, "F")
+		setFill(pdf, reportPurple)
+		pdf.Rect(0, 28, 210, 1.5, "F")
+
+		pdf.SetTextColor(255, 255, 255)
+		pdf.SetXY(reportMargin, 8)
+		pdf.SetFont("Helvetica", "B", 18)
+		pdf.CellFormat(reportWidth/2, 8, "Renderowl", "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(reportWidth/2, 8, tr("Analytics Report"), "", 1, "R", false, 0, "")
+
+		pdf.SetX(reportMargin)
+		pdf.SetFont("Helvetica", "", 9)
+		subtitle := period
+		if export.req.Title != "" {
+			subtitle = "Prepared for " + export.req.Title + "  |  " + period
+		}
+		pdf.CellFormat(reportWidth, 6, tr(subtitle), "", 1, "L", false, 0, "")
+		pdf.SetY(40)
+	})
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "", 8)
+		setText(pdf, reportGray)
+		pdf.CellFormat(reportWidth/2, 5, "Generated "+time.Now().UTC().Format("Jan 2, 2006"), "", 0, "L", false, 0, "")
+		pdf.CellFormat(reportWidth/2, 5, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+
+	pdf.AddPage()
+
+	reportKPIs(pdf, export)
+	reportViewsChart(pdf, tr, export)
+	reportPlatformChart(pdf, tr, export)
+	if err := s.reportVideos(ctx, pdf, tr, export); err != nil {
+		return err
+	}
+
+	if err := pdf.Error(); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+	return pdf.Output(w)
+}
+
+// reportKPIs draws the period's totals as a row of boxes
+func reportKPIs(pdf *fpdf.Fpdf, export *analyticsExport) {
+	var views, likes, comments, shares int64
+	for _, p := range export.platforms {
+		views += p.Views
+		likes += p.Likes
+		comments += p.Comments
+		shares += p.Shares
+	}
+	engagement := 0.0
+	if views > 0 {
+		engagement = float64(likes+comments+shares) / float64(views) * 100
+	}
+
+	kpis := []struct{ label, value string }{
+		{"Views", formatCount(views)},
+		{"Likes", formatCount(likes)},
+		{"Comments", formatCount(comments)},
+		{"Shares", formatCount(shares)},
+		{"Engagement", strconv.FormatFloat(engagement, 'f', 1, 64) + "%"},
+	}
+
+	const gap = 3.0
+	width := (reportWidth - gap*float64(len(kpis)-1)) / float64(len(kpis))
+	y := pdf.GetY()
+	for i, kpi := range kpis {
+		x := reportMargin + float64(i)*(width+gap)
+		setFill(pdf, reportLight)
+		pdf.Rect(x, y, width, 20, "F")
+		setFill(pdf, reportBlue)
+		pdf.Rect(x, y, 1.2, 20, "F")
+
+		pdf.SetXY(x+3, y+3)
+		pdf.SetFont("Helvetica", "", 8)
+		setText(pdf, reportGray)
+		pdf.CellFormat(width-4, 4, kpi.label, "", 2, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "B", 14)
+		setText(pdf, reportText)
+		pdf.CellFormat(width-4, 9, kpi.value, "", 0, "L", false, 0, "")
+	}
+	pdf.SetY(y + 28)
+}
+
+// reportViewsChart draws the views of the period as a line chart
+func reportViewsChart(pdf *fpdf.Fpdf, tr func(string) string, export *analyticsExport) {
+	reportHeading(pdf, tr, "Views over time")
+
+	points := bucketViews(export)
+	const height = 55.0
+	x0, y0 := reportMargin+12, pdf.GetY()
+	width := reportWidth - 12
+
+	if len(points) == 0 {
+		reportEmpty(pdf, "No views were recorded in this period.")
+		return
+	}
+
+	var max int64 = 1
+	for _, p := range points {
+		if p.views > max {
+			max = p.views
+		}
+	}
+
+	// Grid lines with their values
+	pdf.SetFont("Helvetica", "", 7)
+	pdf.SetLineWidth(0.1)
+	setDraw(pdf, reportLight)
+	setText(pdf, reportGray)
+	for i := 0; i <= 4; i++ {
+		y := y0 + height - height*float64(i)/4
+		pdf.Line(x0, y, x0+width, y)
+		pdf.SetXY(reportMargin, y-2)
+		pdf.CellFormat(10, 4, formatCount(max*int64(i)/4), "", 0, "R", false, 0, "")
+	}
+
+	step := width
+	if len(points) > 1 {
+		step = width / float64(len(points)-1)
+	}
+	pointAt := func(i int) (float64, float64) {
+		x := x0
+		if len(points) > 1 {
+			x += step * float64(i)
+		} else {
+			x += width / 2
+		}
+		return x, y0 + height - height*float64(points[i].views)/float64(max)
+	}
+
+	pdf.SetLineWidth(0.6)
+	setDraw(pdf, reportBlue)
+	for i := 1; i < len(points); i++ {
+		x1, y1 := pointAt(i - 1)
+		x2, y2 := pointAt(i)
+		pdf.Line(x1, y1, x2, y2)
+	}
+	setFill(pdf, reportBlue)
+	for i := range points {
+		x, y := pointAt(i)
+		pdf.Circle(x, y, 0.7, "F")
+	}
+
+	// Label at most eight buckets along the axis
+	labelEvery := (len(points) + 7) / 8
+	for i := 0; i < len(points); i += labelEvery {
+		x, _ := pointAt(i)
+		pdf.SetXY(x-10, y0+height+1)
+		pdf.CellFormat(20, 4, tr(points[i].label), "", 0, "C", false, 0, "")
+	}
+
+	pdf.SetY(y0 + height + 12)
+}
+
+// bucketViews sums the views of the period by day for ranges of up to two
+// months, by week for ranges of up to a year and by month beyond
+func bucketViews(export *analyticsExport) []viewPoint {
+	days := export.req.EndDate.Sub(export.req.StartDate).Hours() / 24
+	bucket := func(t time.Time) (time.Time, string) {
+		t = t.UTC()
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		switch {
+		case days <= 62:
+			return day, day.Format("Jan 2")
+		case days <= 366:
+			week := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+			return week, week.Format("Jan 2")
+		default:
+			month := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+			return month, month.Format("Jan 2006")
+		}
+	}
+
+	var points []viewPoint
+	var last time.Time
+	for _, v := range export.views {
+		start, label := bucket(v.Date)
+		if len(points) == 0 || !start.Equal(last) {
+			points = append(points, viewPoint{label: label})
+			last = start
+		}
+		points[len(points)-1].views += v.TotalViews
+	}
+	return points
+}
+
+// reportPlatformChart draws the views of each platform as horizontal bars
+// with their share of views and engagement
+func reportPlatformChart(pdf *fpdf.Fpdf, tr func(string) string, export *analyticsExport) {
+	reportHeading(pdf, tr, "Platform breakdown")
+
+	if len(export.platforms) == 0 {
+		reportEmpty(pdf, "No platform activity in this period.")
+		return
+	}
+
+	var max int64 = 1
+	for _, p := range export.platforms {
+		if p.Views > max {
+			max = p.Views
+		}
+	}
+
+	const labelWidth, valueWidth, barHeight = 28.0, 62.0, 6.0
+	barWidth := reportWidth - labelWidth - valueWidth
+	colors := []reportColor{reportBlue, reportPurple}
+
+	for i, p := range export.platforms {
+		y := pdf.GetY()
+		if y+barHeight+3 > 277 {
+			pdf.AddPage()
+			y = pdf.GetY()
+		}
+
+		pdf.SetFont("Helvetica", "", 9)
+		setText(pdf, reportText)
+		pdf.SetXY(reportMargin, y)
+		pdf.CellFormat(labelWidth, barHeight, tr(platformLabel(p.Platform)), "", 0, "L", false, 0, "")
+
+		setFill(pdf, reportLight)
+		pdf.Rect(reportMargin+labelWidth, y, barWidth, barHeight, "F")
+		setFill(pdf, colors[i%len(colors)])
+		pdf.Rect(reportMargin+labelWidth, y, barWidth*float64(p.Views)/float64(max), barHeight, "F")
+
+		pdf.SetXY(reportMargin+labelWidth+barWidth+2, y)
+		pdf.SetFont("Helvetica", "", 8)
+		setText(pdf, reportGray)
+		summary := fmt.Sprintf("%s views (%.1f%%), %s likes", formatCount(p.Views), p.Percentage, formatCount(p.Likes))
+		pdf.CellFormat(valueWidth-2, barHeight, summary, "", 0, "L", false, 0, "")
+
+		pdf.SetY(y + barHeight + 3)
+	}
+	pdf.Ln(6)
+}
+
+// reportVideos lists the most viewed videos as a table
+func (s *AnalyticsService) reportVideos(ctx context.Context, pdf *fpdf.Fpdf, tr func(string) string, export *analyticsExport) error {
+	videos, err := s.analyticsRepo.GetVideoPerformance(ctx, export.req.UserID, reportTopVideos, 0)
+	if err != nil {
+		return fmt.Errorf("failed to load video performance: %w", err)
+	}
+
+	if pdf.GetY() > 230 {
+		pdf.AddPage()
+	}
+	reportHeading(pdf, tr, "Top videos")
+
+	if len(videos) == 0 {
+		reportEmpty(pdf, "No videos have been published yet.")
+		return nil
+	}
+
+	columns := []struct {
+		title string
+		width float64
+		align string
+	}{
+		{"Title", 70, "L"},
+		{"Platforms", 40, "L"},
+		{"Views", 20, "R"},
+		{"Likes", 18, "R"},
+		{"Comments", 16, "R"},
+		{"Eng. %", 16, "R"},
+	}
+
+	header := func() {
+		pdf.SetFont("Helvetica", "B", 8)
+		setFill(pdf, reportBlue)
+		pdf.SetTextColor(255, 255, 255)
+		for _, col := range columns {
+			pdf.CellFormat(col.width, 7, col.title, "", 0, col.align, true, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	header()
+
+	pdf.SetFont("Helvetica", "", 8)
+	for i, video := range videos {
+		if pdf.GetY()+6 > 277 {
+			pdf.AddPage()
+			header()
+			pdf.SetFont("Helvetica", "", 8)
+		}
+		row := []string{
+			truncateRunes(videoTitle(video), 48),
+			truncateRunes(joinPlatforms(video.Platforms), 26),
+			formatCount(video.TotalViews),
+			formatCount(video.TotalLikes),
+			formatCount(video.TotalComments),
+			strconv.FormatFloat(video.EngagementRate, 'f', 1, 64),
+		}
+		setFill(pdf, reportLight)
+		setText(pdf, reportText)
+		for c, col := range columns {
+			pdf.CellFormat(col.width, 6, tr(row[c]), "", 0, col.align, i%2 == 1, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	return nil
+}
+
+func reportHeading(pdf *fpdf.Fpdf, tr func(string) string, title string) {
+	pdf.SetFont("Helvetica", "B", 12)
+	setText(pdf, reportText)
+	pdf.CellFormat(reportWidth, 8, tr(title), "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+}
+
+func reportEmpty(pdf *fpdf.Fpdf, message string) {
+	pdf.SetFont("Helvetica", "I", 9)
+	setText(pdf, reportGray)
+	pdf.CellFormat(reportWidth, 8, message, "", 1, "L", false, 0, "")
+	pdf.Ln(6)
+}
+
+func setFill(pdf *fpdf.Fpdf, c reportColor) { pdf.SetFillColor(c.r, c.g, c.b) }
+func setDraw(pdf *fpdf.Fpdf, c reportColor) { pdf.SetDrawColor(c.r, c.g, c.b) }
+func setText(pdf *fpdf.Fpdf, c reportColor) { pdf.SetTextColor(c.r, c.g, c.b) }
+
+func videoTitle(video repository.VideoPerformanceData) string {
+	if video.Title != "" {
+		return video.Title
+	}
+	return video.VideoID
+}
+
+func platformLabel(platform string) string {
+	labels := map[string]string{
+		"youtube":   "YouTube",
+		"tiktok":    "TikTok",
+		"instagram": "Instagram",
+		"facebook":  "Facebook",
+		"linkedin":  "LinkedIn",
+		"twitter":   "X (Twitter)",
+		"threads":   "Threads",
+		"pinterest": "Pinterest",
+	}
+	if label, ok := labels[platform]; ok {
+		return label
+	}
+	return platform
+}
+
+// formatCount formats a count compactly, e.g. 12.3K or 4.5M
+func formatCount(n int64) string {
+	switch {
+	case n >= 1000000:
+		return strconv.FormatFloat(float64(n)/1000000, 'f', 1, 64) + "M"
+	case n >= 10000:
+		return strconv.FormatFloat(float64(n)/1000, 'f', 1, 64) + "K"
+	default:
+		return strconv.FormatInt(n, 10)
+	}
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-3]) + "..."
+}
@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"renderowl-api/internal/domain"
+	socialdomain "renderowl-api/internal/domain/social"
+	"renderowl-api/internal/scheduler"
+	socialsvc "renderowl-api/internal/service/social"
+)
+
+// AnalyticsSnapshotRepository stores the analytics time series of
+// platform posts
+type AnalyticsSnapshotRepository interface {
+	Create(ctx context.Context, data *socialdomain.AnalyticsData) error
+	GetLatestByPosts(ctx context.Context, postIDs []string) ([]*socialdomain.AnalyticsData, error)
+	GetPlatformTotals(ctx context.Context) ([]domain.PlatformStats, error)
+}
+
+// AnalyticsRollupRepository stores the analytics rolled up from snapshots
+type AnalyticsRollupRepository interface {
+	RecordViews(ctx context.Context, videoID, userID, platform string, count int64, date time.Time) error
+	RecordHourlyViews(ctx context.Context, videoID, userID, platform string, count int64, at time.Time) error
+	UpdateVideoPerformance(ctx context.Context, performance *domain.VideoPerformance) error
+	UpdatePlatformStats(ctx context.Context, stats *domain.PlatformStats) error
+	UpdateRetention(ctx context.Context, retention *domain.VideoRetention) error
+}
+
+// AnalyticsPostRepository lists the published posts whose analytics are
+// synced
+type AnalyticsPostRepository interface {
+	GetPublishedSince(ctx context.Context, since time.Time) ([]*socialdomain.ScheduledPost, error)
+	GetPublishedByVideos(ctx context.Context, videoIDs []string) ([]*socialdomain.ScheduledPost, error)
+}
+
+// Analytics polling schedule. Posts are polled hourly during their first
+// day, when most of their growth happens, then daily until the sync
+// window closes.
+const (
+	analyticsSyncWindow   = 90 * 24 * time.Hour
+	analyticsLaunchPeriod = 24 * time.Hour
+	analyticsLaunchEvery  = time.Hour
+	analyticsDailyEvery   = 24 * time.Hour
+
+	// analyticsSyncSlack keeps a post due when the hourly job runs a
+	// little early
+	analyticsSyncSlack = 5 * time.Minute
+)
+
+// AnalyticsSyncService polls the analytics of published posts into a time
+// series of snapshots and rolls the latest snapshots up into the video
+// performance and platform stats shown on the dashboards
+type AnalyticsSyncService struct {
+	snapshots     AnalyticsSnapshotRepository
+	rollups       AnalyticsRollupRepository
+	posts         AnalyticsPostRepository
+	socialService *socialsvc.Service
+	scheduler     *scheduler.Scheduler
+}
+
+// NewAnalyticsSyncService creates a new analytics sync service
+func NewAnalyticsSyncService(
+	snapshots AnalyticsSnapshotRepository,
+	rollups AnalyticsRollupRepository,
+	posts AnalyticsPostRepository,
+	socialService *socialsvc.Service,
+	scheduler *scheduler.Scheduler,
+) *AnalyticsSyncService {
+	return &AnalyticsSyncService{
+		snapshots:     snapshots,
+		rollups:       rollups,
+		posts:         posts,
+		socialService: socialService,
+		scheduler:     scheduler,
+	}
+}
+
+// Initialize registers the analytics sync job, runs it once and schedules
+// it hourly
+func (s *AnalyticsSyncService) Initialize(ctx context.Context) error {
+	return s.scheduler.ScheduleRecurring(ctx, "sync_analytics", &socialdomain.RecurringRule{
+		Frequency: "hourly",
+		Interval:  1,
+	}, s.handleSyncJob)
+}
+
+func (s *AnalyticsSyncService) handleSyncJob(ctx context.Context, job *scheduler.Job) error {
+	synced, failed, err := s.SyncAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	if synced > 0 || failed > 0 {
+		log.Printf("Analytics sync: %d posts synced, %d failed", synced, failed)
+	}
+
+	return nil
+}
+
+// SyncAll records a snapshot of every post published within the sync
+// window that is due, then rolls up the videos that were synced and the
+// platform stats. It returns the number of posts synced and of posts that
+// could not be synced. Platforms without analytics are skipped.
+func (s *AnalyticsSyncService) SyncAll(ctx context.Context) (int, int, error) {
+	now := time.Now().UTC()
+	posts, err := s.posts.GetPublishedSince(ctx, now.Add(-analyticsSyncWindow))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to load published posts: %w", err)
+	}
+	if len(posts) == 0 {
+		return 0, 0, nil
+	}
+
+	ids := make([]string, len(posts))
+	for i, post := range posts {
+		ids[i] = post.Platforms[0].ID
+	}
+	snapshots, err := s.snapshots.GetLatestByPosts(ctx, ids)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to load analytics: %w", err)
+	}
+	latest := make(map[string]*socialdomain.AnalyticsData, len(snapshots))
+	for _, snapshot := range snapshots {
+		latest[snapshot.PostID] = snapshot
+	}
+
+	synced, failed := 0, 0
+	var videoIDs []string
+	for _, post := range posts {
+		platformPost := &post.Platforms[0]
+		previous := latest[platformPost.ID]
+		if !analyticsDue(platformPost, previous, now) {
+			continue
+		}
+
+		snapshot, err := s.syncPost(ctx, post, platformPost, previous)
+		switch {
+		case errors.Is(err, socialsvc.ErrUnsupported):
+		case err != nil:
+			failed++
+			log.Printf("Failed to sync analytics of post %s on account %s: %v", post.ID, platformPost.AccountID, err)
+		default:
+			synced++
+			latest[platformPost.ID] = snapshot
+			if post.VideoID != "" && !containsString(videoIDs, post.VideoID) {
+				videoIDs = append(videoIDs, post.VideoID)
+			}
+		}
+	}
+
+	if synced == 0 {
+		return synced, failed, nil
+	}
+	if err := s.rollup(ctx, videoIDs, latest); err != nil {
+		return synced, failed, err
+	}
+
+	return synced, failed, nil
+}
+
+// analyticsDue reports whether a platform post should be polled: hourly
+// during its first day, daily afterwards, and never once the sync window
+// has closed. Posts that were never polled are always due.
+func analyticsDue(platformPost *socialdomain.PlatformPost, previous *socialdomain.AnalyticsData, now time.Time) bool {
+	if previous == nil {
+		return true
+	}
+
+	age := now.Sub(*platformPost.PublishedAt)
+	if age > analyticsSyncWindow {
+		return false
+	}
+
+	every := analyticsDailyEvery
+	if age < analyticsLaunchPeriod {
+		every = analyticsLaunchEvery
+	}
+	return now.Sub(previous.RecordedAt) >= every-analyticsSyncSlack
+}
+
+// syncPost records a snapshot of a platform post and the views it gained
+// since the previous snapshot
+func (s *AnalyticsSyncService) syncPost(ctx context.Context, post *socialdomain.ScheduledPost, platformPost *socialdomain.PlatformPost, previous *socialdomain.AnalyticsData) (*socialdomain.AnalyticsData, error) {
+	snapshot, err := s.socialService.GetAnalytics(ctx, platformPost.AccountID, platformPost.PlatformPostID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot.ID = uuid.New().String()
+	snapshot.PostID = platformPost.ID
+	snapshot.Platform = platformPost.Platform
+	snapshot.RecordedAt = time.Now().UTC()
+	if snapshot.Engagement == 0 && snapshot.Views > 0 {
+		snapshot.Engagement = float64(snapshot.Likes+snapshot.Comments+snapshot.Shares) / float64(snapshot.Views) * 100
+	}
+
+	if err := s.snapshots.Create(ctx, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to store analytics: %w", err)
+	}
+
+	// Views can drop when a platform discards invalid traffic; only
+	// growth is counted
+	gained := snapshot.Views
+	if previous != nil {
+		gained -= previous.Views
+	}
+	if gained > 0 {
+		if err := s.recordViews(ctx, post, platformPost, previous, snapshot, gained); err != nil {
+			log.Printf("Failed to record views of post %s: %v", post.ID, err)
+		}
+	}
+
+	// Platforms report retention once a video has had a day of views, and
+	// it changes slowly, so it is refreshed with the daily polls
+	if post.VideoID != "" && snapshot.RecordedAt.Sub(*platformPost.PublishedAt) >= analyticsLaunchPeriod {
+		s.syncRetention(ctx, post, platformPost)
+	}
+
+	return snapshot, nil
+}
+
+// recordViews records the views a platform post gained between two
+// snapshots. Platforms only report totals, so the views are placed in the
+// middle of the polling interval: views gained between hourly polls keep
+// their hour, and views counted over longer intervals only their day. The
+// views gained before the first snapshot are placed on the publish date.
+func (s *AnalyticsSyncService) recordViews(ctx context.Context, post *socialdomain.ScheduledPost, platformPost *socialdomain.PlatformPost, previous, snapshot *socialdomain.AnalyticsData, gained int64) error {
+	platform := string(platformPost.Platform)
+	if previous == nil {
+		return s.rollups.RecordViews(ctx, post.VideoID, post.UserID, platform, gained, *platformPost.PublishedAt)
+	}
+
+	interval := snapshot.RecordedAt.Sub(previous.RecordedAt)
+	at := previous.RecordedAt.Add(interval / 2)
+	if interval <= analyticsLaunchEvery+analyticsSyncSlack {
+		return s.rollups.RecordHourlyViews(ctx, post.VideoID, post.UserID, platform, gained, at)
+	}
+	return s.rollups.RecordViews(ctx, post.VideoID, post.UserID, platform, gained, at)
+}
+
+// syncRetention stores the retention curve of a platform post as the
+// retention of its video on the platform. Platforms without retention
+// analytics are skipped, as are accounts that did not grant access to
+// it; the social service marks those for reconnection.
+func (s *AnalyticsSyncService) syncRetention(ctx context.Context, post *socialdomain.ScheduledPost, platformPost *socialdomain.PlatformPost) {
+	points, err := s.socialService.GetRetention(ctx, platformPost.AccountID, platformPost.PlatformPostID)
+	if errors.Is(err, socialsvc.ErrUnsupported) || socialsvc.IsInsufficientScope(err) {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to sync retention of post %s: %v", post.ID, err)
+		return
+	}
+	if len(points) == 0 {
+		return
+	}
+
+	curve := make(domain.RetentionCurve, len(points))
+	for i, point := range points {
+		curve[i] = domain.RetentionPoint{Position: point.Position, WatchRatio: point.WatchRatio}
+	}
+	retention := &domain.VideoRetention{
+		VideoID:  post.VideoID,
+		Platform: string(platformPost.Platform),
+		Points:   curve,
+	}
+	if err := s.rollups.UpdateRetention(ctx, retention); err != nil {
+		log.Printf("Failed to store retention of post %s: %v", post.ID, err)
+	}
+}
+
+// rollup sums the latest snapshots of the platform posts of each synced
+// video into its performance, including posts past the sync window, and
+// the latest snapshots of all platform posts into the platform stats
+func (s *AnalyticsSyncService) rollup(ctx context.Context, videoIDs []string, latest map[string]*socialdomain.AnalyticsData) error {
+	posts, err := s.posts.GetPublishedByVideos(ctx, videoIDs)
+	if err != nil {
+		return fmt.Errorf("failed to load posts of synced videos: %w", err)
+	}
+
+	var missing []string
+	for _, post := range posts {
+		if _, ok := latest[post.Platforms[0].ID]; !ok {
+			missing = append(missing, post.Platforms[0].ID)
+		}
+	}
+	snapshots, err := s.snapshots.GetLatestByPosts(ctx, missing)
+	if err != nil {
+		return fmt.Errorf("failed to load analytics: %w", err)
+	}
+	for _, snapshot := range snapshots {
+		latest[snapshot.PostID] = snapshot
+	}
+
+	videos := make(map[string]*domain.VideoPerformance)
+	for _, post := range posts {
+		platformPost := &post.Platforms[0]
+		snapshot, ok := latest[platformPost.ID]
+		if !ok {
+			continue
+		}
+
+		video, ok := videos[post.VideoID]
+		if !ok {
+			video = &domain.VideoPerformance{
+				VideoID:     post.VideoID,
+				UserID:      post.UserID,
+				Title:       post.Title,
+				PublishedAt: platformPost.PublishedAt,
+			}
+			videos[post.VideoID] = video
+		}
+		video.TotalViews += snapshot.Views
+		video.TotalLikes += snapshot.Likes
+		video.TotalComments += snapshot.Comments
+		video.TotalShares += snapshot.Shares
+		if !containsString(video.Platforms, string(platformPost.Platform)) {
+			video.Platforms = append(video.Platforms, string(platformPost.Platform))
+		}
+	}
+
+	for _, video := range videos {
+		sort.Strings(video.Platforms)
+		if video.TotalViews > 0 {
+			video.EngagementRate = float64(video.TotalLikes+video.TotalComments+video.TotalShares) / float64(video.TotalViews) * 100
+		}
+		if err := s.rollups.UpdateVideoPerformance(ctx, video); err != nil {
+			return fmt.Errorf("failed to update performance of video %s: %w", video.VideoID, err)
+		}
+	}
+
+	totals, err := s.snapshots.GetPlatformTotals(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to total platform analytics: %w", err)
+	}
+	for i := range totals {
+		if err := s.rollups.UpdatePlatformStats(ctx, &totals[i]); err != nil {
+			return fmt.Errorf("failed to update %s stats: %w", totals[i].Platform, err)
+		}
+	}
+
+	return nil
+}
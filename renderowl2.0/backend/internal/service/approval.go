@@ -0,0 +1,437 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	socialdomain "renderowl-api/internal/domain/social"
+	socialsvc "renderowl-api/internal/service/social"
+)
+
+// ApprovalRepository defines approval policy and review trail storage
+type ApprovalRepository interface {
+	GetPolicy(ctx context.Context, userID string) (*socialdomain.ApprovalPolicy, error)
+	SavePolicy(ctx context.Context, policy *socialdomain.ApprovalPolicy) error
+	AddEvent(ctx context.Context, event *socialdomain.PostReviewEvent) error
+	GetEvents(ctx context.Context, postID string) ([]*socialdomain.PostReviewEvent, error)
+	GetAwaitingReview(ctx context.Context, reviewerID string, limit int) ([]*socialdomain.ScheduledPost, error)
+}
+
+var (
+	// ErrApprovalRequired is returned when an unapproved post of a
+	// workspace that requires approval would be published
+	ErrApprovalRequired = errors.New("post must be approved before it is published")
+
+	// ErrNotReviewer is returned when someone other than the assigned
+	// reviewer approves or rejects a post
+	ErrNotReviewer = errors.New("only the assigned reviewer can approve or reject this post")
+
+	// ErrNotPostOwner is returned when a reviewer changes how a post is
+	// reviewed
+	ErrNotPostOwner = errors.New("only the owner of the post can do this")
+
+	// ErrReviewerRequired is returned when a post is submitted without a
+	// reviewer and the workspace has no default reviewer
+	ErrReviewerRequired = errors.New("a reviewer must be assigned to submit the post")
+
+	// ErrSelfReview is returned when the owner of a post is assigned to
+	// review it
+	ErrSelfReview = errors.New("posts cannot be reviewed by their owner")
+
+	// ErrCommentRequired is returned when a post is rejected or commented
+	// on without a comment
+	ErrCommentRequired = errors.New("a comment is required")
+
+	// ErrReviewPostNotFound is returned when a post does not exist or is
+	// neither owned nor reviewed by the user
+	ErrReviewPostNotFound = errors.New("post not found")
+)
+
+// ReviewStateError is returned when a review action is not allowed in the
+// current status of a post
+type ReviewStateError struct {
+	Action string
+	Status socialdomain.PostStatus
+}
+
+func (e *ReviewStateError) Error() string {
+	return fmt.Sprintf("cannot %s a post that is %s", e.Action, strings.ReplaceAll(string(e.Status), "_", " "))
+}
+
+// reviewTransitions lists the statuses a post may move to during review.
+// Approved posts are scheduled by the publisher.
+var reviewTransitions = map[socialdomain.PostStatus][]socialdomain.PostStatus{
+	socialdomain.PostStatusDraft:         {socialdomain.PostStatusPendingReview},
+	socialdomain.PostStatusRejected:      {socialdomain.PostStatusPendingReview},
+	socialdomain.PostStatusPendingReview: {socialdomain.PostStatusApproved, socialdomain.PostStatusRejected},
+	socialdomain.PostStatusApproved:      {socialdomain.PostStatusScheduled},
+}
+
+// reviewQueueLimit is the maximum number of posts in a review queue
+const reviewQueueLimit = 100
+
+// ApprovalService runs the review of scheduled posts. A workspace is the
+// user owning the posts; when its policy requires approval, posts are
+// reviewed by another user before they are scheduled for publishing.
+type ApprovalService struct {
+	posts         PostRepository
+	reviews       ApprovalRepository
+	socialService *socialsvc.Service
+	publisher     *Publisher
+}
+
+// NewApprovalService creates a new approval service
+func NewApprovalService(
+	posts PostRepository,
+	reviews ApprovalRepository,
+	socialService *socialsvc.Service,
+	publisher *Publisher,
+) *ApprovalService {
+	return &ApprovalService{
+		posts:         posts,
+		reviews:       reviews,
+		socialService: socialService,
+		publisher:     publisher,
+	}
+}
+
+// GetPolicy returns the approval policy of a workspace. Workspaces without
+// a policy do not require approval.
+func (s *ApprovalService) GetPolicy(ctx context.Context, userID string) (*socialdomain.ApprovalPolicy, error) {
+	policy, err := s.reviews.GetPolicy(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load approval policy: %w", err)
+	}
+	if policy == nil {
+		policy = &socialdomain.ApprovalPolicy{UserID: userID}
+	}
+	return policy, nil
+}
+
+// SetPolicy saves the approval policy of a workspace
+func (s *ApprovalService) SetPolicy(ctx context.Context, policy *socialdomain.ApprovalPolicy) error {
+	if policy.DefaultReviewerID == policy.UserID {
+		return ErrSelfReview
+	}
+	policy.UpdatedAt = time.Now()
+	return s.reviews.SavePolicy(ctx, policy)
+}
+
+// RequiresApproval reports whether posts of a workspace must be approved
+// before they are published
+func (s *ApprovalService) RequiresApproval(ctx context.Context, userID string) (bool, error) {
+	policy, err := s.GetPolicy(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return policy.RequireApproval, nil
+}
+
+// SchedulePost saves a new post. Posts of workspaces that require approval,
+// and posts a reviewer is requested for, are submitted for review and
+// scheduled once approved; other posts are scheduled straight away.
+func (s *ApprovalService) SchedulePost(ctx context.Context, post *socialdomain.ScheduledPost, reviewerID string) error {
+	reviewerID, err := s.reviewerFor(ctx, post.UserID, reviewerID)
+	if err != nil {
+		return err
+	}
+
+	if reviewerID == "" {
+		if err := s.socialService.SchedulePost(ctx, post); err != nil {
+			return err
+		}
+		return s.publisher.SchedulePublish(ctx, post)
+	}
+
+	post.Status = socialdomain.PostStatusDraft
+	if err := s.socialService.SchedulePost(ctx, post); err != nil {
+		return err
+	}
+	return s.submit(ctx, post, post.UserID, reviewerID, "")
+}
+
+// CheckReviewer checks that a user's posts can be scheduled with the
+// requested reviewer, if any, before anything is created for them
+func (s *ApprovalService) CheckReviewer(ctx context.Context, userID, reviewerID string) error {
+	_, err := s.reviewerFor(ctx, userID, reviewerID)
+	return err
+}
+
+// Submit sends a draft or rejected post for review. Without a reviewer the
+// post goes to its current reviewer or the workspace default reviewer.
+func (s *ApprovalService) Submit(ctx context.Context, postID, userID, reviewerID, comment string) (*socialdomain.ScheduledPost, error) {
+	post, err := s.ownPost(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if reviewerID == "" {
+		reviewerID = post.ReviewerID
+	}
+	if reviewerID == "" {
+		policy, err := s.GetPolicy(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		reviewerID = policy.DefaultReviewerID
+	}
+
+	if err := s.submit(ctx, post, userID, reviewerID, comment); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// AssignReviewer changes the reviewer of a post that has not been approved
+func (s *ApprovalService) AssignReviewer(ctx context.Context, postID, userID, reviewerID string) (*socialdomain.ScheduledPost, error) {
+	post, err := s.ownPost(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch post.Status {
+	case socialdomain.PostStatusDraft, socialdomain.PostStatusPendingReview, socialdomain.PostStatusRejected:
+	default:
+		return nil, &ReviewStateError{Action: "assign a reviewer to", Status: post.Status}
+	}
+	if reviewerID == "" {
+		return nil, ErrReviewerRequired
+	}
+	if reviewerID == post.UserID {
+		return nil, ErrSelfReview
+	}
+
+	post.ReviewerID = reviewerID
+	if err := s.posts.Update(ctx, post); err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+	if err := s.record(ctx, post, userID, socialdomain.ReviewActionAssigned, post.Status, ""); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// Approve approves a post under review and schedules it for publishing
+func (s *ApprovalService) Approve(ctx context.Context, postID, userID, comment string) (*socialdomain.ScheduledPost, error) {
+	post, err := s.reviewedPost(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canTransition(post.Status, socialdomain.PostStatusApproved) {
+		return nil, &ReviewStateError{Action: "approve", Status: post.Status}
+	}
+
+	from := post.Status
+	now := time.Now()
+	post.Status = socialdomain.PostStatusApproved
+	post.ApprovedBy = userID
+	post.ApprovedAt = &now
+	if err := s.posts.Update(ctx, post); err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+	if err := s.record(ctx, post, userID, socialdomain.ReviewActionApproved, from, comment); err != nil {
+		return nil, err
+	}
+
+	if err := s.publisher.SchedulePublish(ctx, post); err != nil {
+		return nil, err
+	}
+	if err := s.record(ctx, post, userID, socialdomain.ReviewActionScheduled, socialdomain.PostStatusApproved, ""); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// Reject sends a post under review back to its owner with a comment
+func (s *ApprovalService) Reject(ctx context.Context, postID, userID, comment string) (*socialdomain.ScheduledPost, error) {
+	if strings.TrimSpace(comment) == "" {
+		return nil, ErrCommentRequired
+	}
+
+	post, err := s.reviewedPost(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canTransition(post.Status, socialdomain.PostStatusRejected) {
+		return nil, &ReviewStateError{Action: "reject", Status: post.Status}
+	}
+
+	from := post.Status
+	post.Status = socialdomain.PostStatusRejected
+	if err := s.posts.Update(ctx, post); err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+	if err := s.record(ctx, post, userID, socialdomain.ReviewActionRejected, from, comment); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// Comment adds a comment from the owner or reviewer of a post to its
+// review trail
+func (s *ApprovalService) Comment(ctx context.Context, postID, userID, comment string) (*socialdomain.PostReviewEvent, error) {
+	if strings.TrimSpace(comment) == "" {
+		return nil, ErrCommentRequired
+	}
+
+	post, err := s.visiblePost(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	event := newReviewEvent(post, userID, socialdomain.ReviewActionCommented, post.Status, comment)
+	if err := s.reviews.AddEvent(ctx, event); err != nil {
+		return nil, fmt.Errorf("failed to record comment: %w", err)
+	}
+	return event, nil
+}
+
+// RecordEdit adds an edit of a published post to its review trail when
+// the workspace requires approval, so edits made after approval are not
+// hidden from reviewers
+func (s *ApprovalService) RecordEdit(ctx context.Context, post *socialdomain.ScheduledPost, userID string, update *socialdomain.PostUpdate) error {
+	required, err := s.RequiresApproval(ctx, post.UserID)
+	if err != nil {
+		return err
+	}
+	if !required {
+		return nil
+	}
+
+	var changes []string
+	if update.Title != nil {
+		changes = append(changes, fmt.Sprintf("title: %q", *update.Title))
+	}
+	if update.Description != nil {
+		changes = append(changes, fmt.Sprintf("description: %q", *update.Description))
+	}
+	if update.Privacy != nil {
+		changes = append(changes, fmt.Sprintf("privacy: %s", *update.Privacy))
+	}
+	return s.record(ctx, post, userID, socialdomain.ReviewActionEdited, post.Status, strings.Join(changes, "\n"))
+}
+
+// History returns the review trail of a post, oldest first
+func (s *ApprovalService) History(ctx context.Context, postID, userID string) ([]*socialdomain.PostReviewEvent, error) {
+	if _, err := s.visiblePost(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+	return s.reviews.GetEvents(ctx, postID)
+}
+
+// ReviewQueue returns the posts waiting for a reviewer
+func (s *ApprovalService) ReviewQueue(ctx context.Context, reviewerID string) ([]*socialdomain.ScheduledPost, error) {
+	return s.reviews.GetAwaitingReview(ctx, reviewerID, reviewQueueLimit)
+}
+
+// Private methods
+
+// reviewerFor resolves who reviews a new post: the requested reviewer, or
+// the workspace default reviewer when approval is required. It returns no
+// reviewer for posts that are scheduled without review.
+func (s *ApprovalService) reviewerFor(ctx context.Context, userID, reviewerID string) (string, error) {
+	policy, err := s.GetPolicy(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	if reviewerID == "" && policy.RequireApproval {
+		reviewerID = policy.DefaultReviewerID
+		if reviewerID == "" {
+			return "", ErrReviewerRequired
+		}
+	}
+	if reviewerID != "" && reviewerID == userID {
+		return "", ErrSelfReview
+	}
+	return reviewerID, nil
+}
+
+func (s *ApprovalService) submit(ctx context.Context, post *socialdomain.ScheduledPost, actorID, reviewerID, comment string) error {
+	if !canTransition(post.Status, socialdomain.PostStatusPendingReview) {
+		return &ReviewStateError{Action: "submit", Status: post.Status}
+	}
+	if reviewerID == "" {
+		return ErrReviewerRequired
+	}
+	if reviewerID == post.UserID {
+		return ErrSelfReview
+	}
+
+	from := post.Status
+	post.Status = socialdomain.PostStatusPendingReview
+	post.ReviewerID = reviewerID
+	post.ApprovedBy = ""
+	post.ApprovedAt = nil
+	if err := s.posts.Update(ctx, post); err != nil {
+		return fmt.Errorf("failed to update post: %w", err)
+	}
+	return s.record(ctx, post, actorID, socialdomain.ReviewActionSubmitted, from, comment)
+}
+
+func (s *ApprovalService) record(ctx context.Context, post *socialdomain.ScheduledPost, actorID string, action socialdomain.ReviewAction, from socialdomain.PostStatus, comment string) error {
+	if err := s.reviews.AddEvent(ctx, newReviewEvent(post, actorID, action, from, comment)); err != nil {
+		return fmt.Errorf("failed to record review: %w", err)
+	}
+	return nil
+}
+
+// visiblePost loads a post owned or reviewed by a user
+func (s *ApprovalService) visiblePost(ctx context.Context, postID, userID string) (*socialdomain.ScheduledPost, error) {
+	post, err := s.posts.GetByID(ctx, postID)
+	if err != nil || (post.UserID != userID && post.ReviewerID != userID) {
+		return nil, ErrReviewPostNotFound
+	}
+	return post, nil
+}
+
+// ownPost loads a post owned by a user
+func (s *ApprovalService) ownPost(ctx context.Context, postID, userID string) (*socialdomain.ScheduledPost, error) {
+	post, err := s.visiblePost(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if post.UserID != userID {
+		return nil, ErrNotPostOwner
+	}
+	return post, nil
+}
+
+// reviewedPost loads a post assigned to a user for review
+func (s *ApprovalService) reviewedPost(ctx context.Context, postID, userID string) (*socialdomain.ScheduledPost, error) {
+	post, err := s.visiblePost(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if post.ReviewerID != userID {
+		return nil, ErrNotReviewer
+	}
+	return post, nil
+}
+
+func newReviewEvent(post *socialdomain.ScheduledPost, actorID string, action socialdomain.ReviewAction, from socialdomain.PostStatus, comment string) *socialdomain.PostReviewEvent {
+	return &socialdomain.PostReviewEvent{
+		ID:         uuid.New().String(),
+		PostID:     post.ID,
+		ActorID:    actorID,
+		Action:     action,
+		FromStatus: from,
+		ToStatus:   post.Status,
+		ReviewerID: post.ReviewerID,
+		Comment:    comment,
+		CreatedAt:  time.Now(),
+	}
+}
+
+func canTransition(from, to socialdomain.PostStatus) bool {
+	for _, next := range reviewTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
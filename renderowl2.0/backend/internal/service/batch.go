@@ -3,10 +3,12 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 	"renderowl-api/internal/domain"
@@ -18,19 +20,40 @@ const (
 	TypeBatchProcess = "batch:process"
 )
 
+// batchTask is the payload of a TypeBatchProcess task
+type batchTask struct {
+	BatchID string `json:"batchId"`
+}
+
 // BatchService manages batch video generation with queue processing
 type BatchService struct {
 	repo            domain.BatchRepository
 	queue           *asynq.Client
 	inspector       *asynq.Inspector
+	redis           *redis.Client
 	timelineService *TimelineService
 	clipService     *ClipService
 	aiScriptService *AIScriptService
 	aiSceneService  *AISceneService
 	ttsService      *TTSService
+	renderer        VideoRenderer
 	workerCount     int
 }
 
+// VideoRenderer renders the timelines of generated videos to video files
+type VideoRenderer interface {
+	// Render renders a timeline of a user and returns the rendered file
+	Render(ctx context.Context, userID, timelineID string) (*RenderedVideo, error)
+}
+
+// RenderedVideo is a video file rendered from a timeline
+type RenderedVideo struct {
+	URL       string // URL or path the file is uploaded from
+	Thumbnail string
+	Format    string
+	Size      int64
+}
+
 // CreateBatchRequest represents a request to create a batch
 type CreateBatchRequest struct {
 	Name        string       `json:"name" binding:"required"`
@@ -56,6 +79,7 @@ type BatchProgress struct {
 	InProgress   int     `json:"inProgress"`
 	Progress     float64 `json:"progress"` // 0-100
 	ETA          string  `json:"eta,omitempty"`
+	ETASeconds   int     `json:"etaSeconds,omitempty"`
 	CurrentVideo string  `json:"currentVideo,omitempty"`
 }
 
@@ -92,10 +116,18 @@ func NewBatchService(
 		DB:       0,
 	})
 
+	// Progress events are fanned out to every API replica over pub/sub
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     redisAddr,
+		Password: redisPassword,
+		DB:       0,
+	})
+
 	return &BatchService{
 		repo:            repo,
 		queue:           queue,
 		inspector:       inspector,
+		redis:           redisClient,
 		timelineService: timelineService,
 		clipService:     clipService,
 		aiScriptService: aiScriptService,
@@ -105,6 +137,18 @@ func NewBatchService(
 	}, nil
 }
 
+// SetRenderer makes generated videos render their timeline to a file.
+// Without a renderer videos end at their timeline and have no file to
+// publish.
+func (s *BatchService) SetRenderer(renderer VideoRenderer) {
+	s.renderer = renderer
+}
+
+// RendersVideos reports whether generated videos are rendered to files
+func (s *BatchService) RendersVideos() bool {
+	return s.renderer != nil
+}
+
 // CreateBatch creates a new batch job
 func (s *BatchService) CreateBatch(ctx context.Context, userID string, req *CreateBatchRequest) (*domain.Batch, error) {
 	batch := &domain.Batch{
@@ -179,20 +223,71 @@ func (s *BatchService) StartBatch(ctx context.Context, batchID string) error {
 		return err
 	}
 
-	// Queue videos for processing
-	for i := range batch.Videos {
-		if err := s.queueVideo(&batch.Videos[i]); err != nil {
-			log.Printf("Failed to queue video %s: %v", batch.Videos[i].ID, err)
-			batch.Videos[i].Status = domain.VideoStatusFailed
-			batch.Videos[i].Error = "Failed to queue"
-		}
+	// Videos are handed to the queue by the worker, MaxConcurrent at a time
+	return s.queueDispatch(batchID)
+}
+
+// queueDispatch enqueues a task asking a worker to queue the next pending
+// videos of a batch
+func (s *BatchService) queueDispatch(batchID string) error {
+	payload, err := json.Marshal(batchTask{BatchID: batchID})
+	if err != nil {
+		return err
+	}
+
+	task := asynq.NewTask(TypeBatchProcess, payload)
+	if _, err := s.queue.Enqueue(task, asynq.Queue("batch"), asynq.MaxRetry(5), asynq.Timeout(time.Minute)); err != nil {
+		return fmt.Errorf("failed to queue batch: %w", err)
 	}
 
 	return nil
 }
 
-// queueVideo adds a video to the processing queue
-func (s *BatchService) queueVideo(video *domain.BatchVideo) error {
+// DispatchBatch queues pending videos of a batch until MaxConcurrent of
+// them are queued or processing. Workers call it when a batch starts and
+// whenever one of its videos finishes.
+func (s *BatchService) DispatchBatch(ctx context.Context, batchID string) error {
+	batch, err := s.repo.Get(batchID)
+	if err != nil {
+		return err
+	}
+
+	maxActive := batch.Config.MaxConcurrent
+	if maxActive <= 0 {
+		maxActive = s.workerCount
+	}
+
+	videos, err := s.repo.ClaimVideos(batchID, maxActive)
+	if err != nil {
+		return err
+	}
+	if len(videos) == 0 {
+		return nil
+	}
+
+	for _, video := range videos {
+		err := s.queueVideo(ctx, video, batch.Config.RetryAttempts)
+		if errors.Is(err, asynq.ErrTaskIDConflict) {
+			// Its task is still queued from an earlier run
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to queue video %s: %v", video.ID, err)
+			video.Status = domain.VideoStatusFailed
+			video.Error = "Failed to queue"
+			s.updateVideo(ctx, video)
+		}
+	}
+
+	_, err = s.refreshProgress(ctx, batchID)
+	return err
+}
+
+// queueVideo adds a video to the processing queue and persists its queued status.
+// The video ID doubles as the task ID so a video is never queued twice: it
+// returns asynq.ErrTaskIDConflict while the video's task is pending,
+// scheduled or waiting to retry.
+func (s *BatchService) queueVideo(ctx context.Context, video *domain.BatchVideo, maxRetry int) error {
 	payload, err := json.Marshal(video)
 	if err != nil {
 		return err
@@ -203,17 +298,34 @@ func (s *BatchService) queueVideo(video *domain.BatchVideo) error {
 	// Configure task options
 	opts := []asynq.Option{
 		asynq.Queue("batch"),
-		asynq.MaxRetry(3),
+		asynq.TaskID(video.ID),
+		asynq.MaxRetry(maxRetry),
 		asynq.Timeout(30 * time.Minute),
 		asynq.Retention(24 * time.Hour),
 	}
 
+	// Drop a finished task retained or archived by a previous run. Live
+	// tasks are left alone so that they keep their retry count.
+	if info, err := s.inspector.GetTaskInfo("batch", video.ID); err == nil {
+		switch info.State {
+		case asynq.TaskStateCompleted, asynq.TaskStateArchived:
+			if err := s.inspector.DeleteTask("batch", video.ID); err != nil {
+				return err
+			}
+		default:
+			return asynq.ErrTaskIDConflict
+		}
+	}
+
 	info, err := s.queue.Enqueue(task, opts...)
 	if err != nil {
 		return err
 	}
 
 	video.Status = domain.VideoStatusQueued
+	if err := s.updateVideo(ctx, video); err != nil {
+		return fmt.Errorf("failed to persist video status: %w", err)
+	}
 	log.Printf("Queued video %s with task ID %s", video.ID, info.ID)
 
 	return nil
@@ -250,12 +362,15 @@ func (s *BatchService) GetBatchProgress(ctx context.Context, batchID string) (*B
 		}
 	}
 
-	// Calculate ETA
-	if batch.Status == domain.BatchStatusProcessing && batch.Completed > 0 {
+	// Calculate ETA from observed step durations, falling back to the
+	// overall completion rate until every step has been timed
+	if eta, ok := s.estimateRemaining(ctx, batch); ok {
+		setETA(progress, eta)
+	} else if batch.Status == domain.BatchStatusProcessing && batch.Completed > 0 {
 		elapsed := time.Since(*batch.StartedAt)
 		rate := float64(batch.Completed) / elapsed.Minutes()
 		remaining := float64(batch.TotalVideos-batch.Completed) / rate
-		progress.ETA = fmt.Sprintf("%.0f minutes", remaining)
+		setETA(progress, time.Duration(remaining*float64(time.Minute)))
 	}
 
 	return progress, nil
@@ -288,6 +403,18 @@ func (s *BatchService) ListBatches(ctx context.Context, userID string, limit, of
 	return s.repo.List(userID, limit, offset)
 }
 
+// DeleteBatch deletes a batch of a user that has not been started
+func (s *BatchService) DeleteBatch(ctx context.Context, userID, batchID string) error {
+	batch, err := s.repo.Get(batchID)
+	if err != nil || batch.UserID != userID {
+		return fmt.Errorf("batch not found")
+	}
+	if batch.Status != domain.BatchStatusPending {
+		return fmt.Errorf("batch has been started and cannot be deleted")
+	}
+	return s.repo.Delete(batchID)
+}
+
 // CancelBatch cancels a batch and all pending videos
 func (s *BatchService) CancelBatch(ctx context.Context, batchID string) error {
 	batch, err := s.repo.Get(batchID)
@@ -299,34 +426,62 @@ func (s *BatchService) CancelBatch(ctx context.Context, batchID string) error {
 		return fmt.Errorf("batch cannot be cancelled")
 	}
 
-	batch.Status = domain.BatchStatusCancelled
-	batch.UpdatedAt = time.Now()
+	if err := s.repo.UpdateStatus(batchID, domain.BatchStatusCancelled); err != nil {
+		return err
+	}
 
-	// Cancel all pending videos
+	// Cancel all videos that have not started yet. Videos already being
+	// processed finish their current run and are not picked up again.
 	for i := range batch.Videos {
-		if batch.Videos[i].Status == domain.VideoStatusPending || batch.Videos[i].Status == domain.VideoStatusQueued {
-			batch.Videos[i].Status = domain.VideoStatusCancelled
+		video := &batch.Videos[i]
+		switch video.Status {
+		case domain.VideoStatusPending, domain.VideoStatusQueued, domain.VideoStatusPaused:
+			s.inspector.DeleteTask("batch", video.ID)
+			video.Status = domain.VideoStatusCancelled
+			if err := s.updateVideo(ctx, video); err != nil {
+				return err
+			}
 		}
 	}
 
-	return s.repo.Update(batch)
+	_, err = s.refreshProgress(ctx, batchID)
+	return err
 }
 
-// PauseBatch pauses batch processing
+// PauseBatch pauses batch processing. Queued videos are pulled from the
+// queue; videos already being processed run to completion.
 func (s *BatchService) PauseBatch(ctx context.Context, batchID string) error {
 	batch, err := s.repo.Get(batchID)
 	if err != nil {
 		return err
 	}
 
-	if batch.Status != domain.BatchStatusProcessing {
-		return fmt.Errorf("can only pause processing batches")
+	if batch.Status != domain.BatchStatusProcessing && batch.Status != domain.BatchStatusQueued {
+		return fmt.Errorf("can only pause queued or processing batches")
 	}
 
-	batch.Status = domain.BatchStatusPaused
-	batch.UpdatedAt = time.Now()
+	if err := s.repo.UpdateStatus(batchID, domain.BatchStatusPaused); err != nil {
+		return err
+	}
 
-	return s.repo.Update(batch)
+	for i := range batch.Videos {
+		video := &batch.Videos[i]
+		if video.Status != domain.VideoStatusQueued {
+			continue
+		}
+		// Tasks that cannot be deleted are already running or about to;
+		// the worker will park them as paused when it sees the batch status.
+		if err := s.inspector.DeleteTask("batch", video.ID); err != nil {
+			continue
+		}
+		video.Status = domain.VideoStatusPaused
+		if err := s.updateVideo(ctx, video); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.refreshProgress(ctx, batchID)
+	return err
 }
 
 // ResumeBatch resumes a paused batch
@@ -340,13 +495,95 @@ func (s *BatchService) ResumeBatch(ctx context.Context, batchID string) error {
 		return fmt.Errorf("can only resume paused batches")
 	}
 
-	batch.Status = domain.BatchStatusProcessing
-	batch.UpdatedAt = time.Now()
+	if err := s.repo.UpdateStatus(batchID, domain.BatchStatusQueued); err != nil {
+		return err
+	}
+
+	for i := range batch.Videos {
+		video := &batch.Videos[i]
+		if video.Status != domain.VideoStatusPaused {
+			continue
+		}
+		video.Status = domain.VideoStatusPending
+		if err := s.updateVideo(ctx, video); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.refreshProgress(ctx, batchID); err != nil {
+		return err
+	}
 
-	return s.repo.Update(batch)
+	return s.queueDispatch(batchID)
 }
 
-// RetryFailedVideos retries all failed videos in a batch
+// RecoverBatches re-queues unfinished videos whose task was lost, such as
+// when Redis lost its data, and resumes dispatching pending ones.
+// Completed videos are never touched, and videos whose task is still held
+// by asynq are left for the queue to recover. The worker runs it at
+// startup.
+func (s *BatchService) RecoverBatches(ctx context.Context) error {
+	batches, err := s.repo.ListByStatus(domain.BatchStatusQueued, domain.BatchStatusProcessing)
+	if err != nil {
+		return err
+	}
+
+	for _, batch := range batches {
+		requeued := 0
+		for i := range batch.Videos {
+			video := &batch.Videos[i]
+			switch video.Status {
+			case domain.VideoStatusQueued, domain.VideoStatusProcessing:
+			default:
+				continue
+			}
+
+			missing, err := s.taskMissing(video.ID)
+			if err != nil {
+				log.Printf("Failed to look up task of video %s: %v", video.ID, err)
+				continue
+			}
+			if !missing {
+				continue
+			}
+
+			if err := s.queueVideo(ctx, video, batch.Config.RetryAttempts); err != nil {
+				if !errors.Is(err, asynq.ErrTaskIDConflict) {
+					log.Printf("Failed to recover video %s: %v", video.ID, err)
+				}
+				continue
+			}
+			requeued++
+		}
+
+		if err := s.queueDispatch(batch.ID); err != nil {
+			log.Printf("Failed to resume batch %s: %v", batch.ID, err)
+		}
+		if _, err := s.refreshProgress(ctx, batch.ID); err != nil {
+			log.Printf("Failed to refresh batch %s: %v", batch.ID, err)
+		}
+		if requeued > 0 {
+			log.Printf("Recovered batch %s: requeued %d videos", batch.ID, requeued)
+		}
+	}
+
+	return nil
+}
+
+// taskMissing reports whether the queue has no task for a video
+func (s *BatchService) taskMissing(videoID string) (bool, error) {
+	_, err := s.inspector.GetTaskInfo("batch", videoID)
+	switch {
+	case errors.Is(err, asynq.ErrTaskNotFound), errors.Is(err, asynq.ErrQueueNotFound):
+		return true, nil
+	case err != nil:
+		return false, err
+	}
+	return false, nil
+}
+
+// RetryFailedVideos retries all failed videos in a batch. Each video
+// resumes at the pipeline step that failed.
 func (s *BatchService) RetryFailedVideos(ctx context.Context, batchID string) error {
 	batch, err := s.repo.Get(batchID)
 	if err != nil {
@@ -355,27 +592,34 @@ func (s *BatchService) RetryFailedVideos(ctx context.Context, batchID string) er
 
 	retryCount := 0
 	for i := range batch.Videos {
-		if batch.Videos[i].Status == domain.VideoStatusFailed {
-			batch.Videos[i].Status = domain.VideoStatusPending
-			batch.Videos[i].Error = ""
-			batch.Videos[i].Progress = 0
-			batch.Videos[i].UpdatedAt = time.Now()
-
-			if err := s.queueVideo(&batch.Videos[i]); err != nil {
-				log.Printf("Failed to requeue video %s: %v", batch.Videos[i].ID, err)
-			} else {
-				retryCount++
-			}
+		video := &batch.Videos[i]
+		if video.Status != domain.VideoStatusFailed {
+			continue
 		}
+		video.Status = domain.VideoStatusPending
+		video.Error = ""
+		video.CompletedAt = nil
+		if err := s.updateVideo(ctx, video); err != nil {
+			return err
+		}
+		retryCount++
+	}
+
+	if retryCount == 0 {
+		return fmt.Errorf("no failed videos to retry")
 	}
 
-	if retryCount > 0 {
-		batch.Failed -= retryCount
-		batch.UpdatedAt = time.Now()
-		return s.repo.Update(batch)
+	if batch.Status == domain.BatchStatusCompleted || batch.Status == domain.BatchStatusFailed {
+		if err := s.repo.UpdateStatus(batchID, domain.BatchStatusQueued); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.refreshProgress(ctx, batchID); err != nil {
+		return err
 	}
 
-	return fmt.Errorf("no failed videos to retry")
+	return s.queueDispatch(batchID)
 }
 
 // GetQueueStats retrieves queue statistics
@@ -397,10 +641,18 @@ func (s *BatchService) GetQueueStats(ctx context.Context) (*QueueStats, error) {
 
 // ProcessVideo processes a single video (called by worker)
 func (s *BatchService) ProcessVideo(ctx context.Context, video *domain.BatchVideo) error {
-	// Update status to processing
-	video.Status = domain.VideoStatusProcessing
-	now := time.Now()
-	video.StartedAt = &now
+	// Reload the persisted state; the task payload may be stale
+	current, err := s.repo.GetVideo(video.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get video: %w", err)
+	}
+	*video = *current
+
+	switch video.Status {
+	case domain.VideoStatusCompleted, domain.VideoStatusCancelled, domain.VideoStatusSkipped:
+		log.Printf("Skipping video %s in status %s", video.ID, video.Status)
+		return nil
+	}
 
 	// Get batch for context
 	batch, err := s.repo.Get(video.BatchID)
@@ -408,195 +660,315 @@ func (s *BatchService) ProcessVideo(ctx context.Context, video *domain.BatchVide
 		return fmt.Errorf("failed to get batch: %w", err)
 	}
 
-	// Update batch progress
-	batch.InProgress++
-	batch.Status = domain.BatchStatusProcessing
-	batch.UpdatedAt = time.Now()
+	switch batch.Status {
+	case domain.BatchStatusCancelled:
+		video.Status = domain.VideoStatusCancelled
+		if err := s.updateVideo(ctx, video); err != nil {
+			return err
+		}
+		_, err := s.refreshProgress(ctx, batch.ID)
+		return err
+	case domain.BatchStatusPaused:
+		video.Status = domain.VideoStatusPaused
+		return s.updateVideo(ctx, video)
+	}
 
-	if err := s.repo.Update(batch); err != nil {
+	// Update status to processing
+	video.Status = domain.VideoStatusProcessing
+	video.Error = ""
+	if video.StartedAt == nil {
+		now := time.Now()
+		video.StartedAt = &now
+	}
+	if err := s.updateVideo(ctx, video); err != nil {
+		return fmt.Errorf("failed to update video: %w", err)
+	}
+	if _, err := s.refreshProgress(ctx, batch.ID); err != nil {
 		return fmt.Errorf("failed to update batch: %w", err)
 	}
 
 	// Process the video
 	result, err := s.generateVideo(ctx, video, batch)
 	if err != nil {
-		video.Status = domain.VideoStatusFailed
 		video.Error = err.Error()
-		video.Progress = 0
 
-		// Update batch
-		batch.InProgress--
-		batch.Failed++
-		batch.UpdatedAt = time.Now()
-		s.repo.Update(batch)
+		// Leave the video queued while asynq still has retries left for it
+		retried, _ := asynq.GetRetryCount(ctx)
+		maxRetry, ok := asynq.GetMaxRetry(ctx)
+		if ok && retried < maxRetry {
+			video.Status = domain.VideoStatusQueued
+			s.updateVideo(ctx, video)
+			return err
+		}
+
+		video.Status = domain.VideoStatusFailed
+		s.updateVideo(ctx, video)
+		s.refreshProgress(ctx, batch.ID)
+		s.dispatchNext(ctx, batch.ID)
 
 		return err
 	}
 
 	// Success
 	video.Status = domain.VideoStatusCompleted
+	video.Step = domain.VideoStepDone
 	video.Result = result
 	video.Progress = 100
 	completedAt := time.Now()
 	video.CompletedAt = &completedAt
 	video.TimelineID = result.TimelineID
 
-	// Update batch
-	batch.InProgress--
-	batch.Completed++
-	batch.UpdatedAt = time.Now()
-
-	// Calculate overall progress
-	batch.Progress = float64(batch.Completed+batch.Failed) / float64(batch.TotalVideos) * 100
-
-	// Check if batch is complete
-	if batch.Completed+batch.Failed >= batch.TotalVideos {
-		batch.CompletedAt = &completedAt
-		if batch.Failed == 0 {
-			batch.Status = domain.BatchStatusCompleted
-		} else if batch.Completed > 0 {
-			batch.Status = domain.BatchStatusCompleted // Partial success
-		} else {
-			batch.Status = domain.BatchStatusFailed
+	if err := s.updateVideo(ctx, video); err != nil {
+		return fmt.Errorf("failed to update video: %w", err)
+	}
+
+	if _, err := s.refreshProgress(ctx, batch.ID); err != nil {
+		return err
+	}
+
+	s.dispatchNext(ctx, batch.ID)
+	return nil
+}
+
+// dispatchNext hands the slot of a finished video to the next pending one
+func (s *BatchService) dispatchNext(ctx context.Context, batchID string) {
+	if err := s.DispatchBatch(ctx, batchID); err != nil {
+		log.Printf("Failed to dispatch batch %s: %v", batchID, err)
+	}
+}
+
+// advanceVideo records that a pipeline step finished and persists the new progress
+func (s *BatchService) advanceVideo(ctx context.Context, video *domain.BatchVideo, step domain.VideoStep, progress float64) {
+	// UpdatedAt marks when the previous step was persisted
+	if !video.UpdatedAt.IsZero() {
+		s.recordStepDuration(ctx, video.BatchID, step, time.Since(video.UpdatedAt))
+	}
+
+	video.Step = step
+	video.Progress = progress
+	if err := s.updateVideo(ctx, video); err != nil {
+		log.Printf("Failed to persist progress for video %s: %v", video.ID, err)
+	}
+}
+
+// saveCheckpoint stores the output of a finished pipeline step and persists
+// the new progress, so a retry can pick up after this step
+func (s *BatchService) saveCheckpoint(ctx context.Context, video *domain.BatchVideo, step domain.VideoStep, output interface{}, progress float64) {
+	data, err := json.Marshal(output)
+	if err != nil {
+		log.Printf("Failed to checkpoint %s step for video %s: %v", step, video.ID, err)
+	} else {
+		if video.Checkpoint == nil {
+			video.Checkpoint = make(domain.VideoCheckpoint)
 		}
+		video.Checkpoint[step] = data
 	}
 
-	return s.repo.Update(batch)
+	s.advanceVideo(ctx, video, step, progress)
+}
+
+// loadCheckpoint restores the saved output of a pipeline step into output.
+// It reports false if the step has not finished in a previous run.
+func loadCheckpoint(video *domain.BatchVideo, step domain.VideoStep, output interface{}) bool {
+	data, ok := video.Checkpoint[step]
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(data, output) == nil
 }
 
 // generateVideo generates a single video
 func (s *BatchService) generateVideo(ctx context.Context, video *domain.BatchVideo, batch *domain.Batch) (*domain.VideoResult, error) {
 	startTime := time.Now()
 
-	// Step 1: Generate script if not provided
+	// Step 1: Generate script if not provided, unless a previous run did
 	var script *Script
-	if video.Config.Script != "" {
-		script = &Script{
-			Title:       video.Title,
-			Description: video.Config.Script,
+	if !loadCheckpoint(video, domain.VideoStepScript, &script) || script == nil {
+		if video.Config.Script != "" {
+			script = &Script{
+				Title:       video.Title,
+				Description: video.Config.Script,
+			}
+		} else {
+			scriptReq := &GenerateScriptRequest{
+				Prompt:   video.Config.Topic,
+				Style:    ScriptStyle(batch.Config.ScriptStyle),
+				Tone:     video.Config.Tone,
+				Duration: batch.Config.Duration,
+			}
+
+			var err error
+			script, err = s.aiScriptService.GenerateScript(ctx, scriptReq)
+			if err != nil {
+				return nil, fmt.Errorf("script generation failed: %w", err)
+			}
 		}
+
+		// Update progress
+		s.saveCheckpoint(ctx, video, domain.VideoStepScript, script, 25)
 	} else {
-		scriptReq := &GenerateScriptRequest{
-			Prompt:   video.Config.Topic,
-			Style:    ScriptStyle(batch.Config.ScriptStyle),
-			Tone:     video.Config.Tone,
-			Duration: batch.Config.Duration,
+		log.Printf("Resuming video %s after %s step", video.ID, video.Step)
+	}
+
+	// Step 2: Generate scenes - convert script scenes to SceneInfo
+	var scenes *SceneGenerationResult
+	if !loadCheckpoint(video, domain.VideoStepScenes, &scenes) || scenes == nil {
+		sceneInfos := make([]SceneInfo, 0, len(script.Scenes))
+		for _, scene := range script.Scenes {
+			sceneInfos = append(sceneInfos, SceneInfo{
+				Number:      scene.Number,
+				Title:       scene.Title,
+				Description: scene.Description,
+				Keywords:    scene.Keywords,
+			})
+		}
+
+		sceneReq := &GenerateScenesRequest{
+			ScriptID:       script.Title,
+			Scenes:         sceneInfos,
+			Style:          string(script.Style),
+			ImageSource:    SourceUnsplash,
+			GenerateImages: true,
 		}
 
 		var err error
-		script, err = s.aiScriptService.GenerateScript(ctx, scriptReq)
+		scenes, err = s.aiSceneService.GenerateScenes(ctx, sceneReq)
 		if err != nil {
-			return nil, fmt.Errorf("script generation failed: %w", err)
+			return nil, fmt.Errorf("scene generation failed: %w", err)
 		}
-	}
-
-	// Update progress
-	video.Progress = 25
-	s.repo.Update(batch)
 
-	// Step 2: Generate scenes - convert script scenes to SceneInfo
-	sceneInfos := make([]SceneInfo, 0, len(script.Scenes))
-	for _, scene := range script.Scenes {
-		sceneInfos = append(sceneInfos, SceneInfo{
-			Number:      scene.Number,
-			Title:       scene.Title,
-			Description: scene.Description,
-			Keywords:    scene.Keywords,
-		})
+		// Update progress
+		s.saveCheckpoint(ctx, video, domain.VideoStepScenes, scenes, 50)
 	}
 
-	sceneReq := &GenerateScenesRequest{
-		ScriptID:       script.Title,
-		Scenes:         sceneInfos,
-		Style:          string(script.Style),
-		ImageSource:    SourceUnsplash,
-		GenerateImages: true,
-	}
+	// Step 3: Generate voice if enabled
+	var voice *GenerateVoiceResponse
+	if !loadCheckpoint(video, domain.VideoStepVoice, &voice) {
+		if batch.Config.VoiceID != "" {
+			ttsReq := &GenerateVoiceRequest{
+				Text:           script.Description,
+				VoiceID:        batch.Config.VoiceID,
+				Provider:       ProviderElevenLabs,
+				Speed:          1.0,
+				ResponseFormat: "mp3",
+			}
 
-	scenes, err := s.aiSceneService.GenerateScenes(ctx, sceneReq)
-	if err != nil {
-		return nil, fmt.Errorf("scene generation failed: %w", err)
-	}
+			var err error
+			voice, err = s.ttsService.GenerateVoice(ctx, ttsReq)
+			if err != nil {
+				log.Printf("Voice generation failed for video %s: %v", video.ID, err)
+				// Continue without voice - non-critical
+			}
+		}
 
-	// Update progress
-	video.Progress = 50
-	s.repo.Update(batch)
+		// Update progress
+		s.saveCheckpoint(ctx, video, domain.VideoStepVoice, voice, 75)
+	}
 
-	// Step 3: Generate voice if enabled
-	if batch.Config.VoiceID != "" {
-		ttsReq := &GenerateVoiceRequest{
-			Text:           script.Description,
-			VoiceID:        batch.Config.VoiceID,
-			Provider:       ProviderElevenLabs,
-			Speed:          1.0,
-			ResponseFormat: "mp3",
+	// Step 4: Create timeline, unless a previous run already built it
+	timelineID := video.TimelineID
+	if timelineID == "" {
+		timelineReq := &CreateTimelineRequest{
+			Name:        video.Title,
+			Description: video.Description,
+			Duration:    float64(batch.Config.Duration),
+			Width:       1920,
+			Height:      1080,
+			FPS:         30,
 		}
 
-		_, err := s.ttsService.GenerateVoice(ctx, ttsReq)
+		timeline, err := s.timelineService.Create(batch.UserID, timelineReq)
 		if err != nil {
-			log.Printf("Voice generation failed for video %s: %v", video.ID, err)
-			// Continue without voice - non-critical
+			return nil, fmt.Errorf("timeline creation failed: %w", err)
 		}
-	}
-
-	// Update progress
-	video.Progress = 75
-	s.repo.Update(batch)
 
-	// Step 4: Create timeline
-	timelineReq := &CreateTimelineRequest{
-		Name:        video.Title,
-		Description: video.Description,
-		Duration:    float64(batch.Config.Duration),
-		Width:       1920,
-		Height:      1080,
-		FPS:         30,
-	}
-
-	timeline, err := s.timelineService.Create(batch.UserID, timelineReq)
-	if err != nil {
-		return nil, fmt.Errorf("timeline creation failed: %w", err)
-	}
+		// Step 5: Add scenes as clips
+		// Create a default track first or use the timeline ID as track reference
+		currentTime := 0.0
+		sceneDuration := float64(batch.Config.Duration) / float64(len(scenes.Scenes))
+		if len(scenes.Scenes) == 0 {
+			sceneDuration = 5.0 // Default 5 seconds per scene if no scenes
+		}
 
-	// Step 5: Add scenes as clips
-	// Create a default track first or use the timeline ID as track reference
-	currentTime := 0.0
-	sceneDuration := float64(batch.Config.Duration) / float64(len(scenes.Scenes))
-	if len(scenes.Scenes) == 0 {
-		sceneDuration = 5.0 // Default 5 seconds per scene if no scenes
-	}
+		for i, scene := range scenes.Scenes {
+			clipReq := &CreateClipRequest{
+				TrackID:     timeline.ID, // Using timeline ID as track reference
+				Name:        fmt.Sprintf("Scene %d: %s", i+1, scene.Title),
+				Type:        "image",
+				SourceURL:   scene.ImageURL,
+				StartTime:   currentTime,
+				EndTime:     currentTime + sceneDuration,
+				TextContent: scene.Description,
+			}
 
-	for i, scene := range scenes.Scenes {
-		clipReq := &CreateClipRequest{
-			TrackID:     timeline.ID, // Using timeline ID as track reference
-			Name:        fmt.Sprintf("Scene %d: %s", i+1, scene.Title),
-			Type:        "image",
-			SourceURL:   scene.ImageURL,
-			StartTime:   currentTime,
-			EndTime:     currentTime + sceneDuration,
-			TextContent: scene.Description,
+			_, err := s.clipService.Create(batch.UserID, timeline.ID, clipReq)
+			if err != nil {
+				log.Printf("Failed to add clip: %v", err)
+			}
+			currentTime += sceneDuration
 		}
 
-		_, err := s.clipService.Create(batch.UserID, timeline.ID, clipReq)
-		if err != nil {
-			log.Printf("Failed to add clip: %v", err)
-		}
-		currentTime += sceneDuration
+		timelineID = timeline.ID
+		video.TimelineID = timelineID
+		s.saveCheckpoint(ctx, video, domain.VideoStepTimeline, timelineID, 90)
 	}
 
 	renderTime := int(time.Since(startTime).Seconds())
 
 	result := &domain.VideoResult{
-		TimelineID: timeline.ID,
+		TimelineID: timelineID,
 		Duration:   float64(batch.Config.Duration),
 		Size:       0,
 		Metadata:   map[string]string{"renderTime": fmt.Sprintf("%d", renderTime)},
 	}
 
+	// Step 6: Render the timeline to the file that is published
+	if s.renderer != nil {
+		rendered, err := s.renderer.Render(ctx, batch.UserID, timelineID)
+		if err != nil {
+			return nil, fmt.Errorf("render failed: %w", err)
+		}
+		if rendered.URL == "" {
+			return nil, fmt.Errorf("render failed: renderer returned no file")
+		}
+		result.VideoURL = rendered.URL
+		result.Thumbnail = rendered.Thumbnail
+		result.Format = rendered.Format
+		result.Size = rendered.Size
+		result.Metadata["renderTime"] = fmt.Sprintf("%d", int(time.Since(startTime).Seconds()))
+	}
+
 	return result, nil
 }
 
+// RegisterHandlers registers the batch task handlers on a worker mux
+func (s *BatchService) RegisterHandlers(mux *asynq.ServeMux) {
+	mux.HandleFunc(TypeBatchVideo, s.handleVideoTask)
+	mux.HandleFunc(TypeBatchProcess, s.handleProcessTask)
+}
+
+// handleVideoTask runs the generation pipeline for a TypeBatchVideo task
+func (s *BatchService) handleVideoTask(ctx context.Context, t *asynq.Task) error {
+	var video domain.BatchVideo
+	if err := json.Unmarshal(t.Payload(), &video); err != nil {
+		return fmt.Errorf("invalid batch video payload: %v: %w", err, asynq.SkipRetry)
+	}
+
+	return s.ProcessVideo(ctx, &video)
+}
+
+// handleProcessTask queues the next pending videos for a TypeBatchProcess task
+func (s *BatchService) handleProcessTask(ctx context.Context, t *asynq.Task) error {
+	var payload batchTask
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("invalid batch payload: %v: %w", err, asynq.SkipRetry)
+	}
+
+	return s.DispatchBatch(ctx, payload.BatchID)
+}
+
 // Close closes the batch service
 func (s *BatchService) Close() error {
+	s.redis.Close()
 	return s.queue.Close()
 }
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"renderowl-api/internal/domain"
+)
+
+// Batch event types
+const (
+	BatchEventVideo = "video"
+	BatchEventBatch = "batch"
+)
+
+// pipelineSteps lists the generation steps in the order they run
+var pipelineSteps = []domain.VideoStep{
+	domain.VideoStepScript,
+	domain.VideoStepScenes,
+	domain.VideoStepVoice,
+	domain.VideoStepTimeline,
+}
+
+// stepStatsTTL is how long observed step durations are kept per batch
+const stepStatsTTL = 7 * 24 * time.Hour
+
+// BatchEvent is a progress update published while a batch is processed.
+// Video events carry the video's own progress; batch events carry the
+// overall progress and ETA.
+type BatchEvent struct {
+	Type       string    `json:"type"`
+	BatchID    string    `json:"batchId"`
+	VideoID    string    `json:"videoId,omitempty"`
+	Title      string    `json:"title,omitempty"`
+	Status     string    `json:"status"`
+	Step       string    `json:"step,omitempty"`
+	Progress   float64   `json:"progress"`
+	Completed  int       `json:"completed,omitempty"`
+	Failed     int       `json:"failed,omitempty"`
+	InProgress int       `json:"inProgress,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	ETA        string    `json:"eta,omitempty"`
+	ETASeconds int       `json:"etaSeconds,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+func batchEventsChannel(batchID string) string {
+	return "batch:events:" + batchID
+}
+
+func batchStepStatsKey(batchID string) string {
+	return "batch:steps:" + batchID
+}
+
+// SubscribeBatchEvents streams the progress events of a batch published by
+// any worker. The returned function ends the subscription and closes the
+// channel.
+func (s *BatchService) SubscribeBatchEvents(ctx context.Context, batchID string) (<-chan *BatchEvent, func() error, error) {
+	pubsub := s.redis.Subscribe(ctx, batchEventsChannel(batchID))
+
+	// Wait for the subscription so no event published afterwards is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, nil, fmt.Errorf("failed to subscribe to batch events: %w", err)
+	}
+
+	events := make(chan *BatchEvent)
+	go func() {
+		defer close(events)
+		for msg := range pubsub.Channel() {
+			var event BatchEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				continue
+			}
+			select {
+			case events <- &event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, pubsub.Close, nil
+}
+
+// publishEvent publishes a batch event. Delivery is best effort.
+func (s *BatchService) publishEvent(ctx context.Context, event *BatchEvent) {
+	event.Timestamp = time.Now()
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	if err := s.redis.Publish(ctx, batchEventsChannel(event.BatchID), data).Err(); err != nil {
+		log.Printf("Failed to publish event for batch %s: %v", event.BatchID, err)
+	}
+}
+
+// updateVideo persists a video and publishes its new state
+func (s *BatchService) updateVideo(ctx context.Context, video *domain.BatchVideo) error {
+	if err := s.repo.UpdateVideo(video); err != nil {
+		return err
+	}
+
+	s.publishEvent(ctx, &BatchEvent{
+		Type:     BatchEventVideo,
+		BatchID:  video.BatchID,
+		VideoID:  video.ID,
+		Title:    video.Title,
+		Status:   string(video.Status),
+		Step:     string(video.Step),
+		Progress: video.Progress,
+		Error:    video.Error,
+	})
+
+	return nil
+}
+
+// refreshProgress recalculates the batch counters and publishes the overall progress
+func (s *BatchService) refreshProgress(ctx context.Context, batchID string) (*domain.Batch, error) {
+	batch, err := s.repo.RefreshProgress(batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &BatchEvent{
+		Type:       BatchEventBatch,
+		BatchID:    batch.ID,
+		Status:     string(batch.Status),
+		Progress:   batch.Progress,
+		Completed:  batch.Completed,
+		Failed:     batch.Failed,
+		InProgress: batch.InProgress,
+		Error:      batch.Error,
+	}
+	if eta, ok := s.estimateRemaining(ctx, batch); ok {
+		event.ETA = formatETA(eta)
+		event.ETASeconds = int(eta.Seconds())
+	}
+	s.publishEvent(ctx, event)
+
+	return batch, nil
+}
+
+// recordStepDuration adds an observed step duration to the batch statistics
+func (s *BatchService) recordStepDuration(ctx context.Context, batchID string, step domain.VideoStep, elapsed time.Duration) {
+	key := batchStepStatsKey(batchID)
+
+	pipe := s.redis.TxPipeline()
+	pipe.HIncrByFloat(ctx, key, "total:"+string(step), elapsed.Seconds())
+	pipe.HIncrBy(ctx, key, "count:"+string(step), 1)
+	pipe.Expire(ctx, key, stepStatsTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to record step duration for batch %s: %v", batchID, err)
+	}
+}
+
+// estimateRemaining estimates the time left in a batch from the average
+// duration of each step observed so far. It reports false until every
+// step has been timed at least once.
+func (s *BatchService) estimateRemaining(ctx context.Context, batch *domain.Batch) (time.Duration, bool) {
+	if batch.Status != domain.BatchStatusQueued && batch.Status != domain.BatchStatusProcessing {
+		return 0, false
+	}
+
+	stats, err := s.redis.HGetAll(ctx, batchStepStatsKey(batch.ID)).Result()
+	if err != nil {
+		return 0, false
+	}
+
+	averages := make(map[domain.VideoStep]float64, len(pipelineSteps))
+	for _, step := range pipelineSteps {
+		total, _ := strconv.ParseFloat(stats["total:"+string(step)], 64)
+		count, _ := strconv.Atoi(stats["count:"+string(step)])
+		if count == 0 {
+			return 0, false
+		}
+		averages[step] = total / float64(count)
+	}
+
+	remaining := 0.0
+	unfinished := 0
+	for _, video := range batch.Videos {
+		if video.Status.IsTerminal() {
+			continue
+		}
+		unfinished++
+		for _, step := range pipelineSteps[stepsDone(video.Step):] {
+			remaining += averages[step]
+		}
+	}
+	if unfinished == 0 {
+		return 0, false
+	}
+
+	workers := batch.Config.MaxConcurrent
+	if workers <= 0 {
+		workers = s.workerCount
+	}
+	if workers > unfinished {
+		workers = unfinished
+	}
+
+	return time.Duration(remaining / float64(workers) * float64(time.Second)), true
+}
+
+// stepsDone returns how many pipeline steps finished once step was reached
+func stepsDone(step domain.VideoStep) int {
+	if step == domain.VideoStepDone {
+		return len(pipelineSteps)
+	}
+	for i, s := range pipelineSteps {
+		if s == step {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// formatETA formats a remaining duration in whole minutes
+func formatETA(eta time.Duration) string {
+	return fmt.Sprintf("%.0f minutes", math.Ceil(eta.Minutes()))
+}
+
+// setETA fills the ETA fields of a progress report
+func setETA(progress *BatchProgress, eta time.Duration) {
+	progress.ETA = formatETA(eta)
+	progress.ETASeconds = int(eta.Seconds())
+}
@@ -0,0 +1,488 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"renderowl-api/internal/domain"
+)
+
+// Import limits
+const (
+	MaxBatchVideos  = 30
+	MaxImportRows   = 1000
+	maxTitleLength  = 200
+	maxImportTarget = 3600
+)
+
+// ImportFormat is the file format of a batch import
+type ImportFormat string
+
+const (
+	ImportFormatCSV   ImportFormat = "csv"
+	ImportFormatJSONL ImportFormat = "jsonl"
+)
+
+// Import fields that columns can be mapped to
+const (
+	ImportFieldTitle          = "title"
+	ImportFieldDescription    = "description"
+	ImportFieldTopic          = "topic"
+	ImportFieldScript         = "script"
+	ImportFieldKeywords       = "keywords"
+	ImportFieldTone           = "tone"
+	ImportFieldTargetDuration = "targetDuration"
+)
+
+// importFieldAliases lists the column names matched to each field when no
+// explicit mapping is given. Matching ignores case, spaces and underscores.
+var importFieldAliases = map[string][]string{
+	ImportFieldTitle:          {"title", "name", "videotitle"},
+	ImportFieldDescription:    {"description", "summary"},
+	ImportFieldTopic:          {"topic", "subject"},
+	ImportFieldScript:         {"script", "voiceover"},
+	ImportFieldKeywords:       {"keywords", "tags"},
+	ImportFieldTone:           {"tone"},
+	ImportFieldTargetDuration: {"targetduration", "duration", "length"},
+}
+
+// importFields lists the import fields in the order rows are validated
+var importFields = []string{
+	ImportFieldTitle,
+	ImportFieldDescription,
+	ImportFieldTopic,
+	ImportFieldScript,
+	ImportFieldKeywords,
+	ImportFieldTone,
+	ImportFieldTargetDuration,
+}
+
+// ImportBatchRequest represents a request to create batches from a file
+type ImportBatchRequest struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Format      ImportFormat       `json:"format"`
+	Mapping     map[string]string  `json:"mapping,omitempty"` // field -> column
+	Config      domain.BatchConfig `json:"config"`
+}
+
+// ImportRowError describes an invalid row in an import file
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportValidationError is returned when an import file has invalid rows.
+// No batch is created in that case.
+type ImportValidationError struct {
+	Errors []ImportRowError `json:"errors"`
+}
+
+func (e *ImportValidationError) Error() string {
+	return fmt.Sprintf("import has %d invalid rows", len(e.Errors))
+}
+
+// ImportBatchResult represents the batches created by an import
+type ImportBatchResult struct {
+	TotalVideos int             `json:"totalVideos"`
+	Batches     []*domain.Batch `json:"batches"`
+}
+
+// importRow is a parsed row with the line it started on. Mapped rows are
+// keyed by import field; other rows by their source column names.
+type importRow struct {
+	line   int
+	values map[string]interface{}
+	mapped bool
+}
+
+// ImportBatches creates batches from a CSV or JSON Lines file. Every row is
+// validated before anything is created, and imports larger than
+// MaxBatchVideos are split into several batches.
+func (s *BatchService) ImportBatches(ctx context.Context, userID string, req *ImportBatchRequest, file io.Reader) (*ImportBatchResult, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	for field := range req.Mapping {
+		if _, ok := importFieldAliases[field]; !ok {
+			return nil, fmt.Errorf("unknown mapping field %q", field)
+		}
+	}
+
+	var (
+		rows []importRow
+		err  error
+	)
+	switch req.Format {
+	case ImportFormatCSV:
+		rows, err = parseCSVRows(file, req.Mapping)
+	case ImportFormatJSONL:
+		rows, err = parseJSONLRows(file)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", req.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("import file has no rows")
+	}
+	if len(rows) > MaxImportRows {
+		return nil, fmt.Errorf("import file has %d rows, the limit is %d", len(rows), MaxImportRows)
+	}
+
+	// Validate every row up front so a bad file creates nothing
+	var inputs []VideoInput
+	var rowErrors []ImportRowError
+	for _, row := range rows {
+		input, errs := rowToVideoInput(row, req.Mapping)
+		if len(errs) > 0 {
+			rowErrors = append(rowErrors, errs...)
+			continue
+		}
+		inputs = append(inputs, *input)
+	}
+	if len(rowErrors) > 0 {
+		return nil, &ImportValidationError{Errors: rowErrors}
+	}
+
+	chunks := (len(inputs) + MaxBatchVideos - 1) / MaxBatchVideos
+	result := &ImportBatchResult{TotalVideos: len(inputs)}
+
+	for i := 0; i < chunks; i++ {
+		end := (i + 1) * MaxBatchVideos
+		if end > len(inputs) {
+			end = len(inputs)
+		}
+
+		name := req.Name
+		if chunks > 1 {
+			name = fmt.Sprintf("%s (%d/%d)", req.Name, i+1, chunks)
+		}
+
+		batch, err := s.CreateBatch(ctx, userID, &CreateBatchRequest{
+			Name:        name,
+			Description: req.Description,
+			Videos:      inputs[i*MaxBatchVideos : end],
+			Config:      req.Config,
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to create batch %d of %d: %w", i+1, chunks, err)
+		}
+		result.Batches = append(result.Batches, batch)
+	}
+
+	return result, nil
+}
+
+// parseCSVRows reads a CSV file with a header row. Columns are resolved to
+// import fields through the mapping, falling back to well-known names.
+func parseCSVRows(file io.Reader, mapping map[string]string) ([]importRow, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // Spreadsheet exports often start with a BOM
+		}
+		columns[normalizeColumn(name)] = i
+	}
+
+	fieldColumns := make(map[string]int)
+	for field, aliases := range importFieldAliases {
+		if column, ok := mapping[field]; ok {
+			index, found := columns[normalizeColumn(column)]
+			if !found {
+				return nil, &ImportValidationError{Errors: []ImportRowError{{
+					Line:    1,
+					Field:   field,
+					Message: fmt.Sprintf("mapped column %q not found in header", column),
+				}}}
+			}
+			fieldColumns[field] = index
+			continue
+		}
+		for _, alias := range aliases {
+			if index, found := columns[alias]; found {
+				fieldColumns[field] = index
+				break
+			}
+		}
+	}
+
+	if _, ok := fieldColumns[ImportFieldTitle]; !ok {
+		return nil, &ImportValidationError{Errors: []ImportRowError{{
+			Line:    1,
+			Field:   ImportFieldTitle,
+			Message: "no title column found in header",
+		}}}
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, &ImportValidationError{Errors: []ImportRowError{{
+					Line:    parseErr.StartLine,
+					Message: parseErr.Err.Error(),
+				}}}
+			}
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		values := make(map[string]interface{}, len(fieldColumns))
+		empty := true
+		for field, index := range fieldColumns {
+			if index < len(record) && strings.TrimSpace(record[index]) != "" {
+				values[field] = record[index]
+				empty = false
+			}
+		}
+		if empty {
+			continue
+		}
+
+		rows = append(rows, importRow{line: line, values: values, mapped: true})
+	}
+
+	return rows, nil
+}
+
+// parseJSONLRows reads one JSON object per line. Blank lines are skipped.
+// Keys are resolved to import fields in rowToVideoInput.
+func parseJSONLRows(file io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var rows []importRow
+	var rowErrors []ImportRowError
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var values map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &values); err != nil {
+			rowErrors = append(rowErrors, ImportRowError{
+				Line:    line,
+				Message: "invalid JSON object: " + err.Error(),
+			})
+			continue
+		}
+
+		rows = append(rows, importRow{line: line, values: values})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read import file: %w", err)
+	}
+	if len(rowErrors) > 0 {
+		return nil, &ImportValidationError{Errors: rowErrors}
+	}
+
+	return rows, nil
+}
+
+// rowToVideoInput maps and validates a single row
+func rowToVideoInput(row importRow, mapping map[string]string) (*VideoInput, []ImportRowError) {
+	var errs []ImportRowError
+	fail := func(field, message string) {
+		errs = append(errs, ImportRowError{Line: row.line, Field: field, Message: message})
+	}
+
+	// A JSON Lines row may name a field by several keys; which one to use
+	// would be a guess, so the row is rejected
+	if !row.mapped {
+		for _, field := range importFields {
+			if keys := importKeys(row, mapping, field); len(keys) > 1 {
+				fail(field, fmt.Sprintf("%s is set by both %q and %q", field, keys[0], keys[1]))
+			}
+		}
+		if len(errs) > 0 {
+			return nil, errs
+		}
+	}
+
+	input := &VideoInput{}
+
+	title, err := importString(row, mapping, ImportFieldTitle)
+	switch {
+	case err != nil:
+		fail(ImportFieldTitle, err.Error())
+	case title == "":
+		fail(ImportFieldTitle, "title is required")
+	case utf8.RuneCountInString(title) > maxTitleLength:
+		fail(ImportFieldTitle, fmt.Sprintf("title exceeds %d characters", maxTitleLength))
+	}
+	input.Title = title
+
+	if input.Description, err = importString(row, mapping, ImportFieldDescription); err != nil {
+		fail(ImportFieldDescription, err.Error())
+	}
+	if input.Config.Topic, err = importString(row, mapping, ImportFieldTopic); err != nil {
+		fail(ImportFieldTopic, err.Error())
+	}
+	if input.Config.Script, err = importString(row, mapping, ImportFieldScript); err != nil {
+		fail(ImportFieldScript, err.Error())
+	}
+	if input.Config.Tone, err = importString(row, mapping, ImportFieldTone); err != nil {
+		fail(ImportFieldTone, err.Error())
+	}
+
+	if value, ok := importValue(row, mapping, ImportFieldKeywords); ok {
+		switch v := value.(type) {
+		case string:
+			input.Config.Keywords = splitKeywords(v)
+		case []interface{}:
+			for _, item := range v {
+				keyword, isString := item.(string)
+				if !isString {
+					fail(ImportFieldKeywords, "keywords must be strings")
+					break
+				}
+				if keyword = strings.TrimSpace(keyword); keyword != "" {
+					input.Config.Keywords = append(input.Config.Keywords, keyword)
+				}
+			}
+		default:
+			fail(ImportFieldKeywords, "keywords must be a list or a delimited string")
+		}
+	}
+
+	if value, ok := importValue(row, mapping, ImportFieldTargetDuration); ok {
+		var duration int
+		var parseErr error
+		switch v := value.(type) {
+		case float64:
+			duration = int(v)
+			if float64(duration) != v {
+				parseErr = fmt.Errorf("not a whole number")
+			}
+		case string:
+			duration, parseErr = strconv.Atoi(strings.TrimSpace(v))
+		default:
+			parseErr = fmt.Errorf("not a number")
+		}
+
+		switch {
+		case parseErr != nil:
+			fail(ImportFieldTargetDuration, "target duration must be a whole number of seconds")
+		case duration <= 0 || duration > maxImportTarget:
+			fail(ImportFieldTargetDuration, fmt.Sprintf("target duration must be between 1 and %d seconds", maxImportTarget))
+		default:
+			input.Config.TargetDuration = duration
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return input, nil
+}
+
+// importValue looks up a field in a row, resolving source column names
+// through the mapping or the well-known aliases
+func importValue(row importRow, mapping map[string]string, field string) (interface{}, bool) {
+	if row.mapped {
+		value, ok := row.values[field]
+		return value, ok && value != nil
+	}
+
+	keys := importKeys(row, mapping, field)
+	if len(keys) == 0 {
+		return nil, false
+	}
+	return row.values[keys[0]], true
+}
+
+// importKeys returns the keys of a JSON Lines row that set a field, through
+// the mapped column or else the aliases in their listed order. Keys with a
+// null value do not set the field.
+func importKeys(row importRow, mapping map[string]string, field string) []string {
+	names := importFieldAliases[field]
+	if column, ok := mapping[field]; ok {
+		names = []string{normalizeColumn(column)}
+	}
+
+	var keys []string
+	for _, name := range names {
+		var matched []string
+		for key, value := range row.values {
+			if value != nil && normalizeColumn(key) == name {
+				matched = append(matched, key)
+			}
+		}
+		sort.Strings(matched)
+		keys = append(keys, matched...)
+	}
+	return keys
+}
+
+// importString looks up a text field in a row
+func importString(row importRow, mapping map[string]string, field string) (string, error) {
+	value, ok := importValue(row, mapping, field)
+	if !ok {
+		return "", nil
+	}
+
+	text, isString := value.(string)
+	if !isString {
+		return "", fmt.Errorf("%s must be a string", field)
+	}
+
+	return strings.TrimSpace(text), nil
+}
+
+// splitKeywords splits a keyword cell on commas, semicolons or pipes
+func splitKeywords(value string) []string {
+	var keywords []string
+	for _, keyword := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || r == '|'
+	}) {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	return keywords
+}
+
+// normalizeColumn lowercases a column name and drops spaces, dashes and underscores
+func normalizeColumn(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '_', '-':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(name)))
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"renderowl-api/internal/domain"
+	socialdomain "renderowl-api/internal/domain/social"
+	socialsvc "renderowl-api/internal/service/social"
+)
+
+// defaultPublishTime is the time of day calendar videos are published at
+const defaultPublishTime = "09:00"
+
+// ErrNoVideoRenderer is returned when calendar videos are to be scheduled
+// but generated videos are not rendered to files that can be published
+var ErrNoVideoRenderer = errors.New("videos cannot be scheduled: no renderer is configured to render generated videos to files")
+
+// CalendarBatchService turns content calendars into batches and schedules
+// each generated video on its calendar date. The publisher holds each post
+// until its video has rendered. Videos are only scheduled when the batch
+// service renders them to files.
+type CalendarBatchService struct {
+	batchService  *BatchService
+	approvals     *ApprovalService
+	socialService *socialsvc.Service
+}
+
+// CalendarBatchRequest represents a request to create a batch from a calendar
+type CalendarBatchRequest struct {
+	Name     string                  `json:"name,omitempty"`
+	Calendar *ContentCalendar        `json:"calendar" binding:"required"`
+	From     *time.Time              `json:"from,omitempty"` // inclusive, by calendar date
+	To       *time.Time              `json:"to,omitempty"`   // inclusive, by calendar date
+	Config   domain.BatchConfig      `json:"config"`
+	Publish  *CalendarPublishOptions `json:"publish,omitempty"`
+	Start    bool                    `json:"start,omitempty"`
+}
+
+// CalendarPublishOptions controls how calendar videos are scheduled
+type CalendarPublishOptions struct {
+	Platforms []CalendarPublishTarget `json:"platforms" binding:"required,min=1"`
+	Time      string                  `json:"time,omitempty"`     // HH:MM, default 09:00
+	Timezone  string                  `json:"timezone,omitempty"` // IANA name, default UTC
+}
+
+// CalendarPublishTarget is an account each calendar video is published to
+type CalendarPublishTarget struct {
+	AccountID string `json:"accountId" binding:"required"`
+	Platform  string `json:"platform" binding:"required"`
+	Privacy   string `json:"privacy,omitempty"`
+}
+
+// CalendarBatchResult represents the batch and posts created from a calendar
+type CalendarBatchResult struct {
+	Batch *domain.Batch                 `json:"batch"`
+	Posts []*socialdomain.ScheduledPost `json:"posts,omitempty"`
+}
+
+// NewCalendarBatchService creates a new calendar batch service
+func NewCalendarBatchService(
+	batchService *BatchService,
+	approvals *ApprovalService,
+	socialService *socialsvc.Service,
+) *CalendarBatchService {
+	return &CalendarBatchService{
+		batchService:  batchService,
+		approvals:     approvals,
+		socialService: socialService,
+	}
+}
+
+// CreateFromCalendar creates a batch from the planned videos of a calendar,
+// optionally limited to a date range. Each video's script outline becomes
+// its script, and when publish options are given every video is scheduled
+// for its calendar date. Every post is checked before anything is created,
+// and if a post cannot be saved the batch and the posts already saved are
+// removed.
+func (s *CalendarBatchService) CreateFromCalendar(ctx context.Context, userID string, req *CalendarBatchRequest) (*CalendarBatchResult, error) {
+	var days []ContentCalendarDay
+	for _, day := range req.Calendar.Days {
+		if day.IsRestDay || day.Video == nil {
+			continue
+		}
+		if req.From != nil && dateOnly(day.Date).Before(dateOnly(*req.From)) {
+			continue
+		}
+		if req.To != nil && dateOnly(day.Date).After(dateOnly(*req.To)) {
+			continue
+		}
+		days = append(days, day)
+	}
+
+	if len(days) == 0 {
+		return nil, fmt.Errorf("no planned videos in the selected range")
+	}
+	if len(days) > MaxBatchVideos {
+		return nil, fmt.Errorf("selected range has %d videos, the limit is %d per batch", len(days), MaxBatchVideos)
+	}
+
+	// Build and check the posts before creating anything
+	var posts []*socialdomain.ScheduledPost
+	if req.Publish != nil {
+		if !s.batchService.RendersVideos() {
+			return nil, ErrNoVideoRenderer
+		}
+		location, hour, minute, err := parsePublishOptions(req.Publish)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkAccounts(ctx, userID, req.Publish); err != nil {
+			return nil, err
+		}
+		if err := s.approvals.CheckReviewer(ctx, userID, ""); err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		for _, day := range days {
+			post := calendarPost(userID, day, req.Publish)
+			post.ScheduledAt = time.Date(day.Date.Year(), day.Date.Month(), day.Date.Day(), hour, minute, 0, 0, location)
+			post.Timezone = location.String()
+			if !post.ScheduledAt.After(now) {
+				return nil, fmt.Errorf("cannot schedule %q: %s has already passed, start the range later", day.Video.Title, post.ScheduledAt.Format("2006-01-02 15:04 MST"))
+			}
+			if err := ComposePost(post); err != nil {
+				return nil, fmt.Errorf("cannot schedule %q: %w", day.Video.Title, err)
+			}
+			posts = append(posts, post)
+		}
+	}
+
+	name := req.Name
+	if name == "" {
+		name = fmt.Sprintf("%s calendar %s", req.Calendar.Niche, dateOnly(days[0].Date).Format("2006-01-02"))
+	}
+
+	batchReq := &CreateBatchRequest{
+		Name:        name,
+		Description: fmt.Sprintf("Generated from content calendar %s", req.Calendar.ID),
+		Config:      req.Config,
+	}
+	for _, day := range days {
+		batchReq.Videos = append(batchReq.Videos, VideoInput{
+			Title:       day.Video.Title,
+			Description: day.Video.Description,
+			Config: domain.VideoConfig{
+				Topic:    day.Video.Title,
+				Script:   day.Video.ScriptOutline,
+				Keywords: day.Video.Tags,
+			},
+		})
+	}
+
+	batch, err := s.batchService.CreateBatch(ctx, userID, batchReq)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CalendarBatchResult{Batch: batch}
+
+	// CreateBatch keeps the order of the requested videos. The video is
+	// uploaded from the batch result once it has rendered.
+	for i, post := range posts {
+		video := batch.Videos[i]
+		post.VideoID = video.ID
+		post.Metadata["batchId"] = batch.ID
+		post.Metadata["batchVideoId"] = video.ID
+		post.Metadata["calendarId"] = req.Calendar.ID
+
+		if err := s.approvals.SchedulePost(ctx, post, ""); err != nil {
+			s.rollback(ctx, userID, batch, result.Posts)
+			return nil, fmt.Errorf("failed to schedule %q: %w", video.Title, err)
+		}
+		result.Posts = append(result.Posts, post)
+	}
+
+	if req.Start {
+		if err := s.batchService.StartBatch(ctx, batch.ID); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// checkAccounts checks that the accounts to publish to belong to the user
+func (s *CalendarBatchService) checkAccounts(ctx context.Context, userID string, opts *CalendarPublishOptions) error {
+	for _, target := range opts.Platforms {
+		account, err := s.socialService.GetAccount(ctx, target.AccountID)
+		if err != nil || account.UserID != userID {
+			return fmt.Errorf("account %s not found", target.AccountID)
+		}
+		if string(account.Platform) != target.Platform {
+			return fmt.Errorf("account %s is not a %s account", target.AccountID, target.Platform)
+		}
+	}
+	return nil
+}
+
+// rollback removes a batch whose posts could not all be scheduled, and
+// cancels the posts already scheduled for it
+func (s *CalendarBatchService) rollback(ctx context.Context, userID string, batch *domain.Batch, posts []*socialdomain.ScheduledPost) {
+	for _, post := range posts {
+		if err := s.socialService.CancelScheduledPost(ctx, post.ID, userID); err != nil {
+			log.Printf("Failed to cancel post %s of batch %s: %v", post.ID, batch.ID, err)
+		}
+	}
+	if err := s.batchService.DeleteBatch(ctx, userID, batch.ID); err != nil {
+		log.Printf("Failed to delete batch %s: %v", batch.ID, err)
+	}
+}
+
+// calendarPost builds the post of a calendar day
+func calendarPost(userID string, day ContentCalendarDay, opts *CalendarPublishOptions) *socialdomain.ScheduledPost {
+	post := &socialdomain.ScheduledPost{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Title:       day.Video.Title,
+		Description: day.Video.Description,
+		Metadata:    socialdomain.JSON{},
+	}
+
+	for _, target := range opts.Platforms {
+		post.Platforms = append(post.Platforms, socialdomain.PlatformPost{
+			ID:              uuid.New().String(),
+			ScheduledPostID: post.ID,
+			AccountID:       target.AccountID,
+			Platform:        socialdomain.SocialPlatform(target.Platform),
+			CustomTitle:     day.Video.Title,
+			CustomDesc:      day.Video.Description,
+			Tags:            day.Video.Tags,
+			Privacy:         target.Privacy,
+			Status:          socialdomain.PostStatusScheduled,
+		})
+	}
+
+	return post
+}
+
+// parsePublishOptions resolves the timezone and time of day to publish at
+func parsePublishOptions(opts *CalendarPublishOptions) (*time.Location, int, int, error) {
+	location := time.UTC
+	if opts.Timezone != "" {
+		loc, err := time.LoadLocation(opts.Timezone)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("invalid timezone %q", opts.Timezone)
+		}
+		location = loc
+	}
+
+	publishTime := strings.TrimSpace(opts.Time)
+	if publishTime == "" {
+		publishTime = defaultPublishTime
+	}
+	t, err := time.Parse("15:04", publishTime)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("invalid publish time %q, expected HH:MM", opts.Time)
+	}
+
+	return location, t.Hour(), t.Minute(), nil
+}
+
+// dateOnly truncates a time to midnight UTC of its calendar date
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
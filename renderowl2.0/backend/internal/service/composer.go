@@ -0,0 +1,400 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	socialdomain "renderowl-api/internal/domain/social"
+)
+
+// ContentRules are the text limits of a platform. Lengths are counted in
+// user-perceived characters (grapheme clusters), not bytes.
+type ContentRules struct {
+	TitleMax       int               `json:"titleMax,omitempty"` // 0 when the platform has no title
+	TitleRequired  bool              `json:"titleRequired,omitempty"`
+	DescriptionMax int               `json:"descriptionMax"`
+	TagsMax        int               `json:"tagsMax,omitempty"`
+	TagMax         int               `json:"tagMax,omitempty"`
+	TagsTotalMax   int               `json:"tagsTotalMax,omitempty"`
+	HashtagsMax    int               `json:"hashtagsMax,omitempty"` // tags plus hashtags in the description
+	MentionsMax    int               `json:"mentionsMax,omitempty"`
+	Privacy        map[string]string `json:"privacy,omitempty"` // accepted names to platform values
+	DefaultPrivacy string            `json:"defaultPrivacy,omitempty"`
+	Spec           string            `json:"spec"` // PlatformSpecs key
+}
+
+// PlatformContentRules holds the content rules of each platform
+var PlatformContentRules = map[socialdomain.SocialPlatform]ContentRules{
+	socialdomain.PlatformYouTube: {
+		TitleMax:       100,
+		TitleRequired:  true,
+		DescriptionMax: 5000,
+		TagMax:         100,
+		TagsTotalMax:   500,
+		Privacy: map[string]string{
+			"public":   "public",
+			"unlisted": "unlisted",
+			"private":  "private",
+		},
+		DefaultPrivacy: "private",
+		Spec:           "youtube",
+	},
+	socialdomain.PlatformTikTok: {
+		DescriptionMax: 2200,
+		HashtagsMax:    100,
+		Privacy: map[string]string{
+			"public":    "PUBLIC_TO_EVERYONE",
+			"friends":   "MUTUAL_FOLLOW_FRIENDS",
+			"followers": "FOLLOWER_OF_CREATOR",
+			"private":   "SELF_ONLY",
+		},
+		Spec: "tiktok",
+	},
+	socialdomain.PlatformInstagram: {
+		DescriptionMax: 2200,
+		HashtagsMax:    30,
+		MentionsMax:    20,
+		Spec:           "instagram_reels",
+	},
+	socialdomain.PlatformTwitter: {
+		DescriptionMax: 280,
+		Spec:           "twitter",
+	},
+	socialdomain.PlatformLinkedIn: {
+		TitleMax:       200,
+		DescriptionMax: 3000,
+		Privacy: map[string]string{
+			"public":      "public",
+			"connections": "connections",
+		},
+		DefaultPrivacy: "public",
+		Spec:           "linkedin",
+	},
+	socialdomain.PlatformFacebook: {
+		TitleMax:       255,
+		DescriptionMax: 63206,
+		Privacy: map[string]string{
+			"public":  "public",
+			"private": "private",
+		},
+		DefaultPrivacy: "private",
+		Spec:           "facebook",
+	},
+	socialdomain.PlatformPinterest: {
+		TitleMax:       100,
+		DescriptionMax: 800,
+		Spec:           "pinterest",
+	},
+	socialdomain.PlatformThreads: {
+		DescriptionMax: 500,
+		HashtagsMax:    1,
+		Spec:           "threads",
+	},
+	socialdomain.PlatformSnapchat: {
+		DescriptionMax: 160,
+		Spec:           "snapchat_spotlight",
+	},
+	socialdomain.PlatformBluesky: {
+		DescriptionMax: 300,
+		Spec:           "bluesky",
+	},
+	// Custom destinations take whatever the receiving site accepts
+	socialdomain.PlatformWebhook: {
+		DescriptionMax: 65535,
+	},
+	socialdomain.PlatformFeed: {
+		DescriptionMax: 65535,
+	},
+}
+
+// youtubeDefaultCategory is People & Blogs, used when no category is given
+const youtubeDefaultCategory = "22"
+
+// youtubeCategories are the assignable YouTube video categories
+var youtubeCategories = map[string]bool{
+	"1": true, "2": true, "10": true, "15": true, "17": true, "19": true,
+	"20": true, "22": true, "23": true, "24": true, "25": true, "26": true,
+	"27": true, "28": true, "29": true,
+}
+
+var (
+	hashtagPattern = regexp.MustCompile(`#[\p{L}\p{N}_]+`)
+	mentionPattern = regexp.MustCompile(`@[\p{L}\p{N}_.]+`)
+)
+
+// PostFieldError describes a field of a platform post that breaks the
+// platform's rules
+type PostFieldError struct {
+	Platform  socialdomain.SocialPlatform `json:"platform"`
+	AccountID string                      `json:"accountId,omitempty"`
+	Field     string                      `json:"field"`
+	Code      string                      `json:"code"`
+	Message   string                      `json:"message"`
+}
+
+// PostValidationError is returned when a post cannot be published as
+// composed. Nothing is scheduled in that case.
+type PostValidationError struct {
+	Errors []PostFieldError `json:"errors"`
+}
+
+func (e *PostValidationError) Error() string {
+	return fmt.Sprintf("post has %d invalid fields", len(e.Errors))
+}
+
+// platformChecks are checks beyond the shared content rules
+var platformChecks = map[socialdomain.SocialPlatform]func(platformPost *socialdomain.PlatformPost) []PostFieldError{
+	socialdomain.PlatformYouTube: checkYouTubePost,
+}
+
+// ComposePost prepares every platform post of a post for publishing. Titles
+// and descriptions default to the post's, privacy is mapped to the
+// platform's values, and the result is checked against the platform's
+// content rules and video specs.
+func ComposePost(post *socialdomain.ScheduledPost) error {
+	var errs []PostFieldError
+	for i := range post.Platforms {
+		errs = append(errs, composePlatformPost(post, &post.Platforms[i])...)
+	}
+
+	if len(errs) > 0 {
+		return &PostValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// composePlatformPost prepares and checks a single platform post
+func composePlatformPost(post *socialdomain.ScheduledPost, platformPost *socialdomain.PlatformPost) []PostFieldError {
+	var errs []PostFieldError
+	fail := func(field, code, format string, args ...interface{}) {
+		errs = append(errs, PostFieldError{
+			Platform:  platformPost.Platform,
+			AccountID: platformPost.AccountID,
+			Field:     field,
+			Code:      code,
+			Message:   fmt.Sprintf(format, args...),
+		})
+	}
+
+	rules, ok := PlatformContentRules[platformPost.Platform]
+	if !ok {
+		fail("platform", "unsupported", "platform %q is not supported", platformPost.Platform)
+		return errs
+	}
+
+	if platformPost.CustomTitle == "" {
+		platformPost.CustomTitle = post.Title
+	}
+	if platformPost.CustomDesc == "" {
+		platformPost.CustomDesc = post.Description
+	}
+
+	// Text lengths
+	if rules.TitleMax > 0 {
+		title := strings.TrimSpace(platformPost.CustomTitle)
+		if rules.TitleRequired && title == "" {
+			fail("title", "required", "title is required")
+		}
+		if n := graphemeCount(title); n > rules.TitleMax {
+			fail("title", "too_long", "title is %d characters, the limit is %d", n, rules.TitleMax)
+		}
+	}
+	if n := graphemeCount(platformPost.CustomDesc); n > rules.DescriptionMax {
+		fail("description", "too_long", "description is %d characters, the limit is %d", n, rules.DescriptionMax)
+	}
+
+	// Tags, hashtags and mentions
+	if rules.TagsMax > 0 && len(platformPost.Tags) > rules.TagsMax {
+		fail("tags", "too_many", "%d tags, the limit is %d", len(platformPost.Tags), rules.TagsMax)
+	}
+	total := 0
+	for _, tag := range platformPost.Tags {
+		n := graphemeCount(tag)
+		total += n
+		if rules.TagMax > 0 && n > rules.TagMax {
+			fail("tags", "too_long", "tag %q is %d characters, the limit is %d", tag, n, rules.TagMax)
+		}
+	}
+	if rules.TagsTotalMax > 0 && total > rules.TagsTotalMax {
+		fail("tags", "too_long", "tags total %d characters, the limit is %d", total, rules.TagsTotalMax)
+	}
+	if rules.HashtagsMax > 0 {
+		hashtags := len(hashtagPattern.FindAllString(platformPost.CustomDesc, -1)) + len(platformPost.Tags)
+		if hashtags > rules.HashtagsMax {
+			fail("tags", "too_many", "%d hashtags, the limit is %d", hashtags, rules.HashtagsMax)
+		}
+	}
+	if rules.MentionsMax > 0 {
+		if mentions := len(mentionPattern.FindAllString(platformPost.CustomDesc, -1)); mentions > rules.MentionsMax {
+			fail("description", "too_many", "%d mentions, the limit is %d", mentions, rules.MentionsMax)
+		}
+	}
+
+	// Privacy
+	if rules.Privacy != nil {
+		privacy := strings.TrimSpace(platformPost.Privacy)
+		if privacy == "" {
+			privacy = rules.DefaultPrivacy
+		}
+		if privacy == "" {
+			fail("privacy", "required", "privacy is required, one of %s", privacyOptions(rules))
+		} else if value, ok := mapPrivacy(rules, privacy); ok {
+			platformPost.Privacy = value
+		} else {
+			fail("privacy", "invalid", "privacy %q is not supported, use one of %s", platformPost.Privacy, privacyOptions(rules))
+		}
+	}
+
+	// Video constraints
+	if spec, ok := PlatformSpecs[rules.Spec]; ok {
+		for _, message := range checkVideoSpec(post, spec) {
+			fail("video", "spec", "%s", message)
+		}
+	}
+
+	if check, ok := platformChecks[platformPost.Platform]; ok {
+		errs = append(errs, check(platformPost)...)
+	}
+
+	return errs
+}
+
+// checkYouTubePost requires a valid category and rejects angle brackets,
+// which YouTube refuses in titles and descriptions
+func checkYouTubePost(platformPost *socialdomain.PlatformPost) []PostFieldError {
+	var errs []PostFieldError
+	fail := func(field, code, message string) {
+		errs = append(errs, PostFieldError{
+			Platform:  platformPost.Platform,
+			AccountID: platformPost.AccountID,
+			Field:     field,
+			Code:      code,
+			Message:   message,
+		})
+	}
+
+	if platformPost.Metadata == nil {
+		platformPost.Metadata = socialdomain.JSON{}
+	}
+	category := fmt.Sprint(platformPost.Metadata["categoryId"])
+	if platformPost.Metadata["categoryId"] == nil || category == "" {
+		category = youtubeDefaultCategory
+	}
+	if !youtubeCategories[category] {
+		fail("categoryId", "invalid", fmt.Sprintf("category %q is not an assignable YouTube category", category))
+	}
+	platformPost.Metadata["categoryId"] = category
+
+	if strings.ContainsAny(platformPost.CustomTitle, "<>") {
+		fail("title", "invalid", "title must not contain < or >")
+	}
+	if strings.ContainsAny(platformPost.CustomDesc, "<>") {
+		fail("description", "invalid", "description must not contain < or >")
+	}
+
+	return errs
+}
+
+// checkVideoSpec checks the post's video against a platform spec. The file
+// size is checked when the video is a local file and the duration when the
+// post metadata carries one.
+func checkVideoSpec(post *socialdomain.ScheduledPost, spec PlatformSpec) []string {
+	var problems []string
+
+	if videoPath, ok := post.Metadata["videoPath"].(string); ok {
+		if info, err := os.Stat(videoPath); err == nil && !info.IsDir() && spec.MaxFileSize > 0 && info.Size() > spec.MaxFileSize {
+			problems = append(problems, fmt.Sprintf("video is %d MB, %s allows %d MB",
+				info.Size()>>20, spec.Name, spec.MaxFileSize>>20))
+		}
+	}
+
+	if duration, ok := post.Metadata["duration"].(float64); ok {
+		if spec.MaxDuration > 0 && duration > float64(spec.MaxDuration) {
+			problems = append(problems, fmt.Sprintf("video is %.0f seconds, %s allows %d", duration, spec.Name, spec.MaxDuration))
+		}
+		if duration < float64(spec.MinDuration) {
+			problems = append(problems, fmt.Sprintf("video is %.0f seconds, %s needs at least %d", duration, spec.Name, spec.MinDuration))
+		}
+	}
+
+	return problems
+}
+
+// mapPrivacy maps a privacy name to the platform's value. Platform values
+// themselves are accepted too.
+func mapPrivacy(rules ContentRules, privacy string) (string, bool) {
+	if value, ok := rules.Privacy[strings.ToLower(privacy)]; ok {
+		return value, true
+	}
+	for _, value := range rules.Privacy {
+		if strings.EqualFold(value, privacy) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func privacyOptions(rules ContentRules) string {
+	options := make([]string, 0, len(rules.Privacy))
+	for option := range rules.Privacy {
+		options = append(options, option)
+	}
+	sort.Strings(options)
+	return strings.Join(options, ", ")
+}
+
+// graphemeCount counts user-perceived characters
+func graphemeCount(s string) int {
+	return len(graphemeBoundaries(s)) - 1
+}
+
+// truncateGraphemes shortens s to at most max characters without splitting
+// a character, ending with an ellipsis when cut
+func truncateGraphemes(s string, max int) string {
+	bounds := graphemeBoundaries(s)
+	if len(bounds)-1 <= max {
+		return s
+	}
+	if max <= 3 {
+		return s[:bounds[max]]
+	}
+	return s[:bounds[max-3]] + "..."
+}
+
+// graphemeBoundaries returns the byte offsets at which characters start,
+// followed by len(s). It approximates Unicode grapheme clusters by keeping
+// combining marks, variation selectors, emoji modifiers, zero-width joiner
+// sequences and regional indicator pairs with the character before them.
+func graphemeBoundaries(s string) []int {
+	var bounds []int
+	joined := false
+	regional := 0
+	for i, r := range s {
+		extends := joined ||
+			unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) ||
+			(r >= 0xFE00 && r <= 0xFE0F) || // variation selectors
+			(r >= 0x1F3FB && r <= 0x1F3FF) || // skin tone modifiers
+			r == 0x200D // zero-width joiner
+
+		isRegional := r >= 0x1F1E6 && r <= 0x1F1FF
+		if isRegional {
+			// Flags are pairs of regional indicators
+			if regional%2 == 1 {
+				extends = true
+			}
+			regional++
+		} else {
+			regional = 0
+		}
+
+		if !extends || len(bounds) == 0 {
+			bounds = append(bounds, i)
+		}
+		joined = r == 0x200D
+	}
+	return append(bounds, len(s))
+}
@@ -0,0 +1,539 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"renderowl-api/internal/domain"
+	socialdomain "renderowl-api/internal/domain/social"
+	"renderowl-api/internal/scheduler"
+	socialsvc "renderowl-api/internal/service/social"
+)
+
+// ReportSubscriptionRepository stores analytics digest subscriptions
+type ReportSubscriptionRepository interface {
+	Create(ctx context.Context, sub *domain.ReportSubscription) error
+	GetByID(ctx context.Context, id string) (*domain.ReportSubscription, error)
+	ListByUser(ctx context.Context, userID string) ([]*domain.ReportSubscription, error)
+	Update(ctx context.Context, sub *domain.ReportSubscription) error
+	Delete(ctx context.Context, id, userID string) error
+	GetDue(ctx context.Context, now time.Time, limit int) ([]*domain.ReportSubscription, error)
+	ClaimDue(ctx context.Context, id string, now, nextRunAt time.Time) (bool, error)
+	RecordRun(ctx context.Context, id string, nextRunAt time.Time, sentAt *time.Time, lastError string) error
+	ClaimManualSend(ctx context.Context, id, userID string, now, since time.Time) (bool, error)
+	ConfirmRecipient(ctx context.Context, id, address string) error
+}
+
+// PerformanceReporter generates the optimizer's performance report
+type PerformanceReporter interface {
+	GeneratePerformanceReport(ctx context.Context, userID string, days int) (*PerformanceReport, error)
+}
+
+var (
+	// ErrReportSubscriptionNotFound is returned when a subscription does
+	// not exist or belongs to another user
+	ErrReportSubscriptionNotFound = errors.New("report subscription not found")
+
+	// ErrInvalidReportSubscription is returned for subscriptions that
+	// cannot be delivered
+	ErrInvalidReportSubscription = errors.New("invalid report subscription")
+
+	// ErrDigestSendTooSoon is returned when a user asks for digests to be
+	// sent more often than digestSendInterval
+	ErrDigestSendTooSoon = errors.New("a digest was sent on request too recently")
+
+	// ErrNoConfirmedRecipients is returned for email digests none of
+	// whose recipients confirmed they want it yet
+	ErrNoConfirmedRecipients = errors.New("no recipient has confirmed the digest yet")
+
+	// ErrInvalidRecipientConfirmation is returned for confirmation links
+	// that are not valid for the subscription, or no longer are
+	ErrInvalidRecipientConfirmation = errors.New("invalid or expired confirmation link")
+)
+
+// Digest delivery limits
+const (
+	maxDigestRecipients    = 50
+	maxDigestSubscriptions = 20
+	digestBatchSize        = 100
+
+	// digestSendInterval is how often a user can have a digest sent on
+	// request
+	digestSendInterval = 5 * time.Minute
+
+	// digestRetryDelay is how long a failed digest waits before it is
+	// retried, unless the next one is due sooner
+	digestRetryDelay = time.Hour
+)
+
+var digestSections = []string{
+	domain.ReportSectionSummary,
+	domain.ReportSectionPlatforms,
+	domain.ReportSectionPerformance,
+}
+
+// DigestService delivers recurring analytics digests by email or to
+// incoming webhooks
+type DigestService struct {
+	subscriptions ReportSubscriptionRepository
+	analytics     *AnalyticsService
+	reporter      PerformanceReporter
+	mailer        Mailer
+	webhooks      *DigestWebhook
+	scheduler     *scheduler.Scheduler
+	publicURL     string // where the API is reachable, for confirmation links
+}
+
+// NewDigestService creates a new digest service. The performance section
+// is left out of digests when reporter is nil. Recipients confirm email
+// digests with links to publicURL.
+func NewDigestService(
+	subscriptions ReportSubscriptionRepository,
+	analytics *AnalyticsService,
+	reporter PerformanceReporter,
+	mailer Mailer,
+	scheduler *scheduler.Scheduler,
+	publicURL string,
+) *DigestService {
+	return &DigestService{
+		subscriptions: subscriptions,
+		analytics:     analytics,
+		reporter:      reporter,
+		mailer:        mailer,
+		webhooks:      NewDigestWebhook(),
+		scheduler:     scheduler,
+		publicURL:     publicURL,
+	}
+}
+
+// Initialize registers the digest job, runs it once to catch up on
+// digests missed while the server was down and schedules it at the top
+// of every hour
+func (s *DigestService) Initialize(ctx context.Context) error {
+	return s.scheduler.ScheduleRecurring(ctx, "send_report_digests", &socialdomain.RecurringRule{
+		RRule: "FREQ=HOURLY;BYMINUTE=0",
+	}, s.handleDigestJob)
+}
+
+func (s *DigestService) handleDigestJob(ctx context.Context, job *scheduler.Job) error {
+	sent, failed, err := s.SendDue(ctx)
+	if err != nil {
+		return err
+	}
+
+	if sent > 0 || failed > 0 {
+		log.Printf("Report digests: %d sent, %d failed", sent, failed)
+	}
+
+	return nil
+}
+
+// ReportSubscriptionRequest creates or replaces a digest subscription
+type ReportSubscriptionRequest struct {
+	Name       string   `json:"name" binding:"required"`
+	Cadence    string   `json:"cadence" binding:"required"` // daily, weekly, monthly
+	Weekday    *int     `json:"weekday"`                    // weekly digests, 0 is Sunday; defaults to Monday
+	Hour       *int     `json:"hour"`                       // defaults to 9
+	Timezone   string   `json:"timezone"`                   // defaults to UTC
+	Channel    string   `json:"channel" binding:"required"` // email, webhook
+	Recipients []string `json:"recipients"`
+	WebhookURL string   `json:"webhook_url"`
+	Sections   []string `json:"sections"` // summary, platforms, performance; defaults to all
+	Enabled    *bool    `json:"enabled"`
+}
+
+// ListSubscriptions lists the digest subscriptions of a user
+func (s *DigestService) ListSubscriptions(ctx context.Context, userID string) ([]*domain.ReportSubscription, error) {
+	return s.subscriptions.ListByUser(ctx, userID)
+}
+
+// CreateSubscription validates and stores a digest subscription and
+// schedules its first digest. Recipients other than the user, signed in
+// with userEmail, are asked to confirm they want the digest.
+func (s *DigestService) CreateSubscription(ctx context.Context, userID, userEmail string, req *ReportSubscriptionRequest) (*domain.ReportSubscription, error) {
+	existing, err := s.subscriptions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscriptions: %w", err)
+	}
+	if len(existing) >= maxDigestSubscriptions {
+		return nil, fmt.Errorf("%w: at most %d subscriptions are allowed", ErrInvalidReportSubscription, maxDigestSubscriptions)
+	}
+
+	key, err := newDigestConfirmKey()
+	if err != nil {
+		return nil, err
+	}
+	sub := &domain.ReportSubscription{UserID: userID, Enabled: true, ConfirmKey: key}
+	if err := applySubscriptionRequest(ctx, sub, req); err != nil {
+		return nil, err
+	}
+	unconfirmed := updateConfirmedRecipients(sub, nil, userEmail)
+
+	if err := s.subscriptions.Create(ctx, sub); err != nil {
+		return nil, fmt.Errorf("failed to create subscription: %w", err)
+	}
+	s.requestConfirmations(ctx, sub, unconfirmed)
+	return sub, nil
+}
+
+// UpdateSubscription replaces the settings of a digest subscription and
+// reschedules its next digest. Added recipients are asked to confirm.
+func (s *DigestService) UpdateSubscription(ctx context.Context, userID, userEmail, id string, req *ReportSubscriptionRequest) (*domain.ReportSubscription, error) {
+	sub, err := s.getSubscription(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := []string(sub.Recipients)
+	if sub.ConfirmKey == "" {
+		// Subscriptions created before recipients confirmed ask all of
+		// their recipients
+		if sub.ConfirmKey, err = newDigestConfirmKey(); err != nil {
+			return nil, err
+		}
+		previous = nil
+	}
+
+	if err := applySubscriptionRequest(ctx, sub, req); err != nil {
+		return nil, err
+	}
+	unconfirmed := updateConfirmedRecipients(sub, previous, userEmail)
+
+	if err := s.subscriptions.Update(ctx, sub); err != nil {
+		return nil, fmt.Errorf("failed to update subscription: %w", err)
+	}
+	s.requestConfirmations(ctx, sub, unconfirmed)
+	return sub, nil
+}
+
+// DeleteSubscription deletes a digest subscription
+func (s *DigestService) DeleteSubscription(ctx context.Context, userID, id string) error {
+	if _, err := s.getSubscription(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.subscriptions.Delete(ctx, id, userID)
+}
+
+// SendNow builds and delivers a subscription's digest immediately, for
+// example to test a new subscription. The schedule is left unchanged. A
+// user can have one digest sent this way every digestSendInterval.
+func (s *DigestService) SendNow(ctx context.Context, userID, id string) error {
+	sub, err := s.getSubscription(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	claimed, err := s.subscriptions.ClaimManualSend(ctx, sub.ID, userID, now, now.Add(-digestSendInterval))
+	if err != nil {
+		return fmt.Errorf("failed to record send: %w", err)
+	}
+	if !claimed {
+		return ErrDigestSendTooSoon
+	}
+
+	err = s.deliver(ctx, sub, now)
+	if recordErr := s.recordRun(ctx, sub, sub.NextRunAt, err); recordErr != nil {
+		log.Printf("Failed to record report digest %s: %v", sub.ID, recordErr)
+	}
+	return err
+}
+
+// SendDue delivers every digest that is due. Each digest is claimed by
+// moving it to its next run before it is sent, so runs that overlap send
+// it once. A failed digest is retried an hour later, or with the next
+// digest if that is due sooner. It returns the number of digests sent and
+// failed, and stops when a digest cannot be claimed or recorded.
+func (s *DigestService) SendDue(ctx context.Context) (int, int, error) {
+	now := time.Now().UTC()
+	sent, failed := 0, 0
+
+	for {
+		subs, err := s.subscriptions.GetDue(ctx, now, digestBatchSize)
+		if err != nil {
+			return sent, failed, fmt.Errorf("failed to load due digests: %w", err)
+		}
+
+		for _, sub := range subs {
+			// Digests missed while the server was down are not sent
+			// one by one; the next digest is scheduled from now
+			next, err := nextDigestRun(sub, now)
+			if err != nil {
+				next = now.Add(digestRetryDelay)
+			}
+
+			claimed, err := s.subscriptions.ClaimDue(ctx, sub.ID, now, next)
+			if err != nil {
+				return sent, failed, fmt.Errorf("failed to claim digest %s: %w", sub.ID, err)
+			}
+			if !claimed {
+				// Another run sent it, or it was changed meanwhile
+				continue
+			}
+
+			switch err = s.deliver(ctx, sub, now); {
+			case err == nil:
+				sent++
+			case errors.Is(err, ErrNoConfirmedRecipients):
+				// Not retried; the digest waits for a recipient to confirm
+			default:
+				failed++
+				log.Printf("Failed to send report digest %s of user %s: %v", sub.ID, sub.UserID, err)
+				if retry := now.Add(digestRetryDelay); retry.Before(next) {
+					next = retry
+				}
+			}
+			if err := s.recordRun(ctx, sub, next, err); err != nil {
+				return sent, failed, err
+			}
+		}
+
+		if len(subs) < digestBatchSize {
+			return sent, failed, nil
+		}
+	}
+}
+
+// recordRun records the outcome of a delivery
+func (s *DigestService) recordRun(ctx context.Context, sub *domain.ReportSubscription, next time.Time, deliveryErr error) error {
+	var sentAt *time.Time
+	lastError := ""
+	if deliveryErr != nil {
+		lastError = deliveryErr.Error()
+	} else {
+		now := time.Now().UTC()
+		sentAt = &now
+	}
+
+	if err := s.subscriptions.RecordRun(ctx, sub.ID, next, sentAt, lastError); err != nil {
+		return fmt.Errorf("failed to record report digest %s: %w", sub.ID, err)
+	}
+	return nil
+}
+
+// deliver builds a subscription's digest and sends it on its channel.
+// Email digests are sent to the confirmed recipients.
+func (s *DigestService) deliver(ctx context.Context, sub *domain.ReportSubscription, now time.Time) error {
+	if sub.Channel == domain.ReportChannelEmail && len(sub.ConfirmedRecipients) == 0 {
+		return ErrNoConfirmedRecipients
+	}
+
+	digest, err := s.BuildDigest(ctx, sub, now)
+	if err != nil {
+		return err
+	}
+
+	switch sub.Channel {
+	case domain.ReportChannelEmail:
+		msg, err := digest.Message(sub.ConfirmedRecipients)
+		if err != nil {
+			return err
+		}
+		return s.mailer.Send(ctx, msg)
+	case domain.ReportChannelWebhook:
+		return s.webhooks.Post(ctx, sub.WebhookURL, digest)
+	default:
+		return fmt.Errorf("%w: unknown channel %q", ErrInvalidReportSubscription, sub.Channel)
+	}
+}
+
+// BuildDigest collects the sections of a subscription's digest for the
+// period ending now. Sections that cannot be built are noted in the
+// digest rather than holding it back, unless none can be built.
+func (s *DigestService) BuildDigest(ctx context.Context, sub *domain.ReportSubscription, now time.Time) (*Digest, error) {
+	days := digestDays(sub.Cadence)
+	digest := &Digest{
+		Name:    sub.Name,
+		Cadence: sub.Cadence,
+		Start:   now.AddDate(0, 0, -days),
+		End:     now,
+	}
+
+	built := 0
+	for _, section := range sub.Sections {
+		var err error
+		switch section {
+		case domain.ReportSectionSummary:
+			digest.Summary, err = s.analytics.GetDashboardSummary(ctx, sub.UserID)
+		case domain.ReportSectionPlatforms:
+			var breakdown *PlatformBreakdownResponse
+			breakdown, err = s.analytics.GetPlatformBreakdown(ctx, sub.UserID, days)
+			if err == nil {
+				digest.Platforms = breakdown.Platforms
+			}
+		case domain.ReportSectionPerformance:
+			if s.reporter == nil {
+				digest.Notes = append(digest.Notes, "The performance report is not available yet.")
+				continue
+			}
+			digest.Performance, err = s.reporter.GeneratePerformanceReport(ctx, sub.UserID, days)
+		default:
+			continue
+		}
+
+		if err != nil {
+			log.Printf("Failed to build %s section of report digest %s: %v", section, sub.ID, err)
+			digest.Notes = append(digest.Notes, fmt.Sprintf("The %s section could not be generated.", section))
+			continue
+		}
+		built++
+	}
+
+	if built == 0 && len(sub.Sections) > 0 {
+		return nil, errors.New("no digest section could be generated")
+	}
+	return digest, nil
+}
+
+// getSubscription loads a subscription of the user
+func (s *DigestService) getSubscription(ctx context.Context, userID, id string) (*domain.ReportSubscription, error) {
+	sub, err := s.subscriptions.GetByID(ctx, id)
+	if err != nil || sub.UserID != userID {
+		return nil, ErrReportSubscriptionNotFound
+	}
+	return sub, nil
+}
+
+// applySubscriptionRequest validates a request onto a subscription and
+// schedules its next digest
+func applySubscriptionRequest(ctx context.Context, sub *domain.ReportSubscription, req *ReportSubscriptionRequest) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidReportSubscription, fmt.Sprintf(format, args...))
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return invalid("name is required")
+	}
+
+	switch req.Cadence {
+	case domain.ReportCadenceDaily, domain.ReportCadenceWeekly, domain.ReportCadenceMonthly:
+	default:
+		return invalid("cadence must be daily, weekly or monthly")
+	}
+
+	weekday, hour := int(time.Monday), 9
+	if req.Weekday != nil {
+		weekday = *req.Weekday
+	}
+	if req.Hour != nil {
+		hour = *req.Hour
+	}
+	if weekday < 0 || weekday > 6 {
+		return invalid("weekday must be between 0 (Sunday) and 6")
+	}
+	if hour < 0 || hour > 23 {
+		return invalid("hour must be between 0 and 23")
+	}
+
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return invalid("unknown timezone %q", timezone)
+	}
+
+	var recipients []string
+	webhookURL := ""
+	switch req.Channel {
+	case domain.ReportChannelEmail:
+		if len(req.Recipients) == 0 {
+			return invalid("email digests need at least one recipient")
+		}
+		if len(req.Recipients) > maxDigestRecipients {
+			return invalid("at most %d recipients are allowed", maxDigestRecipients)
+		}
+		for _, recipient := range req.Recipients {
+			addr, err := mail.ParseAddress(recipient)
+			if err != nil {
+				return invalid("invalid recipient %q", recipient)
+			}
+			if !containsString(recipients, addr.Address) {
+				recipients = append(recipients, addr.Address)
+			}
+		}
+	case domain.ReportChannelWebhook:
+		u, err := socialsvc.CheckPublicURL(ctx, req.WebhookURL)
+		if err != nil {
+			return invalid("webhook digests need a public http(s) webhook_url: %v", err)
+		}
+		webhookURL = u.String()
+	default:
+		return invalid("channel must be email or webhook")
+	}
+
+	sections := digestSections
+	if len(req.Sections) > 0 {
+		sections = nil
+		for _, section := range req.Sections {
+			if !containsString(digestSections, section) {
+				return invalid("unknown section %q", section)
+			}
+			if !containsString(sections, section) {
+				sections = append(sections, section)
+			}
+		}
+	}
+
+	sub.Name = name
+	sub.Cadence = req.Cadence
+	sub.Weekday = weekday
+	sub.Hour = hour
+	sub.Timezone = timezone
+	sub.Channel = req.Channel
+	sub.Recipients = recipients
+	sub.WebhookURL = webhookURL
+	sub.Sections = sections
+	if req.Enabled != nil {
+		sub.Enabled = *req.Enabled
+	}
+
+	next, err := nextDigestRun(sub, time.Now().UTC())
+	if err != nil {
+		return invalid("%v", err)
+	}
+	sub.NextRunAt = next
+	return nil
+}
+
+// nextDigestRun returns when a subscription's next digest is due after a
+// time: daily at its hour, weekly on its weekday or monthly on the first,
+// on the wall clock of its timezone
+func nextDigestRun(sub *domain.ReportSubscription, after time.Time) (time.Time, error) {
+	rule := fmt.Sprintf("FREQ=DAILY;BYHOUR=%d;BYMINUTE=0", sub.Hour)
+	switch sub.Cadence {
+	case domain.ReportCadenceWeekly:
+		rule = fmt.Sprintf("FREQ=WEEKLY;BYDAY=%s;BYHOUR=%d;BYMINUTE=0",
+			strings.ToUpper(time.Weekday(sub.Weekday).String()[:2]), sub.Hour)
+	case domain.ReportCadenceMonthly:
+		rule = fmt.Sprintf("FREQ=MONTHLY;BYMONTHDAY=1;BYHOUR=%d;BYMINUTE=0", sub.Hour)
+	}
+
+	recurrence, err := scheduler.NewRecurrence(&socialdomain.RecurringRule{RRule: rule}, after.Truncate(time.Hour), sub.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	occurrence, ok := recurrence.Next(after)
+	if !ok {
+		return time.Time{}, errors.New("digest schedule has no next occurrence")
+	}
+	return occurrence.At.UTC(), nil
+}
+
+// digestDays returns the number of days a digest covers
+func digestDays(cadence string) int {
+	switch cadence {
+	case domain.ReportCadenceDaily:
+		return 1
+	case domain.ReportCadenceMonthly:
+		return 30
+	default:
+		return 7
+	}
+}
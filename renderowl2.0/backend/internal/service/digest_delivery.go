@@ -0,0 +1,412 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"renderowl-api/internal/domain"
+	socialsvc "renderowl-api/internal/service/social"
+)
+
+// Digest is an analytics digest ready to be rendered for delivery
+type Digest struct {
+	Name        string
+	Cadence     string
+	Start       time.Time
+	End         time.Time
+	Summary     *DashboardSummaryResponse
+	Platforms   []PlatformData
+	Performance *PerformanceReport
+	Notes       []string // sections that could not be generated
+}
+
+// Subject returns the subject line of the digest
+func (d *Digest) Subject() string {
+	return fmt.Sprintf("%s: %s", d.Name, d.Period())
+}
+
+// Period returns the dates the digest covers
+func (d *Digest) Period() string {
+	if d.Cadence == domain.ReportCadenceDaily {
+		return d.End.Format("Jan 2, 2006")
+	}
+	return fmt.Sprintf("%s - %s", d.Start.Format("Jan 2"), d.End.Format("Jan 2, 2006"))
+}
+
+// digestTopItems is how many videos and suggestions a digest lists
+const digestTopItems = 3
+
+// Text renders the digest as plain text, using *bold* headings that chat
+// apps such as Slack render as markup
+func (d *Digest) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s*\n%s\n", d.Name, d.Period())
+
+	if s := d.Summary; s != nil {
+		b.WriteString("\n*Summary*\n")
+		fmt.Fprintf(&b, "Total views: %s\n", formatCount(s.TotalViews))
+		fmt.Fprintf(&b, "Views in the last 30 days: %s\n", formatCount(s.ViewsLast30Days))
+		fmt.Fprintf(&b, "Engagement rate: %.1f%%\n", s.EngagementRate)
+		if s.TopVideo != nil {
+			fmt.Fprintf(&b, "Top video: %s (%s views)\n", s.TopVideo.Title, formatCount(s.TopVideo.Views))
+		}
+	}
+
+	if len(d.Platforms) > 0 {
+		b.WriteString("\n*Platforms*\n")
+		for _, p := range d.Platforms {
+			fmt.Fprintf(&b, "%s: %s views (%.1f%%)\n", platformLabel(p.Platform), formatCount(p.Views), p.Percentage)
+		}
+	}
+
+	if r := d.Performance; r != nil {
+		b.WriteString("\n*Performance*\n")
+		if r.Summary != nil {
+			fmt.Fprintf(&b, "Views: %s, average engagement %.1f%%, average CTR %.1f%%\n",
+				formatCount(int64(r.Summary.TotalViews)), r.Summary.AvgEngagementRate, r.Summary.AvgCTR)
+		}
+		for i, video := range r.TopVideos {
+			if i == digestTopItems {
+				break
+			}
+			fmt.Fprintf(&b, "%d. %s (%s views)\n", i+1, video.Title, formatCount(int64(video.Views)))
+		}
+		for i, suggestion := range r.Suggestions {
+			if i == digestTopItems {
+				break
+			}
+			fmt.Fprintf(&b, "Suggestion: %s - %s\n", suggestion.Title, suggestion.Description)
+		}
+	}
+
+	for _, note := range d.Notes {
+		fmt.Fprintf(&b, "\n_%s_\n", note)
+	}
+
+	return b.String()
+}
+
+var digestTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"count":    formatCount,
+	"count32":  func(n int) string { return formatCount(int64(n)) },
+	"percent":  func(f float64) string { return strconv.FormatFloat(f, 'f', 1, 64) + "%" },
+	"platform": platformLabel,
+}).Parse(`<!DOCTYPE html>
+<html>
+<body style="margin:0;padding:0;background:#f3f4f6;font-family:Helvetica,Arial,sans-serif;color:#111827">
+<table width="100%" cellpadding="0" cellspacing="0" style="background:#f3f4f6;padding:24px 0">
+<tr><td align="center">
+<table width="600" cellpadding="0" cellspacing="0" style="background:#ffffff">
+<tr><td style="background:{{.Blue}};padding:24px;color:#ffffff;border-bottom:4px solid {{.Purple}}">
+<div style="font-size:22px;font-weight:bold">Renderowl</div>
+<div style="font-size:14px;margin-top:4px">{{.Digest.Name}} &middot; {{.Digest.Period}}</div>
+</td></tr>
+{{with .Digest.Summary}}
+<tr><td style="padding:24px 24px 0">
+<h2 style="font-size:16px;margin:0 0 12px">Summary</h2>
+<table width="100%" cellpadding="8" cellspacing="0" style="background:#f3f4f6">
+<tr>
+<td><div style="font-size:12px;color:#6b7280">Total views</div><div style="font-size:20px;font-weight:bold">{{count .TotalViews}}</div></td>
+<td><div style="font-size:12px;color:#6b7280">Last 30 days</div><div style="font-size:20px;font-weight:bold">{{count .ViewsLast30Days}}</div></td>
+<td><div style="font-size:12px;color:#6b7280">Engagement</div><div style="font-size:20px;font-weight:bold">{{percent .EngagementRate}}</div></td>
+</tr>
+</table>
+{{with .TopVideo}}<p style="font-size:14px">Top video: <strong>{{.Title}}</strong> ({{count .Views}} views)</p>{{end}}
+</td></tr>
+{{end}}
+{{if .Digest.Platforms}}
+<tr><td style="padding:24px 24px 0">
+<h2 style="font-size:16px;margin:0 0 12px">Platforms</h2>
+<table width="100%" cellpadding="6" cellspacing="0" style="font-size:14px">
+{{range .Digest.Platforms}}<tr><td>{{platform .Platform}}</td><td align="right">{{count .Views}} views</td><td align="right" style="color:#6b7280">{{percent .Percentage}}</td></tr>
+{{end}}</table>
+</td></tr>
+{{end}}
+{{with .Digest.Performance}}
+<tr><td style="padding:24px 24px 0">
+<h2 style="font-size:16px;margin:0 0 12px">Performance</h2>
+{{with .Summary}}<p style="font-size:14px">{{count32 .TotalViews}} views, average engagement {{percent .AvgEngagementRate}}, average CTR {{percent .AvgCTR}}</p>{{end}}
+{{if $.TopVideos}}<ol style="font-size:14px;padding-left:20px">{{range $.TopVideos}}<li>{{.Title}} ({{count32 .Views}} views)</li>{{end}}</ol>{{end}}
+{{range $.Suggestions}}<p style="font-size:14px;border-left:3px solid {{$.Purple}};padding-left:8px"><strong>{{.Title}}</strong><br>{{.Description}}</p>{{end}}
+</td></tr>
+{{end}}
+{{range .Digest.Notes}}<tr><td style="padding:12px 24px 0;font-size:13px;color:#6b7280"><em>{{.}}</em></td></tr>{{end}}
+<tr><td style="padding:24px;font-size:12px;color:#6b7280">You receive this digest because you were added to a Renderowl report subscription.</td></tr>
+</table>
+</td></tr>
+</table>
+</body>
+</html>
+`))
+
+// HTML renders the digest as a branded email body
+func (d *Digest) HTML() (string, error) {
+	data := map[string]interface{}{
+		"Digest": d,
+		"Blue":   "#" + reportBlue.hex(),
+		"Purple": "#" + reportPurple.hex(),
+	}
+	if r := d.Performance; r != nil {
+		videos, suggestions := r.TopVideos, r.Suggestions
+		if len(videos) > digestTopItems {
+			videos = videos[:digestTopItems]
+		}
+		if len(suggestions) > digestTopItems {
+			suggestions = suggestions[:digestTopItems]
+		}
+		data["TopVideos"], data["Suggestions"] = videos, suggestions
+	}
+
+	var b bytes.Buffer
+	err := digestTemplate.Execute(&b, data)
+	return b.String(), err
+}
+
+// Message returns the digest as an email to the given recipients
+func (d *Digest) Message(to []string) (*MailMessage, error) {
+	html, err := d.HTML()
+	if err != nil {
+		return nil, fmt.Errorf("failed to render digest: %w", err)
+	}
+	return &MailMessage{
+		To:      to,
+		Subject: d.Subject(),
+		Text:    d.Text(),
+		HTML:    html,
+	}, nil
+}
+
+// MailMessage is an email with a plain text and an optional HTML body
+type MailMessage struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, msg *MailMessage) error
+}
+
+// LogMailer writes emails to the application log, for deployments
+// without SMTP
+type LogMailer struct{}
+
+// Send logs the email
+func (LogMailer) Send(ctx context.Context, msg *MailMessage) error {
+	log.Printf("Email to %s: %s\n%s", strings.Join(msg.To, ", "), msg.Subject, msg.Text)
+	return nil
+}
+
+// SMTPMailer sends emails through an SMTP server. STARTTLS is used when
+// the server offers it, and credentials are only sent when configured.
+type SMTPMailer struct {
+	host     string
+	addr     string
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPMailer creates a mailer for an SMTP server. The port defaults
+// to 587 and the sender to reports@renderowl.com.
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	if port == "" {
+		port = "587"
+	}
+	if from == "" {
+		from = "Renderowl <reports@renderowl.com>"
+	}
+	return &SMTPMailer{
+		host:     host,
+		addr:     net.JoinHostPort(host, port),
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+// Send sends an email
+func (m *SMTPMailer) Send(ctx context.Context, msg *MailMessage) error {
+	body, err := m.compose(msg)
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	deadline := time.Now().Add(time.Minute)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if m.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	from, err := mailAddress(m.from)
+	if err != nil {
+		return err
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("recipient %s was rejected: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// compose encodes an email as a MIME message, with alternative plain text
+// and HTML bodies
+func (m *SMTPMailer) compose(msg *MailMessage) ([]byte, error) {
+	var b bytes.Buffer
+	parts := multipart.NewWriter(&b)
+
+	headers := []string{
+		"From: " + m.from,
+		"To: " + strings.Join(msg.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: " + messageID(m.host),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + parts.Boundary(),
+	}
+	b.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	bodies := []struct{ contentType, content string }{{"text/plain", msg.Text}}
+	if msg.HTML != "" {
+		bodies = append(bodies, struct{ contentType, content string }{"text/html", msg.HTML})
+	}
+	for _, body := range bodies {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {body.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(part)
+		if _, err := qp.Write([]byte(body.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// mailAddress returns the address of a sender such as
+// "Renderowl <reports@renderowl.com>"
+func mailAddress(from string) (string, error) {
+	if i := strings.LastIndex(from, "<"); i >= 0 && strings.HasSuffix(from, ">") {
+		return from[i+1 : len(from)-1], nil
+	}
+	if !strings.Contains(from, "@") {
+		return "", fmt.Errorf("invalid sender %q", from)
+	}
+	return from, nil
+}
+
+func messageID(host string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), host)
+}
+
+// DigestWebhook posts digests to incoming webhooks in the format used by
+// Slack and compatible chat apps. Webhook URLs are supplied by customers,
+// so it only connects to public addresses.
+type DigestWebhook struct {
+	httpClient *http.Client
+}
+
+// NewDigestWebhook creates a digest webhook poster
+func NewDigestWebhook() *DigestWebhook {
+	return &DigestWebhook{
+		httpClient: socialsvc.NewPublicHTTPClient(10 * time.Second),
+	}
+}
+
+// Post posts a digest to an incoming webhook URL
+func (w *DigestWebhook) Post(ctx context.Context, url string, digest *Digest) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"text": digest.Text(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"renderowl-api/internal/domain"
+)
+
+// Digest emails are only sent to recipients who confirmed they want them,
+// so a subscription cannot be used to send mail to arbitrary addresses.
+// Each new recipient is sent a link to confirm, signed with a key of the
+// subscription. The owner's own address is confirmed by signing in.
+
+// digestConfirmKeyBytes is the length of a subscription's confirm key
+// before hex encoding
+const digestConfirmKeyBytes = 32
+
+// newDigestConfirmKey generates the key signing a subscription's
+// confirmation links
+func newDigestConfirmKey() (string, error) {
+	key := make([]byte, digestConfirmKeyBytes)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate confirm key: %w", err)
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// recipientToken signs the confirmation of a recipient of a subscription
+func recipientToken(sub *domain.ReportSubscription, address string) string {
+	mac := hmac.New(sha256.New, []byte(sub.ConfirmKey))
+	mac.Write([]byte(sub.ID + "\n" + address))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// updateConfirmedRecipients drops the confirmations of removed recipients
+// and confirms the owner's address. It returns the recipients that still
+// need to be asked: those added since previous.
+func updateConfirmedRecipients(sub *domain.ReportSubscription, previous []string, ownerEmail string) []string {
+	var confirmed, unconfirmed []string
+	for _, recipient := range sub.Recipients {
+		switch {
+		case containsString(sub.ConfirmedRecipients, recipient),
+			ownerEmail != "" && strings.EqualFold(recipient, ownerEmail):
+			confirmed = append(confirmed, recipient)
+		case !containsString(previous, recipient):
+			unconfirmed = append(unconfirmed, recipient)
+		}
+	}
+	sub.ConfirmedRecipients = confirmed
+	return unconfirmed
+}
+
+// requestConfirmations emails recipients a link to confirm they want a
+// subscription's digest. Failures are logged; the owner can add the
+// recipient again to resend the request.
+func (s *DigestService) requestConfirmations(ctx context.Context, sub *domain.ReportSubscription, recipients []string) {
+	for _, recipient := range recipients {
+		link := fmt.Sprintf("%s/reports/confirm?%s", strings.TrimRight(s.publicURL, "/"), url.Values{
+			"subscription": {sub.ID},
+			"email":        {recipient},
+			"token":        {recipientToken(sub, recipient)},
+		}.Encode())
+
+		text := fmt.Sprintf("You were added to the %s analytics digest, sent %s by Renderowl.\n\n"+
+			"Open this link to start receiving it:\n%s\n\n"+
+			"If you do not want the digest, ignore this email and you will not receive it.\n",
+			sub.Name, sub.Cadence, link)
+
+		err := s.mailer.Send(ctx, &MailMessage{
+			To:      []string{recipient},
+			Subject: fmt.Sprintf("Confirm your %s digest", sub.Name),
+			Text:    text,
+		})
+		if err != nil {
+			log.Printf("Failed to send confirmation of report digest %s to %s: %v", sub.ID, recipient, err)
+		}
+	}
+}
+
+// ConfirmRecipient confirms that a recipient wants a subscription's
+// digest, with the token of its confirmation link
+func (s *DigestService) ConfirmRecipient(ctx context.Context, id, address, token string) error {
+	sub, err := s.subscriptions.GetByID(ctx, id)
+	if err != nil || sub.ConfirmKey == "" || !containsString(sub.Recipients, address) ||
+		!hmac.Equal([]byte(token), []byte(recipientToken(sub, address))) {
+		return ErrInvalidRecipientConfirmation
+	}
+
+	if err := s.subscriptions.ConfirmRecipient(ctx, id, address); err != nil {
+		return fmt.Errorf("failed to confirm recipient: %w", err)
+	}
+	return nil
+}
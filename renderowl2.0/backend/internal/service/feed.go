@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	socialdomain "renderowl-api/internal/domain/social"
+)
+
+// FeedAccountRepository finds feed destinations by their token
+type FeedAccountRepository interface {
+	GetByPlatformAccount(ctx context.Context, platform socialdomain.SocialPlatform, accountID string) (*socialdomain.SocialAccount, error)
+}
+
+// FeedPostRepository lists the posts published to a destination
+type FeedPostRepository interface {
+	GetPublishedToAccount(ctx context.Context, accountID string, limit int) ([]*socialdomain.ScheduledPost, error)
+}
+
+var (
+	// ErrFeedNotFound is returned for unknown or disconnected feed tokens
+	ErrFeedNotFound = errors.New("feed not found")
+
+	// ErrFeedFormat is returned for feed formats other than rss and atom
+	ErrFeedFormat = errors.New("feed format must be rss or atom")
+)
+
+// Feed formats. RSS feeds carry Media RSS elements.
+const (
+	FeedFormatRSS  = "rss"
+	FeedFormatAtom = "atom"
+)
+
+// feedItemLimit is the number of most recent posts in a feed
+const feedItemLimit = 50
+
+// XML namespaces of the feeds
+const (
+	atomNamespace  = "http://www.w3.org/2005/Atom"
+	mediaNamespace = "http://search.yahoo.com/mrss/"
+)
+
+// FeedService renders the RSS and Atom feeds of feed destinations, so that
+// sites and podcast apps can pick up published videos
+type FeedService struct {
+	accounts FeedAccountRepository
+	posts    FeedPostRepository
+}
+
+// NewFeedService creates a new feed service
+func NewFeedService(accounts FeedAccountRepository, posts FeedPostRepository) *FeedService {
+	return &FeedService{
+		accounts: accounts,
+		posts:    posts,
+	}
+}
+
+// Render renders the feed with the given token. selfURL is the URL the
+// feed is served at. It returns the document and its content type.
+func (s *FeedService) Render(ctx context.Context, token, format, selfURL string) ([]byte, string, error) {
+	if format != FeedFormatRSS && format != FeedFormatAtom {
+		return nil, "", ErrFeedFormat
+	}
+
+	account, err := s.accounts.GetByPlatformAccount(ctx, socialdomain.PlatformFeed, token)
+	if err != nil || account.Status != socialdomain.StatusConnected {
+		return nil, "", ErrFeedNotFound
+	}
+
+	posts, err := s.posts.GetPublishedToAccount(ctx, account.ID, feedItemLimit)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load feed items: %w", err)
+	}
+
+	items := make([]feedItem, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, newFeedItem(post))
+	}
+
+	var document interface{}
+	contentType := "application/rss+xml; charset=utf-8"
+	if format == FeedFormatAtom {
+		document = atomDocument(account, items, selfURL)
+		contentType = "application/atom+xml; charset=utf-8"
+	} else {
+		document = rssDocument(account, items, selfURL)
+	}
+
+	body, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to render feed: %w", err)
+	}
+	return append([]byte(xml.Header), body...), contentType, nil
+}
+
+// feedItem is a published post as it appears in a feed
+type feedItem struct {
+	ID           string
+	Title        string
+	Description  string
+	Link         string
+	VideoURL     string
+	VideoType    string
+	FileSize     int64
+	Duration     int
+	ThumbnailURL string
+	PublishedAt  time.Time
+}
+
+// newFeedItem builds a feed item from a post carrying a single published
+// platform post
+func newFeedItem(post *socialdomain.ScheduledPost) feedItem {
+	platformPost := post.Platforms[0]
+
+	item := feedItem{
+		ID:          platformPost.PlatformPostID,
+		Title:       platformPost.CustomTitle,
+		Description: platformPost.CustomDesc,
+		Link:        platformPost.PostURL,
+		PublishedAt: platformPost.UpdatedAt,
+	}
+	if item.Title == "" {
+		item.Title = post.Title
+	}
+	if item.Description == "" {
+		item.Description = post.Description
+	}
+	if platformPost.PublishedAt != nil {
+		item.PublishedAt = *platformPost.PublishedAt
+	}
+
+	item.VideoURL, _ = post.Metadata["videoUrl"].(string)
+	if item.VideoURL == "" {
+		item.VideoURL = platformPost.PostURL
+	}
+	item.VideoType = videoMIMEType(item.VideoURL)
+	item.ThumbnailURL, _ = post.Metadata["thumbnailUrl"].(string)
+	if size, ok := post.Metadata["fileSize"].(float64); ok {
+		item.FileSize = int64(size)
+	}
+	if duration, ok := post.Metadata["duration"].(float64); ok {
+		item.Duration = int(duration)
+	}
+
+	return item
+}
+
+// videoMIMEType guesses the MIME type of a video from its URL
+func videoMIMEType(videoURL string) string {
+	if u, err := url.Parse(videoURL); err == nil {
+		if t := mime.TypeByExtension(path.Ext(u.Path)); t != "" {
+			return t
+		}
+	}
+	return "video/mp4"
+}
+
+// feedLink returns the site a feed links to, falling back to the feed
+func feedLink(account *socialdomain.SocialAccount, selfURL string) string {
+	if link, ok := account.Metadata["link"].(string); ok && link != "" {
+		return link
+	}
+	return selfURL
+}
+
+// RSS 2.0 with Media RSS
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	MediaNS string     `xml:"xmlns:media,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string          `xml:"title"`
+	Link          string          `xml:"link"`
+	Description   string          `xml:"description"`
+	Self          feedLinkElement `xml:"atom:link"`
+	LastBuildDate string          `xml:"lastBuildDate"`
+	Generator     string          `xml:"generator"`
+	Items         []rssItem       `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string          `xml:"title"`
+	Link        string          `xml:"link"`
+	Description string          `xml:"description"`
+	GUID        rssGUID         `xml:"guid"`
+	PubDate     string          `xml:"pubDate"`
+	Enclosure   rssEnclosure    `xml:"enclosure"`
+	Content     mediaContent    `xml:"media:content"`
+	Thumbnail   *mediaThumbnail `xml:"media:thumbnail,omitempty"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type mediaContent struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Medium   string `xml:"medium,attr"`
+	FileSize int64  `xml:"fileSize,attr,omitempty"`
+	Duration int    `xml:"duration,attr,omitempty"`
+}
+
+type mediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+func rssDocument(account *socialdomain.SocialAccount, items []feedItem, selfURL string) *rssFeed {
+	description, _ := account.Metadata["description"].(string)
+	if description == "" {
+		description = account.AccountName
+	}
+
+	feed := &rssFeed{
+		Version: "2.0",
+		AtomNS:  atomNamespace,
+		MediaNS: mediaNamespace,
+		Channel: rssChannel{
+			Title:         account.AccountName,
+			Link:          feedLink(account, selfURL),
+			Description:   description,
+			Self:          feedLinkElement{Href: selfURL, Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: time.Now().UTC().Format(time.RFC1123Z),
+			Generator:     "Renderowl",
+		},
+	}
+
+	for _, item := range items {
+		rss := rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			GUID:        rssGUID{Value: item.ID},
+			PubDate:     item.PublishedAt.UTC().Format(time.RFC1123Z),
+			Enclosure:   rssEnclosure{URL: item.VideoURL, Length: item.FileSize, Type: item.VideoType},
+			Content: mediaContent{
+				URL:      item.VideoURL,
+				Type:     item.VideoType,
+				Medium:   "video",
+				FileSize: item.FileSize,
+				Duration: item.Duration,
+			},
+		}
+		if item.ThumbnailURL != "" {
+			rss.Thumbnail = &mediaThumbnail{URL: item.ThumbnailURL}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, rss)
+	}
+
+	return feed
+}
+
+// Atom
+
+type atomFeed struct {
+	XMLName  xml.Name          `xml:"feed"`
+	NS       string            `xml:"xmlns,attr"`
+	MediaNS  string            `xml:"xmlns:media,attr"`
+	ID       string            `xml:"id"`
+	Title    string            `xml:"title"`
+	Subtitle string            `xml:"subtitle,omitempty"`
+	Updated  string            `xml:"updated"`
+	Links    []feedLinkElement `xml:"link"`
+	Entries  []atomEntry       `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID        string            `xml:"id"`
+	Title     string            `xml:"title"`
+	Summary   string            `xml:"summary,omitempty"`
+	Published string            `xml:"published"`
+	Updated   string            `xml:"updated"`
+	Links     []feedLinkElement `xml:"link"`
+	Content   mediaContent      `xml:"media:content"`
+	Thumbnail *mediaThumbnail   `xml:"media:thumbnail,omitempty"`
+}
+
+// feedLinkElement is an Atom link, also used for the self link of RSS feeds
+type feedLinkElement struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length string `xml:"length,attr,omitempty"`
+}
+
+func atomDocument(account *socialdomain.SocialAccount, items []feedItem, selfURL string) *atomFeed {
+	subtitle, _ := account.Metadata["description"].(string)
+
+	updated := account.UpdatedAt
+	if len(items) > 0 && items[0].PublishedAt.After(updated) {
+		updated = items[0].PublishedAt
+	}
+
+	feed := &atomFeed{
+		NS:       atomNamespace,
+		MediaNS:  mediaNamespace,
+		ID:       "urn:renderowl:feed:" + account.AccountID,
+		Title:    account.AccountName,
+		Subtitle: subtitle,
+		Updated:  updated.UTC().Format(time.RFC3339),
+		Links: []feedLinkElement{
+			{Href: selfURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: feedLink(account, selfURL), Rel: "alternate"},
+		},
+	}
+
+	for _, item := range items {
+		entry := atomEntry{
+			ID:        "urn:renderowl:item:" + item.ID,
+			Title:     item.Title,
+			Summary:   item.Description,
+			Published: item.PublishedAt.UTC().Format(time.RFC3339),
+			Updated:   item.PublishedAt.UTC().Format(time.RFC3339),
+			Links: []feedLinkElement{
+				{Href: item.Link, Rel: "alternate"},
+				{Href: item.VideoURL, Rel: "enclosure", Type: item.VideoType, Length: enclosureLength(item.FileSize)},
+			},
+			Content: mediaContent{
+				URL:      item.VideoURL,
+				Type:     item.VideoType,
+				Medium:   "video",
+				FileSize: item.FileSize,
+				Duration: item.Duration,
+			},
+		}
+		if item.ThumbnailURL != "" {
+			entry.Thumbnail = &mediaThumbnail{URL: item.ThumbnailURL}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return feed
+}
+
+func enclosureLength(size int64) string {
+	if size <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(size, 10)
+}
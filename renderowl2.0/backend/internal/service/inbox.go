@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	socialdomain "renderowl-api/internal/domain/social"
+	"renderowl-api/internal/scheduler"
+	socialsvc "renderowl-api/internal/service/social"
+)
+
+// CommentRepository defines comment storage
+type CommentRepository interface {
+	GetByID(ctx context.Context, id string) (*socialdomain.Comment, error)
+	GetByPlatformPost(ctx context.Context, platformPostID string) ([]*socialdomain.Comment, error)
+	GetReplies(ctx context.Context, parentIDs []string) ([]*socialdomain.Comment, error)
+	List(ctx context.Context, userID string, filter socialdomain.CommentFilter) ([]*socialdomain.Comment, error)
+	Save(ctx context.Context, comment *socialdomain.Comment) error
+	SaveAll(ctx context.Context, comments []*socialdomain.Comment) error
+	MarkRead(ctx context.Context, userID string, ids []string, read bool) error
+}
+
+// CommentPostRepository lists the published posts whose comments are synced
+type CommentPostRepository interface {
+	GetPublishedSince(ctx context.Context, since time.Time) ([]*socialdomain.ScheduledPost, error)
+}
+
+var (
+	// ErrCommentNotFound is returned when a comment does not exist or
+	// belongs to another workspace
+	ErrCommentNotFound = errors.New("comment not found")
+
+	// ErrCommentDeleted is returned when a deleted comment is moderated or
+	// replied to
+	ErrCommentDeleted = errors.New("comment has been deleted")
+
+	// ErrReplyRequired is returned for empty replies
+	ErrReplyRequired = errors.New("reply text is required")
+)
+
+// commentSyncWindow is how long after publishing the comments of a post
+// are synced
+const commentSyncWindow = 30 * 24 * time.Hour
+
+// Inbox page sizes
+const (
+	inboxDefaultLimit = 50
+	inboxMaxLimit     = 200
+)
+
+// InboxService gathers the comments of published posts from every
+// platform into one inbox, where they can be triaged, replied to, hidden
+// and deleted
+type InboxService struct {
+	comments      CommentRepository
+	posts         CommentPostRepository
+	socialService *socialsvc.Service
+	scheduler     *scheduler.Scheduler
+}
+
+// NewInboxService creates a new inbox service
+func NewInboxService(
+	comments CommentRepository,
+	posts CommentPostRepository,
+	socialService *socialsvc.Service,
+	scheduler *scheduler.Scheduler,
+) *InboxService {
+	return &InboxService{
+		comments:      comments,
+		posts:         posts,
+		socialService: socialService,
+		scheduler:     scheduler,
+	}
+}
+
+// Initialize registers the comment sync job, runs it once and schedules
+// it hourly
+func (s *InboxService) Initialize(ctx context.Context) error {
+	return s.scheduler.ScheduleRecurring(ctx, "sync_comments", &socialdomain.RecurringRule{
+		Frequency: "hourly",
+		Interval:  1,
+	}, s.handleSyncJob)
+}
+
+func (s *InboxService) handleSyncJob(ctx context.Context, job *scheduler.Job) error {
+	added, failed, err := s.SyncAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	if added > 0 || failed > 0 {
+		log.Printf("Comment sync: %d new comments, %d posts failed", added, failed)
+	}
+
+	return nil
+}
+
+// SyncAll pulls the comments of every post published within the sync
+// window. It returns the number of new comments and of posts that could
+// not be synced. Platforms without comment support are skipped.
+func (s *InboxService) SyncAll(ctx context.Context) (int, int, error) {
+	posts, err := s.posts.GetPublishedSince(ctx, time.Now().Add(-commentSyncWindow))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to load published posts: %w", err)
+	}
+
+	added, failed := 0, 0
+	for _, post := range posts {
+		n, err := s.syncPost(ctx, post, &post.Platforms[0])
+		switch {
+		case errors.Is(err, socialsvc.ErrUnsupported):
+		case err != nil:
+			failed++
+			log.Printf("Failed to sync comments of post %s on account %s: %v", post.ID, post.Platforms[0].AccountID, err)
+		default:
+			added += n
+		}
+	}
+
+	return added, failed, nil
+}
+
+// syncPost stores the new comments of a platform post and updates the
+// text, likes and visibility of known ones. It returns the number of new
+// comments.
+func (s *InboxService) syncPost(ctx context.Context, post *socialdomain.ScheduledPost, platformPost *socialdomain.PlatformPost) (int, error) {
+	fetched, err := s.socialService.GetComments(ctx, platformPost.AccountID, platformPost.PlatformPostID)
+	if err != nil {
+		return 0, err
+	}
+
+	stored, err := s.comments.GetByPlatformPost(ctx, platformPost.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load comments: %w", err)
+	}
+	byRemoteID := make(map[string]*socialdomain.Comment, len(stored))
+	for _, comment := range stored {
+		byRemoteID[comment.RemoteID] = comment
+	}
+
+	var changed []*socialdomain.Comment
+	added := 0
+	for _, comment := range fetched {
+		if comment.RemoteID == "" {
+			continue
+		}
+
+		known, ok := byRemoteID[comment.RemoteID]
+		if !ok {
+			s.adopt(comment, post, platformPost)
+			comment.Read = comment.FromAccount
+			byRemoteID[comment.RemoteID] = comment
+			changed = append(changed, comment)
+			added++
+			continue
+		}
+
+		// Comments deleted here stay deleted
+		if known.Status == socialdomain.CommentStatusDeleted {
+			continue
+		}
+		if known.Text != comment.Text || known.Likes != comment.Likes || known.Status != comment.Status {
+			if known.Text != comment.Text {
+				known.Text = comment.Text
+				known.Sentiment = ClassifySentiment(comment.Text)
+			}
+			known.Likes = comment.Likes
+			known.Status = comment.Status
+			changed = append(changed, known)
+		}
+	}
+
+	// Replies point at the top-level comment of their thread, which may
+	// only have been fetched in this sync
+	for _, comment := range byRemoteID {
+		if comment.ParentID != "" || comment.RemoteParentID == "" {
+			continue
+		}
+		if root := threadRoot(byRemoteID, comment); root != nil {
+			comment.ParentID = root.ID
+			if !containsComment(changed, comment) {
+				changed = append(changed, comment)
+			}
+		}
+	}
+
+	if err := s.comments.SaveAll(ctx, changed); err != nil {
+		return 0, fmt.Errorf("failed to save comments: %w", err)
+	}
+	return added, nil
+}
+
+// adopt fills in the local fields of a comment read from a platform
+func (s *InboxService) adopt(comment *socialdomain.Comment, post *socialdomain.ScheduledPost, platformPost *socialdomain.PlatformPost) {
+	comment.ID = uuid.New().String()
+	comment.UserID = post.UserID
+	comment.ScheduledPostID = post.ID
+	comment.PlatformPostID = platformPost.ID
+	comment.AccountID = platformPost.AccountID
+	comment.Platform = platformPost.Platform
+	comment.Sentiment = ClassifySentiment(comment.Text)
+	if comment.Status == "" {
+		comment.Status = socialdomain.CommentStatusVisible
+	}
+	if comment.PublishedAt.IsZero() {
+		comment.PublishedAt = time.Now()
+	}
+}
+
+// threadRoot follows the remote parents of a comment up to the top-level
+// comment of its thread, or nil if a parent was not fetched
+func threadRoot(byRemoteID map[string]*socialdomain.Comment, comment *socialdomain.Comment) *socialdomain.Comment {
+	root := comment
+	for depth := 0; root.RemoteParentID != "" && depth < 10; depth++ {
+		parent, ok := byRemoteID[root.RemoteParentID]
+		if !ok {
+			return nil
+		}
+		root = parent
+	}
+	if root == comment {
+		return nil
+	}
+	return root
+}
+
+func containsComment(comments []*socialdomain.Comment, comment *socialdomain.Comment) bool {
+	for _, c := range comments {
+		if c == comment {
+			return true
+		}
+	}
+	return false
+}
+
+// List lists the comments of a workspace, newest first
+func (s *InboxService) List(ctx context.Context, userID string, filter socialdomain.CommentFilter) ([]*socialdomain.Comment, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = inboxDefaultLimit
+	}
+	if filter.Limit > inboxMaxLimit {
+		filter.Limit = inboxMaxLimit
+	}
+	return s.comments.List(ctx, userID, filter)
+}
+
+// GetThread returns the top-level comment of the thread a comment is in,
+// with its replies
+func (s *InboxService) GetThread(ctx context.Context, userID, commentID string) (*socialdomain.Comment, error) {
+	comment, err := s.getComment(ctx, userID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	root, err := s.root(ctx, userID, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := s.comments.GetReplies(ctx, []string{root.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load replies: %w", err)
+	}
+	root.Replies = make([]socialdomain.Comment, 0, len(replies))
+	for _, reply := range replies {
+		root.Replies = append(root.Replies, *reply)
+	}
+	return root, nil
+}
+
+// Reply replies to a comment on its platform. Replies go to the thread of
+// the comment, which is marked read.
+func (s *InboxService) Reply(ctx context.Context, userID, commentID, text string) (*socialdomain.Comment, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, ErrReplyRequired
+	}
+
+	comment, err := s.getComment(ctx, userID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Status == socialdomain.CommentStatusDeleted {
+		return nil, ErrCommentDeleted
+	}
+	root, err := s.root(ctx, userID, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := s.socialService.ReplyToComment(ctx, root.AccountID, root.RemotePostID, root.RemoteID, text)
+	if err != nil {
+		return nil, err
+	}
+
+	reply.ID = uuid.New().String()
+	reply.UserID = root.UserID
+	reply.ScheduledPostID = root.ScheduledPostID
+	reply.PlatformPostID = root.PlatformPostID
+	reply.AccountID = root.AccountID
+	reply.Platform = root.Platform
+	reply.ParentID = root.ID
+	reply.RemoteParentID = root.RemoteID
+	reply.Sentiment = ClassifySentiment(reply.Text)
+	reply.FromAccount = true
+	reply.Read = true
+	if reply.Status == "" {
+		reply.Status = socialdomain.CommentStatusVisible
+	}
+
+	comment.Read = true
+	if err := s.comments.SaveAll(ctx, []*socialdomain.Comment{reply, comment}); err != nil {
+		return nil, fmt.Errorf("failed to save reply: %w", err)
+	}
+	return reply, nil
+}
+
+// Hide hides a comment on its platform, or shows it again
+func (s *InboxService) Hide(ctx context.Context, userID, commentID string, hidden bool) (*socialdomain.Comment, error) {
+	comment, err := s.getComment(ctx, userID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Status == socialdomain.CommentStatusDeleted {
+		return nil, ErrCommentDeleted
+	}
+
+	if err := s.socialService.HideComment(ctx, comment.AccountID, comment.RemotePostID, comment.RemoteID, hidden); err != nil {
+		return nil, err
+	}
+
+	comment.Status = socialdomain.CommentStatusVisible
+	if hidden {
+		comment.Status = socialdomain.CommentStatusHidden
+	}
+	comment.Read = true
+	if err := s.comments.Save(ctx, comment); err != nil {
+		return nil, fmt.Errorf("failed to save comment: %w", err)
+	}
+	return comment, nil
+}
+
+// Delete deletes a comment on its platform. Deleting a top-level comment
+// removes its replies with it.
+func (s *InboxService) Delete(ctx context.Context, userID, commentID string) error {
+	comment, err := s.getComment(ctx, userID, commentID)
+	if err != nil {
+		return err
+	}
+	if comment.Status == socialdomain.CommentStatusDeleted {
+		return nil
+	}
+
+	if err := s.socialService.DeleteComment(ctx, comment.AccountID, comment.RemotePostID, comment.RemoteID); err != nil {
+		return err
+	}
+
+	deleted := []*socialdomain.Comment{comment}
+	if comment.ParentID == "" {
+		replies, err := s.comments.GetReplies(ctx, []string{comment.ID})
+		if err != nil {
+			return fmt.Errorf("failed to load replies: %w", err)
+		}
+		deleted = append(deleted, replies...)
+	}
+	for _, c := range deleted {
+		c.Status = socialdomain.CommentStatusDeleted
+		c.Read = true
+	}
+	if err := s.comments.SaveAll(ctx, deleted); err != nil {
+		return fmt.Errorf("failed to save comments: %w", err)
+	}
+	return nil
+}
+
+// MarkRead marks comments of a workspace as read or unread
+func (s *InboxService) MarkRead(ctx context.Context, userID string, commentIDs []string, read bool) error {
+	if len(commentIDs) == 0 {
+		return nil
+	}
+	return s.comments.MarkRead(ctx, userID, commentIDs, read)
+}
+
+// getComment loads a comment of the workspace
+func (s *InboxService) getComment(ctx context.Context, userID, commentID string) (*socialdomain.Comment, error) {
+	comment, err := s.comments.GetByID(ctx, commentID)
+	if err != nil || comment.UserID != userID {
+		return nil, ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+// root returns the top-level comment of the thread a comment is in
+func (s *InboxService) root(ctx context.Context, userID string, comment *socialdomain.Comment) (*socialdomain.Comment, error) {
+	if comment.ParentID == "" {
+		return comment, nil
+	}
+	return s.getComment(ctx, userID, comment.ParentID)
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"sort"
@@ -20,6 +21,10 @@ type OptimizerService struct {
 	aiScriptService *AIScriptService
 }
 
+// ErrVideoAnalyticsNotFound is returned when a video of the user has no
+// analytics
+var ErrVideoAnalyticsNotFound = errors.New("video analytics not found")
+
 // AnalyticsRepository defines the interface for analytics data
 type AnalyticsRepository interface {
 	GetVideoPerformance(videoID string, days int) (*VideoAnalytics, error)
@@ -31,7 +36,7 @@ type AnalyticsRepository interface {
 
 // TimelineRepository defines the interface for timeline data
 type TimelineRepository interface {
-	Get(id string, userID string) (*domain.Timeline, error)
+	GetByIDAndUser(id string, userID string) (*domain.Timeline, error)
 	Update(timeline *domain.Timeline) error
 	ListByUser(userID string, limit, offset int) ([]*domain.Timeline, error)
 }
@@ -44,6 +49,7 @@ type SocialService struct {
 // VideoAnalytics represents performance data for a video
 type VideoAnalytics struct {
 	VideoID         string                 `json:"videoId"`
+	UserID          string                 `json:"-"`
 	Title           string                 `json:"title"`
 	Platform        string                 `json:"platform"`
 	Views           int                    `json:"views"`
@@ -54,6 +60,9 @@ type VideoAnalytics struct {
 	AvgWatchDuration float64               `json:"avgWatchDuration"`
 	CTR             float64                `json:"ctr"`       // Click-through rate
 	RetentionCurve  []float64              `json:"retentionCurve"` // Percentage at each 10% mark
+	RetentionDrop   *RetentionDrop         `json:"retentionDrop,omitempty"`
+	Scenes          []SceneRetention       `json:"scenes,omitempty"` // retention over the scenes of the source timeline
+	Duration        float64                `json:"duration,omitempty"` // seconds
 	TrafficSources  map[string]float64     `json:"trafficSources"`
 	AudienceDemographics map[string]interface{} `json:"audienceDemographics"`
 	PublishDate     time.Time              `json:"publishDate"`
@@ -203,13 +212,17 @@ func NewOptimizerService(
 	}
 }
 
-// AnalyzeVideo analyzes a video's performance and generates suggestions
-func (s *OptimizerService) AnalyzeVideo(ctx context.Context, videoID string) ([]*OptimizationSuggestion, error) {
+// AnalyzeVideo analyzes the performance of a user's video and generates
+// suggestions
+func (s *OptimizerService) AnalyzeVideo(ctx context.Context, userID, videoID string) ([]*OptimizationSuggestion, error) {
 	// Get video analytics
 	analytics, err := s.analyticsRepo.GetVideoPerformance(videoID, 30)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get video analytics: %w", err)
 	}
+	if analytics.UserID != userID {
+		return nil, ErrVideoAnalyticsNotFound
+	}
 
 	var suggestions []*OptimizationSuggestion
 
@@ -251,8 +264,8 @@ func (s *OptimizerService) AnalyzeVideo(ctx context.Context, videoID string) ([]
 func (s *OptimizerService) analyzeTitle(ctx context.Context, analytics *VideoAnalytics) []*OptimizationSuggestion {
 	var suggestions []*OptimizationSuggestion
 
-	// Check if CTR is below average
-	if analytics.CTR < 4.0 {
+	// Check if CTR is below average. Not every platform reports it.
+	if analytics.CTR > 0 && analytics.CTR < 4.0 {
 		suggestions = append(suggestions, &OptimizationSuggestion{
 			ID:             uuid.New().String(),
 			VideoID:        analytics.VideoID,
@@ -308,7 +321,7 @@ func (s *OptimizerService) analyzeThumbnail(ctx context.Context, analytics *Vide
 	var suggestions []*OptimizationSuggestion
 
 	// Low CTR often indicates thumbnail issues
-	if analytics.CTR < 3.5 {
+	if analytics.CTR > 0 && analytics.CTR < 3.5 {
 		suggestions = append(suggestions, &OptimizationSuggestion{
 			ID:             uuid.New().String(),
 			VideoID:        analytics.VideoID,
@@ -350,6 +363,42 @@ func (s *OptimizerService) analyzeRetention(ctx context.Context, analytics *Vide
 		})
 	}
 
+	// Point out the steepest drop-off, in the scene where it happens when
+	// the video's timeline is known
+	if drop := analytics.RetentionDrop; drop != nil {
+		where := fmt.Sprintf("%.0f%% of the way in", drop.Position*100)
+		within := "the next 5% of the video"
+		metadata := map[string]interface{}{
+			"position": drop.Position,
+			"drop":     drop.Drop,
+		}
+		if drop.At > 0 {
+			where = "at " + formatTimestamp(drop.At)
+			within = fmt.Sprintf("%.0f seconds", analytics.Duration*retentionDropWindow)
+			metadata["at"] = drop.At
+		}
+		if drop.Scene != nil {
+			where = "at " + drop.Scene.Label + " " + where
+			metadata["scene"] = drop.Scene.Label
+			metadata["clipId"] = drop.Scene.ClipID
+		}
+
+		suggestions = append(suggestions, &OptimizationSuggestion{
+			ID:             uuid.New().String(),
+			VideoID:        analytics.VideoID,
+			Type:           SuggestionTypeRetention,
+			Priority:       PriorityHigh,
+			Title:          "Fix Retention Drop",
+			Description:    fmt.Sprintf("Viewers drop %s: %.0f%% of the audience leaves within %s. Tighten this part, or open it with a visual change or a tease of what's next.", where, drop.Drop*100, within),
+			ExpectedImpact: 18.0,
+			Confidence:     0.80,
+			AutoApplicable: false,
+			Metadata:       metadata,
+			CreatedAt:      time.Now(),
+		})
+		return suggestions
+	}
+
 	// Check for mid-video drop-off
 	if len(analytics.RetentionCurve) > 5 {
 		midPoint := len(analytics.RetentionCurve) / 2
@@ -454,7 +503,7 @@ func (s *OptimizerService) GeneratePerformanceReport(ctx context.Context, userID
 
 	// Generate suggestions for underperforming videos
 	for _, video := range underperforming {
-		suggestions, err := s.AnalyzeVideo(ctx, video.VideoID)
+		suggestions, err := s.AnalyzeVideo(ctx, userID, video.VideoID)
 		if err != nil {
 			continue
 		}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"renderowl-api/internal/domain"
+	"renderowl-api/internal/repository"
+)
+
+// OptimizerAnalytics provides the optimizer with the analytics rolled up
+// from published posts, and maps the retention of each video onto the
+// scenes of its source timeline
+type OptimizerAnalytics struct {
+	analytics *repository.AnalyticsRepository
+	timelines *repository.TimelineRepository
+	batches   *repository.BatchRepository
+}
+
+// NewOptimizerAnalytics creates the optimizer's analytics source
+func NewOptimizerAnalytics(
+	analytics *repository.AnalyticsRepository,
+	timelines *repository.TimelineRepository,
+	batches *repository.BatchRepository,
+) *OptimizerAnalytics {
+	return &OptimizerAnalytics{
+		analytics: analytics,
+		timelines: timelines,
+		batches:   batches,
+	}
+}
+
+// GetVideoPerformance gets the lifetime performance of a video with its
+// retention. It returns ErrVideoAnalyticsNotFound if the video has no
+// analytics yet.
+func (a *OptimizerAnalytics) GetVideoPerformance(videoID string, days int) (*VideoAnalytics, error) {
+	ctx := context.Background()
+
+	performance, err := a.analytics.GetVideoPerformanceByID(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+	if performance == nil {
+		return nil, ErrVideoAnalyticsNotFound
+	}
+	analytics := performanceAnalytics(performance.UserID, &repository.VideoPerformanceData{
+		VideoID:        performance.VideoID,
+		Title:          performance.Title,
+		TotalViews:     performance.TotalViews,
+		TotalLikes:     performance.TotalLikes,
+		TotalComments:  performance.TotalComments,
+		TotalShares:    performance.TotalShares,
+		EngagementRate: performance.EngagementRate,
+		Platforms:      performance.Platforms,
+		PublishedAt:    performance.PublishedAt,
+	})
+
+	curves, err := a.analytics.GetRetention(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+	curve := detailedCurve(curves)
+	if curve == nil {
+		return analytics, nil
+	}
+
+	analytics.RetentionCurve = retentionMarks(curve)
+	analytics.RetentionDrop = findRetentionDrop(curve)
+	if timeline := a.sourceTimeline(videoID, performance.UserID); timeline != nil {
+		scenes := timelineScenes(timeline)
+		duration := timeline.Duration
+		if duration <= 0 && len(scenes) > 0 {
+			duration = scenes[len(scenes)-1].End
+		}
+		if duration > 0 {
+			mapRetention(curve, analytics.RetentionDrop, scenes, duration)
+			analytics.Duration = duration
+			analytics.Scenes = scenes
+		}
+	}
+
+	return analytics, nil
+}
+
+// GetTopPerforming gets the videos of a user with the most views
+func (a *OptimizerAnalytics) GetTopPerforming(userID string, limit int) ([]*VideoAnalytics, error) {
+	videos, err := a.analytics.GetVideoPerformance(context.Background(), userID, limit, 0)
+	if err != nil {
+		return nil, err
+	}
+	return performanceList(userID, videos), nil
+}
+
+// GetUnderperforming gets the published videos of a user with the fewest
+// views
+func (a *OptimizerAnalytics) GetUnderperforming(userID string, limit int) ([]*VideoAnalytics, error) {
+	videos, err := a.analytics.GetLowestPerforming(context.Background(), userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return performanceList(userID, videos), nil
+}
+
+// GetComparativeMetrics returns no metrics: variants are not tracked yet
+func (a *OptimizerAnalytics) GetComparativeMetrics(videoIDs []string) (map[string]*ComparativeMetrics, error) {
+	return map[string]*ComparativeMetrics{}, nil
+}
+
+// GetTrendingTopics returns no topics: topics are not tracked yet
+func (a *OptimizerAnalytics) GetTrendingTopics(days int) ([]*TrendingTopicData, error) {
+	return nil, nil
+}
+
+// sourceTimeline finds the timeline of a user that a video was rendered
+// from: the video is either the timeline itself or a batch video. It
+// returns nil if the timeline is gone.
+func (a *OptimizerAnalytics) sourceTimeline(videoID, userID string) *domain.Timeline {
+	if timeline, err := a.timelines.GetByIDAndUser(videoID, userID); err == nil {
+		return timeline
+	}
+
+	video, err := a.batches.GetVideo(videoID)
+	if err != nil || video.TimelineID == "" {
+		return nil
+	}
+	timeline, err := a.timelines.GetByIDAndUser(video.TimelineID, userID)
+	if err != nil {
+		return nil
+	}
+	return timeline
+}
+
+// detailedCurve picks the retention curve with the most points when a
+// video has one per platform
+func detailedCurve(curves []domain.VideoRetention) domain.RetentionCurve {
+	var best domain.RetentionCurve
+	for _, curve := range curves {
+		if len(curve.Points) > len(best) {
+			best = curve.Points
+		}
+	}
+	return best
+}
+
+func performanceList(userID string, videos []repository.VideoPerformanceData) []*VideoAnalytics {
+	list := make([]*VideoAnalytics, len(videos))
+	for i := range videos {
+		list[i] = performanceAnalytics(userID, &videos[i])
+	}
+	return list
+}
+
+// performanceAnalytics converts the rolled up performance of a video
+func performanceAnalytics(userID string, video *repository.VideoPerformanceData) *VideoAnalytics {
+	analytics := &VideoAnalytics{
+		VideoID:        video.VideoID,
+		UserID:         userID,
+		Title:          video.Title,
+		Platform:       strings.Join(video.Platforms, ","),
+		Views:          int(video.TotalViews),
+		Likes:          int(video.TotalLikes),
+		Comments:       int(video.TotalComments),
+		Shares:         int(video.TotalShares),
+		EngagementRate: video.EngagementRate,
+	}
+	if video.PublishedAt != nil {
+		analytics.PublishDate = *video.PublishedAt
+		analytics.DaysSincePublish = int(time.Since(*video.PublishedAt).Hours() / 24)
+	}
+	return analytics
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	socialdomain "renderowl-api/internal/domain/social"
+	socialsvc "renderowl-api/internal/service/social"
+)
+
+var (
+	// ErrPostNotFound is returned when a post does not exist or belongs to
+	// another user
+	ErrPostNotFound = errors.New("post not found")
+
+	// ErrNothingToUpdate is returned for edits that change no field
+	ErrNothingToUpdate = errors.New("title, description or privacy is required")
+
+	// ErrPostNotPublished is returned when a post that has not been
+	// published anywhere is edited
+	ErrPostNotPublished = errors.New("post has not been published yet")
+)
+
+// errPostPublishing is reported for platform posts that a publish job is
+// uploading while the post is deleted
+const errPostPublishing = "post is being published on this platform; delete it again once it is published"
+
+// PostSyncService propagates edits and deletions of published posts to the
+// platforms they were published on
+type PostSyncService struct {
+	posts         PostRepository
+	socialService *socialsvc.Service
+	approvals     *ApprovalService
+}
+
+// NewPostSyncService creates a new post sync service
+func NewPostSyncService(posts PostRepository, socialService *socialsvc.Service, approvals *ApprovalService) *PostSyncService {
+	return &PostSyncService{
+		posts:         posts,
+		socialService: socialService,
+		approvals:     approvals,
+	}
+}
+
+// Update edits a published post on every platform it was published on.
+// The edit is checked against each platform's content rules first and
+// nothing changes if it breaks one. Fields a platform cannot change are
+// reported in its result and keep their published value. In workspaces
+// that require approval, the edit is added to the review trail of the post.
+func (s *PostSyncService) Update(ctx context.Context, postID, userID string, update *socialdomain.PostUpdate) (*socialdomain.ScheduledPost, []socialdomain.PostSyncResult, error) {
+	if len(update.Fields()) == 0 {
+		return nil, nil, ErrNothingToUpdate
+	}
+
+	post, err := s.getPost(ctx, postID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	edits := make([]socialdomain.PlatformPost, len(post.Platforms))
+	var errs []PostFieldError
+	published := false
+	for i, platformPost := range post.Platforms {
+		if !isLive(&platformPost) {
+			continue
+		}
+		published = true
+		edits[i] = platformPost
+		if update.Title != nil {
+			edits[i].CustomTitle = *update.Title
+		}
+		if update.Description != nil {
+			edits[i].CustomDesc = *update.Description
+		}
+		if update.Privacy != nil {
+			edits[i].Privacy = *update.Privacy
+		}
+		for _, fieldErr := range composePlatformPost(post, &edits[i]) {
+			// The video itself does not change
+			if fieldErr.Field != "video" {
+				errs = append(errs, fieldErr)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, &PostValidationError{Errors: errs}
+	}
+	if !published {
+		return nil, nil, ErrPostNotPublished
+	}
+
+	if err := s.approvals.RecordEdit(ctx, post, userID, update); err != nil {
+		return nil, nil, err
+	}
+
+	if update.Title != nil {
+		post.Title = *update.Title
+	}
+	if update.Description != nil {
+		post.Description = *update.Description
+	}
+
+	results := make([]socialdomain.PostSyncResult, 0, len(post.Platforms))
+	for i := range post.Platforms {
+		platformPost := &post.Platforms[i]
+		result := syncResult(platformPost)
+		if !isLive(platformPost) {
+			result.Status = socialdomain.SyncStatusSkipped
+			result.Error = fmt.Sprintf("post is %s on this platform", platformPost.Status)
+			results = append(results, result)
+			continue
+		}
+
+		// Send each platform its own mapped values
+		edit := &edits[i]
+		platformUpdate := &socialdomain.PostUpdate{}
+		if update.Title != nil {
+			platformUpdate.Title = &edit.CustomTitle
+		}
+		if update.Description != nil {
+			platformUpdate.Description = &edit.CustomDesc
+		}
+		if update.Privacy != nil {
+			platformUpdate.Privacy = &edit.Privacy
+		}
+
+		unsupported, err := s.socialService.UpdatePost(ctx, platformPost.AccountID, platformPost.PlatformPostID, platformUpdate)
+		result.Unsupported = unsupported
+		switch {
+		case errors.Is(err, socialsvc.ErrUnsupported):
+			result.Status = socialdomain.SyncStatusUnsupported
+		case err != nil:
+			result.Status = socialdomain.SyncStatusFailed
+			result.Error = err.Error()
+		default:
+			result.Status = socialdomain.SyncStatusUpdated
+			applyUpdate(platformPost, edit, platformUpdate.Without(unsupported))
+			if err := s.posts.UpdatePlatformPost(ctx, platformPost); err != nil {
+				return nil, nil, fmt.Errorf("failed to save platform post: %w", err)
+			}
+		}
+		results = append(results, result)
+	}
+
+	// Only what the edit changed is written, as publish jobs may be
+	// updating the other platform posts
+	if err := s.posts.UpdateContent(ctx, post); err != nil {
+		return nil, nil, fmt.Errorf("failed to save post: %w", err)
+	}
+
+	return post, results, nil
+}
+
+// Delete removes a post from every platform it was published on and
+// cancels the platform posts that were not published yet. The post is
+// deleted once nothing of it remains live. Platform posts are cancelled
+// only if their status has not changed since they were loaded, so a
+// publish job uploading one meanwhile is not overwritten.
+func (s *PostSyncService) Delete(ctx context.Context, postID, userID string) (*socialdomain.ScheduledPost, []socialdomain.PostSyncResult, error) {
+	post, err := s.getPost(ctx, postID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]socialdomain.PostSyncResult, 0, len(post.Platforms))
+	live, deleted := 0, 0
+	for i := range post.Platforms {
+		platformPost := &post.Platforms[i]
+		result := syncResult(platformPost)
+
+		switch {
+		case isLive(platformPost):
+			err := s.socialService.DeletePost(ctx, platformPost.AccountID, platformPost.PlatformPostID)
+			switch {
+			case errors.Is(err, socialsvc.ErrUnsupported):
+				result.Status = socialdomain.SyncStatusUnsupported
+				live++
+			case err != nil:
+				result.Status = socialdomain.SyncStatusFailed
+				result.Error = err.Error()
+				live++
+			default:
+				if _, err := s.posts.SwapPlatformPostStatus(ctx, platformPost.ID, platformPost.Status, socialdomain.PostStatusDeleted); err != nil {
+					return nil, nil, fmt.Errorf("failed to save platform post: %w", err)
+				}
+				result.Status = socialdomain.SyncStatusDeleted
+				platformPost.Status = socialdomain.PostStatusDeleted
+				deleted++
+			}
+		case isWithdrawn(platformPost.Status):
+			result.Status = socialdomain.SyncStatusSkipped
+		case platformPost.Status == socialdomain.PostStatusPublishing &&
+			time.Since(platformPost.UpdatedAt) < publishAttemptTimeout:
+			result.Status = socialdomain.SyncStatusFailed
+			result.Error = errPostPublishing
+			live++
+		default:
+			cancelled, err := s.posts.SwapPlatformPostStatus(ctx, platformPost.ID, platformPost.Status, socialdomain.PostStatusCancelled)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to save platform post: %w", err)
+			}
+			if !cancelled {
+				// A publish job claimed it after it was loaded
+				result.Status = socialdomain.SyncStatusFailed
+				result.Error = errPostPublishing
+				live++
+				break
+			}
+			result.Status = socialdomain.SyncStatusCancelled
+			platformPost.Status = socialdomain.PostStatusCancelled
+		}
+		results = append(results, result)
+	}
+
+	if live == 0 {
+		post.Status = socialdomain.PostStatusCancelled
+		if deleted > 0 {
+			post.Status = socialdomain.PostStatusDeleted
+		}
+		if err := s.posts.UpdateStatus(ctx, post.ID, post.Status, ""); err != nil {
+			return nil, nil, fmt.Errorf("failed to save post: %w", err)
+		}
+	}
+
+	return post, results, nil
+}
+
+// getPost loads a post owned by the user
+func (s *PostSyncService) getPost(ctx context.Context, postID, userID string) (*socialdomain.ScheduledPost, error) {
+	post, err := s.posts.GetByID(ctx, postID)
+	if err != nil || post.UserID != userID {
+		return nil, ErrPostNotFound
+	}
+	return post, nil
+}
+
+// isLive reports whether a platform post is published on its platform
+func isLive(platformPost *socialdomain.PlatformPost) bool {
+	return platformPost.Status == socialdomain.PostStatusPublished && platformPost.PlatformPostID != ""
+}
+
+// syncResult starts the sync result of a platform post
+func syncResult(platformPost *socialdomain.PlatformPost) socialdomain.PostSyncResult {
+	return socialdomain.PostSyncResult{
+		PlatformPostID: platformPost.PlatformPostID,
+		Platform:       platformPost.Platform,
+		AccountID:      platformPost.AccountID,
+	}
+}
+
+// applyUpdate records the fields a platform accepted on its platform post
+func applyUpdate(platformPost, edit *socialdomain.PlatformPost, update *socialdomain.PostUpdate) {
+	if update.Title != nil {
+		platformPost.CustomTitle = edit.CustomTitle
+	}
+	if update.Description != nil {
+		platformPost.CustomDesc = edit.CustomDesc
+	}
+	if update.Privacy != nil {
+		platformPost.Privacy = edit.Privacy
+	}
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	socialdomain "renderowl-api/internal/domain/social"
+	"renderowl-api/internal/repository"
+)
+
+const (
+	// postingHistoryDays is how far back posting history is mined
+	postingHistoryDays = 90
+
+	// hoursPerWeek is the number of hour-of-week slots
+	hoursPerWeek = 7 * 24
+
+	// postPriorWeight is the number of average posts each slot is assumed
+	// to have, so a single lucky post does not dominate
+	postPriorWeight = 2.0
+
+	// autoScheduleLead is the minimum time between scheduling a post with
+	// "auto" and its publish time
+	autoScheduleLead = 15 * time.Minute
+
+	// autoScheduleTolerance picks the soonest slot scoring within this
+	// share of the best one
+	autoScheduleTolerance = 0.9
+)
+
+// PostingTimeService recommends posting times from an account's own
+// publishing and audience history
+type PostingTimeService struct {
+	history *repository.PostingHistoryRepository
+}
+
+// NewPostingTimeService creates a new posting time service
+func NewPostingTimeService(history *repository.PostingHistoryRepository) *PostingTimeService {
+	return &PostingTimeService{history: history}
+}
+
+// PostingSlot is an hour of the week to post at
+type PostingSlot struct {
+	Weekday       string    `json:"weekday"`
+	Hour          int       `json:"hour"`
+	Score         float64   `json:"score"` // relative to an average slot, 1.0
+	Posts         int       `json:"posts"`
+	AvgEngagement float64   `json:"avgEngagement"` // engagement rate of posts in the slot, in percent
+	AudienceShare float64   `json:"audienceShare"` // share of views in the slot, in percent
+	NextAt        time.Time `json:"nextAt"`
+}
+
+// BestTimesResult contains posting time recommendations for an account
+type BestTimesResult struct {
+	AccountID     string        `json:"accountId"`
+	Platform      string        `json:"platform"`
+	Timezone      string        `json:"timezone"`
+	LookbackDays  int           `json:"lookbackDays"`
+	PostsAnalyzed int           `json:"postsAnalyzed"`
+	ViewsAnalyzed int64         `json:"viewsAnalyzed"`
+	Confidence    string        `json:"confidence"` // low, medium, high
+	Slots         []PostingSlot `json:"slots"`
+}
+
+// slotScores holds the hour-of-week statistics of an account, indexed by
+// weekday*24 + hour in the audience timezone
+type slotScores struct {
+	score      [hoursPerWeek]float64
+	posts      [hoursPerWeek]int
+	engagement [hoursPerWeek]float64
+	views      [hoursPerWeek]int64
+	totalPosts int
+	totalViews int64
+}
+
+// BestTimes returns the best hours of the week for an account to post,
+// in the audience timezone
+func (s *PostingTimeService) BestTimes(ctx context.Context, account *socialdomain.SocialAccount, timezone string, limit int) (*BestTimesResult, error) {
+	loc, err := audienceLocation(account, timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	scores, err := s.scoreSlots(ctx, account, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BestTimesResult{
+		AccountID:     account.ID,
+		Platform:      string(account.Platform),
+		Timezone:      loc.String(),
+		LookbackDays:  postingHistoryDays,
+		PostsAnalyzed: scores.totalPosts,
+		ViewsAnalyzed: scores.totalViews,
+		Confidence:    postingConfidence(scores),
+	}
+
+	now := time.Now()
+	for _, slot := range rankSlots(scores.score[:]) {
+		if len(result.Slots) >= limit {
+			break
+		}
+		weekday, hour := time.Weekday(slot/24), slot%24
+		share := 0.0
+		if scores.totalViews > 0 {
+			share = float64(scores.views[slot]) / float64(scores.totalViews) * 100
+		}
+		result.Slots = append(result.Slots, PostingSlot{
+			Weekday:       weekday.String(),
+			Hour:          hour,
+			Score:         math.Round(scores.score[slot]*100) / 100,
+			Posts:         scores.posts[slot],
+			AvgEngagement: math.Round(scores.engagement[slot]*100) / 100,
+			AudienceShare: math.Round(share*100) / 100,
+			NextAt:        nextSlotTime(now, weekday, hour, loc),
+		})
+	}
+
+	return result, nil
+}
+
+// NextBestTime picks the time to publish a post to a set of accounts: the
+// soonest upcoming slot that scores close to the best one across all of
+// them
+func (s *PostingTimeService) NextBestTime(ctx context.Context, accounts []*socialdomain.SocialAccount, timezone string) (time.Time, error) {
+	if len(accounts) == 0 {
+		return time.Time{}, fmt.Errorf("no accounts to schedule for")
+	}
+
+	loc, err := audienceLocation(accounts[0], timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var combined [hoursPerWeek]float64
+	for _, account := range accounts {
+		scores, err := s.scoreSlots(ctx, account, loc)
+		if err != nil {
+			return time.Time{}, err
+		}
+		for i := range combined {
+			combined[i] += scores.score[i] / float64(len(accounts))
+		}
+	}
+
+	ranked := rankSlots(combined[:])
+	best := combined[ranked[0]]
+
+	earliest := time.Now().Add(autoScheduleLead)
+	var next time.Time
+	for _, slot := range ranked {
+		if combined[slot] < best*autoScheduleTolerance {
+			break
+		}
+		at := nextSlotTime(earliest, time.Weekday(slot/24), slot%24, loc)
+		if next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+
+	return next, nil
+}
+
+// scoreSlots scores each hour of the week for an account. Slots are
+// scored by how the account's posts published in them performed and by
+// when its audience watches, each relative to an average slot. Hours next
+// to each other are smoothed so sparse history still gives useful slots.
+func (s *PostingTimeService) scoreSlots(ctx context.Context, account *socialdomain.SocialAccount, loc *time.Location) (*slotScores, error) {
+	since := time.Now().AddDate(0, 0, -postingHistoryDays)
+
+	posts, err := s.history.GetPublishedPostStats(ctx, account.ID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load post history: %w", err)
+	}
+	views, err := s.history.GetHourlyViews(ctx, account.UserID, string(account.Platform), since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load view history: %w", err)
+	}
+
+	scores := &slotScores{totalPosts: len(posts)}
+
+	// Post performance: reach plus weighted interactions, relative to the
+	// account's average post
+	var postScore [hoursPerWeek]float64
+	if len(posts) > 0 {
+		performance := make([]float64, len(posts))
+		var total float64
+		for i, post := range posts {
+			performance[i] = float64(post.Views + 5*(post.Likes+post.Comments+post.Shares))
+			total += performance[i]
+		}
+		mean := total / float64(len(posts))
+
+		var sums [hoursPerWeek]float64
+		for i, post := range posts {
+			slot := hourOfWeek(post.PublishedAt.In(loc))
+			relative := 1.0
+			if mean > 0 {
+				relative = performance[i] / mean
+			}
+			sums[slot] += relative
+			scores.posts[slot]++
+			if post.Views > 0 {
+				scores.engagement[slot] += float64(post.Likes+post.Comments+post.Shares) / float64(post.Views) * 100
+			}
+		}
+		for slot := range postScore {
+			n := float64(scores.posts[slot])
+			postScore[slot] = (sums[slot] + postPriorWeight) / (n + postPriorWeight)
+			if n > 0 {
+				scores.engagement[slot] /= n
+			}
+		}
+	}
+
+	// Audience activity: views in the slot relative to an even spread
+	var activity [hoursPerWeek]float64
+	for _, v := range views {
+		slot := hourOfWeek(v.Hour.In(loc))
+		scores.views[slot] += v.Views
+		scores.totalViews += v.Views
+	}
+	if scores.totalViews > 0 {
+		perSlot := float64(scores.totalViews) / hoursPerWeek
+		for slot := range activity {
+			activity[slot] = float64(scores.views[slot]) / perSlot
+		}
+	}
+
+	var raw [hoursPerWeek]float64
+	switch {
+	case len(posts) > 0 && scores.totalViews > 0:
+		for slot := range raw {
+			raw[slot] = 0.6*postScore[slot] + 0.4*activity[slot]
+		}
+	case len(posts) > 0:
+		raw = postScore
+	case scores.totalViews > 0:
+		raw = activity
+	default:
+		raw = defaultSlotScores()
+	}
+
+	for slot := range raw {
+		prev := raw[(slot+hoursPerWeek-1)%hoursPerWeek]
+		next := raw[(slot+1)%hoursPerWeek]
+		scores.score[slot] = 0.5*raw[slot] + 0.25*prev + 0.25*next
+	}
+
+	return scores, nil
+}
+
+// defaultSlotScores is used for accounts without history: weekday lunch
+// breaks and evenings, and weekend late mornings
+func defaultSlotScores() [hoursPerWeek]float64 {
+	var scores [hoursPerWeek]float64
+	for slot := range scores {
+		weekday, hour := time.Weekday(slot/24), slot%24
+		weekend := weekday == time.Saturday || weekday == time.Sunday
+		switch {
+		case hour < 7 || hour >= 23:
+			scores[slot] = 0.3
+		case !weekend && (hour == 12 || hour == 13):
+			scores[slot] = 1.5
+		case !weekend && hour >= 17 && hour <= 20:
+			scores[slot] = 1.6
+		case weekend && hour >= 10 && hour <= 13:
+			scores[slot] = 1.4
+		default:
+			scores[slot] = 1.0
+		}
+	}
+	return scores
+}
+
+// audienceLocation resolves the timezone to analyze an account in: the
+// requested one, the account's audience timezone, or UTC
+func audienceLocation(account *socialdomain.SocialAccount, timezone string) (*time.Location, error) {
+	if timezone == "" {
+		timezone, _ = account.Metadata["timezone"].(string)
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q", timezone)
+	}
+	return loc, nil
+}
+
+func postingConfidence(scores *slotScores) string {
+	switch {
+	case scores.totalPosts >= 30 || (scores.totalPosts >= 10 && scores.totalViews >= 10000):
+		return "high"
+	case scores.totalPosts >= 10 || scores.totalViews >= 1000:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// rankSlots returns slot indexes ordered from the highest score
+func rankSlots(scores []float64) []int {
+	slots := make([]int, len(scores))
+	for i := range slots {
+		slots[i] = i
+	}
+	sort.SliceStable(slots, func(i, j int) bool {
+		return scores[slots[i]] > scores[slots[j]]
+	})
+	return slots
+}
+
+func hourOfWeek(t time.Time) int {
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// nextSlotTime returns the first time at the start of an hour of the week
+// that is not before a time, on the wall clock of a location
+func nextSlotTime(after time.Time, weekday time.Weekday, hour int, loc *time.Location) time.Time {
+	local := after.In(loc)
+	days := (int(weekday) - int(local.Weekday()) + 7) % 7
+	at := time.Date(local.Year(), local.Month(), local.Day()+days, hour, 0, 0, 0, loc)
+	if at.Before(after) {
+		at = time.Date(local.Year(), local.Month(), local.Day()+days+7, hour, 0, 0, 0, loc)
+	}
+	return at
+}
@@ -0,0 +1,419 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	socialdomain "renderowl-api/internal/domain/social"
+)
+
+// PlatformQuota describes the publishing limits of a platform. Zero
+// values mean no limit.
+type PlatformQuota struct {
+	// DailyUnits is the API quota shared by all accounts per day, and
+	// UnitsPerPublish what one publish costs against it
+	DailyUnits      int64
+	UnitsPerPublish int64
+
+	// AccountDailyPosts is how many posts one account may publish per day
+	AccountDailyPosts int64
+
+	// Token buckets smoothing bursts, for the whole platform and per
+	// account: up to Burst publishes at once, then one per Refill
+	PlatformBurst  int64
+	PlatformRefill time.Duration
+	AccountBurst   int64
+	AccountRefill  time.Duration
+
+	// ResetTimezone is where midnight resets the daily quotas
+	ResetTimezone string
+}
+
+// PlatformQuotas holds the publishing limits of each platform
+var PlatformQuotas = map[socialdomain.SocialPlatform]PlatformQuota{
+	socialdomain.PlatformYouTube: {
+		// Data API projects get 10,000 units a day; videos.insert costs 1,600
+		DailyUnits:      10000,
+		UnitsPerPublish: 1600,
+		PlatformBurst:   3,
+		PlatformRefill:  time.Minute,
+		ResetTimezone:   "America/Los_Angeles",
+	},
+	socialdomain.PlatformTikTok: {
+		// Creators can post about 15 times a day; init requests are limited to 6 a minute
+		AccountDailyPosts: 15,
+		AccountBurst:      6,
+		AccountRefill:     10 * time.Second,
+		ResetTimezone:     "UTC",
+	},
+	socialdomain.PlatformInstagram: {
+		// Content publishing is limited to 50 posts per account a day
+		AccountDailyPosts: 50,
+		AccountBurst:      5,
+		AccountRefill:     time.Minute,
+		ResetTimezone:     "UTC",
+	},
+	socialdomain.PlatformTwitter: {
+		// Media uploads and tweets are limited per user
+		AccountDailyPosts: 100,
+		AccountBurst:      5,
+		AccountRefill:     3 * time.Minute,
+		ResetTimezone:     "UTC",
+	},
+	socialdomain.PlatformLinkedIn: {
+		// Members can share 150 times a day
+		AccountDailyPosts: 150,
+		AccountBurst:      10,
+		AccountRefill:     time.Minute,
+		ResetTimezone:     "UTC",
+	},
+	socialdomain.PlatformFacebook: {
+		AccountDailyPosts: 100,
+		AccountBurst:      10,
+		AccountRefill:     time.Minute,
+		ResetTimezone:     "UTC",
+	},
+	socialdomain.PlatformPinterest: {
+		// Pin creation is limited per user token
+		AccountDailyPosts: 100,
+		AccountBurst:      10,
+		AccountRefill:     time.Minute,
+		ResetTimezone:     "UTC",
+	},
+	socialdomain.PlatformThreads: {
+		// Profiles can publish 250 posts in a rolling day
+		AccountDailyPosts: 250,
+		AccountBurst:      5,
+		AccountRefill:     time.Minute,
+		ResetTimezone:     "UTC",
+	},
+	socialdomain.PlatformSnapchat: {
+		// Spotlight favours a few posts a day per profile
+		AccountDailyPosts: 25,
+		AccountBurst:      3,
+		AccountRefill:     5 * time.Minute,
+		ResetTimezone:     "UTC",
+	},
+	socialdomain.PlatformBluesky: {
+		// The video service allows about 25 videos a day per account
+		AccountDailyPosts: 25,
+		AccountBurst:      5,
+		AccountRefill:     time.Minute,
+		ResetTimezone:     "UTC",
+	},
+}
+
+// quotaScript atomically refills both token buckets, checks the daily
+// quotas and, when everything allows it, takes one publish from each.
+// It returns {0} when allowed, {1} or {2} when the account or platform
+// daily quota is used up, and {3, wait_ms} when a bucket is empty.
+var quotaScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+
+local function bucket(key, burst, refill)
+	if burst <= 0 then
+		return 0, 0
+	end
+	local state = redis.call('HMGET', key, 'tokens', 'ts')
+	local tokens = tonumber(state[1]) or burst
+	local ts = tonumber(state[2]) or now
+	tokens = math.min(burst, tokens + math.max(0, now - ts) / refill)
+	if tokens < 1 then
+		return tokens, math.ceil((1 - tokens) * refill)
+	end
+	return tokens, 0
+end
+
+local accountBurst, accountRefill = tonumber(ARGV[2]), tonumber(ARGV[3])
+local platformBurst, platformRefill = tonumber(ARGV[4]), tonumber(ARGV[5])
+local accountLimit, platformLimit, cost = tonumber(ARGV[6]), tonumber(ARGV[7]), tonumber(ARGV[8])
+
+if accountLimit > 0 and (tonumber(redis.call('GET', KEYS[3])) or 0) + 1 > accountLimit then
+	return {1, 0}
+end
+if platformLimit > 0 and (tonumber(redis.call('GET', KEYS[4])) or 0) + cost > platformLimit then
+	return {2, 0}
+end
+
+local accountTokens, accountWait = bucket(KEYS[1], accountBurst, accountRefill)
+local platformTokens, platformWait = bucket(KEYS[2], platformBurst, platformRefill)
+local wait = math.max(accountWait, platformWait)
+if wait > 0 then
+	return {3, wait}
+end
+
+if accountBurst > 0 then
+	redis.call('HSET', KEYS[1], 'tokens', tostring(accountTokens - 1), 'ts', now)
+	redis.call('PEXPIRE', KEYS[1], accountBurst * accountRefill)
+end
+if platformBurst > 0 then
+	redis.call('HSET', KEYS[2], 'tokens', tostring(platformTokens - 1), 'ts', now)
+	redis.call('PEXPIRE', KEYS[2], platformBurst * platformRefill)
+end
+if accountLimit > 0 then
+	redis.call('INCR', KEYS[3])
+	redis.call('EXPIRE', KEYS[3], ARGV[9])
+end
+if platformLimit > 0 then
+	redis.call('INCRBY', KEYS[4], cost)
+	redis.call('EXPIRE', KEYS[4], ARGV[9])
+end
+return {0, 0}
+`)
+
+// refundScript gives back a publish taken by quotaScript, without going
+// over the burst of a bucket or below zero on a daily counter
+var refundScript = redis.NewScript(`
+local function give(key, burst)
+	if burst <= 0 then
+		return
+	end
+	local tokens = tonumber(redis.call('HGET', key, 'tokens'))
+	if tokens then
+		redis.call('HSET', key, 'tokens', tostring(math.min(burst, tokens + 1)))
+	end
+end
+
+local function release(key, n)
+	if n <= 0 then
+		return
+	end
+	local used = tonumber(redis.call('GET', key)) or 0
+	redis.call('DECRBY', key, math.min(used, n))
+end
+
+give(KEYS[1], tonumber(ARGV[1]))
+give(KEYS[2], tonumber(ARGV[2]))
+release(KEYS[3], tonumber(ARGV[3]))
+release(KEYS[4], tonumber(ARGV[4]))
+return 0
+`)
+
+// dailyQuotaTTL keeps daily counters a little longer than a day
+const dailyQuotaTTL = 48 * time.Hour
+
+// quotaResetJitter spreads publishes deferred to a daily quota reset over
+// the start of the new day, so they do not all run at midnight
+const quotaResetJitter = 15 * time.Minute
+
+// PublishQuota enforces platform rate limits and daily quotas on publishes.
+// Its state is kept in Redis so it holds across API instances.
+type PublishQuota struct {
+	client *redis.Client
+	quotas map[socialdomain.SocialPlatform]PlatformQuota
+}
+
+// QuotaUsage reports how much of its quotas an account has left
+type QuotaUsage struct {
+	AccountID              string    `json:"accountId"`
+	Platform               string    `json:"platform"`
+	AccountDailyPosts      int64     `json:"accountDailyPosts,omitempty"`
+	AccountPostsRemaining  int64     `json:"accountPostsRemaining,omitempty"`
+	PlatformDailyUnits     int64     `json:"platformDailyUnits,omitempty"`
+	PlatformUnitsRemaining int64     `json:"platformUnitsRemaining,omitempty"`
+	PublishesRemaining     int64     `json:"publishesRemaining"` // -1 when unlimited
+	AvailableNow           bool      `json:"availableNow"`
+	ResetsAt               time.Time `json:"resetsAt"`
+}
+
+// NewPublishQuota creates a quota store backed by Redis
+func NewPublishQuota(redisAddr, redisPassword string) *PublishQuota {
+	client := redis.NewClient(&redis.Options{
+		Addr:     redisAddr,
+		Password: redisPassword,
+		DB:       0,
+	})
+
+	return &PublishQuota{
+		client: client,
+		quotas: PlatformQuotas,
+	}
+}
+
+// Take uses one publish of an account's quotas. It returns how long to
+// wait instead when a limit has been reached. Waits for a daily quota to
+// reset get up to quotaResetJitter added.
+func (q *PublishQuota) Take(ctx context.Context, platform socialdomain.SocialPlatform, accountID string) (time.Duration, error) {
+	quota, ok := q.quotas[platform]
+	if !ok {
+		return 0, nil
+	}
+
+	now := time.Now()
+	day, resetsAt := quotaDay(quota, now)
+
+	result, err := quotaScript.Run(ctx, q.client,
+		quotaKeys(platform, accountID, day),
+		now.UnixMilli(),
+		quota.AccountBurst, quota.AccountRefill.Milliseconds(),
+		quota.PlatformBurst, quota.PlatformRefill.Milliseconds(),
+		quota.AccountDailyPosts, quota.DailyUnits, quota.UnitsPerPublish,
+		int64(dailyQuotaTTL.Seconds()),
+	).Int64Slice()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check quota: %w", err)
+	}
+
+	switch result[0] {
+	case 0:
+		return 0, nil
+	case 1, 2:
+		return time.Until(resetsAt) + time.Duration(rand.Int63n(int64(quotaResetJitter))), nil
+	default:
+		return time.Duration(result[1]) * time.Millisecond, nil
+	}
+}
+
+// Refund gives back a publish taken by Take that did not reach the
+// platform
+func (q *PublishQuota) Refund(ctx context.Context, platform socialdomain.SocialPlatform, accountID string) error {
+	quota, ok := q.quotas[platform]
+	if !ok {
+		return nil
+	}
+
+	day, _ := quotaDay(quota, time.Now())
+	var accountPosts, platformUnits int64
+	if quota.AccountDailyPosts > 0 {
+		accountPosts = 1
+	}
+	if quota.DailyUnits > 0 {
+		platformUnits = quota.UnitsPerPublish
+	}
+
+	err := refundScript.Run(ctx, q.client,
+		quotaKeys(platform, accountID, day),
+		quota.AccountBurst, quota.PlatformBurst, accountPosts, platformUnits,
+	).Err()
+	if err != nil {
+		return fmt.Errorf("failed to refund quota: %w", err)
+	}
+	return nil
+}
+
+// Usage reports the remaining quota of an account
+func (q *PublishQuota) Usage(ctx context.Context, account *socialdomain.SocialAccount) (*QuotaUsage, error) {
+	quota := q.quotas[account.Platform]
+	now := time.Now()
+	day, resetsAt := quotaDay(quota, now)
+
+	usage := &QuotaUsage{
+		AccountID:          account.ID,
+		Platform:           string(account.Platform),
+		AccountDailyPosts:  quota.AccountDailyPosts,
+		PlatformDailyUnits: quota.DailyUnits,
+		PublishesRemaining: -1,
+		AvailableNow:       true,
+		ResetsAt:           resetsAt,
+	}
+
+	if quota.AccountDailyPosts > 0 {
+		used, err := q.counter(ctx, fmt.Sprintf("quota:daily:%s:%s:%s", account.Platform, account.ID, day))
+		if err != nil {
+			return nil, err
+		}
+		usage.AccountPostsRemaining = max(quota.AccountDailyPosts-used, 0)
+		usage.PublishesRemaining = usage.AccountPostsRemaining
+	}
+
+	if quota.DailyUnits > 0 {
+		used, err := q.counter(ctx, fmt.Sprintf("quota:daily:%s:%s", account.Platform, day))
+		if err != nil {
+			return nil, err
+		}
+		usage.PlatformUnitsRemaining = max(quota.DailyUnits-used, 0)
+		if quota.UnitsPerPublish > 0 {
+			publishes := usage.PlatformUnitsRemaining / quota.UnitsPerPublish
+			if usage.PublishesRemaining < 0 || publishes < usage.PublishesRemaining {
+				usage.PublishesRemaining = publishes
+			}
+		}
+	}
+
+	for _, b := range []struct {
+		key    string
+		burst  int64
+		refill time.Duration
+	}{
+		{fmt.Sprintf("quota:bucket:%s:%s", account.Platform, account.ID), quota.AccountBurst, quota.AccountRefill},
+		{fmt.Sprintf("quota:bucket:%s", account.Platform), quota.PlatformBurst, quota.PlatformRefill},
+	} {
+		if b.burst <= 0 {
+			continue
+		}
+		tokens, err := q.tokens(ctx, b.key, b.burst, b.refill, now)
+		if err != nil {
+			return nil, err
+		}
+		if tokens < 1 {
+			usage.AvailableNow = false
+		}
+	}
+	if usage.PublishesRemaining == 0 {
+		usage.AvailableNow = false
+	}
+
+	return usage, nil
+}
+
+// Close closes the Redis connection
+func (q *PublishQuota) Close() error {
+	return q.client.Close()
+}
+
+func (q *PublishQuota) counter(ctx context.Context, key string) (int64, error) {
+	used, err := q.client.Get(ctx, key).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read quota: %w", err)
+	}
+	return used, nil
+}
+
+// tokens returns the current tokens of a bucket, refilled to now
+func (q *PublishQuota) tokens(ctx context.Context, key string, burst int64, refill time.Duration, now time.Time) (float64, error) {
+	state, err := q.client.HMGet(ctx, key, "tokens", "ts").Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read quota: %w", err)
+	}
+
+	tokens, ts := float64(burst), now.UnixMilli()
+	if s, ok := state[0].(string); ok {
+		tokens, _ = strconv.ParseFloat(s, 64)
+	}
+	if s, ok := state[1].(string); ok {
+		ts, _ = strconv.ParseInt(s, 10, 64)
+	}
+
+	elapsed := float64(max(now.UnixMilli()-ts, 0))
+	return math.Min(float64(burst), tokens+elapsed/float64(refill.Milliseconds())), nil
+}
+
+// quotaKeys returns the keys of the account and platform buckets and daily
+// counters
+func quotaKeys(platform socialdomain.SocialPlatform, accountID, day string) []string {
+	return []string{
+		fmt.Sprintf("quota:bucket:%s:%s", platform, accountID),
+		fmt.Sprintf("quota:bucket:%s", platform),
+		fmt.Sprintf("quota:daily:%s:%s:%s", platform, accountID, day),
+		fmt.Sprintf("quota:daily:%s:%s", platform, day),
+	}
+}
+
+// quotaDay returns the day a daily quota is counted on and when it resets
+func quotaDay(quota PlatformQuota, now time.Time) (string, time.Time) {
+	loc, err := time.LoadLocation(quota.ResetTimezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	resetsAt := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
+	return local.Format("2006-01-02"), resetsAt
+}
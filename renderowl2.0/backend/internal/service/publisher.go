@@ -3,10 +3,14 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
+	"renderowl-api/internal/domain"
 	socialdomain "renderowl-api/internal/domain/social"
 	"renderowl-api/internal/scheduler"
 	socialsvc "renderowl-api/internal/service/social"
@@ -20,25 +24,100 @@ type PostRepository interface {
 	GetPending(ctx context.Context, before string) ([]*socialdomain.ScheduledPost, error)
 	Update(ctx context.Context, post *socialdomain.ScheduledPost) error
 	UpdateStatus(ctx context.Context, id string, status socialdomain.PostStatus, errorMsg string) error
+	UpdatePlatformPost(ctx context.Context, platformPost *socialdomain.PlatformPost) error
+	UpdateContent(ctx context.Context, post *socialdomain.ScheduledPost) error
+	SwapPlatformPostStatus(ctx context.Context, id string, from, to socialdomain.PostStatus) (bool, error)
+	AdvanceStatus(ctx context.Context, id string, status socialdomain.PostStatus, errorMsg string) error
+	MarkPublished(ctx context.Context, id string, publishedAt time.Time) error
 	Delete(ctx context.Context, id string) error
 }
 
+// PublishAttemptRepository defines publish ledger operations
+type PublishAttemptRepository interface {
+	Begin(ctx context.Context, attempt *socialdomain.PublishAttempt) (bool, error)
+	Finish(ctx context.Context, attempt *socialdomain.PublishAttempt) error
+	GetLatest(ctx context.Context, idempotencyKey string) (*socialdomain.PublishAttempt, error)
+	GetSucceeded(ctx context.Context, idempotencyKey string) (*socialdomain.PublishAttempt, error)
+}
+
+// ApprovalChecker reports whether posts of a workspace must be approved
+// before they are published
+type ApprovalChecker interface {
+	RequiresApproval(ctx context.Context, userID string) (bool, error)
+}
+
+// PublishLimiter charges publishes against the rate limits and daily
+// quotas of platforms
+type PublishLimiter interface {
+	Take(ctx context.Context, platform socialdomain.SocialPlatform, accountID string) (time.Duration, error)
+	Refund(ctx context.Context, platform socialdomain.SocialPlatform, accountID string) error
+}
+
+// BatchVideoSource looks up the batch videos that calendar posts publish
+type BatchVideoSource interface {
+	GetVideo(id string) (*domain.BatchVideo, error)
+}
+
+// A post of a batch video waits for the video to render, checking every
+// batchVideoPollInterval, and fails if the video is not ready
+// batchVideoWaitLimit after its scheduled time
+const (
+	batchVideoPollInterval = 10 * time.Minute
+	batchVideoWaitLimit    = 24 * time.Hour
+)
+
+// publishAttemptTimeout is how long an attempt may stay in flight before
+// it is considered abandoned. It is longer than the scheduler job timeout.
+const publishAttemptTimeout = 15 * time.Minute
+
+// reconcileSlack widens the time searched for the post of an unknown
+// attempt, for clocks that differ between us and the platform
+const reconcileSlack = 5 * time.Minute
+
+// ErrPublishInProgress is returned when another attempt is already
+// publishing the same platform post
+var ErrPublishInProgress = errors.New("platform post is already being published")
+
+// ErrPublishNeedsReview is returned when an earlier attempt may have
+// published a platform post and the platform cannot tell whether it did
+var ErrPublishNeedsReview = errors.New("an earlier upload may have been published; check the account and retry the post if it is not there")
+
+// errPostWithdrawn is returned when a platform post is cancelled while a
+// publish job for it is running
+var errPostWithdrawn = errors.New("platform post was cancelled")
+
 // Publisher handles automatic publishing of scheduled content
 type Publisher struct {
 	socialService *socialsvc.Service
 	scheduler     *scheduler.Scheduler
 	postRepo      PostRepository
+	attempts      PublishAttemptRepository
+	approvals     ApprovalChecker
+	batchVideos   BatchVideoSource
+	quota         PublishLimiter
+}
+
+// RecurJobData contains data for a job creating the next occurrence of a
+// recurring post
+type RecurJobData struct {
+	PostID string `json:"postId"`
 }
 
 // PublishJobData contains data for a publish job
 type PublishJobData struct {
-	PostID      string   `json:"postId"`
-	AccountID   string   `json:"accountId"`
-	VideoPath   string   `json:"videoPath"`
-	Title       string   `json:"title"`
-	Description string   `json:"description"`
-	Tags        []string `json:"tags"`
-	Privacy     string   `json:"privacy"`
+	PostID         string   `json:"postId"`
+	PlatformPostID string   `json:"platformPostId"`
+	IdempotencyKey string   `json:"idempotencyKey"`
+	AccountID      string   `json:"accountId"`
+	Platform       string   `json:"platform"`
+	VideoPath      string   `json:"videoPath"`
+	VideoURL       string   `json:"videoUrl,omitempty"`
+	Title          string   `json:"title"`
+	Description    string   `json:"description"`
+	Tags           []string `json:"tags"`
+	Privacy        string   `json:"privacy"`
+	// Platform-specific upload options such as the YouTube categoryId
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 // NewPublisher creates a new publisher instance
@@ -46,11 +125,13 @@ func NewPublisher(
 	socialService *socialsvc.Service,
 	scheduler *scheduler.Scheduler,
 	postRepo PostRepository,
+	attempts PublishAttemptRepository,
 ) *Publisher {
 	return &Publisher{
 		socialService: socialService,
 		scheduler:     scheduler,
 		postRepo:      postRepo,
+		attempts:      attempts,
 	}
 }
 
@@ -59,26 +140,69 @@ func (p *Publisher) Initialize() {
 	// Register the publish handler
 	p.scheduler.RegisterHandler("publish", p.handlePublishJob)
 	p.scheduler.RegisterHandler("crosspost", p.handleCrossPostJob)
+	p.scheduler.RegisterHandler("recur", p.handleRecurJob)
+}
+
+// SetApprovals makes the publisher refuse unapproved posts of workspaces
+// that require approval
+func (p *Publisher) SetApprovals(approvals ApprovalChecker) {
+	p.approvals = approvals
+}
+
+// SetQuota makes the publisher charge uploads against platform quotas.
+// Uploads over a limit are deferred until it allows them.
+func (p *Publisher) SetQuota(quota PublishLimiter) {
+	p.quota = quota
+}
+
+// SetBatchVideos makes the publisher hold posts of batch videos until the
+// video has rendered
+func (p *Publisher) SetBatchVideos(batchVideos BatchVideoSource) {
+	p.batchVideos = batchVideos
 }
 
 // SchedulePublish schedules a video for publishing
 func (p *Publisher) SchedulePublish(ctx context.Context, post *socialdomain.ScheduledPost) error {
+	if err := p.checkApproval(ctx, post); err != nil {
+		return err
+	}
+
 	// Update post status
 	post.Status = socialdomain.PostStatusScheduled
+	if post.Recurring != nil && post.SeriesID == "" {
+		post.SeriesID = post.ID
+		post.Occurrence = 1
+	}
+	for i := range post.Platforms {
+		ensureIdempotencyKey(post, &post.Platforms[i])
+		if post.Platforms[i].Status != socialdomain.PostStatusPublished {
+			post.Platforms[i].Status = socialdomain.PostStatusScheduled
+		}
+	}
 	if err := p.postRepo.Update(ctx, post); err != nil {
 		return fmt.Errorf("failed to update post: %w", err)
 	}
 
 	// Schedule job for each platform
+	videoPath, _ := post.Metadata["videoPath"].(string)
 	for _, platformPost := range post.Platforms {
+		if platformPost.Status == socialdomain.PostStatusPublished {
+			continue
+		}
+
 		jobData := PublishJobData{
-			PostID:      post.ID,
-			AccountID:   platformPost.AccountID,
-			VideoPath:   post.Metadata["videoPath"].(string),
-			Title:       platformPost.CustomTitle,
-			Description: platformPost.CustomDesc,
-			Tags:        platformPost.Tags,
-			Privacy:     platformPost.Privacy,
+			PostID:         post.ID,
+			PlatformPostID: platformPost.ID,
+			IdempotencyKey: platformPost.IdempotencyKey,
+			AccountID:      platformPost.AccountID,
+			Platform:       string(platformPost.Platform),
+			VideoPath:      videoPath,
+			VideoURL:       videoURL(post),
+			Title:          platformPost.CustomTitle,
+			Description:    platformPost.CustomDesc,
+			Tags:           platformPost.Tags,
+			Privacy:        platformPost.Privacy,
+			Metadata:       uploadMetadata(platformPost.Metadata),
 		}
 
 		data, _ := json.Marshal(jobData)
@@ -95,27 +219,78 @@ func (p *Publisher) SchedulePublish(ctx context.Context, post *socialdomain.Sche
 		}
 	}
 
+	// Create the next occurrence of a recurring post when this one is due
+	if post.Recurring != nil {
+		data, _ := json.Marshal(RecurJobData{PostID: post.ID})
+		job := &scheduler.Job{
+			Name:       "recur",
+			Data:       data,
+			RunAt:      post.ScheduledAt,
+			MaxRetries: 3,
+		}
+		if err := p.scheduler.AddJob(ctx, job); err != nil {
+			return fmt.Errorf("failed to schedule next occurrence: %w", err)
+		}
+	}
+
 	return nil
 }
 
-// PublishNow immediately publishes a post
-func (p *Publisher) PublishNow(ctx context.Context, postID string) error {
-	post, err := p.postRepo.GetByID(ctx, postID)
+// UpcomingOccurrences returns the next occurrences of a recurring post
+func (p *Publisher) UpcomingOccurrences(ctx context.Context, post *socialdomain.ScheduledPost, limit int) ([]scheduler.Occurrence, error) {
+	if post.Recurring == nil {
+		return nil, fmt.Errorf("post is not recurring")
+	}
+
+	recurrence, err := p.recurrence(ctx, post)
 	if err != nil {
-		return fmt.Errorf("post not found: %w", err)
+		return nil, err
 	}
 
-	// Update status to publishing
-	post.Status = socialdomain.PostStatusPublishing
-	if err := p.postRepo.Update(ctx, post); err != nil {
+	after := time.Now()
+	if post.Status == socialdomain.PostStatusScheduled && post.ScheduledAt.Before(after) {
+		after = post.ScheduledAt.Add(-time.Second)
+	}
+	return recurrence.Upcoming(after, limit), nil
+}
+
+// PublishNow immediately publishes a post of a user. Cancelled and
+// deleted posts are refused.
+func (p *Publisher) PublishNow(ctx context.Context, userID, postID string) error {
+	post, err := p.postRepo.GetByID(ctx, postID)
+	if err != nil || post.UserID != userID {
+		return ErrReviewPostNotFound
+	}
+	if isWithdrawn(post.Status) {
+		return &ReviewStateError{Action: "publish", Status: post.Status}
+	}
+	if err := p.checkApproval(ctx, post); err != nil {
 		return err
 	}
 
-	// Publish to each platform
-	for _, platformPost := range post.Platforms {
-		go p.publishToPlatform(ctx, post, &platformPost)
+	// Only the status is written, so a cancellation made meanwhile is kept
+	if err := p.postRepo.AdvanceStatus(ctx, post.ID, socialdomain.PostStatusPublishing, ""); err != nil {
+		return err
 	}
 
+	// Publish to each platform in the background, outliving the request
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		var wg sync.WaitGroup
+		for i := range post.Platforms {
+			wg.Add(1)
+			go func(platformPost *socialdomain.PlatformPost) {
+				defer wg.Done()
+				p.publishToPlatform(ctx, post, platformPost)
+			}(&post.Platforms[i])
+		}
+		wg.Wait()
+
+		if err := p.finishPost(ctx, post.ID); err != nil {
+			log.Printf("Failed to update post %s: %v", post.ID, err)
+		}
+	}()
+
 	return nil
 }
 
@@ -136,17 +311,22 @@ func (p *Publisher) GetPublishingQueue(ctx context.Context, userID string) ([]*s
 	return p.postRepo.GetByUser(ctx, userID, 100, 0)
 }
 
-// RetryFailedPost retries a failed post
-func (p *Publisher) RetryFailedPost(ctx context.Context, postID string) error {
+// RetryFailedPost retries a failed post of a user
+func (p *Publisher) RetryFailedPost(ctx context.Context, userID, postID string) error {
 	post, err := p.postRepo.GetByID(ctx, postID)
-	if err != nil {
-		return err
+	if err != nil || post.UserID != userID {
+		return ErrReviewPostNotFound
 	}
 
 	if post.Status != socialdomain.PostStatusFailed {
 		return fmt.Errorf("post is not in failed status")
 	}
 
+	// Retrying confirms that uploads left for review did not publish
+	if err := p.resolveUnknown(ctx, post); err != nil {
+		return err
+	}
+
 	// Reset status and reschedule
 	post.Status = socialdomain.PostStatusScheduled
 	post.ErrorMsg = ""
@@ -166,55 +346,85 @@ func (p *Publisher) handlePublishJob(ctx context.Context, job *scheduler.Job) er
 		return fmt.Errorf("failed to unmarshal job data: %w", err)
 	}
 
-	// Update post status to publishing
-	if err := p.postRepo.UpdateStatus(ctx, data.PostID, socialdomain.PostStatusPublishing, ""); err != nil {
+	post, err := p.postRepo.GetByID(ctx, data.PostID)
+	if err != nil {
+		return fmt.Errorf("post not found: %w", err)
+	}
+
+	platformPost := findPlatformPost(post, &data)
+	if platformPost == nil {
+		return fmt.Errorf("post %s has no platform post for account %s", data.PostID, data.AccountID)
+	}
+
+	// Cancelled and deleted posts stay unpublished
+	if isWithdrawn(post.Status) || isWithdrawn(platformPost.Status) {
+		log.Printf("Skipping publish of post %s to account %s: post is %s", post.ID, platformPost.AccountID, post.Status)
+		return nil
+	}
+
+	// The post may have gone back to review after it was queued; it is
+	// queued again once approved
+	if err := p.checkApproval(ctx, post); err != nil {
+		if errors.Is(err, ErrApprovalRequired) {
+			log.Printf("Skipping publish of post %s: %v", post.ID, err)
+			return nil
+		}
 		return err
 	}
 
 	// Create upload request
 	req := &socialdomain.UploadRequest{
 		VideoPath:   data.VideoPath,
+		VideoURL:    data.VideoURL,
 		Title:       data.Title,
 		Description: data.Description,
 		Tags:        data.Tags,
 		Privacy:     data.Privacy,
+		Metadata:    data.Metadata,
 	}
 
-	// Upload to platform
-	resp, err := p.socialService.UploadVideo(ctx, data.AccountID, req)
-	if err != nil {
-		// Update post status to failed
-		p.postRepo.UpdateStatus(ctx, data.PostID, socialdomain.PostStatusFailed, err.Error())
-		return fmt.Errorf("upload failed: %w", err)
-	}
-
-	// Update platform post info
-	post, _ := p.postRepo.GetByID(ctx, data.PostID)
-	for i := range post.Platforms {
-		if post.Platforms[i].AccountID == data.AccountID {
-			post.Platforms[i].PlatformPostID = resp.PlatformPostID
-			post.Platforms[i].PostURL = resp.PostURL
-			post.Platforms[i].Status = socialdomain.PostStatusPublished
-			post.Platforms[i].PublishedAt = &[]time.Time{time.Now()}[0]
-			break
+	// Posts of batch videos wait for the video to render
+	if err := p.resolveBatchVideo(post, req); err != nil {
+		var deferred *scheduler.DeferError
+		if errors.As(err, &deferred) {
+			return err
 		}
+		log.Printf("Failed to publish post %s: %v", post.ID, err)
+		return p.postRepo.AdvanceStatus(ctx, data.PostID, socialdomain.PostStatusFailed, err.Error())
 	}
 
-	// Check if all platforms are published
-	allPublished := true
-	for _, pp := range post.Platforms {
-		if pp.Status != socialdomain.PostStatusPublished {
-			allPublished = false
-			break
-		}
+	// Update post status to publishing
+	if err := p.postRepo.AdvanceStatus(ctx, data.PostID, socialdomain.PostStatusPublishing, ""); err != nil {
+		return err
 	}
 
-	if allPublished {
-		post.Status = socialdomain.PostStatusPublished
-		post.PublishedAt = &[]time.Time{time.Now()}[0]
+	// Upload to platform
+	if err := p.publishPlatformPost(ctx, post, platformPost, req); err != nil {
+		if errors.Is(err, ErrPublishInProgress) {
+			// Let the scheduler retry once the other attempt has finished
+			return err
+		}
+		var deferred *scheduler.DeferError
+		if errors.As(err, &deferred) {
+			// Over a quota; the scheduler runs the job again once it allows it
+			p.postRepo.AdvanceStatus(ctx, data.PostID, socialdomain.PostStatusScheduled, "")
+			return err
+		}
+		if errors.Is(err, errPostWithdrawn) {
+			log.Printf("Skipping publish of post %s to account %s: %v", post.ID, platformPost.AccountID, err)
+			return nil
+		}
+		if errors.Is(err, ErrPublishNeedsReview) {
+			// Retrying cannot tell either; the user checks the account
+			log.Printf("Failed to publish post %s to account %s: %v", post.ID, platformPost.AccountID, err)
+			return p.postRepo.AdvanceStatus(ctx, data.PostID, socialdomain.PostStatusFailed, err.Error())
+		}
+		// Update post status to failed
+		p.postRepo.AdvanceStatus(ctx, data.PostID, socialdomain.PostStatusFailed, err.Error())
+		return fmt.Errorf("upload failed: %w", err)
 	}
 
-	return p.postRepo.Update(ctx, post)
+	return p.finishPost(ctx, data.PostID)
 }
 
 func (p *Publisher) handleCrossPostJob(ctx context.Context, job *scheduler.Job) error {
@@ -245,32 +455,529 @@ func (p *Publisher) handleCrossPostJob(ctx context.Context, job *scheduler.Job)
 	return err
 }
 
+func (p *Publisher) handleRecurJob(ctx context.Context, job *scheduler.Job) error {
+	var data RecurJobData
+	if err := json.Unmarshal(job.Data, &data); err != nil {
+		return fmt.Errorf("failed to unmarshal job data: %w", err)
+	}
+
+	post, err := p.postRepo.GetByID(ctx, data.PostID)
+	if err != nil {
+		return fmt.Errorf("post not found: %w", err)
+	}
+
+	// Cancelling the pending occurrence ends the series
+	if post.Status == socialdomain.PostStatusCancelled || post.Recurring == nil {
+		return nil
+	}
+
+	recurrence, err := p.recurrence(ctx, post)
+	if err != nil {
+		return err
+	}
+
+	occurrence, ok := recurrence.Next(post.ScheduledAt)
+	if !ok {
+		log.Printf("Recurring post %s has ended after %d occurrences", post.SeriesID, post.Occurrence)
+		return nil
+	}
+
+	// Occurrence IDs are derived from the series, so a retried job finds
+	// the occurrence it already created
+	nextID := occurrenceID(post.SeriesID, occurrence.Number)
+	if existing, err := p.postRepo.GetByID(ctx, nextID); err == nil && existing.ID == nextID {
+		return nil
+	}
+
+	next := &socialdomain.ScheduledPost{
+		ID:          nextID,
+		UserID:      post.UserID,
+		VideoID:     post.VideoID,
+		Title:       post.Title,
+		Description: post.Description,
+		ScheduledAt: occurrence.At,
+		Timezone:    post.Timezone,
+		Recurring:   post.Recurring,
+		SeriesID:    post.SeriesID,
+		Occurrence:  occurrence.Number,
+		// Occurrences share the content, and so the approval, of the series
+		ReviewerID: post.ReviewerID,
+		ApprovedBy: post.ApprovedBy,
+		ApprovedAt: post.ApprovedAt,
+		Metadata:   socialdomain.JSON{},
+	}
+	for key, value := range post.Metadata {
+		next.Metadata[key] = value
+	}
+	for i, platformPost := range post.Platforms {
+		next.Platforms = append(next.Platforms, socialdomain.PlatformPost{
+			ID:          occurrenceID(nextID, i),
+			Platform:    platformPost.Platform,
+			AccountID:   platformPost.AccountID,
+			CustomTitle: platformPost.CustomTitle,
+			CustomDesc:  platformPost.CustomDesc,
+			Tags:        platformPost.Tags,
+			Privacy:     platformPost.Privacy,
+			Metadata:    platformPost.Metadata,
+		})
+	}
+
+	if err := p.socialService.SchedulePost(ctx, next); err != nil {
+		return fmt.Errorf("failed to create occurrence %d of post %s: %w", occurrence.Number, post.SeriesID, err)
+	}
+
+	// A series started before its workspace required approval pauses at a
+	// draft occurrence and continues once that is approved
+	if err := p.SchedulePublish(ctx, next); err != nil {
+		if errors.Is(err, ErrApprovalRequired) {
+			return p.postRepo.UpdateStatus(ctx, next.ID, socialdomain.PostStatusDraft, err.Error())
+		}
+		return err
+	}
+	return nil
+}
+
 // Private methods
 
+// checkApproval refuses posts that have not been approved when their
+// workspace requires approval
+func (p *Publisher) checkApproval(ctx context.Context, post *socialdomain.ScheduledPost) error {
+	if p.approvals == nil || post.ApprovedAt != nil {
+		return nil
+	}
+	required, err := p.approvals.RequiresApproval(ctx, post.UserID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrApprovalRequired
+	}
+	return nil
+}
+
+// resolveBatchVideo sets the video of a batch video's post to upload. It
+// returns a scheduler.DeferError while the video is still rendering.
+func (p *Publisher) resolveBatchVideo(post *socialdomain.ScheduledPost, req *socialdomain.UploadRequest) error {
+	videoID, _ := post.Metadata["batchVideoId"].(string)
+	if videoID == "" || p.batchVideos == nil {
+		return nil
+	}
+
+	video, err := p.batchVideos.GetVideo(videoID)
+	if err != nil {
+		return fmt.Errorf("batch video %s not found: %w", videoID, err)
+	}
+
+	switch {
+	case video.Status == domain.VideoStatusCompleted:
+		if video.Result == nil || video.Result.VideoURL == "" {
+			return fmt.Errorf("video %q has no rendered file", video.Title)
+		}
+		req.VideoPath = video.Result.VideoURL
+		req.VideoURL = video.Result.VideoURL
+		return nil
+	case video.Status.IsTerminal():
+		return fmt.Errorf("video %q was not rendered: it is %s", video.Title, video.Status)
+	case time.Since(post.ScheduledAt) > batchVideoWaitLimit:
+		return fmt.Errorf("video %q was not rendered within %s of the scheduled time", video.Title, batchVideoWaitLimit)
+	}
+	return scheduler.Defer(batchVideoPollInterval, fmt.Sprintf("video %q is %s", video.Title, video.Status))
+}
+
+// recurrence returns the recurrence of a post's series, anchored at the
+// first post
+func (p *Publisher) recurrence(ctx context.Context, post *socialdomain.ScheduledPost) (*scheduler.Recurrence, error) {
+	first := post
+	if post.SeriesID != "" && post.SeriesID != post.ID {
+		var err error
+		first, err = p.postRepo.GetByID(ctx, post.SeriesID)
+		if err != nil {
+			return nil, fmt.Errorf("series %s not found: %w", post.SeriesID, err)
+		}
+	}
+
+	recurrence, err := scheduler.NewRecurrence(post.Recurring, first.ScheduledAt, post.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence: %w", err)
+	}
+	return recurrence, nil
+}
+
 func (p *Publisher) publishToPlatform(ctx context.Context, post *socialdomain.ScheduledPost, platformPost *socialdomain.PlatformPost) {
+	videoPath, _ := post.Metadata["videoPath"].(string)
 	req := &socialdomain.UploadRequest{
-		VideoPath:   post.Metadata["videoPath"].(string),
+		VideoPath:   videoPath,
+		VideoURL:    videoURL(post),
 		Title:       platformPost.CustomTitle,
 		Description: platformPost.CustomDesc,
 		Tags:        platformPost.Tags,
 		Privacy:     platformPost.Privacy,
+		Metadata:    uploadMetadata(platformPost.Metadata),
+	}
+
+	if err := p.resolveBatchVideo(post, req); err != nil {
+		log.Printf("Failed to publish post %s to account %s: %v", post.ID, platformPost.AccountID, err)
+		return
+	}
+	if err := p.publishPlatformPost(ctx, post, platformPost, req); err != nil {
+		var deferred *scheduler.DeferError
+		if errors.As(err, &deferred) {
+			platformPost.Status = socialdomain.PostStatusFailed
+			platformPost.ErrorMsg = fmt.Sprintf("%s, try again in %s", deferred.Reason, deferred.Delay.Round(time.Minute))
+			if updateErr := p.postRepo.UpdatePlatformPost(ctx, platformPost); updateErr != nil {
+				log.Printf("Failed to update platform post %s: %v", platformPost.ID, updateErr)
+			}
+		}
+		log.Printf("Failed to publish post %s to account %s: %v", post.ID, platformPost.AccountID, err)
+	}
+}
+
+// publishPlatformPost uploads a platform post at most once. The publish
+// ledger is checked before uploading: a platform post that an earlier
+// attempt already published is marked published without uploading again,
+// an earlier attempt with an unknown outcome is checked against the
+// platform, and only one attempt per idempotency key can run at a time.
+func (p *Publisher) publishPlatformPost(ctx context.Context, post *socialdomain.ScheduledPost, platformPost *socialdomain.PlatformPost, req *socialdomain.UploadRequest) error {
+	ensureIdempotencyKey(post, platformPost)
+
+	// Published by an earlier run that saved its result
+	if platformPost.PlatformPostID != "" {
+		if platformPost.Status != socialdomain.PostStatusPublished {
+			platformPost.Status = socialdomain.PostStatusPublished
+			return p.postRepo.UpdatePlatformPost(ctx, platformPost)
+		}
+		return nil
+	}
+
+	// Published by an earlier run that failed to save its result
+	succeeded, err := p.attempts.GetSucceeded(ctx, platformPost.IdempotencyKey)
+	if err != nil {
+		return fmt.Errorf("failed to check publish ledger: %w", err)
+	}
+	if succeeded != nil {
+		log.Printf("Post %s was already published to account %s as %s, not uploading again",
+			post.ID, platformPost.AccountID, succeeded.RemotePostID)
+		markPublished(platformPost, succeeded.RemotePostID, succeeded.PostURL, succeeded.FinishedAt)
+		return p.postRepo.UpdatePlatformPost(ctx, platformPost)
+	}
+
+	// Destinations that accept one use it to drop repeated deliveries
+	req.IdempotencyKey = platformPost.IdempotencyKey
+
+	// Published by an earlier run whose outcome is unknown
+	found, err := p.reconcile(ctx, platformPost, req)
+	if err != nil {
+		if errors.Is(err, ErrPublishNeedsReview) {
+			platformPost.Status = socialdomain.PostStatusFailed
+			platformPost.ErrorMsg = err.Error()
+			if updateErr := p.postRepo.UpdatePlatformPost(ctx, platformPost); updateErr != nil {
+				log.Printf("Failed to update platform post %s: %v", platformPost.ID, updateErr)
+			}
+		}
+		return err
+	}
+	if found != nil {
+		log.Printf("Post %s was already published to account %s as %s, not uploading again",
+			post.ID, platformPost.AccountID, found.RemotePostID)
+		markPublished(platformPost, found.RemotePostID, found.PostURL, found.FinishedAt)
+		return p.postRepo.UpdatePlatformPost(ctx, platformPost)
 	}
 
-	resp, err := p.socialService.UploadVideo(ctx, platformPost.AccountID, req)
+	// Only uploads that reach the platform use up its quota
+	if err := p.takeQuota(ctx, platformPost); err != nil {
+		return err
+	}
+
+	// Claim the platform post, so it is not cancelled while it uploads
+	if err := p.claimPlatformPost(ctx, platformPost); err != nil {
+		p.refundQuota(ctx, platformPost)
+		return err
+	}
+
+	attempt, err := p.beginAttempt(ctx, platformPost)
 	if err != nil {
+		p.refundQuota(ctx, platformPost)
+		return err
+	}
+
+	resp, uploadErr := p.socialService.UploadVideo(ctx, platformPost.AccountID, req)
+
+	now := time.Now()
+	attempt.FinishedAt = &now
+	if uploadErr != nil {
+		attempt.Status = socialdomain.PublishAttemptFailed
+		if !socialsvc.IsDefiniteFailure(uploadErr) {
+			attempt.Status = socialdomain.PublishAttemptUnknown
+		}
+		attempt.Error = uploadErr.Error()
+	} else {
+		attempt.Status = socialdomain.PublishAttemptSucceeded
+		attempt.RemotePostID = resp.PlatformPostID
+		attempt.PostURL = resp.PostURL
+	}
+	// Use a fresh context so the outcome is recorded even if ours expired
+	if err := p.attempts.Finish(context.WithoutCancel(ctx), attempt); err != nil {
+		log.Printf("Failed to record publish attempt %s for post %s: %v", attempt.ID, post.ID, err)
+	}
+
+	if uploadErr != nil {
 		platformPost.Status = socialdomain.PostStatusFailed
-		platformPost.ErrorMsg = err.Error()
-		p.postRepo.Update(ctx, post)
+		platformPost.ErrorMsg = uploadErr.Error()
+		if err := p.postRepo.UpdatePlatformPost(ctx, platformPost); err != nil {
+			log.Printf("Failed to update platform post %s: %v", platformPost.ID, err)
+		}
+		return uploadErr
+	}
+
+	markPublished(platformPost, resp.PlatformPostID, resp.PostURL, &now)
+	return p.postRepo.UpdatePlatformPost(ctx, platformPost)
+}
+
+// reconcile settles the latest attempt of a platform post when its
+// outcome is unknown. Attempts left in flight by a crashed worker become
+// unknown after publishAttemptTimeout. The platform is asked for the post
+// of an unknown attempt before anything is uploaded again: the attempt is
+// returned as succeeded if the post was found, and ErrPublishNeedsReview
+// if the platform cannot tell.
+func (p *Publisher) reconcile(ctx context.Context, platformPost *socialdomain.PlatformPost, req *socialdomain.UploadRequest) (*socialdomain.PublishAttempt, error) {
+	latest, err := p.attempts.GetLatest(ctx, platformPost.IdempotencyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check publish ledger: %w", err)
+	}
+	if latest == nil {
+		return nil, nil
+	}
+
+	if latest.Status == socialdomain.PublishAttemptStarted {
+		if time.Since(latest.StartedAt) < publishAttemptTimeout {
+			return nil, ErrPublishInProgress
+		}
+		now := time.Now()
+		latest.Status = socialdomain.PublishAttemptUnknown
+		latest.Error = "abandoned in flight"
+		latest.FinishedAt = &now
+		if err := p.attempts.Finish(ctx, latest); err != nil {
+			return nil, fmt.Errorf("failed to abandon publish attempt: %w", err)
+		}
+	}
+	if latest.Status != socialdomain.PublishAttemptUnknown {
+		return nil, nil
+	}
+
+	resp, err := p.socialService.FindUpload(ctx, platformPost.AccountID, req, latest.StartedAt.Add(-reconcileSlack))
+	if errors.Is(err, socialsvc.ErrUnsupported) {
+		return nil, ErrPublishNeedsReview
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look for an earlier upload: %w", err)
+	}
+
+	now := time.Now()
+	latest.FinishedAt = &now
+	if resp == nil {
+		latest.Status = socialdomain.PublishAttemptFailed
+	} else {
+		latest.Status = socialdomain.PublishAttemptSucceeded
+		latest.RemotePostID = resp.PlatformPostID
+		latest.PostURL = resp.PostURL
+	}
+	if err := p.attempts.Finish(ctx, latest); err != nil {
+		return nil, fmt.Errorf("failed to record publish attempt: %w", err)
+	}
+
+	if resp == nil {
+		return nil, nil
+	}
+	return latest, nil
+}
+
+// resolveUnknown marks the unknown attempts that were left for review as
+// failed, once the user has checked that they did not publish the post
+func (p *Publisher) resolveUnknown(ctx context.Context, post *socialdomain.ScheduledPost) error {
+	for i := range post.Platforms {
+		platformPost := &post.Platforms[i]
+		if platformPost.ErrorMsg != ErrPublishNeedsReview.Error() {
+			continue
+		}
+		latest, err := p.attempts.GetLatest(ctx, platformPost.IdempotencyKey)
+		if err != nil {
+			return fmt.Errorf("failed to check publish ledger: %w", err)
+		}
+		if latest == nil || latest.Status != socialdomain.PublishAttemptUnknown {
+			continue
+		}
+		latest.Status = socialdomain.PublishAttemptFailed
+		latest.Error += "; retried by the user"
+		if err := p.attempts.Finish(ctx, latest); err != nil {
+			return fmt.Errorf("failed to record publish attempt: %w", err)
+		}
+	}
+	return nil
+}
+
+// takeQuota charges an upload against the quotas of its platform and
+// account. It returns a scheduler.DeferError when a limit has been reached.
+func (p *Publisher) takeQuota(ctx context.Context, platformPost *socialdomain.PlatformPost) error {
+	if p.quota == nil {
+		return nil
+	}
+	wait, err := p.quota.Take(ctx, platformPost.Platform, platformPost.AccountID)
+	if err != nil {
+		log.Printf("Failed to check quota of account %s, publishing anyway: %v", platformPost.AccountID, err)
+		return nil
+	}
+	if wait > 0 {
+		return scheduler.Defer(wait, fmt.Sprintf("%s publishing limit of account %s reached", platformPost.Platform, platformPost.AccountID))
+	}
+	return nil
+}
+
+// refundQuota gives back the quota taken for an upload that did not start
+func (p *Publisher) refundQuota(ctx context.Context, platformPost *socialdomain.PlatformPost) {
+	if p.quota == nil {
 		return
 	}
+	if err := p.quota.Refund(ctx, platformPost.Platform, platformPost.AccountID); err != nil {
+		log.Printf("Failed to refund quota of account %s: %v", platformPost.AccountID, err)
+	}
+}
+
+// claimPlatformPost moves a platform post to publishing. It returns
+// errPostWithdrawn if the post is cancelled or deleted, or was since it
+// was loaded.
+func (p *Publisher) claimPlatformPost(ctx context.Context, platformPost *socialdomain.PlatformPost) error {
+	if isWithdrawn(platformPost.Status) {
+		return errPostWithdrawn
+	}
+	if platformPost.Status == socialdomain.PostStatusPublishing {
+		return nil
+	}
+	claimed, err := p.postRepo.SwapPlatformPostStatus(ctx, platformPost.ID, platformPost.Status, socialdomain.PostStatusPublishing)
+	if err != nil {
+		return fmt.Errorf("failed to claim platform post: %w", err)
+	}
+	if !claimed {
+		return errPostWithdrawn
+	}
+	platformPost.Status = socialdomain.PostStatusPublishing
+	return nil
+}
+
+// beginAttempt claims the platform post for a new publish attempt
+func (p *Publisher) beginAttempt(ctx context.Context, platformPost *socialdomain.PlatformPost) (*socialdomain.PublishAttempt, error) {
+	latest, err := p.attempts.GetLatest(ctx, platformPost.IdempotencyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check publish ledger: %w", err)
+	}
+
+	number := 1
+	if latest != nil {
+		number = latest.Attempt + 1
+	}
+
+	attempt := &socialdomain.PublishAttempt{
+		ID:             uuid.New().String(),
+		IdempotencyKey: platformPost.IdempotencyKey,
+		PlatformPostID: platformPost.ID,
+		AccountID:      platformPost.AccountID,
+		Platform:       platformPost.Platform,
+		Attempt:        number,
+		Status:         socialdomain.PublishAttemptStarted,
+		StartedAt:      time.Now(),
+	}
+
+	claimed, err := p.attempts.Begin(ctx, attempt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record publish attempt: %w", err)
+	}
+	if !claimed {
+		return nil, ErrPublishInProgress
+	}
+
+	return attempt, nil
+}
 
-	platformPost.PlatformPostID = resp.PlatformPostID
-	platformPost.PostURL = resp.PostURL
+// markPublished records where a platform post was published
+func markPublished(platformPost *socialdomain.PlatformPost, remoteID, postURL string, publishedAt *time.Time) {
+	if publishedAt == nil {
+		now := time.Now()
+		publishedAt = &now
+	}
+	platformPost.PlatformPostID = remoteID
+	platformPost.PostURL = postURL
 	platformPost.Status = socialdomain.PostStatusPublished
-	now := time.Now()
-	platformPost.PublishedAt = &now
+	platformPost.ErrorMsg = ""
+	platformPost.PublishedAt = publishedAt
+}
+
+// finishPost marks a post published once all its platform posts are
+func (p *Publisher) finishPost(ctx context.Context, postID string) error {
+	post, err := p.postRepo.GetByID(ctx, postID)
+	if err != nil {
+		return err
+	}
+
+	for _, pp := range post.Platforms {
+		if pp.Status != socialdomain.PostStatusPublished {
+			return nil
+		}
+	}
 
-	p.postRepo.Update(ctx, post)
+	// Only the status is written, so a deletion made meanwhile is kept
+	return p.postRepo.MarkPublished(ctx, postID, time.Now())
+}
+
+// findPlatformPost finds the platform post a publish job is for. Jobs
+// scheduled before platform post IDs were recorded match on the account.
+func findPlatformPost(post *socialdomain.ScheduledPost, data *PublishJobData) *socialdomain.PlatformPost {
+	for i := range post.Platforms {
+		if data.PlatformPostID != "" {
+			if post.Platforms[i].ID == data.PlatformPostID {
+				return &post.Platforms[i]
+			}
+		} else if post.Platforms[i].AccountID == data.AccountID {
+			return &post.Platforms[i]
+		}
+	}
+	return nil
+}
+
+// isWithdrawn reports whether a post was cancelled or deleted by its owner
+func isWithdrawn(status socialdomain.PostStatus) bool {
+	return status == socialdomain.PostStatusCancelled || status == socialdomain.PostStatusDeleted
+}
+
+// occurrenceID derives a stable ID from a parent ID and a position
+func occurrenceID(parentID string, n int) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("%s:%d", parentID, n))).String()
+}
+
+// ensureIdempotencyKey gives a platform post the key that identifies its
+// publish across retries
+func ensureIdempotencyKey(post *socialdomain.ScheduledPost, platformPost *socialdomain.PlatformPost) {
+	if platformPost.IdempotencyKey == "" {
+		platformPost.IdempotencyKey = fmt.Sprintf("publish:%s:%s", post.ID, platformPost.ID)
+	}
+}
+
+// videoURL returns the public URL of a post's rendered video, if it has one
+func videoURL(post *socialdomain.ScheduledPost) string {
+	url, _ := post.Metadata["videoUrl"].(string)
+	return url
+}
+
+// uploadMetadata keeps the string options of a platform post for its upload
+func uploadMetadata(metadata socialdomain.JSON) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	options := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		if s, ok := value.(string); ok {
+			options[key] = s
+		}
+	}
+	return options
 }
 
 // FormatForPlatform formats content for a specific platform
@@ -278,9 +985,7 @@ func FormatForPlatform(content string, platform socialdomain.SocialPlatform) str
 	switch platform {
 	case socialdomain.PlatformTwitter:
 		// Twitter has 280 character limit
-		if len(content) > 280 {
-			return content[:277] + "..."
-		}
+		return truncateGraphemes(content, 280)
 	case socialdomain.PlatformInstagram:
 		// Instagram works well with hashtags
 		// Already handled in the platform-specific logic
@@ -289,9 +994,13 @@ func FormatForPlatform(content string, platform socialdomain.SocialPlatform) str
 		// Content can be longer
 	case socialdomain.PlatformTikTok:
 		// TikTok description limit is 2200 characters
-		if len(content) > 2200 {
-			return content[:2197] + "..."
-		}
+		return truncateGraphemes(content, 2200)
+	case socialdomain.PlatformThreads:
+		// Threads posts are limited to 500 characters
+		return truncateGraphemes(content, 500)
+	case socialdomain.PlatformBluesky:
+		// Bluesky posts are limited to 300 characters
+		return truncateGraphemes(content, 300)
 	}
 	return content
 }
@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"renderowl-api/internal/domain"
+	socialdomain "renderowl-api/internal/domain/social"
+	"renderowl-api/internal/scheduler"
+	socialsvc "renderowl-api/internal/service/social"
+)
+
+// memoryPosts is a PostRepository keeping posts in memory
+type memoryPosts struct {
+	posts   map[string]*socialdomain.ScheduledPost
+	creates int
+}
+
+func (m *memoryPosts) Create(ctx context.Context, post *socialdomain.ScheduledPost) error {
+	if _, ok := m.posts[post.ID]; ok {
+		return fmt.Errorf("duplicate post %s", post.ID)
+	}
+	m.creates++
+	m.posts[post.ID] = post
+	return nil
+}
+
+func (m *memoryPosts) GetByID(ctx context.Context, id string) (*socialdomain.ScheduledPost, error) {
+	post, ok := m.posts[id]
+	if !ok {
+		return nil, fmt.Errorf("post %s not found", id)
+	}
+	return post, nil
+}
+
+func (m *memoryPosts) GetByUser(ctx context.Context, userID string, limit, offset int) ([]*socialdomain.ScheduledPost, error) {
+	return nil, nil
+}
+
+func (m *memoryPosts) GetPending(ctx context.Context, before string) ([]*socialdomain.ScheduledPost, error) {
+	return nil, nil
+}
+
+func (m *memoryPosts) Update(ctx context.Context, post *socialdomain.ScheduledPost) error {
+	m.posts[post.ID] = post
+	return nil
+}
+
+func (m *memoryPosts) UpdateStatus(ctx context.Context, id string, status socialdomain.PostStatus, errorMsg string) error {
+	if post, ok := m.posts[id]; ok {
+		post.Status = status
+		post.ErrorMsg = errorMsg
+	}
+	return nil
+}
+
+func (m *memoryPosts) UpdatePlatformPost(ctx context.Context, platformPost *socialdomain.PlatformPost) error {
+	return nil
+}
+
+func (m *memoryPosts) UpdateContent(ctx context.Context, post *socialdomain.ScheduledPost) error {
+	return nil
+}
+
+func (m *memoryPosts) SwapPlatformPostStatus(ctx context.Context, id string, from, to socialdomain.PostStatus) (bool, error) {
+	return true, nil
+}
+
+func (m *memoryPosts) AdvanceStatus(ctx context.Context, id string, status socialdomain.PostStatus, errorMsg string) error {
+	return m.UpdateStatus(ctx, id, status, errorMsg)
+}
+
+func (m *memoryPosts) MarkPublished(ctx context.Context, id string, publishedAt time.Time) error {
+	if post, ok := m.posts[id]; ok {
+		post.Status = socialdomain.PostStatusPublished
+		post.PublishedAt = &publishedAt
+	}
+	return nil
+}
+
+func (m *memoryPosts) Delete(ctx context.Context, id string) error {
+	delete(m.posts, id)
+	return nil
+}
+
+// memoryAccounts is an AccountRepository keeping accounts in memory
+type memoryAccounts map[string]*socialdomain.SocialAccount
+
+func (m memoryAccounts) Create(ctx context.Context, account *socialdomain.SocialAccount) error {
+	m[account.ID] = account
+	return nil
+}
+
+func (m memoryAccounts) GetByID(ctx context.Context, id string) (*socialdomain.SocialAccount, error) {
+	account, ok := m[id]
+	if !ok {
+		return nil, fmt.Errorf("account %s not found", id)
+	}
+	return account, nil
+}
+
+func (m memoryAccounts) GetByUser(ctx context.Context, userID string) ([]*socialdomain.SocialAccount, error) {
+	return nil, nil
+}
+
+func (m memoryAccounts) GetByUserAndPlatform(ctx context.Context, userID string, platform socialdomain.SocialPlatform) (*socialdomain.SocialAccount, error) {
+	return nil, fmt.Errorf("not found")
+}
+
+func (m memoryAccounts) GetExpiring(ctx context.Context, before time.Time) ([]*socialdomain.SocialAccount, error) {
+	return nil, nil
+}
+
+func (m memoryAccounts) Update(ctx context.Context, account *socialdomain.SocialAccount) error {
+	return nil
+}
+
+func (m memoryAccounts) Delete(ctx context.Context, id string) error {
+	return nil
+}
+
+// memoryAttempts is a PublishAttemptRepository keeping attempts in memory
+type memoryAttempts map[string]*socialdomain.PublishAttempt
+
+func (m memoryAttempts) Begin(ctx context.Context, attempt *socialdomain.PublishAttempt) (bool, error) {
+	if latest, ok := m[attempt.IdempotencyKey]; ok && latest.Status == socialdomain.PublishAttemptStarted {
+		return false, nil
+	}
+	m[attempt.IdempotencyKey] = attempt
+	return true, nil
+}
+
+func (m memoryAttempts) Finish(ctx context.Context, attempt *socialdomain.PublishAttempt) error {
+	m[attempt.IdempotencyKey] = attempt
+	return nil
+}
+
+func (m memoryAttempts) GetLatest(ctx context.Context, idempotencyKey string) (*socialdomain.PublishAttempt, error) {
+	return m[idempotencyKey], nil
+}
+
+func (m memoryAttempts) GetSucceeded(ctx context.Context, idempotencyKey string) (*socialdomain.PublishAttempt, error) {
+	if latest, ok := m[idempotencyKey]; ok && latest.Status == socialdomain.PublishAttemptSucceeded {
+		return latest, nil
+	}
+	return nil, nil
+}
+
+// memoryBatchVideos is a BatchVideoSource keeping videos in memory
+type memoryBatchVideos map[string]*domain.BatchVideo
+
+func (m memoryBatchVideos) GetVideo(id string) (*domain.BatchVideo, error) {
+	video, ok := m[id]
+	if !ok {
+		return nil, fmt.Errorf("video %s not found", id)
+	}
+	return video, nil
+}
+
+// recordingPlatform is a platform recording the uploads made to it
+type recordingPlatform struct {
+	uploads []*socialdomain.UploadRequest
+}
+
+func (r *recordingPlatform) GetName() socialdomain.SocialPlatform {
+	return socialdomain.PlatformYouTube
+}
+
+func (r *recordingPlatform) GetAuthURL(state string) string {
+	return ""
+}
+
+func (r *recordingPlatform) ExchangeCode(ctx context.Context, code string) (*socialdomain.SocialAccount, error) {
+	return nil, socialsvc.ErrUnsupported
+}
+
+func (r *recordingPlatform) RefreshToken(ctx context.Context, account *socialdomain.SocialAccount) error {
+	return nil
+}
+
+func (r *recordingPlatform) UploadVideo(ctx context.Context, account *socialdomain.SocialAccount, req *socialdomain.UploadRequest) (*socialdomain.UploadResponse, error) {
+	r.uploads = append(r.uploads, req)
+	return &socialdomain.UploadResponse{PlatformPostID: "remote-1", PostURL: "https://youtu.be/remote-1"}, nil
+}
+
+func (r *recordingPlatform) GetAnalytics(ctx context.Context, account *socialdomain.SocialAccount, postID string) (*socialdomain.AnalyticsData, error) {
+	return nil, socialsvc.ErrUnsupported
+}
+
+func (r *recordingPlatform) DeletePost(ctx context.Context, account *socialdomain.SocialAccount, postID string) error {
+	return nil
+}
+
+func (r *recordingPlatform) GetTrends(ctx context.Context, account *socialdomain.SocialAccount, region string) ([]*socialdomain.PlatformTrend, error) {
+	return nil, nil
+}
+
+func TestHandlePublishJobCalendarPost(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     *domain.VideoResult
+		wantStatus socialdomain.PostStatus
+		wantUpload string
+	}{
+		{
+			name:       "rendered video is uploaded",
+			result:     &domain.VideoResult{TimelineID: "timeline-1", VideoURL: "https://cdn.example.com/videos/tips.mp4"},
+			wantStatus: socialdomain.PostStatusPublished,
+			wantUpload: "https://cdn.example.com/videos/tips.mp4",
+		},
+		{
+			name:       "video without a rendered file fails",
+			result:     &domain.VideoResult{TimelineID: "timeline-1"},
+			wantStatus: socialdomain.PostStatusFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			day := ContentCalendarDay{
+				Date:  time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC),
+				Video: &CalendarVideo{Title: "Weekly tips", Description: "Five tips", Tags: []string{"tips"}},
+			}
+			post := calendarPost("user-1", day, &CalendarPublishOptions{
+				Platforms: []CalendarPublishTarget{{AccountID: "account-1", Platform: string(socialdomain.PlatformYouTube)}},
+			})
+			post.ScheduledAt = time.Now().Add(-time.Minute)
+			post.Status = socialdomain.PostStatusScheduled
+			post.VideoID = "video-1"
+			post.Metadata["batchVideoId"] = "video-1"
+
+			posts := &memoryPosts{posts: map[string]*socialdomain.ScheduledPost{post.ID: post}}
+			accounts := memoryAccounts{
+				"account-1": {ID: "account-1", UserID: "user-1", Platform: socialdomain.PlatformYouTube},
+			}
+			platform := &recordingPlatform{}
+			registry := socialsvc.NewPlatformRegistry()
+			registry.Register(platform)
+			socialService := socialsvc.NewService(registry, accounts, posts, nil)
+
+			publisher := NewPublisher(socialService, nil, posts, memoryAttempts{})
+			publisher.SetBatchVideos(memoryBatchVideos{
+				"video-1": {ID: "video-1", Title: "Weekly tips", Status: domain.VideoStatusCompleted, Result: tt.result},
+			})
+
+			platformPost := post.Platforms[0]
+			data, _ := json.Marshal(PublishJobData{
+				PostID:         post.ID,
+				PlatformPostID: platformPost.ID,
+				AccountID:      platformPost.AccountID,
+				Platform:       string(platformPost.Platform),
+				Title:          platformPost.CustomTitle,
+				Description:    platformPost.CustomDesc,
+				Tags:           platformPost.Tags,
+			})
+			if err := publisher.handlePublishJob(ctx, &scheduler.Job{Name: "publish", Data: data}); err != nil {
+				t.Fatalf("handlePublishJob: %v", err)
+			}
+
+			if post.Status != tt.wantStatus {
+				t.Errorf("post is %s (%s), want %s", post.Status, post.ErrorMsg, tt.wantStatus)
+			}
+			if tt.wantUpload == "" {
+				if len(platform.uploads) != 0 {
+					t.Errorf("uploaded %d times, want no upload", len(platform.uploads))
+				}
+				return
+			}
+			if len(platform.uploads) != 1 {
+				t.Fatalf("uploaded %d times, want 1", len(platform.uploads))
+			}
+			if got := platform.uploads[0]; got.VideoPath != tt.wantUpload || got.VideoURL != tt.wantUpload {
+				t.Errorf("uploaded %q (%q), want %q", got.VideoPath, got.VideoURL, tt.wantUpload)
+			}
+			if got := post.Platforms[0]; got.Status != socialdomain.PostStatusPublished || got.PlatformPostID != "remote-1" {
+				t.Errorf("platform post is %s as %q", got.Status, got.PlatformPostID)
+			}
+		})
+	}
+}
+
+func TestHandleRecurJobOccurrenceIDs(t *testing.T) {
+	ctx := context.Background()
+
+	series := &socialdomain.ScheduledPost{
+		ID:          "post-1",
+		UserID:      "user-1",
+		Title:       "Weekly tips",
+		ScheduledAt: time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC),
+		Timezone:    "UTC",
+		Status:      socialdomain.PostStatusScheduled,
+		Recurring:   &socialdomain.RecurringRule{RRule: "FREQ=WEEKLY"},
+		SeriesID:    "post-1",
+		Occurrence:  1,
+		Metadata:    socialdomain.JSON{"videoPath": "/videos/tips.mp4"},
+		Platforms: []socialdomain.PlatformPost{
+			{ID: "platform-1", AccountID: "account-1", Platform: socialdomain.PlatformYouTube},
+			{ID: "platform-2", AccountID: "account-2", Platform: socialdomain.PlatformTikTok},
+		},
+	}
+
+	posts := &memoryPosts{posts: map[string]*socialdomain.ScheduledPost{series.ID: series}}
+	accounts := memoryAccounts{
+		"account-1": {ID: "account-1", UserID: "user-1", Platform: socialdomain.PlatformYouTube},
+		"account-2": {ID: "account-2", UserID: "user-1", Platform: socialdomain.PlatformTikTok},
+	}
+	socialService := socialsvc.NewService(socialsvc.NewPlatformRegistry(), accounts, posts, nil)
+
+	// Nothing listens on the scheduler address, so scheduling the publish
+	// jobs fails after the occurrence is saved, as when the scheduler is
+	// down, and the job is retried
+	sched := scheduler.NewScheduler("127.0.0.1:1", "", 0)
+	publisher := NewPublisher(socialService, sched, posts, nil)
+
+	data, _ := json.Marshal(RecurJobData{PostID: series.ID})
+	job := &scheduler.Job{Name: "recur", Data: data}
+
+	if err := publisher.handleRecurJob(ctx, job); err == nil {
+		t.Fatal("first run: expected the publish jobs to fail to schedule")
+	}
+
+	wantID := occurrenceID("post-1", 2)
+	next, ok := posts.posts[wantID]
+	if !ok {
+		t.Fatalf("occurrence 2 was not created as %s", wantID)
+	}
+	if !next.ScheduledAt.Equal(time.Date(2025, 1, 13, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("occurrence 2 is scheduled at %s", next.ScheduledAt)
+	}
+	if next.SeriesID != "post-1" || next.Occurrence != 2 {
+		t.Errorf("occurrence 2 has series %q and number %d", next.SeriesID, next.Occurrence)
+	}
+	for i, platformPost := range next.Platforms {
+		if want := occurrenceID(wantID, i); platformPost.ID != want {
+			t.Errorf("platform post %d has ID %s, want %s", i, platformPost.ID, want)
+		}
+	}
+
+	// The retried job derives the same ID and finds the occurrence
+	if err := publisher.handleRecurJob(ctx, job); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if posts.creates != 1 {
+		t.Errorf("created %d occurrences, want 1", posts.creates)
+	}
+
+	if occurrenceID("post-1", 2) != wantID || occurrenceID("post-1", 3) == wantID {
+		t.Error("occurrence IDs are not derived from the series and number")
+	}
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+
+	"renderowl-api/internal/domain"
+)
+
+// Drop-off detection. The steepest fall in retention over a window of the
+// video is a drop-off when it is at least twice the average fall over such
+// a window. The opening, where viewers sampling the video leave, and the
+// ending, where viewers leave with the outro, always fall steeply and are
+// left out.
+const (
+	retentionDropWindow  = 0.05
+	retentionSkipStart   = 0.10
+	retentionSkipEnd     = 0.05
+	retentionScanStep    = 0.01
+	retentionDropMinimum = 0.04
+
+	// sceneOverlapTolerance is how far, in seconds, a clip may start before
+	// the previous one ends and still begin a new scene
+	sceneOverlapTolerance = 0.05
+)
+
+// RetentionDrop is the point of a video where viewers stop watching the
+// fastest
+type RetentionDrop struct {
+	Position float64         `json:"position"`        // elapsed share of the video
+	At       float64         `json:"at,omitempty"`    // seconds into the video, when its duration is known
+	Drop     float64         `json:"drop"`            // fall in watch ratio over the drop window
+	Scene    *SceneRetention `json:"scene,omitempty"` // scene of the source timeline playing at the drop
+}
+
+// SceneRetention is the audience retention over a scene of the source
+// timeline of a video
+type SceneRetention struct {
+	Label     string  `json:"label"`
+	ClipID    string  `json:"clipId"`
+	Start     float64 `json:"start"` // seconds
+	End       float64 `json:"end"`
+	Retention float64 `json:"retention"` // watch ratio when the scene starts
+	Drop      float64 `json:"drop"`      // fall in watch ratio over the scene
+}
+
+// sceneNamePattern matches the clip names of generated scenes, such as
+// "Scene 3: Feature 2"
+var sceneNamePattern = regexp.MustCompile(`^Scene (\d+): (.+)$`)
+
+// retentionAt interpolates the watch ratio of a retention curve at a
+// position of the video
+func retentionAt(curve domain.RetentionCurve, position float64) float64 {
+	if len(curve) == 0 {
+		return 0
+	}
+	if position <= curve[0].Position {
+		return curve[0].WatchRatio
+	}
+
+	for i := 1; i < len(curve); i++ {
+		if position > curve[i].Position {
+			continue
+		}
+		prev := curve[i-1]
+		span := curve[i].Position - prev.Position
+		if span <= 0 {
+			return curve[i].WatchRatio
+		}
+		return prev.WatchRatio + (curve[i].WatchRatio-prev.WatchRatio)*(position-prev.Position)/span
+	}
+
+	return curve[len(curve)-1].WatchRatio
+}
+
+// retentionMarks samples a retention curve at every 10% of the video, the
+// resolution of VideoAnalytics.RetentionCurve
+func retentionMarks(curve domain.RetentionCurve) []float64 {
+	marks := make([]float64, 11)
+	for i := range marks {
+		marks[i] = retentionAt(curve, float64(i)/10)
+	}
+	return marks
+}
+
+// findRetentionDrop finds the steepest drop-off of a retention curve. It
+// returns nil if retention falls evenly.
+func findRetentionDrop(curve domain.RetentionCurve) *RetentionDrop {
+	if len(curve) < 2 {
+		return nil
+	}
+
+	start, end := retentionSkipStart, 1-retentionSkipEnd
+	average := (retentionAt(curve, start) - retentionAt(curve, end)) / (end - start) * retentionDropWindow
+	threshold := math.Max(2*average, retentionDropMinimum)
+
+	var drop *RetentionDrop
+	for i := 0; ; i++ {
+		position := math.Round((start+float64(i)*retentionScanStep)*100) / 100
+		if position+retentionDropWindow > end+1e-9 {
+			break
+		}
+		fall := retentionAt(curve, position) - retentionAt(curve, position+retentionDropWindow)
+		if fall >= threshold && (drop == nil || fall > drop.Drop) {
+			drop = &RetentionDrop{Position: position, Drop: fall}
+		}
+	}
+	return drop
+}
+
+// timelineScenes lists the scenes of a timeline: its video and image clips
+// in play order. Clips that start while another is still showing, such as
+// overlays, belong to the scene they overlap.
+func timelineScenes(timeline *domain.Timeline) []SceneRetention {
+	var clips []domain.Clip
+	for _, track := range timeline.Tracks {
+		for _, clip := range track.Clips {
+			if clip.Type == "video" || clip.Type == "image" {
+				clips = append(clips, clip)
+			}
+		}
+	}
+	sort.SliceStable(clips, func(i, j int) bool { return clips[i].StartTime < clips[j].StartTime })
+
+	var scenes []SceneRetention
+	for _, clip := range clips {
+		if n := len(scenes); n > 0 && clip.StartTime < scenes[n-1].End-sceneOverlapTolerance {
+			scenes[n-1].End = math.Max(scenes[n-1].End, clip.EndTime)
+			continue
+		}
+		scenes = append(scenes, SceneRetention{
+			Label:  sceneLabel(len(scenes)+1, clip.Name),
+			ClipID: clip.ID,
+			Start:  clip.StartTime,
+			End:    clip.EndTime,
+		})
+	}
+	return scenes
+}
+
+// sceneLabel labels a scene like "Scene 3: 'Feature 2'", keeping the
+// number of generated scenes
+func sceneLabel(number int, name string) string {
+	if m := sceneNamePattern.FindStringSubmatch(name); m != nil {
+		return fmt.Sprintf("Scene %s: '%s'", m[1], m[2])
+	}
+	if name == "" {
+		return fmt.Sprintf("Scene %d", number)
+	}
+	return fmt.Sprintf("Scene %d: '%s'", number, name)
+}
+
+// mapRetention maps a retention curve onto the scenes of a video lasting
+// duration seconds, and places its drop-off in the scene playing at it
+func mapRetention(curve domain.RetentionCurve, drop *RetentionDrop, scenes []SceneRetention, duration float64) {
+	for i := range scenes {
+		start := retentionAt(curve, scenes[i].Start/duration)
+		scenes[i].Retention = start
+		scenes[i].Drop = start - retentionAt(curve, scenes[i].End/duration)
+	}
+
+	if drop == nil {
+		return
+	}
+	drop.At = math.Round(drop.Position*duration*10) / 10
+	for i := range scenes {
+		if drop.At >= scenes[i].Start && drop.At < scenes[i].End {
+			drop.Scene = &scenes[i]
+			return
+		}
+	}
+}
+
+// formatTimestamp formats seconds into a video like "0:18" or "1:02:05"
+func formatTimestamp(seconds float64) string {
+	total := int(math.Round(seconds))
+	h, m, s := total/3600, total/60%60, total%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	socialdomain "renderowl-api/internal/domain/social"
+)
+
+// sentimentWords scores words that carry a tone in comments
+var sentimentWords = map[string]int{
+	// Positive
+	"love": 2, "loved": 2, "loving": 2, "amazing": 2, "awesome": 2, "excellent": 2,
+	"fantastic": 2, "incredible": 2, "brilliant": 2, "perfect": 2, "beautiful": 2,
+	"great": 1, "good": 1, "nice": 1, "cool": 1, "helpful": 1, "useful": 1,
+	"thanks": 1, "thank": 1, "thx": 1, "like": 1, "liked": 1, "enjoy": 1, "enjoyed": 1,
+	"fun": 1, "funny": 1, "best": 2, "wow": 1, "inspiring": 2, "favorite": 2,
+	"favourite": 2, "recommend": 1, "clear": 1, "wholesome": 2, "lol": 1,
+
+	// Negative
+	"hate": -2, "hated": -2, "terrible": -2, "awful": -2, "horrible": -2,
+	"worst": -2, "garbage": -2, "trash": -2, "scam": -2, "disgusting": -2,
+	"bad": -1, "boring": -1, "useless": -2, "wrong": -1, "annoying": -1,
+	"stupid": -2, "fake": -2, "misleading": -2, "clickbait": -2, "disappointed": -2,
+	"disappointing": -2, "broken": -1, "spam": -2, "unsubscribed": -2, "waste": -2,
+	"sucks": -2, "cringe": -1, "confusing": -1, "poor": -1, "meh": -1,
+}
+
+// sentimentEmoji scores emoji that carry a tone in comments
+var sentimentEmoji = map[rune]int{
+	'❤': 2, '😍': 2, '🥰': 2, '😊': 1, '😀': 1, '😃': 1, '😄': 1, '😁': 1,
+	'😂': 1, '🤣': 1, '👍': 1, '👏': 1, '🔥': 1, '💯': 1, '🙌': 1, '🙏': 1,
+	'😡': -2, '🤬': -2, '😠': -1, '👎': -2, '🤮': -2, '😒': -1, '🙄': -1,
+	'😢': -1, '😭': -1, '💩': -2,
+}
+
+// sentimentNegations flip the tone of the words that follow them
+var sentimentNegations = map[string]bool{
+	"not": true, "no": true, "never": true, "dont": true, "don't": true,
+	"didnt": true, "didn't": true, "isnt": true, "isn't": true, "wasnt": true,
+	"wasn't": true, "cant": true, "can't": true, "wont": true, "won't": true,
+}
+
+// negationReach is how many words after a negation it affects
+const negationReach = 2
+
+// ClassifySentiment tags a comment as positive, negative or neutral from
+// the words and emoji it contains, so that the inbox can be triaged.
+// Negations flip the tone of the next words, as in "not good".
+func ClassifySentiment(text string) socialdomain.Sentiment {
+	score := 0
+	for _, r := range text {
+		score += sentimentEmoji[r]
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	negated := 0
+	for _, word := range words {
+		if sentimentNegations[word] {
+			negated = negationReach
+			continue
+		}
+		value := sentimentWords[word]
+		if negated > 0 {
+			value = -value
+			negated--
+		}
+		score += value
+	}
+
+	switch {
+	case score > 0:
+		return socialdomain.SentimentPositive
+	case score < 0:
+		return socialdomain.SentimentNegative
+	default:
+		return socialdomain.SentimentNeutral
+	}
+}
@@ -0,0 +1,525 @@
+package social
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"renderowl-api/internal/domain/social"
+)
+
+// BlueskyPlatform implements the Platform interface for Bluesky. Accounts
+// connect with an app password and post through their AT Protocol PDS.
+type BlueskyPlatform struct {
+	serviceURL string
+	httpClient *http.Client
+}
+
+// Bluesky endpoints
+const (
+	BlueskyServiceURL   = "https://bsky.social"
+	BlueskyVideoURL     = "https://video.bsky.app"
+	BlueskyPublicAPIURL = "https://public.api.bsky.app"
+
+	blueskyPostCollection = "app.bsky.feed.post"
+)
+
+// Bluesky processes uploaded videos before they can be embedded
+const (
+	blueskyPollInterval = 3 * time.Second
+	blueskyPollTimeout  = 5 * time.Minute
+
+	// blueskyAccessLife is assumed when an access token carries no expiry
+	blueskyAccessLife = 2 * time.Hour
+)
+
+// blueskyHashtagPattern finds hashtags to link as tag facets
+var blueskyHashtagPattern = regexp.MustCompile(`(^|\s)(#[\p{L}\p{N}_]+)`)
+
+// NewBlueskyPlatform creates a new Bluesky platform instance. Accounts log
+// in through the service URL, which defaults to bsky.social.
+func NewBlueskyPlatform(serviceURL string) *BlueskyPlatform {
+	if serviceURL == "" {
+		serviceURL = BlueskyServiceURL
+	}
+	return &BlueskyPlatform{
+		serviceURL: strings.TrimSuffix(serviceURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// GetName returns the platform name
+func (b *BlueskyPlatform) GetName() social.SocialPlatform {
+	return social.PlatformBluesky
+}
+
+// GetAuthURL returns no URL; Bluesky accounts connect with an app password
+func (b *BlueskyPlatform) GetAuthURL(state string) string {
+	return ""
+}
+
+// ExchangeCode is not supported; Bluesky accounts connect with an app password
+func (b *BlueskyPlatform) ExchangeCode(ctx context.Context, code string) (*social.SocialAccount, error) {
+	return nil, fmt.Errorf("bluesky accounts connect with an app password")
+}
+
+// Login creates a session from a handle or email and an app password
+func (b *BlueskyPlatform) Login(ctx context.Context, identifier, password string) (*social.SocialAccount, error) {
+	body := map[string]string{
+		"identifier": identifier,
+		"password":   password,
+	}
+
+	resp, err := b.makeRequest(ctx, "POST", b.serviceURL+"/xrpc/com.atproto.server.createSession", body, nil)
+	if err != nil {
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+
+	var session blueskySession
+	if err := json.Unmarshal(resp, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse session: %w", err)
+	}
+
+	expiry := tokenExpiry(session.AccessJwt, blueskyAccessLife)
+
+	return &social.SocialAccount{
+		Platform:     social.PlatformBluesky,
+		AccountID:    session.DID,
+		AccountName:  session.Handle,
+		AccessToken:  session.AccessJwt,
+		RefreshToken: session.RefreshJwt,
+		TokenExpiry:  &expiry,
+		Status:       social.StatusConnected,
+		Metadata: social.JSON{
+			"did":    session.DID,
+			"handle": session.Handle,
+			"pds":    session.pdsEndpoint(b.serviceURL),
+		},
+	}, nil
+}
+
+// RefreshToken refreshes the session with the refresh token
+func (b *BlueskyPlatform) RefreshToken(ctx context.Context, account *social.SocialAccount) error {
+	headers := map[string]string{
+		"Authorization": "Bearer " + account.RefreshToken,
+	}
+
+	resp, err := b.makeRequest(ctx, "POST", b.pds(account)+"/xrpc/com.atproto.server.refreshSession", nil, headers)
+	if err != nil {
+		account.Status = social.StatusExpired
+		return fmt.Errorf("token refresh failed: %w", err)
+	}
+
+	var session blueskySession
+	if err := json.Unmarshal(resp, &session); err != nil {
+		return fmt.Errorf("failed to parse refresh response: %w", err)
+	}
+
+	expiry := tokenExpiry(session.AccessJwt, blueskyAccessLife)
+
+	account.AccessToken = session.AccessJwt
+	account.RefreshToken = session.RefreshJwt
+	account.TokenExpiry = &expiry
+	account.Status = social.StatusConnected
+	if session.Handle != "" {
+		account.AccountName = session.Handle
+	}
+
+	return nil
+}
+
+// UploadVideo uploads a video to the Bluesky video service and posts it
+func (b *BlueskyPlatform) UploadVideo(ctx context.Context, account *social.SocialAccount, req *social.UploadRequest) (*social.UploadResponse, error) {
+	// Refresh token if needed
+	if account.TokenExpiry != nil && account.TokenExpiry.Before(time.Now()) {
+		if err := b.RefreshToken(ctx, account); err != nil {
+			return nil, err
+		}
+	}
+
+	// Step 1: Upload the video for processing
+	jobID, err := b.uploadToVideoService(ctx, account, req.VideoPath)
+	if err != nil {
+		return nil, fmt.Errorf("video upload failed: %w", err)
+	}
+
+	// Step 2: Wait for the processed blob
+	blob, err := b.waitForVideo(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Create the post record
+	text := req.Description
+	embed := map[string]interface{}{
+		"$type": "app.bsky.embed.video",
+		"video": blob,
+	}
+	if req.Title != "" {
+		embed["alt"] = req.Title
+	}
+
+	record := map[string]interface{}{
+		"$type":     blueskyPostCollection,
+		"text":      text,
+		"createdAt": time.Now().UTC().Format(time.RFC3339),
+		"embed":     embed,
+	}
+	if facets := hashtagFacets(text); len(facets) > 0 {
+		record["facets"] = facets
+	}
+	if lang := req.Metadata["language"]; lang != "" {
+		record["langs"] = []string{lang}
+	}
+
+	body := map[string]interface{}{
+		"repo":       account.AccountID,
+		"collection": blueskyPostCollection,
+		"record":     record,
+	}
+
+	resp, err := b.makeRequest(ctx, "POST", b.pds(account)+"/xrpc/com.atproto.repo.createRecord", body, b.headers(account))
+	if err != nil {
+		return nil, fmt.Errorf("post creation failed: %w", err)
+	}
+
+	var result struct {
+		URI string `json:"uri"`
+		CID string `json:"cid"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse post response: %w", err)
+	}
+
+	return &social.UploadResponse{
+		PlatformPostID: result.URI,
+		PostURL:        fmt.Sprintf("https://bsky.app/profile/%s/post/%s", account.AccountName, recordKey(result.URI)),
+		Status:         "published",
+	}, nil
+}
+
+// GetAnalytics retrieves the interaction counts of a post. Bluesky does
+// not report views.
+func (b *BlueskyPlatform) GetAnalytics(ctx context.Context, account *social.SocialAccount, postID string) (*social.AnalyticsData, error) {
+	postsURL := BlueskyPublicAPIURL + "/xrpc/app.bsky.feed.getPosts?uris=" + url.QueryEscape(postID)
+
+	resp, err := b.makeRequest(ctx, "GET", postsURL, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("post fetch failed: %w", err)
+	}
+
+	var result struct {
+		Posts []struct {
+			LikeCount   int64 `json:"likeCount"`
+			RepostCount int64 `json:"repostCount"`
+			ReplyCount  int64 `json:"replyCount"`
+			QuoteCount  int64 `json:"quoteCount"`
+		} `json:"posts"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse posts: %w", err)
+	}
+	if len(result.Posts) == 0 {
+		return nil, fmt.Errorf("post %s not found", postID)
+	}
+
+	post := result.Posts[0]
+	return &social.AnalyticsData{
+		Platform: social.PlatformBluesky,
+		Likes:    post.LikeCount,
+		Comments: post.ReplyCount,
+		Shares:   post.RepostCount + post.QuoteCount,
+		Data: social.JSON{
+			"reposts": post.RepostCount,
+			"quotes":  post.QuoteCount,
+		},
+	}, nil
+}
+
+// DeletePost deletes a post record
+func (b *BlueskyPlatform) DeletePost(ctx context.Context, account *social.SocialAccount, postID string) error {
+	body := map[string]string{
+		"repo":       account.AccountID,
+		"collection": blueskyPostCollection,
+		"rkey":       recordKey(postID),
+	}
+
+	_, err := b.makeRequest(ctx, "POST", b.pds(account)+"/xrpc/com.atproto.repo.deleteRecord", body, b.headers(account))
+	return err
+}
+
+// GetTrends retrieves trending topics
+func (b *BlueskyPlatform) GetTrends(ctx context.Context, account *social.SocialAccount, region string) ([]*social.PlatformTrend, error) {
+	resp, err := b.makeRequest(ctx, "GET", BlueskyPublicAPIURL+"/xrpc/app.bsky.unspecced.getTrendingTopics", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("trends fetch failed: %w", err)
+	}
+
+	var result struct {
+		Topics []struct {
+			Topic       string `json:"topic"`
+			DisplayName string `json:"displayName"`
+			Description string `json:"description"`
+			Link        string `json:"link"`
+		} `json:"topics"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse trends: %w", err)
+	}
+
+	trends := make([]*social.PlatformTrend, 0, len(result.Topics))
+	for _, topic := range result.Topics {
+		title := topic.DisplayName
+		if title == "" {
+			title = topic.Topic
+		}
+		trends = append(trends, &social.PlatformTrend{
+			Platform:    social.PlatformBluesky,
+			TrendType:   "topic",
+			Title:       title,
+			Description: topic.Description,
+			URL:         "https://bsky.app" + topic.Link,
+			Region:      region,
+			FetchedAt:   time.Now(),
+		})
+	}
+
+	return trends, nil
+}
+
+// Helper methods
+
+// blueskySession is a session returned by createSession and refreshSession
+type blueskySession struct {
+	DID        string `json:"did"`
+	Handle     string `json:"handle"`
+	AccessJwt  string `json:"accessJwt"`
+	RefreshJwt string `json:"refreshJwt"`
+	DIDDoc     struct {
+		Service []struct {
+			ID              string `json:"id"`
+			ServiceEndpoint string `json:"serviceEndpoint"`
+		} `json:"service"`
+	} `json:"didDoc"`
+}
+
+// pdsEndpoint returns the PDS hosting the account, falling back to the
+// service the account logged in through
+func (s *blueskySession) pdsEndpoint(fallback string) string {
+	for _, service := range s.DIDDoc.Service {
+		if service.ID == "#atproto_pds" && service.ServiceEndpoint != "" {
+			return strings.TrimSuffix(service.ServiceEndpoint, "/")
+		}
+	}
+	return fallback
+}
+
+func (b *BlueskyPlatform) pds(account *social.SocialAccount) string {
+	if pds, ok := account.Metadata["pds"].(string); ok && pds != "" {
+		return pds
+	}
+	return b.serviceURL
+}
+
+// uploadToVideoService sends a video to the video service with a service
+// token from the account's PDS and returns the processing job ID
+func (b *BlueskyPlatform) uploadToVideoService(ctx context.Context, account *social.SocialAccount, videoPath string) (string, error) {
+	pds, err := url.Parse(b.pds(account))
+	if err != nil {
+		return "", fmt.Errorf("invalid PDS URL: %w", err)
+	}
+
+	// The video service stores the blob on the PDS on the account's behalf
+	authParams := url.Values{
+		"aud": {"did:web:" + pds.Hostname()},
+		"lxm": {"com.atproto.repo.uploadBlob"},
+		"exp": {fmt.Sprint(time.Now().Add(30 * time.Minute).Unix())},
+	}
+	resp, err := b.makeRequest(ctx, "GET", b.pds(account)+"/xrpc/com.atproto.server.getServiceAuth?"+authParams.Encode(), nil, b.headers(account))
+	if err != nil {
+		return "", fmt.Errorf("service auth failed: %w", err)
+	}
+
+	var serviceAuth struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(resp, &serviceAuth); err != nil {
+		return "", fmt.Errorf("failed to parse service auth: %w", err)
+	}
+
+	file, err := os.Open(videoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open video file: %w", err)
+	}
+	defer file.Close()
+
+	uploadParams := url.Values{
+		"did":  {account.AccountID},
+		"name": {filepath.Base(videoPath)},
+	}
+	uploadResp, body, err := doRequest(ctx, uploadHTTPClient, "POST", BlueskyVideoURL+"/xrpc/app.bsky.video.uploadVideo?"+uploadParams.Encode(), file, map[string]string{
+		"Authorization": "Bearer " + serviceAuth.Token,
+		"Content-Type":  "video/mp4",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var job struct {
+		JobID string `json:"jobId"`
+	}
+	// An already uploaded video is reported as a conflict with its job
+	if uploadResp.StatusCode != http.StatusConflict && (uploadResp.StatusCode < 200 || uploadResp.StatusCode >= 300) {
+		return "", &APIError{StatusCode: uploadResp.StatusCode, Body: string(body)}
+	}
+	if err := json.Unmarshal(body, &job); err != nil || job.JobID == "" {
+		return "", &APIError{StatusCode: uploadResp.StatusCode, Body: string(body)}
+	}
+
+	return job.JobID, nil
+}
+
+// waitForVideo polls a video job until the processed blob is ready
+func (b *BlueskyPlatform) waitForVideo(ctx context.Context, jobID string) (json.RawMessage, error) {
+	statusURL := BlueskyVideoURL + "/xrpc/app.bsky.video.getJobStatus?jobId=" + url.QueryEscape(jobID)
+
+	deadline := time.Now().Add(blueskyPollTimeout)
+	for {
+		resp, err := b.makeRequest(ctx, "GET", statusURL, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("video status check failed: %w", err)
+		}
+
+		var status struct {
+			JobStatus struct {
+				State   string          `json:"state"`
+				Blob    json.RawMessage `json:"blob"`
+				Error   string          `json:"error"`
+				Message string          `json:"message"`
+			} `json:"jobStatus"`
+		}
+		if err := json.Unmarshal(resp, &status); err != nil {
+			return nil, fmt.Errorf("failed to parse video status: %w", err)
+		}
+
+		job := status.JobStatus
+		switch job.State {
+		case "JOB_STATE_COMPLETED":
+			if len(job.Blob) == 0 {
+				return nil, errors.New("processed video has no blob")
+			}
+			return job.Blob, nil
+		case "JOB_STATE_FAILED":
+			return nil, fmt.Errorf("bluesky could not process video: %s %s", job.Error, job.Message)
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("video job %s still %s after %s", jobID, job.State, blueskyPollTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(blueskyPollInterval):
+		}
+	}
+}
+
+func (b *BlueskyPlatform) headers(account *social.SocialAccount) map[string]string {
+	return map[string]string{
+		"Authorization": "Bearer " + account.AccessToken,
+	}
+}
+
+func (b *BlueskyPlatform) makeRequest(ctx context.Context, method, url string, body interface{}, headers map[string]string) ([]byte, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		bodyReader = bytes.NewReader(jsonBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	return respBody, nil
+}
+
+// hashtagFacets links the hashtags in a post's text. Facet offsets are in
+// UTF-8 bytes.
+func hashtagFacets(text string) []map[string]interface{} {
+	var facets []map[string]interface{}
+	for _, match := range blueskyHashtagPattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[4], match[5]
+		facets = append(facets, map[string]interface{}{
+			"index": map[string]int{
+				"byteStart": start,
+				"byteEnd":   end,
+			},
+			"features": []map[string]string{{
+				"$type": "app.bsky.richtext.facet#tag",
+				"tag":   text[start+1 : end],
+			}},
+		})
+	}
+	return facets
+}
+
+// recordKey returns the record key of an at:// URI
+func recordKey(uri string) string {
+	return uri[strings.LastIndex(uri, "/")+1:]
+}
+
+// tokenExpiry reads the expiry of a JWT without verifying it, assuming a
+// default lifetime when it has none
+func tokenExpiry(token string, fallback time.Duration) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) == 3 {
+		if payload, err := base64.RawURLEncoding.DecodeString(parts[1]); err == nil {
+			var claims struct {
+				Exp int64 `json:"exp"`
+			}
+			if json.Unmarshal(payload, &claims) == nil && claims.Exp > 0 {
+				return time.Unix(claims.Exp, 0)
+			}
+		}
+	}
+	return time.Now().Add(fallback)
+}
@@ -0,0 +1,94 @@
+package social
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+// ErrUnsupported is returned for operations a platform does not offer
+var ErrUnsupported = errors.New("not supported by the platform")
+
+// ErrPostPublishing is returned when a post cannot be cancelled because it
+// is being published
+var ErrPostPublishing = errors.New("post is being published and can no longer be cancelled")
+
+// APIError is returned when a platform API responds with a non-success status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Body)
+}
+
+// IsUnauthorized reports whether a platform rejected the access token
+func IsUnauthorized(err error) bool {
+	return statusCode(err) == http.StatusUnauthorized
+}
+
+// IsDefiniteFailure reports whether a failed upload was refused by the
+// platform, so no post was created. Timeouts, dropped connections and
+// server errors leave it unknown whether the platform created the post.
+func IsDefiniteFailure(err error) bool {
+	if errors.Is(err, ErrUnsupported) {
+		return true
+	}
+	code := statusCode(err)
+	return code >= 400 && code < 500 && code != http.StatusRequestTimeout
+}
+
+// isAuthFailure reports whether a token refresh was refused outright, as
+// opposed to failing for a transient reason. Refused grants need the user
+// to reconnect the account.
+func isAuthFailure(err error) bool {
+	switch statusCode(err) {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden:
+		return true
+	}
+	return false
+}
+
+// IsInsufficientScope reports whether a platform refused a call because
+// the account was connected without a permission the call needs, such as
+// YouTube Analytics. Reconnecting the account asks for it again.
+func IsInsufficientScope(err error) bool {
+	var googleErr *googleapi.Error
+	if !errors.As(err, &googleErr) || googleErr.Code != http.StatusForbidden {
+		return false
+	}
+	if strings.Contains(googleErr.Header.Get("WWW-Authenticate"), "insufficient_scope") {
+		return true
+	}
+	for _, item := range googleErr.Errors {
+		if item.Reason == "insufficientPermissions" || item.Reason == "ACCESS_TOKEN_SCOPE_INSUFFICIENT" {
+			return true
+		}
+	}
+	return strings.Contains(googleErr.Message, "insufficient authentication scopes")
+}
+
+// statusCode extracts the HTTP status from a platform or OAuth error
+func statusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) {
+		return googleErr.Code
+	}
+
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) && retrieveErr.Response != nil {
+		return retrieveErr.Response.StatusCode
+	}
+
+	return 0
+}
@@ -6,8 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"time"
 
 	"renderowl-api/internal/domain/social"
@@ -19,6 +22,7 @@ type FacebookPlatform struct {
 	appSecret   string
 	redirectURL string
 	httpClient  *http.Client
+	uploader    *ResumableUploader
 }
 
 // Facebook API endpoints
@@ -26,6 +30,7 @@ const (
 	FacebookAuthURL   = "https://www.facebook.com/v18.0/dialog/oauth"
 	FacebookTokenURL  = "https://graph.facebook.com/v18.0/oauth/access_token"
 	FacebookGraphURL  = "https://graph.facebook.com/v18.0"
+	FacebookVideoURL  = "https://graph-video.facebook.com/v18.0"
 )
 
 // NewFacebookPlatform creates a new Facebook platform instance
@@ -35,9 +40,15 @@ func NewFacebookPlatform(appID, appSecret, redirectURL string) *FacebookPlatform
 		appSecret:   appSecret,
 		redirectURL: redirectURL,
 		httpClient:  &http.Client{Timeout: 60 * time.Second},
+		uploader:    NewResumableUploader(nil),
 	}
 }
 
+// SetUploader sets the uploader used for resumable uploads
+func (f *FacebookPlatform) SetUploader(uploader *ResumableUploader) {
+	f.uploader = uploader
+}
+
 // GetName returns the platform name
 func (f *FacebookPlatform) GetName() social.SocialPlatform {
 	return social.PlatformFacebook
@@ -48,7 +59,7 @@ func (f *FacebookPlatform) GetAuthURL(state string) string {
 	params := url.Values{
 		"client_id":     {f.appID},
 		"redirect_uri":  {f.redirectURL},
-		"scope":         {"pages_manage_posts,pages_read_engagement,publish_video"},
+		"scope":         {"pages_manage_posts,pages_read_engagement,pages_read_user_content,pages_manage_engagement,publish_video"},
 		"response_type": {"code"},
 		"state":         {state},
 	}
@@ -142,38 +153,16 @@ func (f *FacebookPlatform) RefreshToken(ctx context.Context, account *social.Soc
 
 // UploadVideo uploads a video to Facebook Page
 func (f *FacebookPlatform) UploadVideo(ctx context.Context, account *social.SocialAccount, req *social.UploadRequest) (*social.UploadResponse, error) {
-	// Facebook video upload
-	uploadURL := fmt.Sprintf("%s/%s/videos", FacebookGraphURL, account.AccountID)
-
-	params := url.Values{
-		"access_token": {account.AccessToken},
-		"file_url":     {req.VideoPath}, // Publicly accessible URL
-		"title":        {req.Title},
-		"description":  {req.Description},
-	}
-
-	if req.Privacy == "public" {
-		params.Set("published", "true")
-	} else {
-		params.Set("published", "false")
-	}
-
-	resp, err := f.makeRequest(ctx, "POST", uploadURL+"?"+params.Encode(), nil, nil)
+	// Facebook resumable video upload, published when finished
+	session, err := f.uploader.Upload(ctx, account, req.VideoPath, &facebookUpload{platform: f, account: account, req: req})
 	if err != nil {
 		return nil, fmt.Errorf("video upload failed: %w", err)
 	}
-
-	var uploadResp struct {
-		ID string `json:"id"`
-	}
-
-	if err := json.Unmarshal(resp, &uploadResp); err != nil {
-		return nil, fmt.Errorf("failed to parse upload response: %w", err)
-	}
+	f.uploader.Complete(ctx, session)
 
 	return &social.UploadResponse{
-		PlatformPostID: uploadResp.ID,
-		PostURL:        fmt.Sprintf("https://facebook.com/%s/videos/%s", account.AccountID, uploadResp.ID),
+		PlatformPostID: session.RemoteID,
+		PostURL:        fmt.Sprintf("https://facebook.com/%s/videos/%s", account.AccountID, session.RemoteID),
 		Status:         "published",
 	}, nil
 }
@@ -222,6 +211,88 @@ func (f *FacebookPlatform) GetAnalytics(ctx context.Context, account *social.Soc
 	return analytics, nil
 }
 
+// facebookRetentionIntervals is the number of intervals of the lifetime
+// video retention graph, which has a point at the start of each and one at
+// the end of the video
+const facebookRetentionIntervals = 40
+
+// GetRetention retrieves the audience retention curve of a video
+func (f *FacebookPlatform) GetRetention(ctx context.Context, account *social.SocialAccount, postID string) ([]social.RetentionPoint, error) {
+	insightsURL := fmt.Sprintf("%s/%s/video_insights?metric=total_video_retention_graph&period=lifetime&access_token=%s",
+		FacebookGraphURL, postID, account.AccessToken)
+
+	resp, err := f.makeRequest(ctx, "GET", insightsURL, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("video insights fetch failed: %w", err)
+	}
+
+	var insights struct {
+		Data []struct {
+			Name   string `json:"name"`
+			Values []struct {
+				Value map[string]float64 `json:"value"`
+			} `json:"values"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &insights); err != nil {
+		return nil, fmt.Errorf("failed to parse video insights: %w", err)
+	}
+
+	var points []social.RetentionPoint
+	for _, metric := range insights.Data {
+		if metric.Name != "total_video_retention_graph" || len(metric.Values) == 0 {
+			continue
+		}
+		for key, ratio := range metric.Values[0].Value {
+			interval, err := strconv.Atoi(key)
+			if err != nil || interval < 0 || interval > facebookRetentionIntervals {
+				continue
+			}
+			points = append(points, social.RetentionPoint{
+				Position:   float64(interval) / facebookRetentionIntervals,
+				WatchRatio: ratio,
+			})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Position < points[j].Position })
+
+	return points, nil
+}
+
+// FindUpload finds a video with the title of an upload among the videos
+// the page published since a time
+func (f *FacebookPlatform) FindUpload(ctx context.Context, account *social.SocialAccount, req *social.UploadRequest, since time.Time) (*social.UploadResponse, error) {
+	videosURL := fmt.Sprintf("%s/%s/videos?fields=id,title,created_time&since=%d&limit=50&access_token=%s",
+		FacebookGraphURL, account.AccountID, since.Unix(), account.AccessToken)
+
+	resp, err := f.makeRequest(ctx, "GET", videosURL, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("videos fetch failed: %w", err)
+	}
+
+	var videos struct {
+		Data []struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &videos); err != nil {
+		return nil, fmt.Errorf("failed to parse videos: %w", err)
+	}
+
+	for _, video := range videos.Data {
+		if video.Title == req.Title {
+			return &social.UploadResponse{
+				PlatformPostID: video.ID,
+				PostURL:        fmt.Sprintf("https://facebook.com/%s/videos/%s", account.AccountID, video.ID),
+				Status:         "published",
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
+
 // DeletePost deletes a post
 func (f *FacebookPlatform) DeletePost(ctx context.Context, account *social.SocialAccount, postID string) error {
 	deleteURL := fmt.Sprintf("%s/%s?access_token=%s", FacebookGraphURL, postID, account.AccessToken)
@@ -229,6 +300,164 @@ func (f *FacebookPlatform) DeletePost(ctx context.Context, account *social.Socia
 	return err
 }
 
+// EditableFields lists what can be changed on a published video
+func (f *FacebookPlatform) EditableFields() []string {
+	return []string{social.PostFieldTitle, social.PostFieldDescription, social.PostFieldPrivacy}
+}
+
+// UpdatePost changes the title, description or visibility of a video.
+// Like uploads, only public videos are published to the Page.
+func (f *FacebookPlatform) UpdatePost(ctx context.Context, account *social.SocialAccount, postID string, update *social.PostUpdate) error {
+	params := url.Values{"access_token": {account.AccessToken}}
+	if update.Title != nil {
+		params.Set("name", *update.Title)
+	}
+	if update.Description != nil {
+		params.Set("description", *update.Description)
+	}
+	if update.Privacy != nil {
+		params.Set("published", strconv.FormatBool(*update.Privacy == "public"))
+	}
+
+	_, err := f.makeRequest(ctx, "POST", fmt.Sprintf("%s/%s", FacebookGraphURL, postID), []byte(params.Encode()), map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	})
+	return err
+}
+
+// facebookComment is a comment as returned by the Graph API
+type facebookComment struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+	From    struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"from"`
+	CreatedTime string `json:"created_time"`
+	LikeCount   int64  `json:"like_count"`
+	IsHidden    bool   `json:"is_hidden"`
+	Parent      *struct {
+		ID string `json:"id"`
+	} `json:"parent"`
+}
+
+// facebookCommentFields are the comment fields read from the Graph API
+const facebookCommentFields = "id,message,from{id,name},created_time,like_count,is_hidden,parent{id}"
+
+// GetComments lists the comments and replies of a video, newest first.
+// The stream filter returns replies alongside top-level comments.
+func (f *FacebookPlatform) GetComments(ctx context.Context, account *social.SocialAccount, postID string) ([]*social.Comment, error) {
+	params := url.Values{
+		"fields":       {facebookCommentFields},
+		"filter":       {"stream"},
+		"order":        {"reverse_chronological"},
+		"limit":        {"100"},
+		"access_token": {account.AccessToken},
+	}
+	next := fmt.Sprintf("%s/%s/comments?%s", FacebookGraphURL, postID, params.Encode())
+
+	var comments []*social.Comment
+	for next != "" && len(comments) < commentFetchLimit {
+		resp, err := f.makeRequest(ctx, "GET", next, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("comments fetch failed: %w", err)
+		}
+
+		var page struct {
+			Data   []facebookComment `json:"data"`
+			Paging struct {
+				Next string `json:"next"`
+			} `json:"paging"`
+		}
+		if err := json.Unmarshal(resp, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse comments: %w", err)
+		}
+
+		for _, c := range page.Data {
+			comments = append(comments, c.toComment(account, postID))
+		}
+		next = page.Paging.Next
+	}
+
+	return comments, nil
+}
+
+// ReplyToComment replies to a comment as the Page
+func (f *FacebookPlatform) ReplyToComment(ctx context.Context, account *social.SocialAccount, postID, commentID, text string) (*social.Comment, error) {
+	params := url.Values{
+		"message":      {text},
+		"access_token": {account.AccessToken},
+	}
+	resp, err := f.makeRequest(ctx, "POST", fmt.Sprintf("%s/%s/comments", FacebookGraphURL, commentID), []byte(params.Encode()), map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reply failed: %w", err)
+	}
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse reply: %w", err)
+	}
+
+	return &social.Comment{
+		RemoteID:       result.ID,
+		RemotePostID:   postID,
+		RemoteParentID: commentID,
+		AuthorID:       account.AccountID,
+		AuthorName:     account.AccountName,
+		FromAccount:    true,
+		Text:           text,
+		Status:         social.CommentStatusVisible,
+		PublishedAt:    time.Now(),
+	}, nil
+}
+
+// HideComment hides a comment from everyone but its author and their
+// friends, or shows it again
+func (f *FacebookPlatform) HideComment(ctx context.Context, account *social.SocialAccount, postID, commentID string, hidden bool) error {
+	params := url.Values{
+		"is_hidden":    {strconv.FormatBool(hidden)},
+		"access_token": {account.AccessToken},
+	}
+	_, err := f.makeRequest(ctx, "POST", fmt.Sprintf("%s/%s", FacebookGraphURL, commentID), []byte(params.Encode()), map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	})
+	return err
+}
+
+// DeleteComment deletes a comment on a Page video
+func (f *FacebookPlatform) DeleteComment(ctx context.Context, account *social.SocialAccount, postID, commentID string) error {
+	deleteURL := fmt.Sprintf("%s/%s?access_token=%s", FacebookGraphURL, commentID, url.QueryEscape(account.AccessToken))
+	_, err := f.makeRequest(ctx, "DELETE", deleteURL, nil, nil)
+	return err
+}
+
+func (c *facebookComment) toComment(account *social.SocialAccount, postID string) *social.Comment {
+	comment := &social.Comment{
+		RemoteID:     c.ID,
+		RemotePostID: postID,
+		AuthorID:     c.From.ID,
+		AuthorName:   c.From.Name,
+		FromAccount:  c.From.ID == account.AccountID,
+		Text:         c.Message,
+		Likes:        c.LikeCount,
+		Status:       social.CommentStatusVisible,
+	}
+	if c.Parent != nil {
+		comment.RemoteParentID = c.Parent.ID
+	}
+	if c.IsHidden {
+		comment.Status = social.CommentStatusHidden
+	}
+	if t, err := time.Parse("2006-01-02T15:04:05-0700", c.CreatedTime); err == nil {
+		comment.PublishedAt = t
+	}
+	return comment
+}
+
 // GetTrends retrieves trending topics (Facebook doesn't have a public trends API)
 func (f *FacebookPlatform) GetTrends(ctx context.Context, account *social.SocialAccount, region string) ([]*social.PlatformTrend, error) {
 	return []*social.PlatformTrend{
@@ -291,8 +520,177 @@ func (f *FacebookPlatform) makeRequest(ctx context.Context, method, url string,
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	return respBody, nil
 }
+
+// facebookUpload implements the Graph API resumable video upload
+type facebookUpload struct {
+	platform *FacebookPlatform
+	account  *social.SocialAccount
+	req      *social.UploadRequest
+}
+
+// facebookUploadState tracks the upload session and the end of the chunk
+// Facebook asked for next
+type facebookUploadState struct {
+	UploadSessionID string `json:"uploadSessionId"`
+	EndOffset       int64  `json:"endOffset"`
+}
+
+// facebookOffsets is the chunk range returned by the start and transfer phases
+type facebookOffsets struct {
+	StartOffset string `json:"start_offset"`
+	EndOffset   string `json:"end_offset"`
+}
+
+func (o facebookOffsets) parse() (int64, int64, error) {
+	start, err := strconv.ParseInt(o.StartOffset, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid start offset %q", o.StartOffset)
+	}
+	end, err := strconv.ParseInt(o.EndOffset, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid end offset %q", o.EndOffset)
+	}
+	return start, end, nil
+}
+
+func (u *facebookUpload) start(ctx context.Context, session *social.UploadSession) error {
+	params := url.Values{
+		"access_token": {u.account.AccessToken},
+		"upload_phase": {"start"},
+		"file_size":    {strconv.FormatInt(session.FileSize, 10)},
+	}
+
+	resp, err := u.platform.makeRequest(ctx, "POST", u.videosURL(), []byte(params.Encode()), map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	})
+	if err != nil {
+		return err
+	}
+
+	var startResp struct {
+		VideoID         string `json:"video_id"`
+		UploadSessionID string `json:"upload_session_id"`
+		facebookOffsets
+	}
+	if err := json.Unmarshal(resp, &startResp); err != nil {
+		return fmt.Errorf("failed to parse upload response: %w", err)
+	}
+
+	start, end, err := startResp.parse()
+	if err != nil {
+		return err
+	}
+
+	session.RemoteID = startResp.VideoID
+	session.Offset = start
+
+	return storeUploadState(session, facebookUploadState{
+		UploadSessionID: startResp.UploadSessionID,
+		EndOffset:       end,
+	})
+}
+
+// resume trusts the persisted offsets; the next chunk range is kept in the state
+func (u *facebookUpload) resume(ctx context.Context, session *social.UploadSession) (int64, error) {
+	return session.Offset, nil
+}
+
+func (u *facebookUpload) chunkSize(session *social.UploadSession) int64 {
+	var state facebookUploadState
+	if err := loadUploadState(session, &state); err != nil {
+		return 0
+	}
+	return state.EndOffset - session.Offset
+}
+
+func (u *facebookUpload) send(ctx context.Context, session *social.UploadSession, chunk []byte) (int64, error) {
+	var state facebookUploadState
+	if err := loadUploadState(session, &state); err != nil {
+		return 0, err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("access_token", u.account.AccessToken)
+	writer.WriteField("upload_phase", "transfer")
+	writer.WriteField("upload_session_id", state.UploadSessionID)
+	writer.WriteField("start_offset", strconv.FormatInt(session.Offset, 10))
+	part, err := writer.CreateFormFile("video_file_chunk", "chunk")
+	if err != nil {
+		return 0, err
+	}
+	part.Write(chunk)
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+
+	resp, respBody, err := doRequest(ctx, uploadHTTPClient, "POST", u.videosURL(), &body, map[string]string{
+		"Content-Type": writer.FormDataContentType(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	var offsets facebookOffsets
+	if err := json.Unmarshal(respBody, &offsets); err != nil {
+		return 0, fmt.Errorf("failed to parse transfer response: %w", err)
+	}
+	start, end, err := offsets.parse()
+	if err != nil {
+		return 0, err
+	}
+
+	state.EndOffset = end
+	if err := storeUploadState(session, state); err != nil {
+		return 0, err
+	}
+
+	return start, nil
+}
+
+func (u *facebookUpload) finish(ctx context.Context, session *social.UploadSession) error {
+	var state facebookUploadState
+	if err := loadUploadState(session, &state); err != nil {
+		return err
+	}
+
+	params := url.Values{
+		"access_token":      {u.account.AccessToken},
+		"upload_phase":      {"finish"},
+		"upload_session_id": {state.UploadSessionID},
+		"title":             {u.req.Title},
+		"description":       {u.req.Description},
+		"published":         {strconv.FormatBool(u.req.Privacy == "public")},
+	}
+
+	resp, err := u.platform.makeRequest(ctx, "POST", u.videosURL(), []byte(params.Encode()), map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	})
+	if err != nil {
+		return err
+	}
+
+	var finishResp struct {
+		Success bool `json:"success"`
+	}
+	if err := json.Unmarshal(resp, &finishResp); err != nil {
+		return fmt.Errorf("failed to parse finish response: %w", err)
+	}
+	if !finishResp.Success {
+		return fmt.Errorf("facebook did not accept the upload")
+	}
+
+	return nil
+}
+
+func (u *facebookUpload) videosURL() string {
+	return fmt.Sprintf("%s/%s/videos", FacebookVideoURL, u.account.AccountID)
+}
@@ -0,0 +1,138 @@
+package social
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"renderowl-api/internal/domain/social"
+)
+
+// FeedPlatform publishes videos to a workspace's RSS and Atom feed.
+// Publishing records the post as a feed item; the feed itself is rendered
+// from the published platform posts of the account. The account ID is the
+// unguessable token in the feed's public URL.
+type FeedPlatform struct{}
+
+// feedTokenBytes is the length of a feed token before hex encoding
+const feedTokenBytes = 16
+
+// DefaultFeedTitle is used when a feed is configured without a title
+const DefaultFeedTitle = "Renderowl videos"
+
+// NewFeedPlatform creates a new feed destination platform
+func NewFeedPlatform() *FeedPlatform {
+	return &FeedPlatform{}
+}
+
+// GetName returns the platform name
+func (f *FeedPlatform) GetName() social.SocialPlatform {
+	return social.PlatformFeed
+}
+
+// GetAuthURL returns no URL; feeds are configured, not connected
+func (f *FeedPlatform) GetAuthURL(state string) string {
+	return ""
+}
+
+// ExchangeCode is not supported; feeds are configured, not connected
+func (f *FeedPlatform) ExchangeCode(ctx context.Context, code string) (*social.SocialAccount, error) {
+	return nil, ErrConfiguredDestination
+}
+
+// Configure creates a feed with an optional title, description and site link
+func (f *FeedPlatform) Configure(ctx context.Context, settings social.JSON) (*social.SocialAccount, error) {
+	title, _ := settings["title"].(string)
+	description, _ := settings["description"].(string)
+	link, _ := settings["link"].(string)
+
+	if title == "" {
+		title = DefaultFeedTitle
+	}
+	if link != "" {
+		if u, err := url.Parse(link); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("link must be an absolute http or https URL")
+		}
+	}
+
+	token := make([]byte, feedTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate feed token: %w", err)
+	}
+
+	return &social.SocialAccount{
+		Platform:    social.PlatformFeed,
+		AccountID:   hex.EncodeToString(token),
+		AccountName: title,
+		Status:      social.StatusConnected,
+		Metadata: social.JSON{
+			"description": description,
+			"link":        link,
+		},
+	}, nil
+}
+
+// RefreshToken does nothing; feeds have no token
+func (f *FeedPlatform) RefreshToken(ctx context.Context, account *social.SocialAccount) error {
+	return nil
+}
+
+// UploadVideo adds the video to the feed. The item links to the "link"
+// upload option when given, otherwise to the video itself.
+func (f *FeedPlatform) UploadVideo(ctx context.Context, account *social.SocialAccount, req *social.UploadRequest) (*social.UploadResponse, error) {
+	videoURL := publicVideoURL(req)
+	if videoURL == "" {
+		return nil, fmt.Errorf("feeds need a public video URL")
+	}
+
+	itemID := req.IdempotencyKey
+	if itemID == "" {
+		itemID = uuid.New().String()
+	}
+
+	link := req.Metadata["link"]
+	if link == "" {
+		link = videoURL
+	}
+
+	return &social.UploadResponse{
+		PlatformPostID: itemID,
+		PostURL:        link,
+		Status:         "published",
+	}, nil
+}
+
+// FindUpload finds nothing; adding an item again keeps its item ID
+func (f *FeedPlatform) FindUpload(ctx context.Context, account *social.SocialAccount, req *social.UploadRequest, since time.Time) (*social.UploadResponse, error) {
+	return nil, nil
+}
+
+// GetAnalytics is not supported; feed readers do not report analytics
+func (f *FeedPlatform) GetAnalytics(ctx context.Context, account *social.SocialAccount, postID string) (*social.AnalyticsData, error) {
+	return nil, fmt.Errorf("feeds do not report analytics: %w", ErrUnsupported)
+}
+
+// EditableFields lists what can be changed on a feed item
+func (f *FeedPlatform) EditableFields() []string {
+	return []string{social.PostFieldTitle, social.PostFieldDescription}
+}
+
+// UpdatePost does nothing; items are rendered from their platform post,
+// so edits show up in the feed once it is saved
+func (f *FeedPlatform) UpdatePost(ctx context.Context, account *social.SocialAccount, postID string, update *social.PostUpdate) error {
+	return nil
+}
+
+// DeletePost does nothing; items leave the feed with their platform post
+func (f *FeedPlatform) DeletePost(ctx context.Context, account *social.SocialAccount, postID string) error {
+	return nil
+}
+
+// GetTrends returns no trends
+func (f *FeedPlatform) GetTrends(ctx context.Context, account *social.SocialAccount, region string) ([]*social.PlatformTrend, error) {
+	return []*social.PlatformTrend{}, nil
+}
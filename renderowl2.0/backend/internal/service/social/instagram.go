@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"renderowl-api/internal/domain/social"
@@ -49,7 +50,7 @@ func (i *InstagramPlatform) GetAuthURL(state string) string {
 	params := url.Values{
 		"client_id":     {i.appID},
 		"redirect_uri":  {i.redirectURL},
-		"scope":         {"instagram_basic,instagram_content_publish"},
+		"scope":         {"instagram_basic,instagram_content_publish,instagram_manage_comments"},
 		"response_type": {"code"},
 		"state":         {state},
 	}
@@ -241,6 +242,137 @@ func (i *InstagramPlatform) DeletePost(ctx context.Context, account *social.Soci
 	return err
 }
 
+// instagramComment is a comment as returned by the Graph API
+type instagramComment struct {
+	ID       string `json:"id"`
+	Text     string `json:"text"`
+	Username string `json:"username"`
+	From     struct {
+		ID string `json:"id"`
+	} `json:"from"`
+	Timestamp string `json:"timestamp"`
+	LikeCount int64  `json:"like_count"`
+	Hidden    bool   `json:"hidden"`
+	Replies   struct {
+		Data []instagramComment `json:"data"`
+	} `json:"replies"`
+}
+
+// instagramCommentFields are the comment fields read from the Graph API
+const instagramCommentFields = "id,text,username,from{id},timestamp,like_count,hidden"
+
+// GetComments lists the comments of a media object with their replies
+func (i *InstagramPlatform) GetComments(ctx context.Context, account *social.SocialAccount, postID string) ([]*social.Comment, error) {
+	params := url.Values{
+		"fields":       {instagramCommentFields + ",replies{" + instagramCommentFields + "}"},
+		"limit":        {"50"},
+		"access_token": {account.AccessToken},
+	}
+	next := fmt.Sprintf("%s/%s/comments?%s", InstagramGraphAPIURL, postID, params.Encode())
+
+	var comments []*social.Comment
+	for next != "" && len(comments) < commentFetchLimit {
+		resp, err := i.makeRequest(ctx, "GET", next, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("comments fetch failed: %w", err)
+		}
+
+		var page struct {
+			Data   []instagramComment `json:"data"`
+			Paging struct {
+				Next string `json:"next"`
+			} `json:"paging"`
+		}
+		if err := json.Unmarshal(resp, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse comments: %w", err)
+		}
+
+		for _, c := range page.Data {
+			comments = append(comments, c.toComment(account, postID, ""))
+			for _, reply := range c.Replies.Data {
+				comments = append(comments, reply.toComment(account, postID, c.ID))
+			}
+		}
+		next = page.Paging.Next
+	}
+
+	return comments, nil
+}
+
+// ReplyToComment replies to a comment as the account
+func (i *InstagramPlatform) ReplyToComment(ctx context.Context, account *social.SocialAccount, postID, commentID, text string) (*social.Comment, error) {
+	params := url.Values{
+		"message":      {text},
+		"access_token": {account.AccessToken},
+	}
+	resp, err := i.makeRequest(ctx, "POST", fmt.Sprintf("%s/%s/replies", InstagramGraphAPIURL, commentID), []byte(params.Encode()), map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reply failed: %w", err)
+	}
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse reply: %w", err)
+	}
+
+	return &social.Comment{
+		RemoteID:       result.ID,
+		RemotePostID:   postID,
+		RemoteParentID: commentID,
+		AuthorID:       account.AccountID,
+		AuthorName:     account.AccountName,
+		FromAccount:    true,
+		Text:           text,
+		Status:         social.CommentStatusVisible,
+		PublishedAt:    time.Now(),
+	}, nil
+}
+
+// HideComment hides a comment from everyone but its author, or shows it
+// again
+func (i *InstagramPlatform) HideComment(ctx context.Context, account *social.SocialAccount, postID, commentID string, hidden bool) error {
+	params := url.Values{
+		"hide":         {strconv.FormatBool(hidden)},
+		"access_token": {account.AccessToken},
+	}
+	_, err := i.makeRequest(ctx, "POST", fmt.Sprintf("%s/%s", InstagramGraphAPIURL, commentID), []byte(params.Encode()), map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	})
+	return err
+}
+
+// DeleteComment deletes a comment on the account's media
+func (i *InstagramPlatform) DeleteComment(ctx context.Context, account *social.SocialAccount, postID, commentID string) error {
+	deleteURL := fmt.Sprintf("%s/%s?access_token=%s", InstagramGraphAPIURL, commentID, url.QueryEscape(account.AccessToken))
+	_, err := i.makeRequest(ctx, "DELETE", deleteURL, nil, nil)
+	return err
+}
+
+func (c *instagramComment) toComment(account *social.SocialAccount, postID, parentID string) *social.Comment {
+	comment := &social.Comment{
+		RemoteID:       c.ID,
+		RemotePostID:   postID,
+		RemoteParentID: parentID,
+		AuthorID:       c.From.ID,
+		AuthorName:     c.Username,
+		FromAccount:    c.From.ID == account.AccountID || c.Username == account.AccountName,
+		Text:           c.Text,
+		Likes:          c.LikeCount,
+		Status:         social.CommentStatusVisible,
+	}
+	if c.Hidden {
+		comment.Status = social.CommentStatusHidden
+	}
+	if t, err := time.Parse("2006-01-02T15:04:05-0700", c.Timestamp); err == nil {
+		comment.PublishedAt = t
+	}
+	return comment
+}
+
 // GetTrends retrieves trending hashtags
 func (i *InstagramPlatform) GetTrends(ctx context.Context, account *social.SocialAccount, region string) ([]*social.PlatformTrend, error) {
 	// Instagram doesn't provide a direct trending API
@@ -304,7 +436,7 @@ func (i *InstagramPlatform) makeRequest(ctx context.Context, method, url string,
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	return respBody, nil
@@ -323,7 +455,7 @@ func (i *InstagramPlatform) makeFormRequest(ctx context.Context, method, url str
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	return respBody, nil
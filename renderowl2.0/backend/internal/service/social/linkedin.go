@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"renderowl-api/internal/domain/social"
@@ -19,6 +20,7 @@ type LinkedInPlatform struct {
 	clientSecret string
 	redirectURL  string
 	httpClient   *http.Client
+	uploader     *ResumableUploader
 }
 
 // LinkedIn API endpoints
@@ -26,7 +28,8 @@ const (
 	LinkedInAuthURL     = "https://www.linkedin.com/oauth/v2/authorization"
 	LinkedInTokenURL    = "https://www.linkedin.com/oauth/v2/accessToken"
 	LinkedInAPIURL      = "https://api.linkedin.com/v2"
-	LinkedInUploadURL   = "https://api.linkedin.com/v2/assets?action=registerUpload"
+	LinkedInRestURL     = "https://api.linkedin.com/rest"
+	LinkedInVersion     = "202401"
 )
 
 // NewLinkedInPlatform creates a new LinkedIn platform instance
@@ -36,9 +39,15 @@ func NewLinkedInPlatform(clientID, clientSecret, redirectURL string) *LinkedInPl
 		clientSecret: clientSecret,
 		redirectURL:  redirectURL,
 		httpClient:   &http.Client{Timeout: 60 * time.Second},
+		uploader:     NewResumableUploader(nil),
 	}
 }
 
+// SetUploader sets the uploader used for resumable uploads
+func (l *LinkedInPlatform) SetUploader(uploader *ResumableUploader) {
+	l.uploader = uploader
+}
+
 // GetName returns the platform name
 func (l *LinkedInPlatform) GetName() social.SocialPlatform {
 	return social.PlatformLinkedIn
@@ -115,93 +124,54 @@ func (l *LinkedInPlatform) RefreshToken(ctx context.Context, account *social.Soc
 // UploadVideo uploads a video to LinkedIn
 func (l *LinkedInPlatform) UploadVideo(ctx context.Context, account *social.SocialAccount, req *social.UploadRequest) (*social.UploadResponse, error) {
 	// LinkedIn video upload process:
-	// 1. Register upload
-	// 2. Upload video bytes
-	// 3. Create share with video
-
-	// Step 1: Register upload
-	registerData := map[string]interface{}{
-		"registerUploadRequest": map[string]interface{}{
-			"recipes": []string{"urn:li:digitalmediaRecipe:feedshare-video"},
-			"owner":   "urn:li:person:" + account.AccountID,
-			"serviceRelationships": []map[string]string{
-				{
-					"relationshipType": "OWNER",
-					"identifier":       "urn:li:userGeneratedContent",
-				},
-			},
-		},
-	}
-
-	jsonData, _ := json.Marshal(registerData)
-	headers := map[string]string{
-		"Authorization": "Bearer " + account.AccessToken,
-		"Content-Type":  "application/json",
-		"X-Restli-Protocol-Version": "2.0.0",
-	}
-
-	resp, err := l.makeRequest(ctx, "POST", LinkedInUploadURL, jsonData, headers)
+	// 1. Initialize a multipart upload
+	// 2. Upload each part and finalize with the part ETags
+	// 3. Create a post with the video
+	session, err := l.uploader.Upload(ctx, account, req.VideoPath, &linkedinUpload{platform: l, account: account})
 	if err != nil {
-		return nil, fmt.Errorf("register upload failed: %w", err)
+		return nil, fmt.Errorf("video upload failed: %w", err)
 	}
 
-	var registerResp struct {
-		Value struct {
-			Asset       string `json:"asset"`
-			UploadMechanism struct {
-				ComLinkedInDigitalmediaUploadingMediaUploadHttpRequest struct {
-					UploadURL string `json:"uploadUrl"`
-				} `json:"com.linkedin.digitalmedia.uploading.MediaUploadHttpRequest"`
-			} `json:"uploadMechanism"`
-		} `json:"value"`
+	visibility := "PUBLIC"
+	if req.Privacy != "" && req.Privacy != "public" {
+		visibility = "CONNECTIONS"
 	}
 
-	if err := json.Unmarshal(resp, &registerResp); err != nil {
-		return nil, fmt.Errorf("failed to parse register response: %w", err)
-	}
-
-	// Step 2: Upload video (would need actual video bytes)
-	// In production, upload to registerResp.Value.UploadMechanism.ComLinkedInDigitalmediaUploadingMediaUploadHttpRequest.UploadURL
-
-	// Step 3: Create share
-	shareData := map[string]interface{}{
-		"author":          "urn:li:person:" + account.AccountID,
-		"lifecycleState":  "PUBLISHED",
-		"specificContent": map[string]interface{}{
-			"com.linkedin.ugc.ShareContent": map[string]interface{}{
-				"shareCommentary": map[string]string{
-					"text": req.Description,
-				},
-				"shareMediaCategory": "VIDEO",
-				"media": []map[string]interface{}{
-					{
-						"status":      "READY",
-						"description": map[string]string{"text": req.Title},
-						"media":       registerResp.Value.Asset,
-						"title":       map[string]string{"text": req.Title},
-					},
-				},
-			},
+	postData := map[string]interface{}{
+		"author":     "urn:li:person:" + account.AccountID,
+		"commentary": req.Description,
+		"visibility": visibility,
+		"distribution": map[string]interface{}{
+			"feedDistribution":               "MAIN_FEED",
+			"targetEntities":                 []interface{}{},
+			"thirdPartyDistributionChannels": []interface{}{},
 		},
-		"visibility": map[string]string{
-			"com.linkedin.ugc.MemberNetworkVisibility": req.Privacy,
+		"content": map[string]interface{}{
+			"media": map[string]string{
+				"title": req.Title,
+				"id":    session.RemoteID,
+			},
 		},
+		"lifecycleState":            "PUBLISHED",
+		"isReshareDisabledByAuthor": false,
 	}
 
-	shareJSON, _ := json.Marshal(shareData)
-	shareURL := LinkedInAPIURL + "/ugcPosts"
-
-	shareResp, err := l.makeRequest(ctx, "POST", shareURL, shareJSON, headers)
+	postJSON, _ := json.Marshal(postData)
+	resp, body, err := doRequest(ctx, l.httpClient, "POST", LinkedInRestURL+"/posts", bytes.NewReader(postJSON), l.restHeaders(account.AccessToken))
 	if err != nil {
-		return nil, fmt.Errorf("share creation failed: %w", err)
+		return nil, fmt.Errorf("post creation failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("post creation failed: %w", &APIError{StatusCode: resp.StatusCode, Body: string(body)})
 	}
+	l.uploader.Complete(ctx, session)
 
-	// Extract share ID from response header
-	shareID := extractURN(string(shareResp))
+	// The post URN is returned in a header
+	postID := resp.Header.Get("x-restli-id")
 
 	return &social.UploadResponse{
-		PlatformPostID: shareID,
-		PostURL:        fmt.Sprintf("https://www.linkedin.com/feed/update/%s", shareID),
+		PlatformPostID: postID,
+		PostURL:        fmt.Sprintf("https://www.linkedin.com/feed/update/%s", postID),
 		Status:         "published",
 	}, nil
 }
@@ -221,16 +191,189 @@ func (l *LinkedInPlatform) GetAnalytics(ctx context.Context, account *social.Soc
 
 // DeletePost deletes a post
 func (l *LinkedInPlatform) DeletePost(ctx context.Context, account *social.SocialAccount, postID string) error {
-	deleteURL := LinkedInAPIURL + "/ugcPosts/" + postID
-	headers := map[string]string{
-		"Authorization":             "Bearer " + account.AccessToken,
-		"X-Restli-Protocol-Version": "2.0.0",
+	deleteURL := LinkedInRestURL + "/posts/" + url.PathEscape(postID)
+	_, err := l.makeRequest(ctx, "DELETE", deleteURL, nil, l.restHeaders(account.AccessToken))
+	return err
+}
+
+// EditableFields lists what can be changed on a published post. LinkedIn
+// only allows the commentary of a post to be edited.
+func (l *LinkedInPlatform) EditableFields() []string {
+	return []string{social.PostFieldDescription}
+}
+
+// UpdatePost changes the commentary of a post
+func (l *LinkedInPlatform) UpdatePost(ctx context.Context, account *social.SocialAccount, postID string, update *social.PostUpdate) error {
+	patch := map[string]interface{}{
+		"patch": map[string]interface{}{
+			"$set": map[string]string{
+				"commentary": *update.Description,
+			},
+		},
+	}
+	patchJSON, _ := json.Marshal(patch)
+
+	headers := l.restHeaders(account.AccessToken)
+	headers["X-RestLi-Method"] = "PARTIAL_UPDATE"
+
+	_, err := l.makeRequest(ctx, "POST", LinkedInRestURL+"/posts/"+url.PathEscape(postID), patchJSON, headers)
+	return err
+}
+
+// linkedinComment is a comment as returned by the Social Actions API
+type linkedinComment struct {
+	URN     string `json:"$URN"`
+	Actor   string `json:"actor"`
+	Message struct {
+		Text string `json:"text"`
+	} `json:"message"`
+	Created struct {
+		Time int64 `json:"time"`
+	} `json:"created"`
+	LikesSummary struct {
+		TotalLikes int64 `json:"totalLikes"`
+	} `json:"likesSummary"`
+	CommentsSummary struct {
+		AggregatedTotalComments int64 `json:"aggregatedTotalComments"`
+	} `json:"commentsSummary"`
+}
+
+// GetComments lists the comments of a post with their replies. LinkedIn
+// identifies authors by member URN only, so comments carry no name.
+func (l *LinkedInPlatform) GetComments(ctx context.Context, account *social.SocialAccount, postID string) ([]*social.Comment, error) {
+	threads, err := l.listComments(ctx, account, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []*social.Comment
+	for _, thread := range threads {
+		comments = append(comments, thread.toComment(account, postID, ""))
+		if thread.CommentsSummary.AggregatedTotalComments == 0 {
+			continue
+		}
+		replies, err := l.listComments(ctx, account, thread.URN)
+		if err != nil {
+			return nil, err
+		}
+		for _, reply := range replies {
+			comments = append(comments, reply.toComment(account, postID, thread.URN))
+		}
+		if len(comments) >= commentFetchLimit {
+			break
+		}
+	}
+
+	return comments, nil
+}
+
+// listComments lists the comments on a post or the replies to a comment
+func (l *LinkedInPlatform) listComments(ctx context.Context, account *social.SocialAccount, target string) ([]linkedinComment, error) {
+	var comments []linkedinComment
+	for start := 0; start < commentFetchLimit; start += 100 {
+		listURL := fmt.Sprintf("%s/socialActions/%s/comments?start=%d&count=100", LinkedInRestURL, url.PathEscape(target), start)
+		resp, err := l.makeRequest(ctx, "GET", listURL, nil, l.restHeaders(account.AccessToken))
+		if err != nil {
+			return nil, fmt.Errorf("comments fetch failed: %w", err)
+		}
+
+		var page struct {
+			Elements []linkedinComment `json:"elements"`
+		}
+		if err := json.Unmarshal(resp, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse comments: %w", err)
+		}
+
+		comments = append(comments, page.Elements...)
+		if len(page.Elements) < 100 {
+			break
+		}
+	}
+	return comments, nil
+}
+
+// ReplyToComment replies to a comment as the member
+func (l *LinkedInPlatform) ReplyToComment(ctx context.Context, account *social.SocialAccount, postID, commentID, text string) (*social.Comment, error) {
+	reply := map[string]interface{}{
+		"actor":         "urn:li:person:" + account.AccountID,
+		"object":        postID,
+		"parentComment": commentID,
+		"message": map[string]string{
+			"text": text,
+		},
+	}
+	replyJSON, _ := json.Marshal(reply)
+
+	resp, err := l.makeRequest(ctx, "POST", fmt.Sprintf("%s/socialActions/%s/comments", LinkedInRestURL, url.PathEscape(commentID)), replyJSON, l.restHeaders(account.AccessToken))
+	if err != nil {
+		return nil, fmt.Errorf("reply failed: %w", err)
 	}
 
-	_, err := l.makeRequest(ctx, "DELETE", deleteURL, nil, headers)
+	var created linkedinComment
+	if err := json.Unmarshal(resp, &created); err != nil || created.URN == "" {
+		return nil, fmt.Errorf("failed to parse reply: %s", string(resp))
+	}
+
+	comment := created.toComment(account, postID, commentID)
+	if comment.PublishedAt.IsZero() {
+		comment.PublishedAt = time.Now()
+	}
+	return comment, nil
+}
+
+// HideComment is not supported; LinkedIn has no API to hide comments
+func (l *LinkedInPlatform) HideComment(ctx context.Context, account *social.SocialAccount, postID, commentID string, hidden bool) error {
+	return fmt.Errorf("LinkedIn does not support hiding comments: %w", ErrUnsupported)
+}
+
+// DeleteComment deletes a comment on the member's post
+func (l *LinkedInPlatform) DeleteComment(ctx context.Context, account *social.SocialAccount, postID, commentID string) error {
+	target, id, ok := splitLinkedInComment(commentID)
+	if !ok {
+		return fmt.Errorf("invalid comment URN %q", commentID)
+	}
+
+	deleteURL := fmt.Sprintf("%s/socialActions/%s/comments/%s?actor=%s", LinkedInRestURL,
+		url.PathEscape(target), url.PathEscape(id), url.QueryEscape("urn:li:person:"+account.AccountID))
+	_, err := l.makeRequest(ctx, "DELETE", deleteURL, nil, l.restHeaders(account.AccessToken))
 	return err
 }
 
+// splitLinkedInComment splits a comment URN such as
+// urn:li:comment:(urn:li:activity:123,456) into the URN it was made on and
+// its ID
+func splitLinkedInComment(urn string) (string, string, bool) {
+	inner := strings.TrimSuffix(strings.TrimPrefix(urn, "urn:li:comment:("), ")")
+	if inner == urn {
+		return "", "", false
+	}
+	i := strings.LastIndex(inner, ",")
+	if i < 0 {
+		return "", "", false
+	}
+	return inner[:i], inner[i+1:], true
+}
+
+func (c *linkedinComment) toComment(account *social.SocialAccount, postID, parentID string) *social.Comment {
+	comment := &social.Comment{
+		RemoteID:       c.URN,
+		RemotePostID:   postID,
+		RemoteParentID: parentID,
+		AuthorID:       c.Actor,
+		FromAccount:    c.Actor == "urn:li:person:"+account.AccountID,
+		Text:           c.Message.Text,
+		Likes:          c.LikesSummary.TotalLikes,
+		Status:         social.CommentStatusVisible,
+	}
+	if comment.FromAccount {
+		comment.AuthorName = account.AccountName
+	}
+	if c.Created.Time > 0 {
+		comment.PublishedAt = time.UnixMilli(c.Created.Time)
+	}
+	return comment
+}
+
 // GetTrends retrieves trending topics (LinkedIn doesn't have a public trends API)
 func (l *LinkedInPlatform) GetTrends(ctx context.Context, account *social.SocialAccount, region string) ([]*social.PlatformTrend, error) {
 	return []*social.PlatformTrend{
@@ -294,7 +437,7 @@ func (l *LinkedInPlatform) makeRequest(ctx context.Context, method, url string,
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	return respBody, nil
@@ -313,14 +456,158 @@ func (l *LinkedInPlatform) makeFormRequest(ctx context.Context, method, url stri
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	return respBody, nil
 }
 
-func extractURN(response string) string {
-	// Extract URN from LinkedIn response
-	// Format: urn:li:share:123456789
-	return response
+// restHeaders returns the headers of the versioned LinkedIn REST API
+func (l *LinkedInPlatform) restHeaders(accessToken string) map[string]string {
+	return map[string]string{
+		"Authorization":             "Bearer " + accessToken,
+		"Content-Type":              "application/json",
+		"LinkedIn-Version":          LinkedInVersion,
+		"X-Restli-Protocol-Version": "2.0.0",
+	}
+}
+
+// linkedinUpload implements the LinkedIn Videos API multipart upload
+type linkedinUpload struct {
+	platform *LinkedInPlatform
+	account  *social.SocialAccount
+}
+
+// linkedinUploadState holds the parts LinkedIn asked for and the ETags of
+// the parts uploaded so far
+type linkedinUploadState struct {
+	UploadToken string               `json:"uploadToken"`
+	Parts       []linkedinUploadPart `json:"parts"`
+	ETags       []string             `json:"etags"`
+}
+
+type linkedinUploadPart struct {
+	UploadURL string `json:"uploadUrl"`
+	FirstByte int64  `json:"firstByte"`
+	LastByte  int64  `json:"lastByte"`
+}
+
+func (u *linkedinUpload) start(ctx context.Context, session *social.UploadSession) error {
+	initData := map[string]interface{}{
+		"initializeUploadRequest": map[string]interface{}{
+			"owner":           "urn:li:person:" + u.account.AccountID,
+			"fileSizeBytes":   session.FileSize,
+			"uploadCaptions":  false,
+			"uploadThumbnail": false,
+		},
+	}
+
+	jsonData, _ := json.Marshal(initData)
+	resp, err := u.platform.makeRequest(ctx, "POST", LinkedInRestURL+"/videos?action=initializeUpload", jsonData, u.platform.restHeaders(u.account.AccessToken))
+	if err != nil {
+		return fmt.Errorf("initialize upload failed: %w", err)
+	}
+
+	var initResp struct {
+		Value struct {
+			Video              string               `json:"video"`
+			UploadToken        string               `json:"uploadToken"`
+			UploadURLsExpireAt int64                `json:"uploadUrlsExpireAt"`
+			UploadInstructions []linkedinUploadPart `json:"uploadInstructions"`
+		} `json:"value"`
+	}
+
+	if err := json.Unmarshal(resp, &initResp); err != nil {
+		return fmt.Errorf("failed to parse initialize response: %w", err)
+	}
+	if len(initResp.Value.UploadInstructions) == 0 {
+		return fmt.Errorf("no upload instructions returned")
+	}
+
+	session.RemoteID = initResp.Value.Video
+	if initResp.Value.UploadURLsExpireAt > 0 {
+		expiresAt := time.UnixMilli(initResp.Value.UploadURLsExpireAt)
+		session.ExpiresAt = &expiresAt
+	}
+
+	return storeUploadState(session, linkedinUploadState{
+		UploadToken: initResp.Value.UploadToken,
+		Parts:       initResp.Value.UploadInstructions,
+	})
+}
+
+// resume trusts the persisted offset; the ETags of sent parts are kept in the state
+func (u *linkedinUpload) resume(ctx context.Context, session *social.UploadSession) (int64, error) {
+	return session.Offset, nil
+}
+
+func (u *linkedinUpload) chunkSize(session *social.UploadSession) int64 {
+	part, _, err := u.nextPart(session)
+	if err != nil {
+		return 0
+	}
+	return part.LastByte - part.FirstByte + 1
+}
+
+func (u *linkedinUpload) send(ctx context.Context, session *social.UploadSession, chunk []byte) (int64, error) {
+	part, state, err := u.nextPart(session)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, body, err := doRequest(ctx, uploadHTTPClient, "PUT", part.UploadURL, bytes.NewReader(chunk), map[string]string{
+		"Content-Type": "application/octet-stream",
+	})
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	state.ETags = append(state.ETags, resp.Header.Get("ETag"))
+	if err := storeUploadState(session, state); err != nil {
+		return 0, err
+	}
+
+	return part.LastByte + 1, nil
+}
+
+func (u *linkedinUpload) finish(ctx context.Context, session *social.UploadSession) error {
+	var state linkedinUploadState
+	if err := loadUploadState(session, &state); err != nil {
+		return err
+	}
+
+	finalizeData := map[string]interface{}{
+		"finalizeUploadRequest": map[string]interface{}{
+			"video":           session.RemoteID,
+			"uploadToken":     state.UploadToken,
+			"uploadedPartIds": state.ETags,
+		},
+	}
+
+	jsonData, _ := json.Marshal(finalizeData)
+	_, err := u.platform.makeRequest(ctx, "POST", LinkedInRestURL+"/videos?action=finalizeUpload", jsonData, u.platform.restHeaders(u.account.AccessToken))
+	if err != nil {
+		return fmt.Errorf("finalize upload failed: %w", err)
+	}
+
+	return nil
+}
+
+// nextPart returns the upload instruction starting at the session offset
+func (u *linkedinUpload) nextPart(session *social.UploadSession) (linkedinUploadPart, linkedinUploadState, error) {
+	var state linkedinUploadState
+	if err := loadUploadState(session, &state); err != nil {
+		return linkedinUploadPart{}, state, err
+	}
+
+	for _, part := range state.Parts {
+		if part.FirstByte == session.Offset {
+			return part, state, nil
+		}
+	}
+
+	return linkedinUploadPart{}, state, fmt.Errorf("no upload part starts at byte %d", session.Offset)
 }
@@ -0,0 +1,104 @@
+package social
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.216.34", want: true},
+		{addr: "2606:4700::1111", want: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "10.0.0.1"},
+		{addr: "172.16.5.4"},
+		{addr: "192.168.1.1"},
+		{addr: "169.254.169.254"}, // cloud metadata service
+		{addr: "100.64.0.1"},
+		{addr: "0.0.0.0"},
+		{addr: "224.0.0.1"},
+		{addr: "255.255.255.255"},
+		{addr: "198.51.100.7"},
+		{addr: "fd00::1"},
+		{addr: "fe80::1"},
+		{addr: "::ffff:127.0.0.1"}, // IPv4-mapped loopback
+		{addr: "::ffff:10.0.0.1"},
+		{addr: "64:ff9b::a00:1"}, // NAT64 of 10.0.0.1
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := isPublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("isPublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPublicHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewPublicHTTPClient(5 * time.Second)
+
+	t.Run("refuses to dial a loopback server", func(t *testing.T) {
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+			t.Fatal("Get succeeded, want ErrNonPublicAddress")
+		}
+		if !errors.Is(err, ErrNonPublicAddress) {
+			t.Errorf("Get error = %v, want ErrNonPublicAddress", err)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		target  string
+		via     int
+		wantErr error
+		fails   bool
+	}{
+		{name: "public redirect", target: "https://93.184.216.34/feed", via: 1},
+		{name: "redirect to the metadata service", target: "http://169.254.169.254/latest/meta-data", via: 1, wantErr: ErrNonPublicAddress, fails: true},
+		{name: "redirect to a private host", target: "http://10.1.2.3:8080/", via: 1, wantErr: ErrNonPublicAddress, fails: true},
+		{name: "redirect to another scheme", target: "file:///etc/passwd", via: 1, fails: true},
+		{name: "too many redirects", target: "https://93.184.216.34/feed", via: maxPublicRedirects, fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := url.Parse(tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := &http.Request{URL: target, Header: http.Header{}}
+			via := make([]*http.Request, tt.via)
+
+			err = client.CheckRedirect(req, via)
+			if !tt.fails {
+				if err != nil {
+					t.Errorf("CheckRedirect = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("CheckRedirect = nil, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckRedirect = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package social
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"renderowl-api/internal/domain/social"
+)
+
+// Notifier tells account owners about problems with their accounts
+type Notifier interface {
+	// NotifyAccountStatus is called when an account leaves the connected state
+	NotifyAccountStatus(ctx context.Context, account *social.SocialAccount) error
+}
+
+// LogNotifier writes account notifications to the application log
+type LogNotifier struct{}
+
+// NotifyAccountStatus logs the account status change
+func (LogNotifier) NotifyAccountStatus(ctx context.Context, account *social.SocialAccount) error {
+	log.Printf("Account %s (%s) of user %s is %s: %s",
+		account.ID, account.Platform, account.UserID, account.Status, account.StatusReason)
+	return nil
+}
+
+// WebhookNotifier posts account notifications as JSON to a URL
+type WebhookNotifier struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting to the given URL
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:        url,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NotifyAccountStatus posts the account status change to the webhook
+func (n *WebhookNotifier) NotifyAccountStatus(ctx context.Context, account *social.SocialAccount) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"event":       "account.status_changed",
+		"userId":      account.UserID,
+		"accountId":   account.ID,
+		"platform":    account.Platform,
+		"accountName": account.AccountName,
+		"status":      account.Status,
+		"reason":      account.StatusReason,
+		"timestamp":   time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", n.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package social
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"renderowl-api/internal/domain/social"
+)
+
+// oauthSessionTTL is how long a user has to complete an OAuth connect flow
+const oauthSessionTTL = 10 * time.Minute
+
+var (
+	// ErrOAuthSessionNotFound is returned for unknown, expired or already used states
+	ErrOAuthSessionNotFound = errors.New("oauth session not found or expired")
+	// ErrOAuthSessionMismatch is returned when a callback does not match the session it claims
+	ErrOAuthSessionMismatch = errors.New("oauth session does not match callback")
+	// ErrPasswordLogin is returned when an OAuth flow is started for a platform that connects with an app password
+	ErrPasswordLogin = errors.New("platform connects with an app password, not oauth")
+	// ErrConfiguredDestination is returned when an OAuth flow is started for a custom destination
+	ErrConfiguredDestination = errors.New("destination is configured with settings, not oauth")
+)
+
+// OAuthSession is the server-side record of a pending connect flow
+type OAuthSession struct {
+	State        string                `json:"state"`
+	UserID       string                `json:"userId"`
+	Platform     social.SocialPlatform `json:"platform"`
+	CodeVerifier string                `json:"codeVerifier,omitempty"`
+	CreatedAt    time.Time             `json:"createdAt"`
+}
+
+// OAuthSessionStore keeps pending OAuth sessions in Redis. Sessions expire
+// after a few minutes and can be consumed once.
+type OAuthSessionStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewOAuthSessionStore creates a session store backed by Redis
+func NewOAuthSessionStore(redisAddr, redisPassword string) *OAuthSessionStore {
+	client := redis.NewClient(&redis.Options{
+		Addr:     redisAddr,
+		Password: redisPassword,
+		DB:       0,
+	})
+
+	return &OAuthSessionStore{
+		client: client,
+		ttl:    oauthSessionTTL,
+	}
+}
+
+// Save stores a new session. States are never reused.
+func (s *OAuthSessionStore) Save(ctx context.Context, session *OAuthSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.client.SetNX(ctx, oauthSessionKey(session.State), data, s.ttl).Result()
+	if err != nil {
+		return fmt.Errorf("failed to save oauth session: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("oauth state already in use")
+	}
+
+	return nil
+}
+
+// Consume removes and returns the session for a state. A second call for
+// the same state returns ErrOAuthSessionNotFound.
+func (s *OAuthSessionStore) Consume(ctx context.Context, state string) (*OAuthSession, error) {
+	data, err := s.client.GetDel(ctx, oauthSessionKey(state)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrOAuthSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load oauth session: %w", err)
+	}
+
+	var session OAuthSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse oauth session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// Close closes the Redis connection
+func (s *OAuthSessionStore) Close() error {
+	return s.client.Close()
+}
+
+func oauthSessionKey(state string) string {
+	return "oauth:session:" + state
+}
+
+// generateState returns an unguessable OAuth state
+func generateState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
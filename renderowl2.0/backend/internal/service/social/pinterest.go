@@ -0,0 +1,529 @@
+package social
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"renderowl-api/internal/domain/social"
+)
+
+// PinterestPlatform implements the Platform interface for Pinterest video pins
+type PinterestPlatform struct {
+	appID       string
+	appSecret   string
+	redirectURL string
+	httpClient  *http.Client
+}
+
+// Pinterest API endpoints
+const (
+	PinterestAuthURL  = "https://www.pinterest.com/oauth/"
+	PinterestTokenURL = "https://api.pinterest.com/v5/oauth/token"
+	PinterestAPIURL   = "https://api.pinterest.com/v5"
+)
+
+// Pinterest processes uploaded videos before they can be pinned
+const (
+	pinterestPollInterval = 5 * time.Second
+	pinterestPollTimeout  = 10 * time.Minute
+
+	// pinterestAnalyticsDays is the longest range pin analytics cover
+	pinterestAnalyticsDays = 90
+)
+
+// NewPinterestPlatform creates a new Pinterest platform instance
+func NewPinterestPlatform(appID, appSecret, redirectURL string) *PinterestPlatform {
+	return &PinterestPlatform{
+		appID:       appID,
+		appSecret:   appSecret,
+		redirectURL: redirectURL,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// GetName returns the platform name
+func (p *PinterestPlatform) GetName() social.SocialPlatform {
+	return social.PlatformPinterest
+}
+
+// GetAuthURL returns the OAuth URL
+func (p *PinterestPlatform) GetAuthURL(state string) string {
+	params := url.Values{
+		"client_id":     {p.appID},
+		"redirect_uri":  {p.redirectURL},
+		"response_type": {"code"},
+		"scope":         {"boards:read,pins:read,pins:write,user_accounts:read"},
+		"state":         {state},
+	}
+
+	return PinterestAuthURL + "?" + params.Encode()
+}
+
+// ExchangeCode exchanges OAuth code for tokens
+func (p *PinterestPlatform) ExchangeCode(ctx context.Context, code string) (*social.SocialAccount, error) {
+	data := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {p.redirectURL},
+	}
+
+	tokenResp, err := p.requestToken(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+
+	// Get user info
+	userInfo, err := p.getUserInfo(ctx, tokenResp.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	username, _ := userInfo["username"].(string)
+	accountID, _ := userInfo["id"].(string)
+	if accountID == "" {
+		accountID = username
+	}
+
+	expiry := time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+
+	return &social.SocialAccount{
+		Platform:     social.PlatformPinterest,
+		AccountID:    accountID,
+		AccountName:  username,
+		AccessToken:  tokenResp.AccessToken,
+		RefreshToken: tokenResp.RefreshToken,
+		TokenExpiry:  &expiry,
+		Status:       social.StatusConnected,
+		Metadata:     userInfo,
+	}, nil
+}
+
+// RefreshToken refreshes the access token
+func (p *PinterestPlatform) RefreshToken(ctx context.Context, account *social.SocialAccount) error {
+	data := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {account.RefreshToken},
+	}
+
+	tokenResp, err := p.requestToken(ctx, data)
+	if err != nil {
+		account.Status = social.StatusExpired
+		return fmt.Errorf("token refresh failed: %w", err)
+	}
+
+	expiry := time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+
+	account.AccessToken = tokenResp.AccessToken
+	if tokenResp.RefreshToken != "" {
+		account.RefreshToken = tokenResp.RefreshToken
+	}
+	account.TokenExpiry = &expiry
+	account.Status = social.StatusConnected
+
+	return nil
+}
+
+// UploadVideo uploads a video and pins it to a board. The board is taken
+// from the boardId upload option, the account's default board, or the
+// account's first board.
+func (p *PinterestPlatform) UploadVideo(ctx context.Context, account *social.SocialAccount, req *social.UploadRequest) (*social.UploadResponse, error) {
+	// Refresh token if needed
+	if account.TokenExpiry != nil && account.TokenExpiry.Before(time.Now()) {
+		if err := p.RefreshToken(ctx, account); err != nil {
+			return nil, err
+		}
+	}
+
+	boardID, err := p.boardFor(ctx, account, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 1: Register the upload
+	resp, err := p.makeRequest(ctx, "POST", PinterestAPIURL+"/media", map[string]string{"media_type": "video"}, p.headers(account))
+	if err != nil {
+		return nil, fmt.Errorf("media registration failed: %w", err)
+	}
+
+	var media struct {
+		MediaID          string            `json:"media_id"`
+		UploadURL        string            `json:"upload_url"`
+		UploadParameters map[string]string `json:"upload_parameters"`
+	}
+	if err := json.Unmarshal(resp, &media); err != nil {
+		return nil, fmt.Errorf("failed to parse media response: %w", err)
+	}
+
+	// Step 2: Upload the file to the storage Pinterest handed out
+	if err := p.uploadFile(ctx, media.UploadURL, media.UploadParameters, req.VideoPath); err != nil {
+		return nil, fmt.Errorf("video upload failed: %w", err)
+	}
+
+	// Step 3: Wait for the video to be processed
+	if err := p.waitForMedia(ctx, account, media.MediaID); err != nil {
+		return nil, err
+	}
+
+	// Step 4: Create the pin
+	mediaSource := map[string]interface{}{
+		"source_type": "video_id",
+		"media_id":    media.MediaID,
+	}
+	if cover := req.Metadata["coverImageUrl"]; cover != "" {
+		mediaSource["cover_image_url"] = cover
+	} else {
+		mediaSource["cover_image_key_frame_time"] = 0
+	}
+
+	pin := map[string]interface{}{
+		"board_id":     boardID,
+		"title":        req.Title,
+		"description":  req.Description,
+		"media_source": mediaSource,
+	}
+	if link := req.Metadata["link"]; link != "" {
+		pin["link"] = link
+	}
+
+	resp, err = p.makeRequest(ctx, "POST", PinterestAPIURL+"/pins", pin, p.headers(account))
+	if err != nil {
+		return nil, fmt.Errorf("pin creation failed: %w", err)
+	}
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse pin response: %w", err)
+	}
+
+	return &social.UploadResponse{
+		PlatformPostID: result.ID,
+		PostURL:        fmt.Sprintf("https://www.pinterest.com/pin/%s/", result.ID),
+		Status:         "published",
+	}, nil
+}
+
+// GetAnalytics retrieves analytics for a pin over the last 90 days
+func (p *PinterestPlatform) GetAnalytics(ctx context.Context, account *social.SocialAccount, postID string) (*social.AnalyticsData, error) {
+	end := time.Now().UTC()
+	start := end.AddDate(0, 0, -(pinterestAnalyticsDays - 1))
+	params := url.Values{
+		"start_date":   {start.Format("2006-01-02")},
+		"end_date":     {end.Format("2006-01-02")},
+		"metric_types": {"IMPRESSION,SAVE,PIN_CLICK,OUTBOUND_CLICK,VIDEO_MRC_VIEW,VIDEO_AVG_WATCH_TIME,VIDEO_V50_WATCH_TIME"},
+	}
+
+	resp, err := p.makeRequest(ctx, "GET", fmt.Sprintf("%s/pins/%s/analytics?%s", PinterestAPIURL, postID, params.Encode()), nil, p.headers(account))
+	if err != nil {
+		return nil, fmt.Errorf("analytics fetch failed: %w", err)
+	}
+
+	var result struct {
+		All struct {
+			SummaryMetrics map[string]float64 `json:"summary_metrics"`
+		} `json:"all"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse analytics: %w", err)
+	}
+
+	metrics := result.All.SummaryMetrics
+	analytics := &social.AnalyticsData{
+		Platform: social.PlatformPinterest,
+		Views:    int64(metrics["VIDEO_MRC_VIEW"]),
+		Shares:   int64(metrics["SAVE"]),
+		// Total watch time is reported in milliseconds
+		WatchTime: int64(metrics["VIDEO_V50_WATCH_TIME"] / 1000),
+		Data: social.JSON{
+			"impressions":    int64(metrics["IMPRESSION"]),
+			"saves":          int64(metrics["SAVE"]),
+			"pinClicks":      int64(metrics["PIN_CLICK"]),
+			"outboundClicks": int64(metrics["OUTBOUND_CLICK"]),
+			"avgWatchTimeMs": metrics["VIDEO_AVG_WATCH_TIME"],
+		},
+	}
+	if impressions := metrics["IMPRESSION"]; impressions > 0 {
+		analytics.Engagement = (metrics["SAVE"] + metrics["PIN_CLICK"] + metrics["OUTBOUND_CLICK"]) / impressions * 100
+	}
+
+	return analytics, nil
+}
+
+// DeletePost deletes a pin
+func (p *PinterestPlatform) DeletePost(ctx context.Context, account *social.SocialAccount, postID string) error {
+	_, err := p.makeRequest(ctx, "DELETE", fmt.Sprintf("%s/pins/%s", PinterestAPIURL, postID), nil, p.headers(account))
+	return err
+}
+
+// EditableFields lists what can be changed on a published pin
+func (p *PinterestPlatform) EditableFields() []string {
+	return []string{social.PostFieldTitle, social.PostFieldDescription}
+}
+
+// UpdatePost changes the title or description of a pin
+func (p *PinterestPlatform) UpdatePost(ctx context.Context, account *social.SocialAccount, postID string, update *social.PostUpdate) error {
+	body := map[string]string{}
+	if update.Title != nil {
+		body["title"] = *update.Title
+	}
+	if update.Description != nil {
+		body["description"] = *update.Description
+	}
+
+	_, err := p.makeRequest(ctx, "PATCH", fmt.Sprintf("%s/pins/%s", PinterestAPIURL, postID), body, p.headers(account))
+	return err
+}
+
+// GetTrends retrieves the keywords growing fastest in a region
+func (p *PinterestPlatform) GetTrends(ctx context.Context, account *social.SocialAccount, region string) ([]*social.PlatformTrend, error) {
+	trendsURL := fmt.Sprintf("%s/trends/keywords/%s/top/growing?limit=20", PinterestAPIURL, url.PathEscape(region))
+
+	resp, err := p.makeRequest(ctx, "GET", trendsURL, nil, p.headers(account))
+	if err != nil {
+		return nil, fmt.Errorf("trends fetch failed: %w", err)
+	}
+
+	var result struct {
+		Trends []struct {
+			Keyword      string  `json:"keyword"`
+			PctGrowthWoW float64 `json:"pct_growth_wow"`
+			PctGrowthMoM float64 `json:"pct_growth_mom"`
+		} `json:"trends"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse trends: %w", err)
+	}
+
+	trends := make([]*social.PlatformTrend, 0, len(result.Trends))
+	for _, t := range result.Trends {
+		trends = append(trends, &social.PlatformTrend{
+			Platform:    social.PlatformPinterest,
+			TrendType:   "keyword",
+			Title:       t.Keyword,
+			Description: fmt.Sprintf("%+.0f%% week over week, %+.0f%% month over month", t.PctGrowthWoW, t.PctGrowthMoM),
+			URL:         "https://www.pinterest.com/search/pins/?q=" + url.QueryEscape(t.Keyword),
+			Region:      region,
+			FetchedAt:   time.Now(),
+		})
+	}
+
+	return trends, nil
+}
+
+// Helper methods
+
+type pinterestToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// requestToken calls the token endpoint, which authenticates the app with
+// HTTP basic auth
+func (p *PinterestPlatform) requestToken(ctx context.Context, data url.Values) (*pinterestToken, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", PinterestTokenURL, bytes.NewBufferString(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(p.appID, p.appSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var token pinterestToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	return &token, nil
+}
+
+func (p *PinterestPlatform) getUserInfo(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+	headers := map[string]string{
+		"Authorization": "Bearer " + accessToken,
+	}
+
+	resp, err := p.makeRequest(ctx, "GET", PinterestAPIURL+"/user_account", nil, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// boardFor resolves the board a video is pinned to
+func (p *PinterestPlatform) boardFor(ctx context.Context, account *social.SocialAccount, req *social.UploadRequest) (string, error) {
+	if boardID := req.Metadata["boardId"]; boardID != "" {
+		return boardID, nil
+	}
+	if boardID, ok := account.Metadata["defaultBoardId"].(string); ok && boardID != "" {
+		return boardID, nil
+	}
+
+	resp, err := p.makeRequest(ctx, "GET", PinterestAPIURL+"/boards?page_size=1", nil, p.headers(account))
+	if err != nil {
+		return "", fmt.Errorf("failed to list boards: %w", err)
+	}
+
+	var boards struct {
+		Items []struct {
+			ID string `json:"id"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(resp, &boards); err != nil {
+		return "", fmt.Errorf("failed to parse boards: %w", err)
+	}
+	if len(boards.Items) == 0 {
+		return "", fmt.Errorf("pinterest account %s has no board to pin to", account.AccountName)
+	}
+
+	return boards.Items[0].ID, nil
+}
+
+// uploadFile posts the video as a multipart form with the fields Pinterest
+// signed, streaming it from disk
+func (p *PinterestPlatform) uploadFile(ctx context.Context, uploadURL string, fields map[string]string, videoPath string) error {
+	file, err := os.Open(videoPath)
+	if err != nil {
+		return fmt.Errorf("failed to open video file: %w", err)
+	}
+	defer file.Close()
+
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		for key, value := range fields {
+			if err := form.WriteField(key, value); err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+		}
+		part, err := form.CreateFormFile("file", filepath.Base(videoPath))
+		if err == nil {
+			_, err = io.Copy(part, file)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	resp, respBody, err := doRequest(ctx, uploadHTTPClient, "POST", uploadURL, body, map[string]string{
+		"Content-Type": form.FormDataContentType(),
+	})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	return nil
+}
+
+// waitForMedia polls an uploaded video until Pinterest has processed it
+func (p *PinterestPlatform) waitForMedia(ctx context.Context, account *social.SocialAccount, mediaID string) error {
+	deadline := time.Now().Add(pinterestPollTimeout)
+	for {
+		resp, err := p.makeRequest(ctx, "GET", fmt.Sprintf("%s/media/%s", PinterestAPIURL, mediaID), nil, p.headers(account))
+		if err != nil {
+			return fmt.Errorf("media status check failed: %w", err)
+		}
+
+		var media struct {
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal(resp, &media); err != nil {
+			return fmt.Errorf("failed to parse media status: %w", err)
+		}
+
+		switch media.Status {
+		case "succeeded":
+			return nil
+		case "failed":
+			return fmt.Errorf("pinterest failed to process video %s", mediaID)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("video %s still %s after %s", mediaID, media.Status, pinterestPollTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pinterestPollInterval):
+		}
+	}
+}
+
+func (p *PinterestPlatform) headers(account *social.SocialAccount) map[string]string {
+	return map[string]string{
+		"Authorization": "Bearer " + account.AccessToken,
+	}
+}
+
+func (p *PinterestPlatform) makeRequest(ctx context.Context, method, url string, body interface{}, headers map[string]string) ([]byte, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		bodyReader = bytes.NewReader(jsonBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	return respBody, nil
+}
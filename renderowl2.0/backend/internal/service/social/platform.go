@@ -2,6 +2,9 @@ package social
 
 import (
 	"context"
+	"errors"
+	"time"
+
 	"renderowl-api/internal/domain/social"
 )
 
@@ -32,6 +35,82 @@ type Platform interface {
 	GetTrends(ctx context.Context, account *social.SocialAccount, region string) ([]*social.PlatformTrend, error)
 }
 
+// PKCEPlatform is implemented by platforms whose OAuth flow uses PKCE. The
+// verifier is kept in the OAuth session between the two calls.
+type PKCEPlatform interface {
+	// GetAuthURLWithChallenge returns the OAuth URL with an S256 code challenge
+	GetAuthURLWithChallenge(state, codeChallenge string) string
+
+	// ExchangeCodeWithVerifier exchanges OAuth code for tokens using the PKCE verifier
+	ExchangeCodeWithVerifier(ctx context.Context, code, codeVerifier string) (*social.SocialAccount, error)
+}
+
+// PasswordPlatform is implemented by platforms whose accounts connect with
+// an app password instead of an OAuth flow
+type PasswordPlatform interface {
+	// Login creates a session for an account from its identifier and app password
+	Login(ctx context.Context, identifier, password string) (*social.SocialAccount, error)
+}
+
+// EditablePlatform is implemented by platforms that can change a post
+// after it is published
+type EditablePlatform interface {
+	// EditableFields lists the PostUpdate fields the platform can change
+	EditableFields() []string
+
+	// UpdatePost changes a published post. Only editable fields are set.
+	UpdatePost(ctx context.Context, account *social.SocialAccount, postID string, update *social.PostUpdate) error
+}
+
+// CommentPlatform is implemented by platforms whose comments can be read
+// and moderated. Moderation actions a platform does not offer return
+// ErrUnsupported.
+type CommentPlatform interface {
+	// GetComments lists the comments and replies of a published post, most
+	// recent threads first, up to commentFetchLimit
+	GetComments(ctx context.Context, account *social.SocialAccount, postID string) ([]*social.Comment, error)
+
+	// ReplyToComment replies to a top-level comment of a post
+	ReplyToComment(ctx context.Context, account *social.SocialAccount, postID, commentID, text string) (*social.Comment, error)
+
+	// HideComment hides a comment from the public, or shows it again
+	HideComment(ctx context.Context, account *social.SocialAccount, postID, commentID string, hidden bool) error
+
+	// DeleteComment deletes a comment
+	DeleteComment(ctx context.Context, account *social.SocialAccount, postID, commentID string) error
+}
+
+// RetentionPlatform is implemented by platforms that report the audience
+// retention of videos
+type RetentionPlatform interface {
+	// GetRetention returns the retention curve of a published video, from
+	// its start to its end. It is empty while the platform has too few
+	// views to report one.
+	GetRetention(ctx context.Context, account *social.SocialAccount, postID string) ([]social.RetentionPoint, error)
+}
+
+// UploadLookupPlatform is implemented by platforms that can tell whether an
+// upload whose outcome is unknown went through, so it is not uploaded twice
+type UploadLookupPlatform interface {
+	// FindUpload returns the post that an upload made since a time created.
+	// It returns nil if the account has no such post, or if the platform
+	// drops repeated uploads with the same idempotency key.
+	FindUpload(ctx context.Context, account *social.SocialAccount, req *social.UploadRequest, since time.Time) (*social.UploadResponse, error)
+}
+
+// commentFetchLimit is the most comments read from a post per sync
+const commentFetchLimit = 500
+
+// errEnoughComments stops paging once commentFetchLimit is reached
+var errEnoughComments = errors.New("comment fetch limit reached")
+
+// DestinationPlatform is implemented by custom destinations, which are
+// configured with settings instead of connected through an OAuth flow
+type DestinationPlatform interface {
+	// Configure validates destination settings and returns the account to save
+	Configure(ctx context.Context, settings social.JSON) (*social.SocialAccount, error)
+}
+
 // PlatformRegistry manages all available platforms
 type PlatformRegistry struct {
 	platforms map[social.SocialPlatform]Platform
@@ -0,0 +1,272 @@
+package social
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"renderowl-api/internal/domain/social"
+)
+
+// uploadHTTPClient sends upload chunks, which take longer than API calls
+var uploadHTTPClient = &http.Client{Timeout: 10 * time.Minute}
+
+// UploadSessionRepository defines upload session storage operations
+type UploadSessionRepository interface {
+	GetByKey(ctx context.Context, key string) (*social.UploadSession, error)
+	Save(ctx context.Context, session *social.UploadSession) error
+	Delete(ctx context.Context, id string) error
+}
+
+// ResumablePlatform is implemented by platforms that upload through a
+// ResumableUploader
+type ResumablePlatform interface {
+	SetUploader(uploader *ResumableUploader)
+}
+
+// chunkedUpload is the platform side of a resumable upload. Each platform
+// keeps whatever it needs to continue the upload on the session.
+type chunkedUpload interface {
+	// start opens the upload on the platform
+	start(ctx context.Context, session *social.UploadSession) error
+
+	// resume returns the number of bytes the platform has acknowledged
+	// for an upload started earlier
+	resume(ctx context.Context, session *social.UploadSession) (int64, error)
+
+	// chunkSize returns the length of the next chunk to send
+	chunkSize(session *social.UploadSession) int64
+
+	// send uploads the chunk starting at session.Offset and returns the
+	// number of bytes acknowledged afterwards
+	send(ctx context.Context, session *social.UploadSession, chunk []byte) (int64, error)
+
+	// finish completes the upload once every byte is acknowledged
+	finish(ctx context.Context, session *social.UploadSession) error
+}
+
+// ResumableUploader sends videos in chunks and persists the progress after
+// every chunk, so a failed upload continues from the last acknowledged
+// byte the next time the same video is uploaded to the same account.
+type ResumableUploader struct {
+	sessions UploadSessionRepository
+}
+
+// NewResumableUploader creates an uploader. Without a session repository
+// uploads are still chunked but cannot be resumed after a failure.
+func NewResumableUploader(sessions UploadSessionRepository) *ResumableUploader {
+	return &ResumableUploader{sessions: sessions}
+}
+
+// Upload sends a video through the platform protocol, continuing an
+// earlier session for the same account and file if one is still valid
+func (u *ResumableUploader) Upload(ctx context.Context, account *social.SocialAccount, videoPath string, upload chunkedUpload) (*social.UploadSession, error) {
+	file, err := os.Open(videoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open video file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat video file: %w", err)
+	}
+
+	session, err := u.resume(ctx, account, videoPath, info, upload)
+	if err != nil {
+		return nil, err
+	}
+
+	if session == nil {
+		session = &social.UploadSession{
+			ID:          uuid.New().String(),
+			UploadKey:   uploadKey(account, videoPath),
+			AccountID:   account.ID,
+			Platform:    account.Platform,
+			VideoPath:   videoPath,
+			FileSize:    info.Size(),
+			FileModTime: fileModTime(info),
+			Status:      social.UploadStatusUploading,
+		}
+		if err := upload.start(ctx, session); err != nil {
+			return nil, fmt.Errorf("failed to start upload: %w", err)
+		}
+		if err := u.save(ctx, session); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf []byte
+	for session.Status == social.UploadStatusUploading && session.Offset < session.FileSize {
+		size := upload.chunkSize(session)
+		if size <= 0 || size > session.FileSize-session.Offset {
+			return nil, fmt.Errorf("invalid chunk of %d bytes at byte %d", size, session.Offset)
+		}
+		if int64(cap(buf)) < size {
+			buf = make([]byte, size)
+		}
+		chunk := buf[:size]
+
+		if _, err := file.ReadAt(chunk, session.Offset); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read video file: %w", err)
+		}
+
+		offset, err := upload.send(ctx, session, chunk)
+		if err != nil {
+			return nil, fmt.Errorf("upload stopped at byte %d of %d: %w", session.Offset, session.FileSize, err)
+		}
+		session.Offset = offset
+
+		if err := u.save(ctx, session); err != nil {
+			return nil, err
+		}
+	}
+
+	if session.Status == social.UploadStatusUploading {
+		if err := upload.finish(ctx, session); err != nil {
+			return nil, fmt.Errorf("failed to finish upload: %w", err)
+		}
+		session.Status = social.UploadStatusUploaded
+		if err := u.save(ctx, session); err != nil {
+			return nil, err
+		}
+	}
+
+	return session, nil
+}
+
+// Complete forgets an upload once the post using it has been created
+func (u *ResumableUploader) Complete(ctx context.Context, session *social.UploadSession) {
+	if u.sessions == nil {
+		return
+	}
+	if err := u.sessions.Delete(ctx, session.ID); err != nil {
+		log.Printf("Warning: Failed to delete upload session %s: %v", session.ID, err)
+	}
+}
+
+// resume loads a persisted session and asks the platform how far it got.
+// Sessions for a changed file, expired sessions and sessions the platform
+// no longer knows are discarded so the upload starts over.
+func (u *ResumableUploader) resume(ctx context.Context, account *social.SocialAccount, videoPath string, info os.FileInfo, upload chunkedUpload) (*social.UploadSession, error) {
+	if u.sessions == nil {
+		return nil, nil
+	}
+
+	session, err := u.sessions.GetByKey(ctx, uploadKey(account, videoPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load upload session: %w", err)
+	}
+	if session == nil {
+		return nil, nil
+	}
+
+	expired := session.ExpiresAt != nil && time.Now().After(*session.ExpiresAt)
+	changed := session.FileSize != info.Size() || !session.FileModTime.Equal(fileModTime(info))
+	if expired || changed {
+		return nil, u.discard(ctx, session)
+	}
+
+	if session.Status == social.UploadStatusUploaded {
+		return session, nil
+	}
+
+	offset, err := upload.resume(ctx, session)
+	if err != nil {
+		if code := statusCode(err); code == http.StatusNotFound || code == http.StatusGone {
+			return nil, u.discard(ctx, session)
+		}
+		return nil, fmt.Errorf("failed to resume upload: %w", err)
+	}
+
+	if offset != session.Offset {
+		log.Printf("Resuming %s upload of %s at byte %d of %d", session.Platform, videoPath, offset, session.FileSize)
+	}
+	session.Offset = offset
+
+	return session, nil
+}
+
+func (u *ResumableUploader) save(ctx context.Context, session *social.UploadSession) error {
+	if u.sessions == nil {
+		return nil
+	}
+	if err := u.sessions.Save(ctx, session); err != nil {
+		return fmt.Errorf("failed to save upload session: %w", err)
+	}
+	return nil
+}
+
+func (u *ResumableUploader) discard(ctx context.Context, session *social.UploadSession) error {
+	if err := u.sessions.Delete(ctx, session.ID); err != nil {
+		return fmt.Errorf("failed to discard upload session: %w", err)
+	}
+	return nil
+}
+
+// uploadKey identifies an upload of a file to an account across retries
+func uploadKey(account *social.SocialAccount, videoPath string) string {
+	return fmt.Sprintf("%s:%s:%s", account.Platform, account.ID, videoPath)
+}
+
+// fileModTime returns the modification time of a file at the precision
+// the database keeps
+func fileModTime(info os.FileInfo) time.Time {
+	return info.ModTime().Truncate(time.Microsecond)
+}
+
+// loadUploadState decodes the platform state of a session
+func loadUploadState(session *social.UploadSession, state interface{}) error {
+	if session.State == "" {
+		return fmt.Errorf("upload session has no state")
+	}
+	return json.Unmarshal([]byte(session.State), state)
+}
+
+// storeUploadState encodes the platform state of a session
+func storeUploadState(session *social.UploadSession, state interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	session.State = string(data)
+	return nil
+}
+
+// contentRange formats the Content-Range header of a chunk
+func contentRange(offset, length, total int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, total)
+}
+
+// doRequest sends a request and returns the response status, headers and
+// body without treating non-success statuses as errors. Upload protocols
+// use statuses such as 308 to report progress.
+func doRequest(ctx context.Context, client *http.Client, method, url string, body io.Reader, headers map[string]string) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp, respBody, nil
+}
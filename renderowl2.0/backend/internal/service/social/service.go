@@ -3,8 +3,12 @@ package social
 import (
 	"context"
 	"fmt"
+	"log"
 	"os"
+	"time"
 
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
 	"renderowl-api/internal/domain/social"
 )
 
@@ -14,6 +18,8 @@ type Service struct {
 	accounts  AccountRepository
 	posts     PostRepository
 	analytics AnalyticsRepository
+	notifier  Notifier
+	sessions  *OAuthSessionStore
 }
 
 // AccountRepository defines account storage operations
@@ -22,6 +28,7 @@ type AccountRepository interface {
 	GetByID(ctx context.Context, id string) (*social.SocialAccount, error)
 	GetByUser(ctx context.Context, userID string) ([]*social.SocialAccount, error)
 	GetByUserAndPlatform(ctx context.Context, userID string, platform social.SocialPlatform) (*social.SocialAccount, error)
+	GetExpiring(ctx context.Context, before time.Time) ([]*social.SocialAccount, error)
 	Update(ctx context.Context, account *social.SocialAccount) error
 	Delete(ctx context.Context, id string) error
 }
@@ -34,6 +41,7 @@ type PostRepository interface {
 	GetPending(ctx context.Context, before string) ([]*social.ScheduledPost, error)
 	Update(ctx context.Context, post *social.ScheduledPost) error
 	UpdateStatus(ctx context.Context, id string, status social.PostStatus, errorMsg string) error
+	SwapPlatformPostStatus(ctx context.Context, id string, from, to social.PostStatus) (bool, error)
 	Delete(ctx context.Context, id string) error
 }
 
@@ -56,6 +64,29 @@ func NewService(
 		accounts:  accounts,
 		posts:     posts,
 		analytics: analytics,
+		notifier:  LogNotifier{},
+	}
+}
+
+// SetNotifier replaces the notifier used to alert account owners
+func (s *Service) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// SetSessionStore sets the store that binds OAuth states to users
+func (s *Service) SetSessionStore(sessions *OAuthSessionStore) {
+	s.sessions = sessions
+}
+
+// SetUploadSessions persists resumable upload progress in the repository,
+// so failed uploads continue where they stopped when the publish is retried.
+// Call it after InitializePlatforms.
+func (s *Service) SetUploadSessions(sessions UploadSessionRepository) {
+	uploader := NewResumableUploader(sessions)
+	for _, p := range s.registry.GetAll() {
+		if resumable, ok := p.(ResumablePlatform); ok {
+			resumable.SetUploader(uploader)
+		}
 	}
 }
 
@@ -120,29 +151,181 @@ func (s *Service) InitializePlatforms() {
 		)
 		s.registry.Register(fb)
 	}
+
+	// Pinterest
+	if appID := os.Getenv("PINTEREST_APP_ID"); appID != "" {
+		pin := NewPinterestPlatform(
+			appID,
+			os.Getenv("PINTEREST_APP_SECRET"),
+			os.Getenv("PINTEREST_REDIRECT_URL"),
+		)
+		s.registry.Register(pin)
+	}
+
+	// Threads
+	if appID := os.Getenv("THREADS_APP_ID"); appID != "" {
+		th := NewThreadsPlatform(
+			appID,
+			os.Getenv("THREADS_APP_SECRET"),
+			os.Getenv("THREADS_REDIRECT_URL"),
+		)
+		s.registry.Register(th)
+	}
+
+	// Snapchat Spotlight
+	if clientID := os.Getenv("SNAPCHAT_CLIENT_ID"); clientID != "" {
+		sc := NewSnapchatPlatform(
+			clientID,
+			os.Getenv("SNAPCHAT_CLIENT_SECRET"),
+			os.Getenv("SNAPCHAT_REDIRECT_URL"),
+		)
+		s.registry.Register(sc)
+	}
+
+	// Bluesky needs no app credentials; accounts log in with app passwords
+	s.registry.Register(NewBlueskyPlatform(os.Getenv("BLUESKY_SERVICE_URL")))
+
+	// Custom destinations are configured per account
+	s.registry.Register(NewWebhookPlatform())
+	s.registry.Register(NewFeedPlatform())
 }
 
-// GetAuthURL returns the OAuth URL for a platform
-func (s *Service) GetAuthURL(platform social.SocialPlatform, state string) (string, error) {
+// GetAuthURL starts a connect flow for a user. It records a single-use
+// state bound to the user and platform, along with the PKCE verifier for
+// platforms that use one, and returns the OAuth URL and state.
+func (s *Service) GetAuthURL(ctx context.Context, userID string, platform social.SocialPlatform) (string, string, error) {
 	p, ok := s.registry.Get(platform)
 	if !ok {
-		return "", fmt.Errorf("platform %s not configured", platform)
+		return "", "", fmt.Errorf("platform %s not configured", platform)
+	}
+	if _, ok := p.(PasswordPlatform); ok {
+		return "", "", ErrPasswordLogin
+	}
+	if _, ok := p.(DestinationPlatform); ok {
+		return "", "", ErrConfiguredDestination
+	}
+	if s.sessions == nil {
+		return "", "", fmt.Errorf("oauth session store not configured")
+	}
+
+	state, err := generateState()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate state: %w", err)
+	}
+
+	session := &OAuthSession{
+		State:     state,
+		UserID:    userID,
+		Platform:  platform,
+		CreatedAt: time.Now(),
+	}
+
+	authURL := ""
+	if pkce, ok := p.(PKCEPlatform); ok {
+		session.CodeVerifier = oauth2.GenerateVerifier()
+		authURL = pkce.GetAuthURLWithChallenge(state, oauth2.S256ChallengeFromVerifier(session.CodeVerifier))
+	} else {
+		authURL = p.GetAuthURL(state)
 	}
-	return p.GetAuthURL(state), nil
+
+	if err := s.sessions.Save(ctx, session); err != nil {
+		return "", "", err
+	}
+
+	return authURL, state, nil
 }
 
-// ConnectAccount connects a new social media account
-func (s *Service) ConnectAccount(ctx context.Context, platform social.SocialPlatform, code string, userID string) (*social.SocialAccount, error) {
+// ConnectAccount completes a connect flow. The state must belong to a
+// pending session of the same user and platform; the session is consumed
+// whether or not the exchange succeeds.
+func (s *Service) ConnectAccount(ctx context.Context, platform social.SocialPlatform, state, code, userID string) (*social.SocialAccount, error) {
 	p, ok := s.registry.Get(platform)
 	if !ok {
 		return nil, fmt.Errorf("platform %s not configured", platform)
 	}
+	if s.sessions == nil {
+		return nil, fmt.Errorf("oauth session store not configured")
+	}
+
+	session, err := s.sessions.Consume(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != userID || session.Platform != platform {
+		log.Printf("Rejected OAuth callback for %s: state issued to user %s for %s, presented by user %s",
+			platform, session.UserID, session.Platform, userID)
+		return nil, ErrOAuthSessionMismatch
+	}
 
-	account, err := p.ExchangeCode(ctx, code)
+	var account *social.SocialAccount
+	if pkce, ok := p.(PKCEPlatform); ok {
+		account, err = pkce.ExchangeCodeWithVerifier(ctx, code, session.CodeVerifier)
+	} else {
+		account, err = p.ExchangeCode(ctx, code)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange code: %w", err)
 	}
 
+	account.ID = uuid.New().String()
+	account.UserID = userID
+	if err := s.accounts.Create(ctx, account); err != nil {
+		return nil, fmt.Errorf("failed to save account: %w", err)
+	}
+
+	return account, nil
+}
+
+// ConnectWithPassword connects an account of a platform that logs in with
+// credentials instead of OAuth
+func (s *Service) ConnectWithPassword(ctx context.Context, userID string, platform social.SocialPlatform, identifier, password string) (*social.SocialAccount, error) {
+	p, ok := s.registry.Get(platform)
+	if !ok {
+		return nil, fmt.Errorf("platform %s not configured", platform)
+	}
+	pp, ok := p.(PasswordPlatform)
+	if !ok {
+		return nil, fmt.Errorf("platform %s does not support password login", platform)
+	}
+
+	account, err := pp.Login(ctx, identifier, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log in: %w", err)
+	}
+
+	account.ID = uuid.New().String()
+	account.UserID = userID
+	if err := s.accounts.Create(ctx, account); err != nil {
+		return nil, fmt.Errorf("failed to save account: %w", err)
+	}
+
+	return account, nil
+}
+
+// ConnectDestination adds a custom destination from its settings. A
+// workspace has a single feed; connecting it again returns the existing one.
+func (s *Service) ConnectDestination(ctx context.Context, userID string, platform social.SocialPlatform, settings social.JSON) (*social.SocialAccount, error) {
+	p, ok := s.registry.Get(platform)
+	if !ok {
+		return nil, fmt.Errorf("platform %s not configured", platform)
+	}
+	dp, ok := p.(DestinationPlatform)
+	if !ok {
+		return nil, fmt.Errorf("platform %s is not a custom destination", platform)
+	}
+
+	if platform == social.PlatformFeed {
+		if existing, err := s.accounts.GetByUserAndPlatform(ctx, userID, platform); err == nil {
+			return existing, nil
+		}
+	}
+
+	account, err := dp.Configure(ctx, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	account.ID = uuid.New().String()
 	account.UserID = userID
 	if err := s.accounts.Create(ctx, account); err != nil {
 		return nil, fmt.Errorf("failed to save account: %w", err)
@@ -178,7 +361,252 @@ func (s *Service) UploadVideo(ctx context.Context, accountID string, req *social
 		return nil, fmt.Errorf("platform %s not configured", account.Platform)
 	}
 
-	return p.UploadVideo(ctx, account, req)
+	accessToken := account.AccessToken
+	resp, err := p.UploadVideo(ctx, account, req)
+
+	// Retry once with a fresh token if the platform rejected ours
+	if err != nil && IsUnauthorized(err) {
+		if refreshErr := s.refreshAccount(ctx, p, account); refreshErr != nil {
+			return nil, fmt.Errorf("%w (token refresh failed: %v)", err, refreshErr)
+		}
+		accessToken = account.AccessToken
+		resp, err = p.UploadVideo(ctx, account, req)
+	}
+
+	// Platforms refresh expired tokens on their own; keep what they got
+	if account.AccessToken != accessToken {
+		now := time.Now()
+		account.LastRefresh = &now
+		if updateErr := s.accounts.Update(ctx, account); updateErr != nil {
+			log.Printf("Failed to save refreshed token for account %s: %v", account.ID, updateErr)
+		}
+	}
+
+	return resp, err
+}
+
+// UpdatePost changes a published post on its platform. Fields the
+// platform cannot change are left out and returned; ErrUnsupported is
+// returned when it can change none of the requested fields.
+func (s *Service) UpdatePost(ctx context.Context, accountID, postID string, update *social.PostUpdate) ([]string, error) {
+	account, err := s.accounts.GetByID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	p, ok := s.registry.Get(account.Platform)
+	if !ok {
+		return nil, fmt.Errorf("platform %s not configured", account.Platform)
+	}
+	editable, ok := p.(EditablePlatform)
+	if !ok {
+		return update.Fields(), ErrUnsupported
+	}
+
+	supported := make(map[string]bool)
+	for _, field := range editable.EditableFields() {
+		supported[field] = true
+	}
+	var unsupported []string
+	for _, field := range update.Fields() {
+		if !supported[field] {
+			unsupported = append(unsupported, field)
+		}
+	}
+	if len(unsupported) == len(update.Fields()) {
+		return unsupported, ErrUnsupported
+	}
+
+	update = update.Without(unsupported)
+	err = s.withFreshToken(ctx, p, account, func() error {
+		return editable.UpdatePost(ctx, account, postID, update)
+	})
+	return unsupported, err
+}
+
+// DeletePost deletes a published post from its platform
+func (s *Service) DeletePost(ctx context.Context, accountID, postID string) error {
+	account, err := s.accounts.GetByID(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("account not found: %w", err)
+	}
+
+	p, ok := s.registry.Get(account.Platform)
+	if !ok {
+		return fmt.Errorf("platform %s not configured", account.Platform)
+	}
+
+	return s.withFreshToken(ctx, p, account, func() error {
+		return p.DeletePost(ctx, account, postID)
+	})
+}
+
+// commentPlatform returns the account and its platform if the platform
+// supports comments
+func (s *Service) commentPlatform(ctx context.Context, accountID string) (*social.SocialAccount, Platform, CommentPlatform, error) {
+	account, err := s.accounts.GetByID(ctx, accountID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	p, ok := s.registry.Get(account.Platform)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("platform %s not configured", account.Platform)
+	}
+	commenter, ok := p.(CommentPlatform)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("%s comments: %w", account.Platform, ErrUnsupported)
+	}
+	return account, p, commenter, nil
+}
+
+// GetComments lists the comments and replies of a published post
+func (s *Service) GetComments(ctx context.Context, accountID, postID string) ([]*social.Comment, error) {
+	account, p, commenter, err := s.commentPlatform(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []*social.Comment
+	err = s.withFreshToken(ctx, p, account, func() error {
+		comments, err = commenter.GetComments(ctx, account, postID)
+		return err
+	})
+	return comments, err
+}
+
+// ReplyToComment replies to a top-level comment of a published post
+func (s *Service) ReplyToComment(ctx context.Context, accountID, postID, commentID, text string) (*social.Comment, error) {
+	account, p, commenter, err := s.commentPlatform(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply *social.Comment
+	err = s.withFreshToken(ctx, p, account, func() error {
+		reply, err = commenter.ReplyToComment(ctx, account, postID, commentID, text)
+		return err
+	})
+	return reply, err
+}
+
+// HideComment hides a comment of a published post, or shows it again
+func (s *Service) HideComment(ctx context.Context, accountID, postID, commentID string, hidden bool) error {
+	account, p, commenter, err := s.commentPlatform(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	return s.withFreshToken(ctx, p, account, func() error {
+		return commenter.HideComment(ctx, account, postID, commentID, hidden)
+	})
+}
+
+// DeleteComment deletes a comment of a published post
+func (s *Service) DeleteComment(ctx context.Context, accountID, postID, commentID string) error {
+	account, p, commenter, err := s.commentPlatform(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	return s.withFreshToken(ctx, p, account, func() error {
+		return commenter.DeleteComment(ctx, account, postID, commentID)
+	})
+}
+
+// withFreshToken runs a platform call, retrying it once with a refreshed
+// token if the platform rejected ours
+func (s *Service) withFreshToken(ctx context.Context, p Platform, account *social.SocialAccount, call func() error) error {
+	err := call()
+	if err != nil && IsUnauthorized(err) {
+		if refreshErr := s.refreshAccount(ctx, p, account); refreshErr != nil {
+			return fmt.Errorf("%w (token refresh failed: %v)", err, refreshErr)
+		}
+		err = call()
+	}
+	return err
+}
+
+// RefreshAccount refreshes the access token of an account and records its health
+func (s *Service) RefreshAccount(ctx context.Context, accountID string) (*social.SocialAccount, error) {
+	account, err := s.accounts.GetByID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	p, ok := s.registry.Get(account.Platform)
+	if !ok {
+		return nil, fmt.Errorf("platform %s not configured", account.Platform)
+	}
+
+	if err := s.refreshAccount(ctx, p, account); err != nil {
+		return account, err
+	}
+
+	return account, nil
+}
+
+// RefreshExpiringTokens refreshes every account whose token expires within
+// the given window. It returns how many accounts were refreshed and how
+// many failed.
+func (s *Service) RefreshExpiringTokens(ctx context.Context, within time.Duration) (int, int, error) {
+	accounts, err := s.accounts.GetExpiring(ctx, time.Now().Add(within))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	refreshed, failed := 0, 0
+	for _, account := range accounts {
+		p, ok := s.registry.Get(account.Platform)
+		if !ok {
+			continue
+		}
+
+		if err := s.refreshAccount(ctx, p, account); err != nil {
+			log.Printf("Failed to refresh token for account %s: %v", account.ID, err)
+			failed++
+			continue
+		}
+		refreshed++
+	}
+
+	return refreshed, failed, nil
+}
+
+// refreshAccount refreshes a token and persists the outcome. Refused
+// refreshes and tokens that already lapsed mark the account expired;
+// transient failures mark it as erroring so the next run retries it.
+// The owner is notified when the account stops being connected.
+func (s *Service) refreshAccount(ctx context.Context, p Platform, account *social.SocialAccount) error {
+	previous := account.Status
+	refreshErr := p.RefreshToken(ctx, account)
+
+	now := time.Now()
+	if refreshErr == nil {
+		account.Status = social.StatusConnected
+		account.StatusReason = ""
+		account.LastRefresh = &now
+	} else {
+		lapsed := account.TokenExpiry != nil && account.TokenExpiry.Before(now)
+		if isAuthFailure(refreshErr) || lapsed {
+			account.Status = social.StatusExpired
+		} else {
+			account.Status = social.StatusError
+		}
+		account.StatusReason = refreshErr.Error()
+	}
+
+	if err := s.accounts.Update(ctx, account); err != nil {
+		return fmt.Errorf("failed to save account: %w", err)
+	}
+
+	if account.Status != social.StatusConnected && account.Status != previous {
+		if err := s.notifier.NotifyAccountStatus(ctx, account); err != nil {
+			log.Printf("Failed to notify owner of account %s: %v", account.ID, err)
+		}
+	}
+
+	return refreshErr
 }
 
 // CrossPost uploads a video to multiple platforms
@@ -212,16 +640,46 @@ func (s *Service) SchedulePost(ctx context.Context, post *social.ScheduledPost)
 		}
 	}
 
-	post.Status = social.PostStatusScheduled
+	// Drafts are saved for review and scheduled once approved
+	if post.Status != social.PostStatusDraft {
+		post.Status = social.PostStatusScheduled
+	}
+
+	if post.ID == "" {
+		post.ID = uuid.New().String()
+	}
+	for i := range post.Platforms {
+		if post.Platforms[i].ID == "" {
+			post.Platforms[i].ID = uuid.New().String()
+		}
+		post.Platforms[i].ScheduledPostID = post.ID
+		post.Platforms[i].Status = post.Status
+	}
+
 	return s.posts.Create(ctx, post)
 }
 
+// GetScheduledPost returns a scheduled post of a user
+func (s *Service) GetScheduledPost(ctx context.Context, postID string, userID string) (*social.ScheduledPost, error) {
+	post, err := s.posts.GetByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.UserID != userID {
+		return nil, fmt.Errorf("unauthorized")
+	}
+	return post, nil
+}
+
 // GetScheduledPosts returns scheduled posts for a user
 func (s *Service) GetScheduledPosts(ctx context.Context, userID string, limit, offset int) ([]*social.ScheduledPost, error) {
 	return s.posts.GetByUser(ctx, userID, limit, offset)
 }
 
-// CancelScheduledPost cancels a scheduled post
+// CancelScheduledPost cancels a scheduled post. Each platform post that is
+// not published yet is cancelled by swapping its status, so one that a
+// publish job claimed first is left to publish; the post then stays as
+// it is and ErrPostPublishing is returned.
 func (s *Service) CancelScheduledPost(ctx context.Context, postID string, userID string) error {
 	post, err := s.posts.GetByID(ctx, postID)
 	if err != nil {
@@ -231,9 +689,41 @@ func (s *Service) CancelScheduledPost(ctx context.Context, postID string, userID
 		return fmt.Errorf("unauthorized")
 	}
 
+	publishing := false
+	for _, platformPost := range post.Platforms {
+		switch platformPost.Status {
+		case social.PostStatusPublished, social.PostStatusCancelled, social.PostStatusDeleted:
+			continue
+		case social.PostStatusPublishing:
+			publishing = true
+			continue
+		}
+		cancelled, err := s.posts.SwapPlatformPostStatus(ctx, platformPost.ID, platformPost.Status, social.PostStatusCancelled)
+		if err != nil {
+			return fmt.Errorf("failed to cancel platform post: %w", err)
+		}
+		if !cancelled {
+			publishing = true
+		}
+	}
+	if publishing {
+		return ErrPostPublishing
+	}
+
 	return s.posts.UpdateStatus(ctx, postID, social.PostStatusCancelled, "")
 }
 
+// HasAnalytics reports whether the analytics of posts on a platform are
+// polled from its API. Custom destinations report none.
+func (s *Service) HasAnalytics(platform social.SocialPlatform) bool {
+	p, ok := s.registry.Get(platform)
+	if !ok {
+		return false
+	}
+	_, destination := p.(DestinationPlatform)
+	return !destination
+}
+
 // GetAnalytics retrieves analytics for a post
 func (s *Service) GetAnalytics(ctx context.Context, accountID string, postID string) (*social.AnalyticsData, error) {
 	account, err := s.accounts.GetByID(ctx, accountID)
@@ -246,7 +736,86 @@ func (s *Service) GetAnalytics(ctx context.Context, accountID string, postID str
 		return nil, fmt.Errorf("platform %s not configured", account.Platform)
 	}
 
-	return p.GetAnalytics(ctx, account, postID)
+	var data *social.AnalyticsData
+	err = s.withFreshToken(ctx, p, account, func() error {
+		data, err = p.GetAnalytics(ctx, account, postID)
+		return err
+	})
+	return data, err
+}
+
+// GetRetention retrieves the audience retention curve of a published video.
+// Accounts connected without access to it are marked for reconnection.
+func (s *Service) GetRetention(ctx context.Context, accountID string, postID string) ([]social.RetentionPoint, error) {
+	account, err := s.accounts.GetByID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	p, ok := s.registry.Get(account.Platform)
+	if !ok {
+		return nil, fmt.Errorf("platform %s not configured", account.Platform)
+	}
+	retention, ok := p.(RetentionPlatform)
+	if !ok {
+		return nil, fmt.Errorf("%s retention: %w", account.Platform, ErrUnsupported)
+	}
+
+	var points []social.RetentionPoint
+	err = s.withFreshToken(ctx, p, account, func() error {
+		points, err = retention.GetRetention(ctx, account, postID)
+		return err
+	})
+	if IsInsufficientScope(err) {
+		s.markNeedsReconnect(ctx, account, fmt.Sprintf(
+			"%s did not grant access to retention analytics; reconnect the account to grant it", account.Platform))
+	}
+	return points, err
+}
+
+// markNeedsReconnect marks an account expired because it has to be
+// reconnected, and notifies the owner unless it was already expired
+func (s *Service) markNeedsReconnect(ctx context.Context, account *social.SocialAccount, reason string) {
+	previous := account.Status
+	account.Status = social.StatusExpired
+	account.StatusReason = reason
+
+	if err := s.accounts.Update(ctx, account); err != nil {
+		log.Printf("Failed to save account %s: %v", account.ID, err)
+		return
+	}
+
+	if previous != social.StatusExpired {
+		if err := s.notifier.NotifyAccountStatus(ctx, account); err != nil {
+			log.Printf("Failed to notify owner of account %s: %v", account.ID, err)
+		}
+	}
+}
+
+// FindUpload looks for the post that an upload to an account made since a
+// time created, or nil if there is none. It returns ErrUnsupported if the
+// platform cannot tell.
+func (s *Service) FindUpload(ctx context.Context, accountID string, req *social.UploadRequest, since time.Time) (*social.UploadResponse, error) {
+	account, err := s.accounts.GetByID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	p, ok := s.registry.Get(account.Platform)
+	if !ok {
+		return nil, fmt.Errorf("platform %s not configured", account.Platform)
+	}
+	lookup, ok := p.(UploadLookupPlatform)
+	if !ok {
+		return nil, fmt.Errorf("%s upload lookup: %w", account.Platform, ErrUnsupported)
+	}
+
+	var resp *social.UploadResponse
+	err = s.withFreshToken(ctx, p, account, func() error {
+		resp, err = lookup.FindUpload(ctx, account, req, since)
+		return err
+	})
+	return resp, err
 }
 
 // GetTrends retrieves trends for a platform
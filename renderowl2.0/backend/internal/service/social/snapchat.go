@@ -0,0 +1,522 @@
+package social
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"renderowl-api/internal/domain/social"
+)
+
+// SnapchatPlatform implements the Platform interface for Snapchat
+// Spotlight, through the Public Profile API
+type SnapchatPlatform struct {
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	httpClient   *http.Client
+	uploader     *ResumableUploader
+}
+
+// Snapchat API endpoints
+const (
+	SnapchatAuthURL  = "https://accounts.snapchat.com/login/oauth2/authorize"
+	SnapchatTokenURL = "https://accounts.snapchat.com/login/oauth2/access_token"
+	SnapchatAPIURL   = "https://businessapi.snapchat.com/v1"
+)
+
+// Snapchat media is uploaded in parts of up to 32 MB. Upload sessions are
+// kept for a day.
+const (
+	snapchatChunkSize   = 32 * 1024 * 1024
+	snapchatSessionLife = 24 * time.Hour
+
+	// snapchatDefaultLocale is the Spotlight locale when none is given
+	snapchatDefaultLocale = "en_US"
+)
+
+// NewSnapchatPlatform creates a new Snapchat platform instance
+func NewSnapchatPlatform(clientID, clientSecret, redirectURL string) *SnapchatPlatform {
+	return &SnapchatPlatform{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		uploader:     NewResumableUploader(nil),
+	}
+}
+
+// SetUploader sets the uploader used for resumable uploads
+func (s *SnapchatPlatform) SetUploader(uploader *ResumableUploader) {
+	s.uploader = uploader
+}
+
+// GetName returns the platform name
+func (s *SnapchatPlatform) GetName() social.SocialPlatform {
+	return social.PlatformSnapchat
+}
+
+// GetAuthURL returns the OAuth URL
+func (s *SnapchatPlatform) GetAuthURL(state string) string {
+	params := url.Values{
+		"client_id":     {s.clientID},
+		"redirect_uri":  {s.redirectURL},
+		"response_type": {"code"},
+		"scope":         {"snapchat-profile-api"},
+		"state":         {state},
+	}
+
+	return SnapchatAuthURL + "?" + params.Encode()
+}
+
+// ExchangeCode exchanges OAuth code for tokens. The account is the first
+// public profile the user manages.
+func (s *SnapchatPlatform) ExchangeCode(ctx context.Context, code string) (*social.SocialAccount, error) {
+	data := url.Values{
+		"client_id":     {s.clientID},
+		"client_secret": {s.clientSecret},
+		"grant_type":    {"authorization_code"},
+		"redirect_uri":  {s.redirectURL},
+		"code":          {code},
+	}
+
+	tokenResp, err := s.requestToken(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+
+	profile, err := s.getProfile(ctx, tokenResp.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	profileID, _ := profile["id"].(string)
+	name, _ := profile["display_name"].(string)
+	if username, ok := profile["username"].(string); ok && username != "" {
+		name = username
+	}
+
+	expiry := time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+
+	return &social.SocialAccount{
+		Platform:     social.PlatformSnapchat,
+		AccountID:    profileID,
+		AccountName:  name,
+		AccessToken:  tokenResp.AccessToken,
+		RefreshToken: tokenResp.RefreshToken,
+		TokenExpiry:  &expiry,
+		Status:       social.StatusConnected,
+		Metadata:     profile,
+	}, nil
+}
+
+// RefreshToken refreshes the access token
+func (s *SnapchatPlatform) RefreshToken(ctx context.Context, account *social.SocialAccount) error {
+	data := url.Values{
+		"client_id":     {s.clientID},
+		"client_secret": {s.clientSecret},
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {account.RefreshToken},
+	}
+
+	tokenResp, err := s.requestToken(ctx, data)
+	if err != nil {
+		account.Status = social.StatusExpired
+		return fmt.Errorf("token refresh failed: %w", err)
+	}
+
+	expiry := time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+
+	account.AccessToken = tokenResp.AccessToken
+	if tokenResp.RefreshToken != "" {
+		account.RefreshToken = tokenResp.RefreshToken
+	}
+	account.TokenExpiry = &expiry
+	account.Status = social.StatusConnected
+
+	return nil
+}
+
+// UploadVideo uploads a video and posts it to Spotlight
+func (s *SnapchatPlatform) UploadVideo(ctx context.Context, account *social.SocialAccount, req *social.UploadRequest) (*social.UploadResponse, error) {
+	// Refresh token if needed
+	if account.TokenExpiry != nil && account.TokenExpiry.Before(time.Now()) {
+		if err := s.RefreshToken(ctx, account); err != nil {
+			return nil, err
+		}
+	}
+
+	// Upload the file in parts
+	session, err := s.uploader.Upload(ctx, account, req.VideoPath, &snapchatUpload{platform: s, account: account})
+	if err != nil {
+		return nil, fmt.Errorf("video upload failed: %w", err)
+	}
+
+	locale := req.Metadata["locale"]
+	if locale == "" {
+		locale = snapchatDefaultLocale
+	}
+
+	spotlight := map[string]interface{}{
+		"media_id":    session.RemoteID,
+		"description": spotlightDescription(req),
+		"locale":      locale,
+	}
+
+	resp, err := s.makeRequest(ctx, "POST", fmt.Sprintf("%s/public_profiles/%s/spotlights", SnapchatAPIURL, account.AccountID), spotlight, s.headers(account))
+	if err != nil {
+		return nil, fmt.Errorf("spotlight creation failed: %w", err)
+	}
+	s.uploader.Complete(ctx, session)
+
+	var result struct {
+		Spotlights []struct {
+			Spotlight struct {
+				ID string `json:"id"`
+			} `json:"spotlight"`
+		} `json:"spotlights"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse spotlight response: %w", err)
+	}
+	if len(result.Spotlights) == 0 {
+		return nil, fmt.Errorf("spotlight response has no spotlight")
+	}
+	spotlightID := result.Spotlights[0].Spotlight.ID
+
+	// Snapchat reviews Spotlight submissions before they are shown
+	return &social.UploadResponse{
+		PlatformPostID: spotlightID,
+		PostURL:        fmt.Sprintf("https://www.snapchat.com/spotlight/%s", spotlightID),
+		Status:         "processing",
+	}, nil
+}
+
+// GetAnalytics retrieves lifetime stats for a Spotlight
+func (s *SnapchatPlatform) GetAnalytics(ctx context.Context, account *social.SocialAccount, postID string) (*social.AnalyticsData, error) {
+	statsURL := fmt.Sprintf("%s/public_profiles/%s/spotlights/%s/stats?granularity=LIFETIME", SnapchatAPIURL, account.AccountID, postID)
+
+	resp, err := s.makeRequest(ctx, "GET", statsURL, nil, s.headers(account))
+	if err != nil {
+		return nil, fmt.Errorf("stats fetch failed: %w", err)
+	}
+
+	var result struct {
+		TotalStats []struct {
+			TotalStat struct {
+				Stats map[string]float64 `json:"stats"`
+			} `json:"total_stat"`
+		} `json:"total_stats"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse stats: %w", err)
+	}
+
+	analytics := &social.AnalyticsData{
+		Platform: social.PlatformSnapchat,
+		Data:     make(social.JSON),
+	}
+	if len(result.TotalStats) == 0 {
+		return analytics, nil
+	}
+
+	stats := result.TotalStats[0].TotalStat.Stats
+	analytics.Views = int64(stats["views"])
+	analytics.Likes = int64(stats["favorites"])
+	analytics.Comments = int64(stats["replies"])
+	analytics.Shares = int64(stats["shares"])
+	analytics.WatchTime = int64(stats["view_time_millis"] / 1000)
+	analytics.Subscribers = int64(stats["subscribes"])
+	for _, key := range []string{"unique_viewers", "screenshots", "avg_view_time_millis"} {
+		if value, ok := stats[key]; ok {
+			analytics.Data[key] = value
+		}
+	}
+	if analytics.Views > 0 {
+		analytics.Engagement = float64(analytics.Likes+analytics.Comments+analytics.Shares) / float64(analytics.Views) * 100
+	}
+
+	return analytics, nil
+}
+
+// DeletePost removes a Spotlight
+func (s *SnapchatPlatform) DeletePost(ctx context.Context, account *social.SocialAccount, postID string) error {
+	deleteURL := fmt.Sprintf("%s/public_profiles/%s/spotlights/%s", SnapchatAPIURL, account.AccountID, postID)
+	_, err := s.makeRequest(ctx, "DELETE", deleteURL, nil, s.headers(account))
+	return err
+}
+
+// GetTrends returns no trends; Snapchat has no trends API
+func (s *SnapchatPlatform) GetTrends(ctx context.Context, account *social.SocialAccount, region string) ([]*social.PlatformTrend, error) {
+	return []*social.PlatformTrend{}, nil
+}
+
+// Helper methods
+
+type snapchatToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+func (s *SnapchatPlatform) requestToken(ctx context.Context, data url.Values) (*snapchatToken, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", SnapchatTokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var token snapchatToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	return &token, nil
+}
+
+// getProfile returns the first public profile the user manages
+func (s *SnapchatPlatform) getProfile(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+	headers := map[string]string{
+		"Authorization": "Bearer " + accessToken,
+	}
+
+	resp, err := s.makeRequest(ctx, "GET", SnapchatAPIURL+"/me/public_profiles", nil, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		PublicProfiles []struct {
+			PublicProfile map[string]interface{} `json:"public_profile"`
+		} `json:"public_profiles"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, err
+	}
+	if len(result.PublicProfiles) == 0 {
+		return nil, fmt.Errorf("snapchat user has no public profile to post to")
+	}
+
+	return result.PublicProfiles[0].PublicProfile, nil
+}
+
+func (s *SnapchatPlatform) headers(account *social.SocialAccount) map[string]string {
+	return map[string]string{
+		"Authorization": "Bearer " + account.AccessToken,
+	}
+}
+
+func (s *SnapchatPlatform) makeRequest(ctx context.Context, method, url string, body interface{}, headers map[string]string) ([]byte, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		bodyReader = bytes.NewReader(jsonBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	return respBody, nil
+}
+
+// spotlightDescription joins the description and tags; Spotlight topics
+// are taken from hashtags in the description
+func spotlightDescription(req *social.UploadRequest) string {
+	description := req.Description
+	for _, tag := range req.Tags {
+		tag = "#" + strings.TrimPrefix(tag, "#")
+		if !strings.Contains(description, tag) {
+			description = strings.TrimSpace(description + " " + tag)
+		}
+	}
+	return description
+}
+
+// snapchatUpload implements the Snapchat multipart media upload (INIT,
+// ADD, FINALIZE)
+type snapchatUpload struct {
+	platform *SnapchatPlatform
+	account  *social.SocialAccount
+}
+
+// snapchatUploadState holds the upload paths Snapchat returned at init
+type snapchatUploadState struct {
+	UploadID     string `json:"uploadId"`
+	AddPath      string `json:"addPath"`
+	FinalizePath string `json:"finalizePath"`
+}
+
+func (u *snapchatUpload) start(ctx context.Context, session *social.UploadSession) error {
+	// Register the media
+	createURL := fmt.Sprintf("%s/public_profiles/%s/media", SnapchatAPIURL, u.account.AccountID)
+	media := map[string]string{
+		"type": "VIDEO",
+		"name": filepath.Base(session.VideoPath),
+	}
+
+	resp, err := u.platform.makeRequest(ctx, "POST", createURL, media, u.platform.headers(u.account))
+	if err != nil {
+		return fmt.Errorf("media creation failed: %w", err)
+	}
+
+	var createResp struct {
+		MediaID string `json:"media_id"`
+	}
+	if err := json.Unmarshal(resp, &createResp); err != nil {
+		return fmt.Errorf("failed to parse media response: %w", err)
+	}
+
+	// Start the multipart upload
+	parts := (session.FileSize + snapchatChunkSize - 1) / snapchatChunkSize
+	initParams := url.Values{
+		"action":          {"INIT"},
+		"file_name":       {filepath.Base(session.VideoPath)},
+		"file_size":       {strconv.FormatInt(session.FileSize, 10)},
+		"number_of_parts": {strconv.FormatInt(parts, 10)},
+	}
+	initURL := fmt.Sprintf("%s/media/%s/multipart-upload-v2?%s", SnapchatAPIURL, createResp.MediaID, initParams.Encode())
+
+	resp, err = u.platform.makeRequest(ctx, "POST", initURL, nil, u.platform.headers(u.account))
+	if err != nil {
+		return fmt.Errorf("upload initialization failed: %w", err)
+	}
+
+	var initResp struct {
+		UploadID     string `json:"upload_id"`
+		AddPath      string `json:"add_path"`
+		FinalizePath string `json:"finalize_path"`
+	}
+	if err := json.Unmarshal(resp, &initResp); err != nil {
+		return fmt.Errorf("failed to parse upload response: %w", err)
+	}
+
+	session.RemoteID = createResp.MediaID
+	expiresAt := time.Now().Add(snapchatSessionLife)
+	session.ExpiresAt = &expiresAt
+
+	return storeUploadState(session, snapchatUploadState{
+		UploadID:     initResp.UploadID,
+		AddPath:      initResp.AddPath,
+		FinalizePath: initResp.FinalizePath,
+	})
+}
+
+// resume trusts the persisted offset; parts are acknowledged one by one
+func (u *snapchatUpload) resume(ctx context.Context, session *social.UploadSession) (int64, error) {
+	return session.Offset, nil
+}
+
+func (u *snapchatUpload) chunkSize(session *social.UploadSession) int64 {
+	if remaining := session.FileSize - session.Offset; remaining < snapchatChunkSize {
+		return remaining
+	}
+	return snapchatChunkSize
+}
+
+func (u *snapchatUpload) send(ctx context.Context, session *social.UploadSession, chunk []byte) (int64, error) {
+	var state snapchatUploadState
+	if err := loadUploadState(session, &state); err != nil {
+		return 0, err
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("upload_id", state.UploadID)
+	form.WriteField("part_number", strconv.FormatInt(session.Offset/snapchatChunkSize+1, 10))
+	part, err := form.CreateFormFile("file", filepath.Base(session.VideoPath))
+	if err != nil {
+		return 0, err
+	}
+	part.Write(chunk)
+	form.Close()
+
+	headers := u.platform.headers(u.account)
+	headers["Content-Type"] = form.FormDataContentType()
+
+	resp, respBody, err := doRequest(ctx, uploadHTTPClient, "POST", snapchatPathURL(state.AddPath), &body, headers)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	return session.Offset + int64(len(chunk)), nil
+}
+
+func (u *snapchatUpload) finish(ctx context.Context, session *social.UploadSession) error {
+	var state snapchatUploadState
+	if err := loadUploadState(session, &state); err != nil {
+		return err
+	}
+
+	form := url.Values{"upload_id": {state.UploadID}}
+	headers := u.platform.headers(u.account)
+	headers["Content-Type"] = "application/x-www-form-urlencoded"
+
+	resp, respBody, err := doRequest(ctx, u.platform.httpClient, "POST", snapchatPathURL(state.FinalizePath), strings.NewReader(form.Encode()), headers)
+	if err != nil {
+		return fmt.Errorf("upload finalize failed: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("upload finalize failed: %w", &APIError{StatusCode: resp.StatusCode, Body: string(respBody)})
+	}
+	return nil
+}
+
+// snapchatPathURL resolves an upload path returned by the API
+func snapchatPathURL(path string) string {
+	if strings.HasPrefix(path, "http") {
+		return path
+	}
+	return strings.TrimSuffix(SnapchatAPIURL, "/v1") + path
+}
@@ -0,0 +1,409 @@
+package social
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"renderowl-api/internal/domain/social"
+)
+
+// ThreadsPlatform implements the Platform interface for Threads
+type ThreadsPlatform struct {
+	appID       string
+	appSecret   string
+	redirectURL string
+	httpClient  *http.Client
+}
+
+// Threads API endpoints
+const (
+	ThreadsAuthURL  = "https://threads.net/oauth/authorize"
+	ThreadsTokenURL = "https://graph.threads.net/oauth/access_token"
+	ThreadsGraphURL = "https://graph.threads.net"
+	ThreadsAPIURL   = "https://graph.threads.net/v1.0"
+)
+
+// Threads processes video containers before they can be published
+const (
+	threadsPollInterval = 5 * time.Second
+	threadsPollTimeout  = 5 * time.Minute
+)
+
+// NewThreadsPlatform creates a new Threads platform instance
+func NewThreadsPlatform(appID, appSecret, redirectURL string) *ThreadsPlatform {
+	return &ThreadsPlatform{
+		appID:       appID,
+		appSecret:   appSecret,
+		redirectURL: redirectURL,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// GetName returns the platform name
+func (t *ThreadsPlatform) GetName() social.SocialPlatform {
+	return social.PlatformThreads
+}
+
+// GetAuthURL returns the OAuth URL
+func (t *ThreadsPlatform) GetAuthURL(state string) string {
+	params := url.Values{
+		"client_id":     {t.appID},
+		"redirect_uri":  {t.redirectURL},
+		"scope":         {"threads_basic,threads_content_publish,threads_manage_insights,threads_delete"},
+		"response_type": {"code"},
+		"state":         {state},
+	}
+
+	return ThreadsAuthURL + "?" + params.Encode()
+}
+
+// ExchangeCode exchanges OAuth code for a long-lived token
+func (t *ThreadsPlatform) ExchangeCode(ctx context.Context, code string) (*social.SocialAccount, error) {
+	data := url.Values{
+		"client_id":     {t.appID},
+		"client_secret": {t.appSecret},
+		"grant_type":    {"authorization_code"},
+		"redirect_uri":  {t.redirectURL},
+		"code":          {code},
+	}
+
+	resp, err := t.makeFormRequest(ctx, ThreadsTokenURL, data)
+	if err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+
+	var tokenResp struct {
+		AccessToken string      `json:"access_token"`
+		UserID      json.Number `json:"user_id"`
+	}
+	if err := json.Unmarshal(resp, &tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+
+	// Short-lived tokens last an hour; swap it for one that lasts 60 days
+	params := url.Values{
+		"grant_type":    {"th_exchange_token"},
+		"client_secret": {t.appSecret},
+		"access_token":  {tokenResp.AccessToken},
+	}
+	longLived, err := t.requestToken(ctx, ThreadsGraphURL+"/access_token?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("long-lived token exchange failed: %w", err)
+	}
+
+	// Get user info
+	userInfo, err := t.getUserInfo(ctx, longLived.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	username, _ := userInfo["username"].(string)
+	expiry := time.Now().Add(time.Duration(longLived.ExpiresIn) * time.Second)
+
+	return &social.SocialAccount{
+		Platform:    social.PlatformThreads,
+		AccountID:   tokenResp.UserID.String(),
+		AccountName: username,
+		AccessToken: longLived.AccessToken,
+		TokenExpiry: &expiry,
+		Status:      social.StatusConnected,
+		Metadata:    userInfo,
+	}, nil
+}
+
+// RefreshToken extends a long-lived token, which must be at least a day
+// old and not yet expired
+func (t *ThreadsPlatform) RefreshToken(ctx context.Context, account *social.SocialAccount) error {
+	params := url.Values{
+		"grant_type":   {"th_refresh_token"},
+		"access_token": {account.AccessToken},
+	}
+
+	tokenResp, err := t.requestToken(ctx, ThreadsGraphURL+"/refresh_access_token?"+params.Encode())
+	if err != nil {
+		account.Status = social.StatusExpired
+		return fmt.Errorf("token refresh failed: %w", err)
+	}
+
+	expiry := time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+
+	account.AccessToken = tokenResp.AccessToken
+	account.TokenExpiry = &expiry
+	account.Status = social.StatusConnected
+
+	return nil
+}
+
+// UploadVideo publishes a video thread
+func (t *ThreadsPlatform) UploadVideo(ctx context.Context, account *social.SocialAccount, req *social.UploadRequest) (*social.UploadResponse, error) {
+	// Threads fetches the video itself, so it must be at a public URL
+
+	// Step 1: Create a media container
+	params := url.Values{
+		"media_type":   {"VIDEO"},
+		"video_url":    {req.VideoPath},
+		"text":         {req.Description},
+		"access_token": {account.AccessToken},
+	}
+	if len(req.Tags) > 0 {
+		// Threads links a post to a single topic
+		params.Set("topic_tag", req.Tags[0])
+	}
+
+	resp, err := t.makeRequest(ctx, "POST", fmt.Sprintf("%s/%s/threads?%s", ThreadsAPIURL, account.AccountID, params.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("container creation failed: %w", err)
+	}
+
+	var container struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp, &container); err != nil {
+		return nil, fmt.Errorf("failed to parse container response: %w", err)
+	}
+
+	// Step 2: Wait for the video to be processed
+	if err := t.waitForContainer(ctx, account, container.ID); err != nil {
+		return nil, err
+	}
+
+	// Step 3: Publish the container
+	publishParams := url.Values{
+		"creation_id":  {container.ID},
+		"access_token": {account.AccessToken},
+	}
+
+	resp, err = t.makeRequest(ctx, "POST", fmt.Sprintf("%s/%s/threads_publish?%s", ThreadsAPIURL, account.AccountID, publishParams.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("thread publish failed: %w", err)
+	}
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse publish response: %w", err)
+	}
+
+	postURL := fmt.Sprintf("https://www.threads.net/@%s", account.AccountName)
+	if permalink, err := t.getPermalink(ctx, account, result.ID); err == nil && permalink != "" {
+		postURL = permalink
+	}
+
+	return &social.UploadResponse{
+		PlatformPostID: result.ID,
+		PostURL:        postURL,
+		Status:         "published",
+	}, nil
+}
+
+// GetAnalytics retrieves insights for a thread
+func (t *ThreadsPlatform) GetAnalytics(ctx context.Context, account *social.SocialAccount, postID string) (*social.AnalyticsData, error) {
+	insightsURL := fmt.Sprintf("%s/%s/insights?metric=views,likes,replies,reposts,quotes,shares&access_token=%s",
+		ThreadsAPIURL, postID, url.QueryEscape(account.AccessToken))
+
+	resp, err := t.makeRequest(ctx, "GET", insightsURL)
+	if err != nil {
+		return nil, fmt.Errorf("insights fetch failed: %w", err)
+	}
+
+	var insights struct {
+		Data []struct {
+			Name   string `json:"name"`
+			Values []struct {
+				Value int64 `json:"value"`
+			} `json:"values"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &insights); err != nil {
+		return nil, fmt.Errorf("failed to parse insights: %w", err)
+	}
+
+	analytics := &social.AnalyticsData{
+		Platform: social.PlatformThreads,
+		Data:     make(social.JSON),
+	}
+
+	for _, metric := range insights.Data {
+		if len(metric.Values) == 0 {
+			continue
+		}
+		value := metric.Values[0].Value
+		switch metric.Name {
+		case "views":
+			analytics.Views = value
+		case "likes":
+			analytics.Likes = value
+		case "replies":
+			analytics.Comments = value
+		case "reposts", "quotes", "shares":
+			analytics.Shares += value
+			analytics.Data[metric.Name] = value
+		}
+	}
+
+	if analytics.Views > 0 {
+		analytics.Engagement = float64(analytics.Likes+analytics.Comments+analytics.Shares) / float64(analytics.Views) * 100
+	}
+
+	return analytics, nil
+}
+
+// DeletePost deletes a thread
+func (t *ThreadsPlatform) DeletePost(ctx context.Context, account *social.SocialAccount, postID string) error {
+	deleteURL := fmt.Sprintf("%s/%s?access_token=%s", ThreadsAPIURL, postID, url.QueryEscape(account.AccessToken))
+	_, err := t.makeRequest(ctx, "DELETE", deleteURL)
+	return err
+}
+
+// GetTrends returns no trends; Threads has no trends API
+func (t *ThreadsPlatform) GetTrends(ctx context.Context, account *social.SocialAccount, region string) ([]*social.PlatformTrend, error) {
+	return []*social.PlatformTrend{}, nil
+}
+
+// Helper methods
+
+type threadsToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func (t *ThreadsPlatform) requestToken(ctx context.Context, tokenURL string) (*threadsToken, error) {
+	resp, err := t.makeRequest(ctx, "GET", tokenURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var token threadsToken
+	if err := json.Unmarshal(resp, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	return &token, nil
+}
+
+func (t *ThreadsPlatform) getUserInfo(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+	userURL := fmt.Sprintf("%s/me?fields=id,username,threads_profile_picture_url&access_token=%s",
+		ThreadsAPIURL, url.QueryEscape(accessToken))
+
+	resp, err := t.makeRequest(ctx, "GET", userURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (t *ThreadsPlatform) getPermalink(ctx context.Context, account *social.SocialAccount, postID string) (string, error) {
+	resp, err := t.makeRequest(ctx, "GET", fmt.Sprintf("%s/%s?fields=permalink&access_token=%s",
+		ThreadsAPIURL, postID, url.QueryEscape(account.AccessToken)))
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Permalink string `json:"permalink"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return "", err
+	}
+	return result.Permalink, nil
+}
+
+// waitForContainer polls a media container until its video is processed
+func (t *ThreadsPlatform) waitForContainer(ctx context.Context, account *social.SocialAccount, containerID string) error {
+	statusURL := fmt.Sprintf("%s/%s?fields=status,error_message&access_token=%s",
+		ThreadsAPIURL, containerID, url.QueryEscape(account.AccessToken))
+
+	deadline := time.Now().Add(threadsPollTimeout)
+	for {
+		resp, err := t.makeRequest(ctx, "GET", statusURL)
+		if err != nil {
+			return fmt.Errorf("container status check failed: %w", err)
+		}
+
+		var container struct {
+			Status       string `json:"status"`
+			ErrorMessage string `json:"error_message"`
+		}
+		if err := json.Unmarshal(resp, &container); err != nil {
+			return fmt.Errorf("failed to parse container status: %w", err)
+		}
+
+		switch container.Status {
+		case "FINISHED":
+			return nil
+		case "ERROR", "EXPIRED":
+			return fmt.Errorf("threads could not process video: %s %s", container.Status, container.ErrorMessage)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("container %s still %s after %s", containerID, container.Status, threadsPollTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(threadsPollInterval):
+		}
+	}
+}
+
+func (t *ThreadsPlatform) makeRequest(ctx context.Context, method, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	return respBody, nil
+}
+
+func (t *ThreadsPlatform) makeFormRequest(ctx context.Context, url string, data url.Values) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	return respBody, nil
+}
@@ -8,7 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
+	"strconv"
 	"time"
 
 	"renderowl-api/internal/domain/social"
@@ -20,6 +20,7 @@ type TikTokPlatform struct {
 	clientSecret string
 	redirectURL  string
 	httpClient   *http.Client
+	uploader     *ResumableUploader
 }
 
 // TikTok API endpoints
@@ -29,6 +30,14 @@ const (
 	TikTokUploadURL      = "https://open.tiktokapis.com/v2/post/publish/video/init/"
 	TikTokQueryUploadURL = "https://open.tiktokapis.com/v2/post/publish/video/status/"
 	TikTokUserInfoURL    = "https://open.tiktokapis.com/v2/user/info/"
+	TikTokBusinessURL    = "https://business-api.tiktok.com/open_api/v1.3"
+)
+
+// TikTok chunks must be 5-64 MB; the last chunk takes the remainder and
+// files smaller than one chunk are sent whole. Upload URLs last an hour.
+const (
+	tiktokChunkSize   = 10 * 1024 * 1024
+	tiktokSessionLife = time.Hour
 )
 
 // NewTikTokPlatform creates a new TikTok platform instance
@@ -38,9 +47,15 @@ func NewTikTokPlatform(clientKey, clientSecret, redirectURL string) *TikTokPlatf
 		clientSecret: clientSecret,
 		redirectURL:  redirectURL,
 		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		uploader:     NewResumableUploader(nil),
 	}
 }
 
+// SetUploader sets the uploader used for resumable uploads
+func (t *TikTokPlatform) SetUploader(uploader *ResumableUploader) {
+	t.uploader = uploader
+}
+
 // GetName returns the platform name
 func (t *TikTokPlatform) GetName() social.SocialPlatform {
 	return social.PlatformTikTok
@@ -157,53 +172,17 @@ func (t *TikTokPlatform) UploadVideo(ctx context.Context, account *social.Social
 		}
 	}
 
-	// Get file info (for future use with file size validation)
-	_, err := os.Stat(req.VideoPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to stat video file: %w", err)
-	}
-	
-	// fileSize := fileInfo.Size() // Available for future size validation
-
-	// Initialize upload
-	initData := map[string]interface{}{
-		"post_info": map[string]string{
-			"title":       req.Title,
-			"description": req.Description,
-			"privacy_level": req.Privacy,
-		},
-		"source_info": map[string]interface{}{
-			"source": "PULL_FROM_URL",
-			"url":    req.VideoPath, // For direct upload, we'd need to host the file first
-		},
-	}
-
-	headers := map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", account.AccessToken),
-	}
-
-	resp, err := t.makeRequest(ctx, "POST", TikTokUploadURL, initData, headers)
+	// Initialize and upload the file in chunks
+	session, err := t.uploader.Upload(ctx, account, req.VideoPath, &tiktokUpload{platform: t, account: account, req: req})
 	if err != nil {
-		return nil, fmt.Errorf("upload initialization failed: %w", err)
+		return nil, fmt.Errorf("video upload failed: %w", err)
 	}
+	t.uploader.Complete(ctx, session)
 
-	var uploadResp struct {
-		Data struct {
-			PublishID string `json:"publish_id"`
-			UploadURL string `json:"upload_url"`
-		} `json:"data"`
-	}
-
-	if err := json.Unmarshal(resp, &uploadResp); err != nil {
-		return nil, fmt.Errorf("failed to parse upload response: %w", err)
-	}
-
-	// Note: TikTok requires the video to be uploaded to their URL
-	// In production, we'd upload the file bytes to uploadResp.Data.UploadURL
-
+	// TikTok processes the video once every chunk has arrived
 	return &social.UploadResponse{
-		PlatformPostID: uploadResp.Data.PublishID,
-		PostURL:        fmt.Sprintf("https://tiktok.com/@%s/video/%s", account.AccountName, uploadResp.Data.PublishID),
+		PlatformPostID: session.RemoteID,
+		PostURL:        fmt.Sprintf("https://tiktok.com/@%s/video/%s", account.AccountName, session.RemoteID),
 		Status:         "processing",
 	}, nil
 }
@@ -223,7 +202,183 @@ func (t *TikTokPlatform) GetAnalytics(ctx context.Context, account *social.Socia
 
 // DeletePost deletes a post (not directly supported by TikTok API)
 func (t *TikTokPlatform) DeletePost(ctx context.Context, account *social.SocialAccount, postID string) error {
-	return fmt.Errorf("TikTok does not support deleting posts via API")
+	return fmt.Errorf("TikTok does not support deleting posts via API: %w", ErrUnsupported)
+}
+
+// tiktokComment is a comment as returned by the TikTok Accounts API
+type tiktokComment struct {
+	CommentID       string      `json:"comment_id"`
+	ParentCommentID string      `json:"parent_comment_id"`
+	UserID          string      `json:"user_id"`
+	Username        string      `json:"username"`
+	DisplayName     string      `json:"display_name"`
+	ProfileImage    string      `json:"profile_image"`
+	Owner           bool        `json:"owner"`
+	Text            string      `json:"text"`
+	Likes           int64       `json:"likes"`
+	Replies         int64       `json:"replies"`
+	Status          string      `json:"status"`
+	CreateTime      json.Number `json:"create_time"`
+}
+
+// tiktokCommentList is a page of comments or replies
+type tiktokCommentList struct {
+	Comments []tiktokComment `json:"comments"`
+	Cursor   int64           `json:"cursor"`
+	HasMore  bool            `json:"has_more"`
+}
+
+// tiktokCommentFields are the comment fields read from the Accounts API
+const tiktokCommentFields = `["comment_id","parent_comment_id","user_id","username","display_name","profile_image","owner","text","likes","replies","status","create_time"]`
+
+// GetComments lists the comments of a video with their replies. Comments
+// are read through the TikTok Accounts API, so the account must be a
+// business account authorised for comment management.
+func (t *TikTokPlatform) GetComments(ctx context.Context, account *social.SocialAccount, postID string) ([]*social.Comment, error) {
+	var comments []*social.Comment
+	var cursor int64
+	for len(comments) < commentFetchLimit {
+		params := url.Values{
+			"business_id": {account.AccountID},
+			"video_id":    {postID},
+			"fields":      {tiktokCommentFields},
+			"sort_field":  {"create_time"},
+			"sort_order":  {"DESC"},
+			"max_count":   {"30"},
+			"cursor":      {strconv.FormatInt(cursor, 10)},
+		}
+
+		var page tiktokCommentList
+		if err := t.businessRequest(ctx, "GET", "/business/comment/list/?"+params.Encode(), nil, account, &page); err != nil {
+			return nil, fmt.Errorf("comments fetch failed: %w", err)
+		}
+
+		for _, c := range page.Comments {
+			comments = append(comments, c.toComment(postID, ""))
+			if c.Replies == 0 {
+				continue
+			}
+			replies, err := t.getReplies(ctx, account, postID, c.CommentID)
+			if err != nil {
+				return nil, err
+			}
+			comments = append(comments, replies...)
+		}
+
+		if !page.HasMore {
+			break
+		}
+		cursor = page.Cursor
+	}
+
+	return comments, nil
+}
+
+// getReplies lists the replies to a comment
+func (t *TikTokPlatform) getReplies(ctx context.Context, account *social.SocialAccount, postID, commentID string) ([]*social.Comment, error) {
+	var replies []*social.Comment
+	var cursor int64
+	for {
+		params := url.Values{
+			"business_id": {account.AccountID},
+			"video_id":    {postID},
+			"comment_id":  {commentID},
+			"fields":      {tiktokCommentFields},
+			"max_count":   {"30"},
+			"cursor":      {strconv.FormatInt(cursor, 10)},
+		}
+
+		var page tiktokCommentList
+		if err := t.businessRequest(ctx, "GET", "/business/comment/reply/list/?"+params.Encode(), nil, account, &page); err != nil {
+			return nil, fmt.Errorf("replies fetch failed: %w", err)
+		}
+
+		for _, c := range page.Comments {
+			replies = append(replies, c.toComment(postID, commentID))
+		}
+
+		if !page.HasMore || len(replies) >= commentFetchLimit {
+			return replies, nil
+		}
+		cursor = page.Cursor
+	}
+}
+
+// ReplyToComment replies to a comment as the account
+func (t *TikTokPlatform) ReplyToComment(ctx context.Context, account *social.SocialAccount, postID, commentID, text string) (*social.Comment, error) {
+	body := map[string]string{
+		"business_id": account.AccountID,
+		"video_id":    postID,
+		"comment_id":  commentID,
+		"text":        text,
+	}
+
+	var reply tiktokComment
+	if err := t.businessRequest(ctx, "POST", "/business/comment/reply/create/", body, account, &reply); err != nil {
+		return nil, fmt.Errorf("reply failed: %w", err)
+	}
+
+	comment := reply.toComment(postID, commentID)
+	comment.AuthorID = account.AccountID
+	comment.AuthorName = account.AccountName
+	comment.FromAccount = true
+	comment.Text = text
+	if comment.PublishedAt.IsZero() {
+		comment.PublishedAt = time.Now()
+	}
+	return comment, nil
+}
+
+// HideComment hides a comment from the public, or shows it again
+func (t *TikTokPlatform) HideComment(ctx context.Context, account *social.SocialAccount, postID, commentID string, hidden bool) error {
+	action := "UNHIDE"
+	if hidden {
+		action = "HIDE"
+	}
+	body := map[string]string{
+		"business_id": account.AccountID,
+		"video_id":    postID,
+		"comment_id":  commentID,
+		"action":      action,
+	}
+	return t.businessRequest(ctx, "POST", "/business/comment/hide/", body, account, nil)
+}
+
+// DeleteComment deletes a comment on the account's video
+func (t *TikTokPlatform) DeleteComment(ctx context.Context, account *social.SocialAccount, postID, commentID string) error {
+	body := map[string]string{
+		"business_id": account.AccountID,
+		"comment_id":  commentID,
+	}
+	return t.businessRequest(ctx, "POST", "/business/comment/delete/", body, account, nil)
+}
+
+func (c *tiktokComment) toComment(postID, parentID string) *social.Comment {
+	comment := &social.Comment{
+		RemoteID:        c.CommentID,
+		RemotePostID:    postID,
+		RemoteParentID:  parentID,
+		AuthorID:        c.UserID,
+		AuthorName:      c.DisplayName,
+		AuthorAvatarURL: c.ProfileImage,
+		FromAccount:     c.Owner,
+		Text:            c.Text,
+		Likes:           c.Likes,
+		Status:          social.CommentStatusVisible,
+	}
+	if comment.AuthorName == "" {
+		comment.AuthorName = c.Username
+	}
+	if comment.RemoteParentID == "" && c.ParentCommentID != c.CommentID {
+		comment.RemoteParentID = c.ParentCommentID
+	}
+	if c.Status == "HIDDEN" {
+		comment.Status = social.CommentStatusHidden
+	}
+	if seconds, err := c.CreateTime.Int64(); err == nil && seconds > 0 {
+		comment.PublishedAt = time.Unix(seconds, 0)
+	}
+	return comment
 }
 
 // GetTrends retrieves trending sounds and hashtags
@@ -312,8 +467,155 @@ func (t *TikTokPlatform) makeRequest(ctx context.Context, method, url string, bo
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	return respBody, nil
 }
+
+// businessRequest calls the TikTok Accounts API and decodes the data of
+// its response into result. The API reports errors in the body with a
+// non-zero code.
+func (t *TikTokPlatform) businessRequest(ctx context.Context, method, path string, body interface{}, account *social.SocialAccount, result interface{}) error {
+	headers := map[string]string{
+		"Access-Token": account.AccessToken,
+	}
+
+	resp, err := t.makeRequest(ctx, method, TikTokBusinessURL+path, body, headers)
+	if err != nil {
+		return err
+	}
+
+	var envelope struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &envelope); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if envelope.Code != 0 {
+		return &APIError{StatusCode: tiktokErrorStatus(envelope.Code), Body: envelope.Message}
+	}
+
+	if result == nil || len(envelope.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(envelope.Data, result)
+}
+
+// tiktokErrorStatus maps Accounts API error codes to HTTP statuses, so
+// expired tokens are refreshed like on other platforms
+func tiktokErrorStatus(code int) int {
+	switch code {
+	case 40104, 40105:
+		return http.StatusUnauthorized
+	case 40100:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusBadRequest
+}
+
+// tiktokUpload implements the TikTok chunked file upload
+type tiktokUpload struct {
+	platform *TikTokPlatform
+	account  *social.SocialAccount
+	req      *social.UploadRequest
+}
+
+// tiktokUploadState is the chunk plan agreed with TikTok at init
+type tiktokUploadState struct {
+	ChunkSize  int64 `json:"chunkSize"`
+	ChunkCount int64 `json:"chunkCount"`
+}
+
+func (u *tiktokUpload) start(ctx context.Context, session *social.UploadSession) error {
+	state := tiktokUploadState{ChunkSize: tiktokChunkSize}
+	if session.FileSize < state.ChunkSize {
+		state.ChunkSize = session.FileSize
+	}
+	state.ChunkCount = 1
+	if state.ChunkSize > 0 {
+		state.ChunkCount = session.FileSize / state.ChunkSize
+	}
+
+	initData := map[string]interface{}{
+		"post_info": map[string]string{
+			"title":         u.req.Title,
+			"description":   u.req.Description,
+			"privacy_level": u.req.Privacy,
+		},
+		"source_info": map[string]interface{}{
+			"source":            "FILE_UPLOAD",
+			"video_size":        session.FileSize,
+			"chunk_size":        state.ChunkSize,
+			"total_chunk_count": state.ChunkCount,
+		},
+	}
+
+	headers := map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", u.account.AccessToken),
+	}
+
+	resp, err := u.platform.makeRequest(ctx, "POST", TikTokUploadURL, initData, headers)
+	if err != nil {
+		return fmt.Errorf("upload initialization failed: %w", err)
+	}
+
+	var initResp struct {
+		Data struct {
+			PublishID string `json:"publish_id"`
+			UploadURL string `json:"upload_url"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(resp, &initResp); err != nil {
+		return fmt.Errorf("failed to parse upload response: %w", err)
+	}
+
+	session.RemoteID = initResp.Data.PublishID
+	session.SessionURL = initResp.Data.UploadURL
+	expiresAt := time.Now().Add(tiktokSessionLife)
+	session.ExpiresAt = &expiresAt
+
+	return storeUploadState(session, state)
+}
+
+// resume trusts the persisted offset; TikTok has no query for received bytes
+func (u *tiktokUpload) resume(ctx context.Context, session *social.UploadSession) (int64, error) {
+	return session.Offset, nil
+}
+
+func (u *tiktokUpload) chunkSize(session *social.UploadSession) int64 {
+	var state tiktokUploadState
+	if err := loadUploadState(session, &state); err != nil || state.ChunkSize == 0 {
+		return 0
+	}
+
+	// The last chunk absorbs whatever is left over
+	if session.Offset/state.ChunkSize >= state.ChunkCount-1 {
+		return session.FileSize - session.Offset
+	}
+	return state.ChunkSize
+}
+
+func (u *tiktokUpload) send(ctx context.Context, session *social.UploadSession, chunk []byte) (int64, error) {
+	resp, body, err := doRequest(ctx, uploadHTTPClient, "PUT", session.SessionURL, bytes.NewReader(chunk), map[string]string{
+		"Content-Type":  "video/mp4",
+		"Content-Range": contentRange(session.Offset, int64(len(chunk)), session.FileSize),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// 206 acknowledges a chunk, 201 the final one
+	if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusCreated {
+		return 0, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return session.Offset + int64(len(chunk)), nil
+}
+
+func (u *tiktokUpload) finish(ctx context.Context, session *social.UploadSession) error {
+	return nil
+}
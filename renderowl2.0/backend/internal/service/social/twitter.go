@@ -8,8 +8,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"mime/multipart"
 	"net/url"
-	"os"
+	"strconv"
 	"time"
 
 	"renderowl-api/internal/domain/social"
@@ -23,6 +24,7 @@ type TwitterPlatform struct {
 	clientSecret string
 	redirectURL  string
 	httpClient   *http.Client
+	uploader     *ResumableUploader
 }
 
 // Twitter API v2 endpoints
@@ -33,6 +35,12 @@ const (
 	TwitterUploadURL    = "https://upload.twitter.com/1.1/media/upload.json"
 )
 
+// Twitter media segments can be up to 5 MB. Uploaded media expires after a day.
+const (
+	twitterChunkSize   = 4 * 1024 * 1024
+	twitterSessionLife = 24 * time.Hour
+)
+
 // NewTwitterPlatform creates a new Twitter/X platform instance
 func NewTwitterPlatform(clientID, clientSecret, redirectURL string) *TwitterPlatform {
 	return &TwitterPlatform{
@@ -40,26 +48,38 @@ func NewTwitterPlatform(clientID, clientSecret, redirectURL string) *TwitterPlat
 		clientSecret: clientSecret,
 		redirectURL:  redirectURL,
 		httpClient:   &http.Client{Timeout: 60 * time.Second},
+		uploader:     NewResumableUploader(nil),
 	}
 }
 
+// SetUploader sets the uploader used for resumable uploads
+func (t *TwitterPlatform) SetUploader(uploader *ResumableUploader) {
+	t.uploader = uploader
+}
+
 // GetName returns the platform name
 func (t *TwitterPlatform) GetName() social.SocialPlatform {
 	return social.PlatformTwitter
 }
 
-// GetAuthURL returns the OAuth 2.0 URL
+// GetAuthURL returns the OAuth 2.0 URL. Twitter requires PKCE, so
+// connect flows go through GetAuthURLWithChallenge.
 func (t *TwitterPlatform) GetAuthURL(state string) string {
-	codeChallenge := generateCodeChallenge()
-	
+	return t.GetAuthURLWithChallenge(state, "")
+}
+
+// GetAuthURLWithChallenge returns the OAuth 2.0 URL with a PKCE challenge
+func (t *TwitterPlatform) GetAuthURLWithChallenge(state, codeChallenge string) string {
 	params := url.Values{
-		"response_type":         {"code"},
-		"client_id":             {t.clientID},
-		"redirect_uri":          {t.redirectURL},
-		"scope":                 {"tweet.read tweet.write users.read media.write offline.access"},
-		"state":                 {state},
-		"code_challenge":        {codeChallenge},
-		"code_challenge_method": {"S256"},
+		"response_type": {"code"},
+		"client_id":     {t.clientID},
+		"redirect_uri":  {t.redirectURL},
+		"scope":         {"tweet.read tweet.write users.read media.write offline.access"},
+		"state":         {state},
+	}
+	if codeChallenge != "" {
+		params.Set("code_challenge", codeChallenge)
+		params.Set("code_challenge_method", "S256")
 	}
 
 	return TwitterAuthURL + "?" + params.Encode()
@@ -67,15 +87,22 @@ func (t *TwitterPlatform) GetAuthURL(state string) string {
 
 // ExchangeCode exchanges OAuth code for tokens
 func (t *TwitterPlatform) ExchangeCode(ctx context.Context, code string) (*social.SocialAccount, error) {
+	return t.ExchangeCodeWithVerifier(ctx, code, "")
+}
+
+// ExchangeCodeWithVerifier exchanges OAuth code for tokens using the PKCE verifier
+func (t *TwitterPlatform) ExchangeCodeWithVerifier(ctx context.Context, code, codeVerifier string) (*social.SocialAccount, error) {
 	// Create Basic Auth header
 	auth := base64.StdEncoding.EncodeToString([]byte(t.clientID + ":" + t.clientSecret))
 
 	data := url.Values{
-		"code":          {code},
-		"grant_type":    {"authorization_code"},
-		"client_id":     {t.clientID},
-		"redirect_uri":  {t.redirectURL},
-		"code_verifier": {"challenge"}, // Should match the code_challenge
+		"code":         {code},
+		"grant_type":   {"authorization_code"},
+		"client_id":    {t.clientID},
+		"redirect_uri": {t.redirectURL},
+	}
+	if codeVerifier != "" {
+		data.Set("code_verifier", codeVerifier)
 	}
 
 	headers := map[string]string{
@@ -170,10 +197,11 @@ func (t *TwitterPlatform) UploadVideo(ctx context.Context, account *social.Socia
 	}
 
 	// Step 1: Upload media using chunked upload for videos
-	mediaID, err := t.uploadVideoChunked(ctx, account, req.VideoPath)
+	session, err := t.uploader.Upload(ctx, account, req.VideoPath, &twitterUpload{platform: t, account: account})
 	if err != nil {
 		return nil, fmt.Errorf("video upload failed: %w", err)
 	}
+	mediaID := session.RemoteID
 
 	// Step 2: Create tweet with media
 	tweetURL := TwitterAPIURL + "/tweets"
@@ -204,6 +232,7 @@ func (t *TwitterPlatform) UploadVideo(ctx context.Context, account *social.Socia
 	if err := json.Unmarshal(resp, &tweetResp); err != nil {
 		return nil, fmt.Errorf("failed to parse tweet response: %w", err)
 	}
+	t.uploader.Complete(ctx, session)
 
 	return &social.UploadResponse{
 		PlatformPostID: tweetResp.Data.ID,
@@ -256,73 +285,103 @@ func (t *TwitterPlatform) GetTrends(ctx context.Context, account *social.SocialA
 
 // Helper methods
 
-func (t *TwitterPlatform) uploadVideoChunked(ctx context.Context, account *social.SocialAccount, videoPath string) (string, error) {
-	file, err := os.Open(videoPath)
-	if err != nil {
-		return "", err
-	}
-	defer file.Close()
-
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return "", err
-	}
-
-	fileSize := fileInfo.Size()
+// twitterUpload implements the chunked media upload (INIT, APPEND, FINALIZE)
+type twitterUpload struct {
+	platform *TwitterPlatform
+	account  *social.SocialAccount
+}
 
-	// Step 1: INIT
+func (u *twitterUpload) start(ctx context.Context, session *social.UploadSession) error {
 	initParams := url.Values{
-		"command":     {"INIT"},
-		"media_type":  {"video/mp4"},
-		"total_bytes": {fmt.Sprintf("%d", fileSize)},
-	}
-
-	headers := map[string]string{
-		"Authorization": "Bearer " + account.AccessToken,
+		"command":        {"INIT"},
+		"media_type":     {"video/mp4"},
+		"media_category": {"tweet_video"},
+		"total_bytes":    {strconv.FormatInt(session.FileSize, 10)},
 	}
 
-	initResp, err := t.makeRequest(ctx, "POST", TwitterUploadURL+"?"+initParams.Encode(), nil, headers)
+	initResp, err := u.platform.makeRequest(ctx, "POST", TwitterUploadURL+"?"+initParams.Encode(), nil, u.headers())
 	if err != nil {
-		return "", fmt.Errorf("upload init failed: %w", err)
+		return fmt.Errorf("upload init failed: %w", err)
 	}
 
 	var initResult struct {
-		MediaID string `json:"media_id_string"`
+		MediaID          string `json:"media_id_string"`
+		ExpiresAfterSecs int64  `json:"expires_after_secs"`
 	}
 	if err := json.Unmarshal(initResp, &initResult); err != nil {
-		return "", err
+		return err
+	}
+
+	session.RemoteID = initResult.MediaID
+	expiresAt := time.Now().Add(twitterSessionLife)
+	if initResult.ExpiresAfterSecs > 0 {
+		expiresAt = time.Now().Add(time.Duration(initResult.ExpiresAfterSecs) * time.Second)
 	}
+	session.ExpiresAt = &expiresAt
+
+	return nil
+}
+
+// resume trusts the persisted offset; segments are numbered from it
+func (u *twitterUpload) resume(ctx context.Context, session *social.UploadSession) (int64, error) {
+	return session.Offset, nil
+}
 
-	// Step 2: APPEND (simplified - would need chunking for large files)
-	// For production, split into 5MB chunks
-	buf := make([]byte, fileSize)
-	file.Read(buf)
+func (u *twitterUpload) chunkSize(session *social.UploadSession) int64 {
+	remaining := session.FileSize - session.Offset
+	if remaining < twitterChunkSize {
+		return remaining
+	}
+	return twitterChunkSize
+}
 
-	appendParams := url.Values{
-		"command":       {"APPEND"},
-		"media_id":      {initResult.MediaID},
-		"segment_index": {"0"},
+func (u *twitterUpload) send(ctx context.Context, session *social.UploadSession, chunk []byte) (int64, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("command", "APPEND")
+	writer.WriteField("media_id", session.RemoteID)
+	writer.WriteField("segment_index", strconv.FormatInt(session.Offset/twitterChunkSize, 10))
+	part, err := writer.CreateFormFile("media", "chunk")
+	if err != nil {
+		return 0, err
 	}
+	part.Write(chunk)
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+
+	headers := u.headers()
+	headers["Content-Type"] = writer.FormDataContentType()
 
-	// Use multipart form for video data
-	// Simplified - production code would use proper multipart handling
-	_, err = t.makeRequest(ctx, "POST", TwitterUploadURL+"?"+appendParams.Encode(), buf, headers)
+	resp, respBody, err := doRequest(ctx, uploadHTTPClient, "POST", TwitterUploadURL, &body, headers)
 	if err != nil {
-		return "", fmt.Errorf("upload append failed: %w", err)
+		return 0, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
-	// Step 3: FINALIZE
+	return session.Offset + int64(len(chunk)), nil
+}
+
+func (u *twitterUpload) finish(ctx context.Context, session *social.UploadSession) error {
 	finalizeParams := url.Values{
 		"command":  {"FINALIZE"},
-		"media_id": {initResult.MediaID},
+		"media_id": {session.RemoteID},
 	}
 
-	_, err = t.makeRequest(ctx, "POST", TwitterUploadURL+"?"+finalizeParams.Encode(), nil, headers)
+	_, err := u.platform.makeRequest(ctx, "POST", TwitterUploadURL+"?"+finalizeParams.Encode(), nil, u.headers())
 	if err != nil {
-		return "", fmt.Errorf("upload finalize failed: %w", err)
+		return fmt.Errorf("upload finalize failed: %w", err)
 	}
 
-	return initResult.MediaID, nil
+	return nil
+}
+
+func (u *twitterUpload) headers() map[string]string {
+	return map[string]string{
+		"Authorization": "Bearer " + u.account.AccessToken,
+	}
 }
 
 func (t *TwitterPlatform) getUserInfo(ctx context.Context, accessToken string) (map[string]interface{}, error) {
@@ -373,13 +432,8 @@ func (t *TwitterPlatform) makeRequest(ctx context.Context, method, url string, b
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	return respBody, nil
 }
-
-func generateCodeChallenge() string {
-	// Simplified - in production use PKCE properly
-	return "challenge"
-}
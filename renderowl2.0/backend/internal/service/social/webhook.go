@@ -0,0 +1,439 @@
+package social
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"renderowl-api/internal/domain/social"
+)
+
+// WebhookPlatform publishes videos to a customer's own HTTP endpoint, such
+// as a CMS or a WordPress site. Each delivery is a JSON payload signed with
+// the destination's secret, which is kept in the account's access token.
+type WebhookPlatform struct {
+	httpClient  *http.Client
+	maxAttempts int
+	retryDelay  time.Duration
+}
+
+// Headers sent with every delivery
+const (
+	WebhookSignatureHeader = "X-Renderowl-Signature"
+	WebhookEventHeader     = "X-Renderowl-Event"
+	WebhookDeliveryHeader  = "X-Renderowl-Delivery"
+
+	webhookPublishEvent = "post.published"
+	webhookUpdateEvent  = "post.updated"
+	webhookDeleteEvent  = "post.deleted"
+)
+
+// Failed deliveries are retried with exponential backoff
+const (
+	webhookMaxAttempts   = 4
+	webhookRetryDelay    = 2 * time.Second
+	webhookMaxRetryDelay = time.Minute
+
+	webhookSecretBytes  = 32
+	webhookMinSecretLen = 16
+)
+
+// NewWebhookPlatform creates a new webhook destination platform
+func NewWebhookPlatform() *WebhookPlatform {
+	return &WebhookPlatform{
+		httpClient:  NewPublicHTTPClient(30 * time.Second),
+		maxAttempts: webhookMaxAttempts,
+		retryDelay:  webhookRetryDelay,
+	}
+}
+
+// webhookSettings configure a webhook destination. They are kept in the
+// account metadata, except for the secret and the authorization header,
+// which are kept in the encrypted token columns.
+type webhookSettings struct {
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint"`
+	Method   string `json:"method"`
+	// Authorization is sent as the Authorization header, e.g. for a
+	// WordPress application password
+	Authorization string `json:"authorization,omitempty"`
+	Secret        string `json:"secret,omitempty"`
+	// ResponseMapping holds dotted paths of the remote post's id, url and
+	// status in the endpoint's JSON response
+	ResponseMapping map[string]string `json:"responseMapping"`
+}
+
+// webhookPayload is the body of a publish delivery
+type webhookPayload struct {
+	Event       string            `json:"event"`
+	DeliveryID  string            `json:"deliveryId"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Tags        []string          `json:"tags"`
+	Privacy     string            `json:"privacy,omitempty"`
+	VideoURL    string            `json:"videoUrl"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Timestamp   time.Time         `json:"timestamp"`
+}
+
+// webhookChangePayload is the body of an update or delete delivery. PostID
+// is the ID the endpoint returned when the post was published; updates
+// carry only the changed fields.
+type webhookChangePayload struct {
+	Event       string    `json:"event"`
+	DeliveryID  string    `json:"deliveryId"`
+	PostID      string    `json:"postId"`
+	Title       *string   `json:"title,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Privacy     *string   `json:"privacy,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// GetName returns the platform name
+func (w *WebhookPlatform) GetName() social.SocialPlatform {
+	return social.PlatformWebhook
+}
+
+// GetAuthURL returns no URL; webhook destinations are configured, not connected
+func (w *WebhookPlatform) GetAuthURL(state string) string {
+	return ""
+}
+
+// ExchangeCode is not supported; webhook destinations are configured, not connected
+func (w *WebhookPlatform) ExchangeCode(ctx context.Context, code string) (*social.SocialAccount, error) {
+	return nil, ErrConfiguredDestination
+}
+
+// Configure validates the settings of a webhook destination. A signing
+// secret is generated unless one is given.
+func (w *WebhookPlatform) Configure(ctx context.Context, settings social.JSON) (*social.SocialAccount, error) {
+	var config webhookSettings
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("invalid webhook settings: %w", err)
+	}
+
+	endpoint, err := CheckPublicURL(ctx, config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	config.Method = strings.ToUpper(config.Method)
+	switch config.Method {
+	case "":
+		config.Method = http.MethodPost
+	case http.MethodPost, http.MethodPut:
+	default:
+		return nil, fmt.Errorf("method must be POST or PUT")
+	}
+
+	switch {
+	case config.Secret == "":
+		secret := make([]byte, webhookSecretBytes)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate secret: %w", err)
+		}
+		config.Secret = hex.EncodeToString(secret)
+	case len(config.Secret) < webhookMinSecretLen:
+		return nil, fmt.Errorf("secret must be at least %d characters", webhookMinSecretLen)
+	}
+
+	mapping := map[string]interface{}{}
+	for field, path := range config.ResponseMapping {
+		if field != "id" && field != "url" && field != "status" {
+			return nil, fmt.Errorf("responseMapping supports id, url and status, not %q", field)
+		}
+		mapping[field] = path
+	}
+
+	name := config.Name
+	if name == "" {
+		name = endpoint.Host
+	}
+
+	return &social.SocialAccount{
+		Platform:     social.PlatformWebhook,
+		AccountID:    uuid.New().String(),
+		AccountName:  name,
+		AccessToken:  config.Secret,
+		RefreshToken: config.Authorization,
+		Status:       social.StatusConnected,
+		Metadata: social.JSON{
+			"endpoint":        endpoint.String(),
+			"method":          config.Method,
+			"responseMapping": mapping,
+		},
+	}, nil
+}
+
+// RefreshToken does nothing; the signing secret does not expire
+func (w *WebhookPlatform) RefreshToken(ctx context.Context, account *social.SocialAccount) error {
+	return nil
+}
+
+// UploadVideo delivers the video's URL and metadata to the endpoint
+func (w *WebhookPlatform) UploadVideo(ctx context.Context, account *social.SocialAccount, req *social.UploadRequest) (*social.UploadResponse, error) {
+	videoURL := publicVideoURL(req)
+	if videoURL == "" {
+		return nil, fmt.Errorf("webhook destinations need a public video URL")
+	}
+
+	deliveryID := req.IdempotencyKey
+	if deliveryID == "" {
+		deliveryID = uuid.New().String()
+	}
+
+	tags := req.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	body, err := json.Marshal(webhookPayload{
+		Event:       webhookPublishEvent,
+		DeliveryID:  deliveryID,
+		Title:       req.Title,
+		Description: req.Description,
+		Tags:        tags,
+		Privacy:     req.Privacy,
+		VideoURL:    videoURL,
+		Metadata:    req.Metadata,
+		Timestamp:   time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := w.deliver(ctx, account, webhookPublishEvent, deliveryID, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapWebhookResponse(account, respBody, deliveryID), nil
+}
+
+// FindUpload finds nothing; endpoints drop repeated deliveries by their
+// Idempotency-Key, so a delivery can be sent again
+func (w *WebhookPlatform) FindUpload(ctx context.Context, account *social.SocialAccount, req *social.UploadRequest, since time.Time) (*social.UploadResponse, error) {
+	return nil, nil
+}
+
+// GetAnalytics is not supported; endpoints do not report analytics
+func (w *WebhookPlatform) GetAnalytics(ctx context.Context, account *social.SocialAccount, postID string) (*social.AnalyticsData, error) {
+	return nil, fmt.Errorf("webhook destinations do not report analytics: %w", ErrUnsupported)
+}
+
+// EditableFields lists what can be changed on a delivered post
+func (w *WebhookPlatform) EditableFields() []string {
+	return []string{social.PostFieldTitle, social.PostFieldDescription, social.PostFieldPrivacy}
+}
+
+// UpdatePost delivers the changed fields of a post to the endpoint
+func (w *WebhookPlatform) UpdatePost(ctx context.Context, account *social.SocialAccount, postID string, update *social.PostUpdate) error {
+	return w.deliverChange(ctx, account, webhookChangePayload{
+		Event:       webhookUpdateEvent,
+		PostID:      postID,
+		Title:       update.Title,
+		Description: update.Description,
+		Privacy:     update.Privacy,
+	})
+}
+
+// DeletePost tells the endpoint that a post was deleted
+func (w *WebhookPlatform) DeletePost(ctx context.Context, account *social.SocialAccount, postID string) error {
+	return w.deliverChange(ctx, account, webhookChangePayload{
+		Event:  webhookDeleteEvent,
+		PostID: postID,
+	})
+}
+
+// GetTrends returns no trends
+func (w *WebhookPlatform) GetTrends(ctx context.Context, account *social.SocialAccount, region string) ([]*social.PlatformTrend, error) {
+	return []*social.PlatformTrend{}, nil
+}
+
+// Helper methods
+
+// deliverChange delivers an update or delete event
+func (w *WebhookPlatform) deliverChange(ctx context.Context, account *social.SocialAccount, payload webhookChangePayload) error {
+	payload.DeliveryID = uuid.New().String()
+	payload.Timestamp = time.Now().UTC()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.deliver(ctx, account, payload.Event, payload.DeliveryID, body)
+	return err
+}
+
+// deliver sends a payload, retrying network errors, timeouts, rate limits
+// and server errors. Every attempt is signed afresh so that its timestamp
+// is current.
+func (w *WebhookPlatform) deliver(ctx context.Context, account *social.SocialAccount, event, deliveryID string, body []byte) ([]byte, error) {
+	endpoint, _ := account.Metadata["endpoint"].(string)
+	method, _ := account.Metadata["method"].(string)
+	if endpoint == "" {
+		return nil, fmt.Errorf("webhook destination %s has no endpoint", account.ID)
+	}
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	headers := map[string]string{
+		"Content-Type":        "application/json",
+		"User-Agent":          "Renderowl-Webhook/1.0",
+		"Idempotency-Key":     deliveryID,
+		WebhookEventHeader:    event,
+		WebhookDeliveryHeader: deliveryID,
+	}
+	if account.RefreshToken != "" {
+		headers["Authorization"] = account.RefreshToken
+	}
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		headers[WebhookSignatureHeader] = SignWebhookPayload(account.AccessToken, time.Now(), body)
+
+		resp, respBody, err := doRequest(ctx, w.httpClient, method, endpoint, bytes.NewReader(body), headers)
+		if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return respBody, nil
+		}
+
+		retryable := true
+		delay := w.retryDelay << (attempt - 1)
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+			retryable = resp.StatusCode == http.StatusRequestTimeout ||
+				resp.StatusCode == http.StatusTooManyRequests ||
+				resp.StatusCode >= 500
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+				delay = time.Duration(seconds) * time.Second
+			}
+		}
+
+		if !retryable || attempt >= w.maxAttempts || ctx.Err() != nil {
+			return nil, fmt.Errorf("webhook delivery failed after %d attempts: %w", attempt, lastErr)
+		}
+
+		if delay > webhookMaxRetryDelay {
+			delay = webhookMaxRetryDelay
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// SignWebhookPayload returns the signature header of a delivery: the
+// timestamp and an HMAC-SHA256 of "timestamp.body" keyed with the secret.
+// Receivers should recompute it and reject stale timestamps.
+func SignWebhookPayload(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	io.WriteString(mac, t+".")
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// mapWebhookResponse reads the remote post from the endpoint's response
+// using the destination's response mapping. Without a mapping the id, url
+// or link and status fields are used; the delivery ID stands in for a
+// missing post ID.
+func mapWebhookResponse(account *social.SocialAccount, body []byte, deliveryID string) *social.UploadResponse {
+	mapping := map[string]string{}
+	if configured, ok := account.Metadata["responseMapping"].(map[string]interface{}); ok {
+		for field, path := range configured {
+			if s, ok := path.(string); ok && s != "" {
+				mapping[field] = s
+			}
+		}
+	}
+
+	var data interface{}
+	json.Unmarshal(body, &data)
+
+	field := func(name string, defaults ...string) string {
+		paths := defaults
+		if path, ok := mapping[name]; ok {
+			paths = []string{path}
+		}
+		for _, path := range paths {
+			if value := lookupPath(data, path); value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	resp := &social.UploadResponse{
+		PlatformPostID: field("id", "id"),
+		PostURL:        field("url", "url", "link"),
+		Status:         field("status"),
+	}
+	if resp.PlatformPostID == "" {
+		resp.PlatformPostID = deliveryID
+	}
+	if resp.Status == "" {
+		resp.Status = "published"
+	}
+	return resp
+}
+
+// lookupPath reads a scalar from decoded JSON by a dotted path such as
+// "data.post.id" or "items.0.link"
+func lookupPath(data interface{}, path string) string {
+	for _, key := range strings.Split(path, ".") {
+		switch node := data.(type) {
+		case map[string]interface{}:
+			data = node[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return ""
+			}
+			data = node[i]
+		default:
+			return ""
+		}
+	}
+
+	switch value := data.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	}
+	return ""
+}
+
+// publicVideoURL returns the URL a destination can fetch the video from
+func publicVideoURL(req *social.UploadRequest) string {
+	if req.VideoURL != "" {
+		return req.VideoURL
+	}
+	if strings.HasPrefix(req.VideoPath, "https://") || strings.HasPrefix(req.VideoPath, "http://") {
+		return req.VideoPath
+	}
+	return ""
+}
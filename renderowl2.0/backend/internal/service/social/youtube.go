@@ -1,15 +1,20 @@
 package social
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"net/http"
+	"strconv"
 	"time"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
+	"google.golang.org/api/youtubeanalytics/v2"
 
 	"renderowl-api/internal/domain/social"
 )
@@ -20,8 +25,17 @@ type YouTubePlatform struct {
 	clientSecret string
 	redirectURL  string
 	config       *oauth2.Config
+	uploader     *ResumableUploader
 }
 
+// YouTube upload endpoint and chunk size. Resumable chunks must be a
+// multiple of 256 KiB.
+const (
+	YouTubeUploadURL   = "https://www.googleapis.com/upload/youtube/v3/videos"
+	youtubeChunkSize   = 32 * 256 * 1024
+	youtubeSessionLife = 7 * 24 * time.Hour
+)
+
 // NewYouTubePlatform creates a new YouTube platform instance
 func NewYouTubePlatform(clientID, clientSecret, redirectURL string) *YouTubePlatform {
 	config := &oauth2.Config{
@@ -32,6 +46,7 @@ func NewYouTubePlatform(clientID, clientSecret, redirectURL string) *YouTubePlat
 			youtube.YoutubeUploadScope,
 			youtube.YoutubeReadonlyScope,
 			youtube.YoutubeScope,
+			youtubeanalytics.YtAnalyticsReadonlyScope,
 		},
 		Endpoint: google.Endpoint,
 	}
@@ -41,9 +56,15 @@ func NewYouTubePlatform(clientID, clientSecret, redirectURL string) *YouTubePlat
 		clientSecret: clientSecret,
 		redirectURL:  redirectURL,
 		config:       config,
+		uploader:     NewResumableUploader(nil),
 	}
 }
 
+// SetUploader sets the uploader used for resumable uploads
+func (y *YouTubePlatform) SetUploader(uploader *ResumableUploader) {
+	y.uploader = uploader
+}
+
 // GetName returns the platform name
 func (y *YouTubePlatform) GetName() social.SocialPlatform {
 	return social.PlatformYouTube
@@ -138,17 +159,6 @@ func (y *YouTubePlatform) UploadVideo(ctx context.Context, account *social.Socia
 	}
 
 	client := y.config.Client(ctx, token)
-	service, err := youtube.NewService(ctx, option.WithHTTPClient(client))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create YouTube service: %w", err)
-	}
-
-	// Open video file
-	file, err := os.Open(req.VideoPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open video file: %w", err)
-	}
-	defer file.Close()
 
 	// Create video snippet
 	snippet := &youtube.VideoSnippet{
@@ -159,6 +169,9 @@ func (y *YouTubePlatform) UploadVideo(ctx context.Context, account *social.Socia
 	if len(req.Tags) > 0 {
 		snippet.Tags = req.Tags
 	}
+	if categoryID := req.Metadata["categoryId"]; categoryID != "" {
+		snippet.CategoryId = categoryID
+	}
 
 	// Set privacy status
 	status := &youtube.VideoStatus{}
@@ -179,21 +192,65 @@ func (y *YouTubePlatform) UploadVideo(ctx context.Context, account *social.Socia
 		Status:  status,
 	}
 
-	// Upload
-	call := service.Videos.Insert([]string{"snippet", "status"}, video)
-	call = call.Media(file)
-	response, err := call.Do()
+	// Upload in resumable chunks
+	session, err := y.uploader.Upload(ctx, account, req.VideoPath, &youtubeUpload{client: client, video: video})
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload video: %w", err)
 	}
+	y.uploader.Complete(ctx, session)
 
 	return &social.UploadResponse{
-		PlatformPostID: response.Id,
-		PostURL:        fmt.Sprintf("https://youtube.com/watch?v=%s", response.Id),
+		PlatformPostID: session.RemoteID,
+		PostURL:        fmt.Sprintf("https://youtube.com/watch?v=%s", session.RemoteID),
 		Status:         "published",
 	}, nil
 }
 
+// FindUpload finds a video with the title of an upload among the videos
+// the channel uploaded since a time
+func (y *YouTubePlatform) FindUpload(ctx context.Context, account *social.SocialAccount, req *social.UploadRequest, since time.Time) (*social.UploadResponse, error) {
+	service, err := y.commentService(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+
+	channels, err := service.Channels.List([]string{"contentDetails"}).Mine(true).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel info: %w", err)
+	}
+	if len(channels.Items) == 0 || channels.Items[0].ContentDetails == nil {
+		return nil, fmt.Errorf("no YouTube channel found")
+	}
+	uploads := channels.Items[0].ContentDetails.RelatedPlaylists.Uploads
+
+	// The uploads playlist lists the most recent videos first
+	items, err := service.PlaylistItems.List([]string{"snippet"}).
+		PlaylistId(uploads).
+		MaxResults(50).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list uploads: %w", err)
+	}
+	for _, item := range items.Items {
+		publishedAt, err := time.Parse(time.RFC3339, item.Snippet.PublishedAt)
+		if err != nil || publishedAt.Before(since) {
+			continue
+		}
+		if item.Snippet.Title != req.Title || item.Snippet.ResourceId == nil {
+			continue
+		}
+		videoID := item.Snippet.ResourceId.VideoId
+		return &social.UploadResponse{
+			PlatformPostID: videoID,
+			PostURL:        fmt.Sprintf("https://youtube.com/watch?v=%s", videoID),
+			Status:         "published",
+		}, nil
+	}
+
+	return nil, nil
+}
+
 // GetAnalytics retrieves analytics for a video
 func (y *YouTubePlatform) GetAnalytics(ctx context.Context, account *social.SocialAccount, postID string) (*social.AnalyticsData, error) {
 	// Refresh token if needed
@@ -264,6 +321,276 @@ func (y *YouTubePlatform) DeletePost(ctx context.Context, account *social.Social
 	return call.Do()
 }
 
+// EditableFields lists what can be changed on a published video
+func (y *YouTubePlatform) EditableFields() []string {
+	return []string{social.PostFieldTitle, social.PostFieldDescription, social.PostFieldPrivacy}
+}
+
+// UpdatePost changes the title, description or privacy of a video. The
+// video is read first because videos.update replaces the parts it is given.
+func (y *YouTubePlatform) UpdatePost(ctx context.Context, account *social.SocialAccount, postID string, update *social.PostUpdate) error {
+	// Refresh token if needed
+	if account.TokenExpiry != nil && account.TokenExpiry.Before(time.Now()) {
+		if err := y.RefreshToken(ctx, account); err != nil {
+			return err
+		}
+	}
+
+	token := &oauth2.Token{
+		AccessToken:  account.AccessToken,
+		RefreshToken: account.RefreshToken,
+	}
+
+	client := y.config.Client(ctx, token)
+	service, err := youtube.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return fmt.Errorf("failed to create YouTube service: %w", err)
+	}
+
+	list, err := service.Videos.List([]string{"snippet", "status"}).Id(postID).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("video fetch failed: %w", err)
+	}
+	if len(list.Items) == 0 {
+		return fmt.Errorf("video %s not found", postID)
+	}
+	current := list.Items[0]
+
+	video := &youtube.Video{Id: postID}
+	var parts []string
+	if update.Title != nil || update.Description != nil {
+		video.Snippet = current.Snippet
+		if update.Title != nil {
+			video.Snippet.Title = *update.Title
+		}
+		if update.Description != nil {
+			video.Snippet.Description = *update.Description
+		}
+		parts = append(parts, "snippet")
+	}
+	if update.Privacy != nil {
+		video.Status = current.Status
+		video.Status.PrivacyStatus = *update.Privacy
+		parts = append(parts, "status")
+	}
+
+	_, err = service.Videos.Update(parts, video).Context(ctx).Do()
+	return err
+}
+
+// GetComments lists the comment threads of a video with their replies.
+// Threads carry a few replies; longer threads are read in full.
+func (y *YouTubePlatform) GetComments(ctx context.Context, account *social.SocialAccount, postID string) ([]*social.Comment, error) {
+	service, err := y.commentService(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []*social.Comment
+	call := service.CommentThreads.List([]string{"snippet", "replies"}).
+		VideoId(postID).
+		Order("time").
+		TextFormat("plainText").
+		MaxResults(100)
+	err = call.Pages(ctx, func(page *youtube.CommentThreadListResponse) error {
+		for _, thread := range page.Items {
+			top := thread.Snippet.TopLevelComment
+			comments = append(comments, youtubeComment(account, postID, top))
+
+			replies := []*youtube.Comment{}
+			if thread.Replies != nil {
+				replies = thread.Replies.Comments
+			}
+			if int64(len(replies)) < thread.Snippet.TotalReplyCount {
+				replies = replies[:0]
+				err := service.Comments.List([]string{"snippet"}).
+					ParentId(top.Id).
+					TextFormat("plainText").
+					MaxResults(100).
+					Pages(ctx, func(page *youtube.CommentListResponse) error {
+						replies = append(replies, page.Items...)
+						return nil
+					})
+				if err != nil {
+					return fmt.Errorf("replies fetch failed: %w", err)
+				}
+			}
+			for _, reply := range replies {
+				comments = append(comments, youtubeComment(account, postID, reply))
+			}
+		}
+		if len(comments) >= commentFetchLimit {
+			return errEnoughComments
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errEnoughComments) {
+		return nil, fmt.Errorf("comments fetch failed: %w", err)
+	}
+
+	return comments, nil
+}
+
+// ReplyToComment replies to a comment thread
+func (y *YouTubePlatform) ReplyToComment(ctx context.Context, account *social.SocialAccount, postID, commentID, text string) (*social.Comment, error) {
+	service, err := y.commentService(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := service.Comments.Insert([]string{"snippet"}, &youtube.Comment{
+		Snippet: &youtube.CommentSnippet{
+			ParentId:     commentID,
+			TextOriginal: text,
+		},
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("reply failed: %w", err)
+	}
+
+	return youtubeComment(account, postID, reply), nil
+}
+
+// HideComment holds a comment for review, which hides it until it is
+// published again
+func (y *YouTubePlatform) HideComment(ctx context.Context, account *social.SocialAccount, postID, commentID string, hidden bool) error {
+	service, err := y.commentService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	status := "published"
+	if hidden {
+		status = "heldForReview"
+	}
+	return service.Comments.SetModerationStatus([]string{commentID}, status).Context(ctx).Do()
+}
+
+// DeleteComment deletes a comment. YouTube only deletes comments written
+// by the channel; others can be hidden instead.
+func (y *YouTubePlatform) DeleteComment(ctx context.Context, account *social.SocialAccount, postID, commentID string) error {
+	service, err := y.commentService(ctx, account)
+	if err != nil {
+		return err
+	}
+	return service.Comments.Delete(commentID).Context(ctx).Do()
+}
+
+// commentService returns a YouTube client for the comment calls
+func (y *YouTubePlatform) commentService(ctx context.Context, account *social.SocialAccount) (*youtube.Service, error) {
+	// Refresh token if needed
+	if account.TokenExpiry != nil && account.TokenExpiry.Before(time.Now()) {
+		if err := y.RefreshToken(ctx, account); err != nil {
+			return nil, err
+		}
+	}
+
+	token := &oauth2.Token{
+		AccessToken:  account.AccessToken,
+		RefreshToken: account.RefreshToken,
+	}
+
+	service, err := youtube.NewService(ctx, option.WithHTTPClient(y.config.Client(ctx, token)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create YouTube service: %w", err)
+	}
+	return service, nil
+}
+
+// youtubeAnalyticsStart is the start date of lifetime YouTube Analytics
+// queries, before any video could have been published
+const youtubeAnalyticsStart = "2005-01-01"
+
+// GetRetention retrieves the audience retention curve of a video. YouTube
+// Analytics reports it at 100 points of elapsed video time.
+func (y *YouTubePlatform) GetRetention(ctx context.Context, account *social.SocialAccount, postID string) ([]social.RetentionPoint, error) {
+	// Refresh token if needed
+	if account.TokenExpiry != nil && account.TokenExpiry.Before(time.Now()) {
+		if err := y.RefreshToken(ctx, account); err != nil {
+			return nil, err
+		}
+	}
+
+	token := &oauth2.Token{
+		AccessToken:  account.AccessToken,
+		RefreshToken: account.RefreshToken,
+	}
+
+	service, err := youtubeanalytics.NewService(ctx, option.WithHTTPClient(y.config.Client(ctx, token)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create YouTube Analytics service: %w", err)
+	}
+
+	report, err := service.Reports.Query().
+		Ids("channel==MINE").
+		StartDate(youtubeAnalyticsStart).
+		EndDate(time.Now().UTC().Format("2006-01-02")).
+		Metrics("audienceWatchRatio").
+		Dimensions("elapsedVideoTimeRatio").
+		Filters("video==" + postID).
+		Sort("elapsedVideoTimeRatio").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get video retention: %w", err)
+	}
+
+	position, ratio := -1, -1
+	for i, header := range report.ColumnHeaders {
+		switch header.Name {
+		case "elapsedVideoTimeRatio":
+			position = i
+		case "audienceWatchRatio":
+			ratio = i
+		}
+	}
+	if position < 0 || ratio < 0 {
+		return nil, fmt.Errorf("unexpected YouTube retention report columns")
+	}
+
+	points := make([]social.RetentionPoint, 0, len(report.Rows))
+	for _, row := range report.Rows {
+		if len(row) <= position || len(row) <= ratio {
+			continue
+		}
+		p, ok1 := row[position].(float64)
+		r, ok2 := row[ratio].(float64)
+		if !ok1 || !ok2 {
+			continue
+		}
+		points = append(points, social.RetentionPoint{Position: p, WatchRatio: r})
+	}
+	return points, nil
+}
+
+// youtubeComment converts a YouTube comment
+func youtubeComment(account *social.SocialAccount, postID string, c *youtube.Comment) *social.Comment {
+	comment := &social.Comment{
+		RemoteID:        c.Id,
+		RemotePostID:    postID,
+		RemoteParentID:  c.Snippet.ParentId,
+		AuthorName:      c.Snippet.AuthorDisplayName,
+		AuthorAvatarURL: c.Snippet.AuthorProfileImageUrl,
+		Text:            c.Snippet.TextOriginal,
+		Likes:           c.Snippet.LikeCount,
+		Status:          social.CommentStatusVisible,
+	}
+	if comment.Text == "" {
+		comment.Text = c.Snippet.TextDisplay
+	}
+	if c.Snippet.AuthorChannelId != nil {
+		comment.AuthorID = c.Snippet.AuthorChannelId.Value
+		comment.FromAccount = comment.AuthorID == account.AccountID
+	}
+	if c.Snippet.ModerationStatus == "heldForReview" || c.Snippet.ModerationStatus == "rejected" {
+		comment.Status = social.CommentStatusHidden
+	}
+	if t, err := time.Parse(time.RFC3339, c.Snippet.PublishedAt); err == nil {
+		comment.PublishedAt = t
+	}
+	return comment
+}
+
 // GetTrends retrieves trending videos
 func (y *YouTubePlatform) GetTrends(ctx context.Context, account *social.SocialAccount, region string) ([]*social.PlatformTrend, error) {
 	token := &oauth2.Token{
@@ -304,3 +631,101 @@ func (y *YouTubePlatform) GetTrends(ctx context.Context, account *social.SocialA
 
 	return trends, nil
 }
+
+// youtubeUpload implements the YouTube resumable upload protocol
+type youtubeUpload struct {
+	client *http.Client
+	video  *youtube.Video
+}
+
+func (u *youtubeUpload) start(ctx context.Context, session *social.UploadSession) error {
+	metadata, err := json.Marshal(u.video)
+	if err != nil {
+		return err
+	}
+
+	startURL := YouTubeUploadURL + "?uploadType=resumable&part=snippet,status"
+	resp, body, err := doRequest(ctx, u.client, "POST", startURL, bytes.NewReader(metadata), map[string]string{
+		"Content-Type":            "application/json; charset=UTF-8",
+		"X-Upload-Content-Type":   "video/*",
+		"X-Upload-Content-Length": strconv.FormatInt(session.FileSize, 10),
+	})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	session.SessionURL = resp.Header.Get("Location")
+	if session.SessionURL == "" {
+		return fmt.Errorf("no upload session URL returned")
+	}
+	expiresAt := time.Now().Add(youtubeSessionLife)
+	session.ExpiresAt = &expiresAt
+
+	return nil
+}
+
+func (u *youtubeUpload) resume(ctx context.Context, session *social.UploadSession) (int64, error) {
+	resp, body, err := doRequest(ctx, u.client, "PUT", session.SessionURL, nil, map[string]string{
+		"Content-Range": fmt.Sprintf("bytes */%d", session.FileSize),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return u.progress(session, resp, body)
+}
+
+func (u *youtubeUpload) chunkSize(session *social.UploadSession) int64 {
+	remaining := session.FileSize - session.Offset
+	if remaining < youtubeChunkSize {
+		return remaining
+	}
+	return youtubeChunkSize
+}
+
+func (u *youtubeUpload) send(ctx context.Context, session *social.UploadSession, chunk []byte) (int64, error) {
+	resp, body, err := doRequest(ctx, u.client, "PUT", session.SessionURL, bytes.NewReader(chunk), map[string]string{
+		"Content-Type":  "video/*",
+		"Content-Range": contentRange(session.Offset, int64(len(chunk)), session.FileSize),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return u.progress(session, resp, body)
+}
+
+func (u *youtubeUpload) finish(ctx context.Context, session *social.UploadSession) error {
+	if session.RemoteID == "" {
+		return fmt.Errorf("upload completed without a video ID")
+	}
+	return nil
+}
+
+// progress reads the acknowledged offset from a 308 Resume Incomplete
+// response, or the video ID once the upload is complete
+func (u *youtubeUpload) progress(session *social.UploadSession, resp *http.Response, body []byte) (int64, error) {
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var video youtube.Video
+		if err := json.Unmarshal(body, &video); err != nil {
+			return 0, fmt.Errorf("failed to parse upload response: %w", err)
+		}
+		session.RemoteID = video.Id
+		return session.FileSize, nil
+	case http.StatusPermanentRedirect:
+		// Range is "bytes=0-<last byte>", absent when nothing was received
+		uploaded := resp.Header.Get("Range")
+		if uploaded == "" {
+			return 0, nil
+		}
+		var first, last int64
+		if _, err := fmt.Sscanf(uploaded, "bytes=%d-%d", &first, &last); err != nil {
+			return 0, fmt.Errorf("invalid Range header %q", uploaded)
+		}
+		return last + 1, nil
+	default:
+		return 0, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+}
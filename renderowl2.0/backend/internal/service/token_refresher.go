@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	socialdomain "renderowl-api/internal/domain/social"
+	"renderowl-api/internal/scheduler"
+	socialsvc "renderowl-api/internal/service/social"
+)
+
+// tokenRefreshLookahead is how far ahead of expiry tokens are refreshed.
+// It spans two runs so a transient failure gets a second chance.
+const tokenRefreshLookahead = 2 * time.Hour
+
+// TokenRefresher keeps social account tokens fresh ahead of expiry
+type TokenRefresher struct {
+	socialService *socialsvc.Service
+	scheduler     *scheduler.Scheduler
+}
+
+// NewTokenRefresher creates a new token refresher
+func NewTokenRefresher(socialService *socialsvc.Service, scheduler *scheduler.Scheduler) *TokenRefresher {
+	return &TokenRefresher{
+		socialService: socialService,
+		scheduler:     scheduler,
+	}
+}
+
+// Initialize registers the refresh job, runs it once and schedules it hourly
+func (r *TokenRefresher) Initialize(ctx context.Context) error {
+	return r.scheduler.ScheduleRecurring(ctx, "refresh_tokens", &socialdomain.RecurringRule{
+		Frequency: "hourly",
+		Interval:  1,
+	}, r.handleRefreshJob)
+}
+
+func (r *TokenRefresher) handleRefreshJob(ctx context.Context, job *scheduler.Job) error {
+	refreshed, failed, err := r.socialService.RefreshExpiringTokens(ctx, tokenRefreshLookahead)
+	if err != nil {
+		return err
+	}
+
+	if refreshed > 0 || failed > 0 {
+		log.Printf("Token refresh: %d refreshed, %d failed", refreshed, failed)
+	}
+
+	return nil
+}
@@ -176,6 +176,50 @@ var PlatformSpecs = map[string]PlatformSpec{
 		MaxFileSize:     5 * 1024 * 1024 * 1024, // 5GB
 		SupportedCodecs: []string{"H.264"},
 	},
+	"pinterest": {
+		Name:            "Pinterest",
+		Width:           1080,
+		Height:          1920,
+		AspectRatio:     "9:16",
+		MaxDuration:     15 * 60, // 15 minutes
+		MinDuration:     4,
+		RecommendedFPS:  30,
+		MaxFileSize:     2 * 1024 * 1024 * 1024, // 2GB
+		SupportedCodecs: []string{"H.264", "H.265"},
+	},
+	"threads": {
+		Name:            "Threads",
+		Width:           1080,
+		Height:          1920,
+		AspectRatio:     "9:16",
+		MaxDuration:     5 * 60, // 5 minutes
+		MinDuration:     1,
+		RecommendedFPS:  30,
+		MaxFileSize:     1024 * 1024 * 1024, // 1GB
+		SupportedCodecs: []string{"H.264", "HEVC"},
+	},
+	"snapchat_spotlight": {
+		Name:            "Snapchat Spotlight",
+		Width:           1080,
+		Height:          1920,
+		AspectRatio:     "9:16",
+		MaxDuration:     60,
+		MinDuration:     5,
+		RecommendedFPS:  30,
+		MaxFileSize:     1024 * 1024 * 1024, // 1GB
+		SupportedCodecs: []string{"H.264"},
+	},
+	"bluesky": {
+		Name:            "Bluesky",
+		Width:           1080,
+		Height:          1920,
+		AspectRatio:     "9:16",
+		MaxDuration:     3 * 60, // 3 minutes
+		MinDuration:     1,
+		RecommendedFPS:  30,
+		MaxFileSize:     100 * 1024 * 1024, // 100MB
+		SupportedCodecs: []string{"H.264"},
+	},
 }
 
 // CreateVariationsRequest represents a request to create variations
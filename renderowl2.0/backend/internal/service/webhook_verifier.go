@@ -0,0 +1,401 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrWebhookUnsupported is returned for platforms that do not send
+	// verifiable webhooks, or whose webhook secret is not configured
+	ErrWebhookUnsupported = errors.New("webhooks are not accepted from this platform")
+
+	// ErrWebhookSignature is returned when a webhook is unsigned or its
+	// signature does not match
+	ErrWebhookSignature = errors.New("invalid webhook signature")
+
+	// ErrWebhookStale is returned for webhooks sent too long ago, or
+	// dated in the future, which are treated as replays
+	ErrWebhookStale = errors.New("webhook timestamp is outside the accepted window")
+
+	// ErrWebhookPayload is returned for webhooks that cannot be parsed
+	ErrWebhookPayload = errors.New("invalid webhook payload")
+
+	// ErrWebhookChallenge is returned when a subscription verification
+	// request was not made for one of our subscriptions
+	ErrWebhookChallenge = errors.New("invalid webhook subscription request")
+
+	// ErrWebhookDuplicate is returned for deliveries that were already
+	// received
+	ErrWebhookDuplicate = errors.New("webhook already received")
+)
+
+// Replay windows. A timestamp signed separately from the payload, as
+// TikTok sends, must be recent. Timestamps inside a signed payload are
+// allowed to be older because Meta retries failed deliveries for up to
+// 36 hours; retries of a delivery are caught by its delivery ID instead.
+const (
+	webhookSignedTolerance = 5 * time.Minute
+	webhookMaxAge          = 48 * time.Hour
+)
+
+// youtubeTopicPrefix is the topic of the YouTube channel feeds we
+// subscribe to through PubSubHubbub
+const youtubeTopicPrefix = "https://www.youtube.com/xml/feeds/videos.xml?channel_id="
+
+// WebhookSecrets holds the secrets webhooks are signed with. Platforms
+// whose secret is empty do not accept webhooks.
+type WebhookSecrets struct {
+	// YouTube is the hub.secret of the PubSubHubbub subscriptions
+	YouTube string
+	// Facebook, Instagram and Threads are the app secrets of the Meta apps
+	Facebook  string
+	Instagram string
+	Threads   string
+	// MetaVerifyToken is the verify token of the Meta webhook subscriptions
+	MetaVerifyToken string
+	// TikTok is the client secret of the TikTok app
+	TikTok string
+}
+
+// WebhookVerifier checks that analytics webhooks were sent by the
+// platform they claim to come from, and are not replays, before they are
+// stored
+type WebhookVerifier struct {
+	secrets WebhookSecrets
+}
+
+// NewWebhookVerifier creates a new webhook verifier
+func NewWebhookVerifier(secrets WebhookSecrets) *WebhookVerifier {
+	return &WebhookVerifier{secrets: secrets}
+}
+
+// Challenge answers a subscription verification request, returning the
+// challenge to echo back. YouTube verifies PubSubHubbub subscriptions and
+// Meta verifies its webhook subscriptions this way.
+func (v *WebhookVerifier) Challenge(platform string, query url.Values) (string, error) {
+	challenge := query.Get("hub.challenge")
+	if challenge == "" {
+		return "", ErrWebhookChallenge
+	}
+
+	switch platform {
+	case "youtube":
+		if v.secrets.YouTube == "" {
+			return "", ErrWebhookUnsupported
+		}
+		mode := query.Get("hub.mode")
+		if (mode != "subscribe" && mode != "unsubscribe") || !strings.HasPrefix(query.Get("hub.topic"), youtubeTopicPrefix) {
+			return "", ErrWebhookChallenge
+		}
+	case "facebook", "instagram", "threads":
+		if v.secrets.MetaVerifyToken == "" || v.metaSecret(platform) == "" {
+			return "", ErrWebhookUnsupported
+		}
+		if query.Get("hub.mode") != "subscribe" ||
+			!hmac.Equal([]byte(query.Get("hub.verify_token")), []byte(v.secrets.MetaVerifyToken)) {
+			return "", ErrWebhookChallenge
+		}
+	default:
+		return "", ErrWebhookUnsupported
+	}
+
+	return challenge, nil
+}
+
+// Verify checks the signature and timestamp of a webhook and parses it
+// into an event. The body must be the raw request body the signature was
+// computed over.
+func (v *WebhookVerifier) Verify(platform string, header http.Header, body []byte) (*WebhookEventRequest, error) {
+	switch platform {
+	case "youtube":
+		return v.verifyYouTube(header, body)
+	case "facebook", "instagram", "threads":
+		return v.verifyMeta(platform, header, body)
+	case "tiktok":
+		return v.verifyTikTok(header, body)
+	default:
+		return nil, ErrWebhookUnsupported
+	}
+}
+
+func (v *WebhookVerifier) metaSecret(platform string) string {
+	switch platform {
+	case "facebook":
+		return v.secrets.Facebook
+	case "instagram":
+		return v.secrets.Instagram
+	case "threads":
+		return v.secrets.Threads
+	}
+	return ""
+}
+
+// youtubeFeed is a PubSubHubbub notification for a YouTube channel: an
+// entry for a new or updated video, or a deleted entry
+type youtubeFeed struct {
+	Entry *struct {
+		VideoID   string `xml:"videoId"`
+		ChannelID string `xml:"channelId"`
+		Title     string `xml:"title"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+	} `xml:"entry"`
+	Deleted *struct {
+		Ref  string `xml:"ref,attr"`
+		When string `xml:"when,attr"`
+	} `xml:"deleted-entry"`
+}
+
+// verifyYouTube verifies a PubSubHubbub notification, signed with
+// HMAC-SHA1 of the body in X-Hub-Signature
+func (v *WebhookVerifier) verifyYouTube(header http.Header, body []byte) (*WebhookEventRequest, error) {
+	if v.secrets.YouTube == "" {
+		return nil, ErrWebhookUnsupported
+	}
+	if !verifyHubSignature(header.Get("X-Hub-Signature"), "sha1=", sha1.New, v.secrets.YouTube, body) {
+		return nil, ErrWebhookSignature
+	}
+
+	var feed youtubeFeed
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebhookPayload, err)
+	}
+
+	var req *WebhookEventRequest
+	var sentAt string
+	switch {
+	case feed.Entry != nil && feed.Entry.VideoID != "":
+		sentAt = feed.Entry.Updated
+		req = &WebhookEventRequest{
+			EventType: "video_updated",
+			VideoID:   feed.Entry.VideoID,
+			Payload: map[string]interface{}{
+				"video_id":   feed.Entry.VideoID,
+				"channel_id": feed.Entry.ChannelID,
+				"title":      feed.Entry.Title,
+				"published":  feed.Entry.Published,
+				"updated":    feed.Entry.Updated,
+			},
+		}
+	case feed.Deleted != nil:
+		sentAt = feed.Deleted.When
+		videoID := strings.TrimPrefix(feed.Deleted.Ref, "yt:video:")
+		req = &WebhookEventRequest{
+			EventType: "video_deleted",
+			VideoID:   videoID,
+			Payload: map[string]interface{}{
+				"video_id": videoID,
+				"deleted":  feed.Deleted.When,
+			},
+		}
+	default:
+		return nil, fmt.Errorf("%w: no entry in feed", ErrWebhookPayload)
+	}
+
+	at, err := time.Parse(time.RFC3339, sentAt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid timestamp %q", ErrWebhookPayload, sentAt)
+	}
+	if err := checkWebhookAge(at, webhookMaxAge); err != nil {
+		return nil, err
+	}
+
+	req.Platform = "youtube"
+	req.DeliveryID = fmt.Sprintf("youtube:%s:%s:%s", req.EventType, req.VideoID, sentAt)
+	return req, nil
+}
+
+// metaNotification is a Meta webhook notification. Each entry holds the
+// changes to one object, such as a page or an Instagram account.
+type metaNotification struct {
+	Object string `json:"object"`
+	Entry  []struct {
+		ID      string `json:"id"`
+		Time    int64  `json:"time"`
+		Changes []struct {
+			Field string                 `json:"field"`
+			Value map[string]interface{} `json:"value"`
+		} `json:"changes"`
+	} `json:"entry"`
+}
+
+// verifyMeta verifies a Facebook, Instagram or Threads notification,
+// signed with HMAC-SHA256 of the body and the app secret in
+// X-Hub-Signature-256
+func (v *WebhookVerifier) verifyMeta(platform string, header http.Header, body []byte) (*WebhookEventRequest, error) {
+	secret := v.metaSecret(platform)
+	if secret == "" {
+		return nil, ErrWebhookUnsupported
+	}
+	if !verifyHubSignature(header.Get("X-Hub-Signature-256"), "sha256=", sha256.New, secret, body) {
+		return nil, ErrWebhookSignature
+	}
+
+	var notification metaNotification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebhookPayload, err)
+	}
+	if len(notification.Entry) == 0 {
+		return nil, fmt.Errorf("%w: no entries", ErrWebhookPayload)
+	}
+
+	// Entries are timestamped in seconds, or milliseconds for some objects
+	var sentAt int64
+	for _, entry := range notification.Entry {
+		if entry.Time > sentAt {
+			sentAt = entry.Time
+		}
+	}
+	at := time.Unix(sentAt, 0)
+	if sentAt > 1e12 {
+		at = time.UnixMilli(sentAt)
+	}
+	if err := checkWebhookAge(at, webhookMaxAge); err != nil {
+		return nil, err
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebhookPayload, err)
+	}
+
+	req := &WebhookEventRequest{
+		Platform:   platform,
+		EventType:  notification.Object,
+		DeliveryID: platform + ":" + bodyDigest(body),
+		Payload:    payload,
+	}
+	if changes := notification.Entry[0].Changes; len(changes) > 0 {
+		req.EventType = changes[0].Field
+		for _, key := range []string{"media_id", "video_id", "post_id"} {
+			if id, ok := changes[0].Value[key].(string); ok && id != "" {
+				req.VideoID = id
+				break
+			}
+		}
+	}
+	return req, nil
+}
+
+// tiktokNotification is a TikTok webhook event
+type tiktokNotification struct {
+	ClientKey  string `json:"client_key"`
+	Event      string `json:"event"`
+	CreateTime int64  `json:"create_time"`
+	UserOpenID string `json:"user_openid"`
+	Content    string `json:"content"`
+}
+
+// verifyTikTok verifies a TikTok event, signed in the TikTok-Signature
+// header as "t=<timestamp>,s=<signature>", where the signature is the
+// HMAC-SHA256 of "<timestamp>.<body>" with the client secret
+func (v *WebhookVerifier) verifyTikTok(header http.Header, body []byte) (*WebhookEventRequest, error) {
+	if v.secrets.TikTok == "" {
+		return nil, ErrWebhookUnsupported
+	}
+
+	var timestamp, signature string
+	for _, part := range strings.Split(header.Get("TikTok-Signature"), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "s":
+			signature = value
+		}
+	}
+	sentAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return nil, ErrWebhookSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(v.secrets.TikTok))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac.Sum(nil), expected) {
+		return nil, ErrWebhookSignature
+	}
+	if err := checkWebhookAge(time.Unix(sentAt, 0), webhookSignedTolerance); err != nil {
+		return nil, err
+	}
+
+	var notification tiktokNotification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebhookPayload, err)
+	}
+
+	// The content of an event is itself JSON encoded
+	var content map[string]interface{}
+	if notification.Content != "" {
+		if err := json.Unmarshal([]byte(notification.Content), &content); err != nil {
+			return nil, fmt.Errorf("%w: invalid content: %v", ErrWebhookPayload, err)
+		}
+	}
+
+	req := &WebhookEventRequest{
+		Platform:   "tiktok",
+		EventType:  notification.Event,
+		DeliveryID: "tiktok:" + bodyDigest(body),
+		Payload: map[string]interface{}{
+			"client_key":  notification.ClientKey,
+			"event":       notification.Event,
+			"create_time": notification.CreateTime,
+			"user_openid": notification.UserOpenID,
+			"content":     content,
+		},
+	}
+	for _, key := range []string{"video_id", "publish_id"} {
+		if id, ok := content[key].(string); ok && id != "" {
+			req.VideoID = id
+			break
+		}
+	}
+	return req, nil
+}
+
+// checkWebhookAge rejects timestamps older than maxAge, or more than the signed
+// tolerance in the future to allow for clock skew
+func checkWebhookAge(at time.Time, maxAge time.Duration) error {
+	age := time.Since(at)
+	if age > maxAge || age < -webhookSignedTolerance {
+		return ErrWebhookStale
+	}
+	return nil
+}
+
+// verifyHubSignature checks a "<algorithm>=<hex>" HMAC signature of the
+// body, as sent by PubSubHubbub hubs and Meta
+func verifyHubSignature(header, prefix string, newHash func() hash.Hash, secret string, body []byte) bool {
+	if !strings.HasPrefix(header, prefix) {
+		return false
+	}
+	expected, err := hex.DecodeString(strings.TrimPrefix(header, prefix))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// bodyDigest identifies a delivery by its body, for platforms that do not
+// send a delivery ID. Retries resend the same body.
+func bodyDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hubSignature signs a body as PubSubHubbub hubs and Meta do
+func hubSignature(prefix string, newHash func() hash.Hash, secret string, body []byte) string {
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return prefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// tiktokSignature signs a body as TikTok does
+func tiktokSignature(secret string, timestamp int64, body []byte) string {
+	t := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(body)
+	return "t=" + t + ",s=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyHubSignature(t *testing.T) {
+	body := []byte(`{"object":"page","entry":[]}`)
+
+	tests := []struct {
+		name    string
+		header  string
+		prefix  string
+		newHash func() hash.Hash
+		body    []byte
+		want    bool
+	}{
+		{
+			name:    "valid sha256",
+			header:  hubSignature("sha256=", sha256.New, "secret", body),
+			prefix:  "sha256=",
+			newHash: sha256.New,
+			body:    body,
+			want:    true,
+		},
+		{
+			name:    "valid sha1",
+			header:  hubSignature("sha1=", sha1.New, "secret", body),
+			prefix:  "sha1=",
+			newHash: sha1.New,
+			body:    body,
+			want:    true,
+		},
+		{
+			name:    "tampered body",
+			header:  hubSignature("sha256=", sha256.New, "secret", body),
+			prefix:  "sha256=",
+			newHash: sha256.New,
+			body:    []byte(`{"object":"page","entry":[{}]}`),
+		},
+		{
+			name:    "signed with another secret",
+			header:  hubSignature("sha256=", sha256.New, "other", body),
+			prefix:  "sha256=",
+			newHash: sha256.New,
+			body:    body,
+		},
+		{
+			name:    "sha1 signature where sha256 is required",
+			header:  hubSignature("sha1=", sha1.New, "secret", body),
+			prefix:  "sha256=",
+			newHash: sha256.New,
+			body:    body,
+		},
+		{
+			name:    "signature that is not hex",
+			header:  "sha256=not-hex",
+			prefix:  "sha256=",
+			newHash: sha256.New,
+			body:    body,
+		},
+		{
+			name:    "unsigned",
+			prefix:  "sha256=",
+			newHash: sha256.New,
+			body:    body,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := verifyHubSignature(tt.header, tt.prefix, tt.newHash, "secret", tt.body)
+			if got != tt.want {
+				t.Errorf("verifyHubSignature = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckWebhookAge(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		maxAge time.Duration
+		want   error
+	}{
+		{name: "just sent", at: now, maxAge: webhookSignedTolerance},
+		{name: "retried within the payload window", at: now.Add(-47 * time.Hour), maxAge: webhookMaxAge},
+		{name: "clock skew within tolerance", at: now.Add(time.Minute), maxAge: webhookSignedTolerance},
+		{name: "stale signed timestamp", at: now.Add(-6 * time.Minute), maxAge: webhookSignedTolerance, want: ErrWebhookStale},
+		{name: "replayed after the payload window", at: now.Add(-49 * time.Hour), maxAge: webhookMaxAge, want: ErrWebhookStale},
+		{name: "dated in the future", at: now.Add(10 * time.Minute), maxAge: webhookMaxAge, want: ErrWebhookStale},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkWebhookAge(tt.at, tt.maxAge); !errors.Is(err, tt.want) {
+				t.Errorf("checkWebhookAge = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyTikTok(t *testing.T) {
+	verifier := NewWebhookVerifier(WebhookSecrets{TikTok: "secret"})
+	body := []byte(`{"client_key":"key","event":"video.publish.complete","create_time":1700000000,` +
+		`"user_openid":"user","content":"{\"publish_id\":\"pub-1\"}"}`)
+	now := time.Now().Unix()
+
+	// A valid signature moved to another timestamp
+	signature := tiktokSignature("secret", now, body)
+	retimed := "t=" + strconv.FormatInt(now+1, 10) + signature[strings.Index(signature, ",s="):]
+
+	tests := []struct {
+		name      string
+		signature string
+		body      []byte
+		want      error
+	}{
+		{
+			name:      "valid",
+			signature: tiktokSignature("secret", now, body),
+			body:      body,
+		},
+		{
+			name:      "tampered body",
+			signature: tiktokSignature("secret", now, body),
+			body:      []byte(`{"client_key":"key","event":"video.publish.failed"}`),
+			want:      ErrWebhookSignature,
+		},
+		{
+			name:      "tampered timestamp",
+			signature: retimed,
+			body:      body,
+			want:      ErrWebhookSignature,
+		},
+		{
+			name:      "signed with another secret",
+			signature: tiktokSignature("other", now, body),
+			body:      body,
+			want:      ErrWebhookSignature,
+		},
+		{
+			name:      "stale",
+			signature: tiktokSignature("secret", now-int64((10*time.Minute).Seconds()), body),
+			body:      body,
+			want:      ErrWebhookStale,
+		},
+		{
+			name:      "replayed an hour later",
+			signature: tiktokSignature("secret", now-int64(time.Hour.Seconds()), body),
+			body:      body,
+			want:      ErrWebhookStale,
+		},
+		{
+			name: "unsigned",
+			body: body,
+			want: ErrWebhookSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.signature != "" {
+				header.Set("TikTok-Signature", tt.signature)
+			}
+
+			req, err := verifier.Verify("tiktok", header, tt.body)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify = %v, want %v", err, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			if req.VideoID != "pub-1" {
+				t.Errorf("VideoID = %q, want pub-1", req.VideoID)
+			}
+
+			// A replay inside the window has the same delivery ID, which
+			// the webhook store rejects as a duplicate
+			replayed, err := verifier.Verify("tiktok", header, tt.body)
+			if err != nil {
+				t.Fatalf("replayed Verify = %v", err)
+			}
+			if replayed.DeliveryID != req.DeliveryID {
+				t.Errorf("replayed delivery ID = %q, want %q", replayed.DeliveryID, req.DeliveryID)
+			}
+		})
+	}
+}
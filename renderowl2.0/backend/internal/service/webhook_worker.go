@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"renderowl-api/internal/domain"
+	socialdomain "renderowl-api/internal/domain/social"
+	"renderowl-api/internal/scheduler"
+	socialsvc "renderowl-api/internal/service/social"
+)
+
+// WebhookEventRepository stores webhook events and the analytics they
+// report
+type WebhookEventRepository interface {
+	ClaimWebhookEvents(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookEvent, error)
+	MarkWebhookEventProcessed(ctx context.Context, eventID string) error
+	RetryWebhookEvent(ctx context.Context, eventID, lastError string, next time.Time) error
+	DeadLetterWebhookEvent(ctx context.Context, eventID, lastError string) error
+	RecordHourlyViews(ctx context.Context, videoID, userID, platform string, count int64, at time.Time) error
+	AddEngagement(ctx context.Context, videoID, platform string, likes, comments, shares int64, date time.Time) error
+}
+
+// WebhookPostRepository finds the posts webhooks report on
+type WebhookPostRepository interface {
+	GetByRemoteID(ctx context.Context, platform socialdomain.SocialPlatform, remoteID string) (*socialdomain.ScheduledPost, error)
+}
+
+// errMalformedWebhook is returned by parsers for events that can never be
+// processed; they are dead lettered without being retried
+var errMalformedWebhook = errors.New("malformed webhook event")
+
+// Webhook processing. Each run drains the queue in batches. Failed events
+// are retried with exponential backoff until they have been attempted
+// webhookMaxAttempts times, then dead lettered.
+const (
+	webhookBatchSize    = 50
+	webhookMaxPerRun    = 1000
+	webhookLease        = 5 * time.Minute
+	webhookMaxAttempts  = 5
+	webhookRetryBackoff = time.Minute
+	webhookMaxBackoff   = time.Hour
+)
+
+// webhookSchedule runs the webhook worker every five minutes
+const webhookSchedule = "FREQ=HOURLY;BYMINUTE=0,5,10,15,20,25,30,35,40,45,50,55"
+
+// webhookUpdate is what a webhook event reports about a post: views and
+// engagement to add to its video
+type webhookUpdate struct {
+	RemoteID string // the platform's ID for the post
+	VideoID  string // our video ID, for events that carry it instead
+	Views    int64
+	Likes    int64
+	Comments int64
+	Shares   int64
+	At       time.Time
+}
+
+// webhookParser extracts the updates a webhook event reports. Events that
+// report no analytics return no updates.
+type webhookParser func(event *domain.WebhookEvent) ([]webhookUpdate, error)
+
+// webhookParsers parse the events of each platform. Events of other
+// platforms are parsed as generic view and engagement events.
+var webhookParsers = map[string]webhookParser{
+	"youtube":   parseYouTubeWebhook,
+	"facebook":  parseMetaWebhook,
+	"instagram": parseMetaWebhook,
+	"threads":   parseMetaWebhook,
+	"tiktok":    parseTikTokWebhook,
+}
+
+// WebhookWorker drains stored analytics webhook events into views and
+// engagement
+type WebhookWorker struct {
+	events        WebhookEventRepository
+	posts         WebhookPostRepository
+	socialService *socialsvc.Service
+	scheduler     *scheduler.Scheduler
+}
+
+// NewWebhookWorker creates a new webhook worker
+func NewWebhookWorker(events WebhookEventRepository, posts WebhookPostRepository, socialService *socialsvc.Service, scheduler *scheduler.Scheduler) *WebhookWorker {
+	return &WebhookWorker{
+		events:        events,
+		posts:         posts,
+		socialService: socialService,
+		scheduler:     scheduler,
+	}
+}
+
+// Initialize registers the webhook processing job, runs it once and
+// schedules it every five minutes
+func (w *WebhookWorker) Initialize(ctx context.Context) error {
+	return w.scheduler.ScheduleRecurring(ctx, "process_webhooks", &socialdomain.RecurringRule{
+		RRule: webhookSchedule,
+	}, w.handleProcessJob)
+}
+
+func (w *WebhookWorker) handleProcessJob(ctx context.Context, job *scheduler.Job) error {
+	processed, failed, err := w.ProcessWebhookEvents(ctx)
+	if err != nil {
+		return err
+	}
+
+	if processed > 0 || failed > 0 {
+		log.Printf("Webhook processing: %d events processed, %d failed", processed, failed)
+	}
+
+	return nil
+}
+
+// ProcessWebhookEvents processes pending webhook events until none are
+// due. It returns the number of events processed and of events that
+// failed.
+func (w *WebhookWorker) ProcessWebhookEvents(ctx context.Context) (int, int, error) {
+	processed, failed := 0, 0
+	for processed+failed < webhookMaxPerRun {
+		events, err := w.events.ClaimWebhookEvents(ctx, webhookBatchSize, webhookLease)
+		if err != nil {
+			return processed, failed, fmt.Errorf("failed to claim webhook events: %w", err)
+		}
+
+		for i := range events {
+			if err := w.processEvent(ctx, &events[i]); err != nil {
+				failed++
+				w.fail(ctx, &events[i], err)
+				continue
+			}
+			if err := w.events.MarkWebhookEventProcessed(ctx, events[i].ID); err != nil {
+				log.Printf("Failed to mark webhook event %s processed: %v", events[i].ID, err)
+			}
+			processed++
+		}
+
+		if len(events) < webhookBatchSize {
+			break
+		}
+	}
+
+	return processed, failed, nil
+}
+
+// processEvent applies the updates an event reports
+func (w *WebhookWorker) processEvent(ctx context.Context, event *domain.WebhookEvent) error {
+	parse, ok := webhookParsers[event.Platform]
+	if !ok {
+		parse = parseGenericWebhook
+	}
+	updates, err := parse(event)
+	if err != nil {
+		return err
+	}
+
+	for _, update := range updates {
+		if err := w.apply(ctx, event, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply adds an update to the views and engagement of its video. Updates
+// for posts that were not published through us are ignored. Views keep
+// the hour the platform reported them in.
+func (w *WebhookWorker) apply(ctx context.Context, event *domain.WebhookEvent, update webhookUpdate) error {
+	videoID, userID := update.VideoID, ""
+	if update.RemoteID != "" {
+		post, err := w.posts.GetByRemoteID(ctx, socialdomain.SocialPlatform(event.Platform), update.RemoteID)
+		if err != nil {
+			return fmt.Errorf("failed to find post %s: %w", update.RemoteID, err)
+		}
+		if post == nil || post.VideoID == "" {
+			return nil
+		}
+		videoID, userID = post.VideoID, post.UserID
+	}
+
+	at := update.At
+	if at.IsZero() {
+		at = event.CreatedAt
+	}
+
+	// The views of platforms whose analytics are polled are counted by the
+	// analytics sync, so they are not counted twice
+	if update.Views > 0 && !w.socialService.HasAnalytics(socialdomain.SocialPlatform(event.Platform)) {
+		if err := w.events.RecordHourlyViews(ctx, videoID, userID, event.Platform, update.Views, at); err != nil {
+			return fmt.Errorf("failed to record views: %w", err)
+		}
+	}
+	if update.Likes != 0 || update.Comments != 0 || update.Shares != 0 {
+		if err := w.events.AddEngagement(ctx, videoID, event.Platform, update.Likes, update.Comments, update.Shares, at); err != nil {
+			return fmt.Errorf("failed to record engagement: %w", err)
+		}
+	}
+	return nil
+}
+
+// fail schedules a failed event for another attempt with exponential
+// backoff, or dead letters it once it is out of attempts or malformed
+func (w *WebhookWorker) fail(ctx context.Context, event *domain.WebhookEvent, cause error) {
+	if errors.Is(cause, errMalformedWebhook) || event.Attempts >= webhookMaxAttempts {
+		log.Printf("Webhook event %s moved to dead letter after %d attempts: %v", event.ID, event.Attempts, cause)
+		if err := w.events.DeadLetterWebhookEvent(ctx, event.ID, cause.Error()); err != nil {
+			log.Printf("Failed to dead letter webhook event %s: %v", event.ID, err)
+		}
+		return
+	}
+
+	backoff := webhookRetryBackoff << (event.Attempts - 1)
+	if backoff > webhookMaxBackoff {
+		backoff = webhookMaxBackoff
+	}
+	if err := w.events.RetryWebhookEvent(ctx, event.ID, cause.Error(), time.Now().UTC().Add(backoff)); err != nil {
+		log.Printf("Failed to reschedule webhook event %s: %v", event.ID, err)
+	}
+}
+
+// parseYouTubeWebhook parses PubSubHubbub notifications, which announce
+// uploads, edits and deletions but carry no analytics
+func parseYouTubeWebhook(event *domain.WebhookEvent) ([]webhookUpdate, error) {
+	switch event.EventType {
+	case "video_updated", "video_deleted":
+		return nil, nil
+	}
+	return parseGenericWebhook(event)
+}
+
+// parseTikTokWebhook parses TikTok events, which report publishing and
+// authorization changes but carry no analytics
+func parseTikTokWebhook(event *domain.WebhookEvent) ([]webhookUpdate, error) {
+	if _, ok := event.Payload["event"]; ok {
+		return nil, nil
+	}
+	return parseGenericWebhook(event)
+}
+
+// parseMetaWebhook parses Facebook, Instagram and Threads notifications.
+// Comments, replies, reactions and shares are counted one at a time, and
+// story insights report the impressions of a story once it expires.
+func parseMetaWebhook(event *domain.WebhookEvent) ([]webhookUpdate, error) {
+	entries, ok := event.Payload["entry"].([]interface{})
+	if !ok {
+		return parseGenericWebhook(event)
+	}
+
+	var updates []webhookUpdate
+	for _, e := range entries {
+		entry, _ := e.(map[string]interface{})
+		at := metaTime(payloadInt(entry, "time"))
+
+		changes, _ := entry["changes"].([]interface{})
+		for _, c := range changes {
+			change, _ := c.(map[string]interface{})
+			value, _ := change["value"].(map[string]interface{})
+			if value == nil {
+				continue
+			}
+
+			update := webhookUpdate{At: at}
+			switch payloadString(change, "field") {
+			case "comments":
+				// Instagram comments name their media
+				media, _ := value["media"].(map[string]interface{})
+				update.RemoteID = payloadString(media, "id")
+				update.Comments = 1
+			case "replies":
+				// Threads replies name the post their thread started on
+				root, _ := value["root_post"].(map[string]interface{})
+				update.RemoteID = payloadString(root, "id")
+				update.Comments = 1
+			case "feed":
+				// Facebook page feed changes add or remove one item
+				update.RemoteID = payloadString(value, "post_id")
+				delta := int64(1)
+				if payloadString(value, "verb") == "remove" {
+					delta = -1
+				}
+				switch payloadString(value, "item") {
+				case "comment":
+					update.Comments = delta
+				case "reaction", "like":
+					update.Likes = delta
+				case "share":
+					update.Shares = delta
+				default:
+					continue
+				}
+			case "story_insights":
+				update.RemoteID = payloadString(value, "media_id")
+				update.Views = payloadInt(value, "impressions")
+			default:
+				continue
+			}
+
+			if update.RemoteID == "" {
+				return nil, fmt.Errorf("%w: %s change without a post ID", errMalformedWebhook, payloadString(change, "field"))
+			}
+			updates = append(updates, update)
+		}
+	}
+	return updates, nil
+}
+
+// metaTime converts an entry time, in seconds or for some objects in
+// milliseconds
+func metaTime(t int64) time.Time {
+	if t == 0 {
+		return time.Time{}
+	}
+	if t > 1e12 {
+		return time.UnixMilli(t)
+	}
+	return time.Unix(t, 0)
+}
+
+// parseGenericWebhook parses "view" events, which add count views (one if
+// not given), and "engagement" events, which add likes, comments and
+// shares, to the video_id of the payload. Other events are ignored.
+func parseGenericWebhook(event *domain.WebhookEvent) ([]webhookUpdate, error) {
+	if event.EventType != "view" && event.EventType != "engagement" {
+		return nil, nil
+	}
+
+	videoID := payloadString(event.Payload, "video_id")
+	if videoID == "" {
+		videoID = event.VideoID
+	}
+	if videoID == "" {
+		return nil, fmt.Errorf("%w: %s event without a video_id", errMalformedWebhook, event.EventType)
+	}
+
+	update := webhookUpdate{VideoID: videoID}
+	if event.EventType == "view" {
+		update.Views = 1
+		if _, ok := event.Payload["count"]; ok {
+			update.Views = payloadInt(event.Payload, "count")
+		}
+		if update.Views <= 0 {
+			return nil, fmt.Errorf("%w: invalid view count", errMalformedWebhook)
+		}
+	} else {
+		update.Likes = payloadInt(event.Payload, "likes")
+		update.Comments = payloadInt(event.Payload, "comments")
+		update.Shares = payloadInt(event.Payload, "shares")
+	}
+	return []webhookUpdate{update}, nil
+}
+
+// payloadString reads a string from a JSON object
+func payloadString(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// payloadInt reads a number from a JSON object, which may be encoded as a
+// string
+func payloadInt(m map[string]interface{}, key string) int64 {
+	switch v := m[key].(type) {
+	case float64:
+		return int64(v)
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	}
+	return 0
+}